@@ -0,0 +1,239 @@
+package ddotel
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/exporters/datadog"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/processors/spansampling"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/samplers/ddsampler"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// _____________________ With option functions _____________________
+
+// WithService sets the service, taking precedence over DD_SERVICE environment variable.
+func WithService(value string) configFn {
+	return func(conf *config) {
+		conf.service = value
+	}
+}
+
+// WithEnv sets the environment, taking precedence over DD_ENV environment variable.
+func WithEnv(value string) configFn {
+	return func(conf *config) {
+		conf.env = value
+	}
+}
+
+// WithVersion sets the version, taking precedence over DD_VERSION environment variable.
+func WithVersion(value string) configFn {
+	return func(conf *config) {
+		conf.version = value
+	}
+}
+
+// WithExporter sets the span exporter.
+// It defaults to a Datadog exporter configured from environment variables.
+func WithExporter(value sdktrace.SpanExporter) configFn {
+	return func(conf *config) {
+		conf.exporter = value
+	}
+}
+
+// WithSampler sets the sampler.
+// It defaults to a parent based sampler keeping the DD_TRACE_SAMPLE_RATE ratio
+// of root traces, or applying the agent sample rates when not set.
+func WithSampler(value sdktrace.Sampler) configFn {
+	return func(conf *config) {
+		conf.sampler = value
+	}
+}
+
+// WithPropagator sets the global propagator.
+// It defaults to the Datadog trace context propagator.
+func WithPropagator(value propagation.TextMapPropagator) configFn {
+	return func(conf *config) {
+		conf.propagator = value
+	}
+}
+
+// WithShutdownTimeout sets the maximum duration of Stop, when its context has no deadline.
+// It defaults to DefaultShutdownTimeout.
+func WithShutdownTimeout(value time.Duration) configFn {
+	return func(conf *config) {
+		conf.shutdownTimeout = value
+	}
+}
+
+// WithStartupLogs enables the report of the configuration, logged as a JSON line
+// when tracing starts.
+// It defaults to DD_TRACE_STARTUP_LOGS environment variable or true.
+func WithStartupLogs(enabled bool) configFn {
+	return func(conf *config) {
+		conf.startupLogs = &enabled
+	}
+}
+
+// WithLogger sets the logger of the startup report.
+// It defaults to the standard logger.
+func WithLogger(value *log.Logger) configFn {
+	return func(conf *config) {
+		conf.logger = value
+	}
+}
+
+// _____________________ Definition _____________________
+
+type configFn func(*config)
+
+// ErrInvalidSampleRate is returned when DD_TRACE_SAMPLE_RATE is not in [0, 1].
+var ErrInvalidSampleRate = ddsampler.ErrInvalidSampleRate
+
+// Ref https://docs.datadoghq.com/tracing/trace_collection/library_config/go/
+
+const (
+	envTraceEnabled = "DD_TRACE_ENABLED"
+	envStartupLogs  = "DD_TRACE_STARTUP_LOGS"
+)
+
+const (
+	// DefaultShutdownTimeout specifies the maximum duration of Stop.
+	DefaultShutdownTimeout = 5 * time.Second
+)
+
+// _____________________ Configuration _____________________
+
+func newConfig(cfg ...configFn) (*config, error) {
+	var conf = &config{}
+
+	// Apply configurations
+	for _, v := range cfg {
+		if v != nil {
+			v(conf)
+		}
+	}
+
+	// Apply default value on empty
+	if err := conf.applyDefault(); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+type config struct {
+	enabled         bool
+	service         string
+	env             string
+	version         string
+	exporter        sdktrace.SpanExporter
+	sampler         sdktrace.Sampler
+	rulesSampler    *ddsampler.RulesSampler // default sampler, updated by the exporter
+	propagator      propagation.TextMapPropagator
+	shutdownTimeout time.Duration
+	startupLogs     *bool
+	logger          *log.Logger
+}
+
+func (obj *config) applyDefault() error {
+	// Tracing enabled unless disabled by environment
+	obj.enabled = true
+	if enabled, err := strconv.ParseBool(os.Getenv(envTraceEnabled)); err == nil {
+		obj.enabled = enabled
+	}
+
+	// Set default sampler, following upstream decisions then applying sampling rules and agent rates
+	if obj.sampler == nil {
+		sampler, err := ddsampler.NewRulesSampler(ddsampler.WithService(obj.service), ddsampler.WithEnv(obj.env))
+		if err != nil {
+			return err
+		}
+		parentBased, err := ddsampler.NewParentBasedSampler(sampler)
+		if err != nil {
+			return err
+		}
+		obj.rulesSampler = sampler
+		obj.sampler = parentBased
+	}
+
+	// Set default propagator
+	if obj.propagator == nil {
+		obj.propagator = tracecontext.NewDefault()
+	}
+
+	if obj.shutdownTimeout <= 0 {
+		obj.shutdownTimeout = DefaultShutdownTimeout
+	}
+
+	// Set default startup report
+	if obj.startupLogs == nil {
+		var enabled = true
+		if value, err := strconv.ParseBool(os.Getenv(envStartupLogs)); err == nil {
+			enabled = value
+		}
+		obj.startupLogs = &enabled
+	}
+	if obj.logger == nil {
+		obj.logger = log.Default()
+	}
+	return nil
+}
+
+// newExporter returns the configured exporter, or a Datadog exporter updating
+// the rates of the default sampler.
+func (obj *config) newExporter() (sdktrace.SpanExporter, error) {
+	if obj.exporter != nil {
+		return obj.exporter, nil
+	}
+	if obj.rulesSampler != nil {
+		return datadog.New(datadog.WithRateByServiceHandler(obj.rulesSampler.UpdateRates))
+	}
+	return datadog.New()
+}
+
+// newSpanProcessors returns the span processors and the sampler: a batch span
+// processor exporting with exporter, wrapped by the span sampling processor
+// with span sampling rules (DD_SPAN_SAMPLING_RULES), and the stats processor
+// of a Datadog exporter computing client-side stats. Spans of dropped traces
+// are then recorded, to be kept by rules and counted in stats. Rules are read
+// before the batch span processor is started, the exporter being shut down by
+// the caller on error.
+func (obj *config) newSpanProcessors(exporter sdktrace.SpanExporter) ([]sdktrace.SpanProcessor, sdktrace.Sampler, error) {
+	rules, err := spansampling.ParseRules()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		processors    []sdktrace.SpanProcessor
+		recordDropped bool
+	)
+	if ddExporter, ok := exporter.(*datadog.Exporter); ok && ddExporter.StatsComputation() {
+		processors = append(processors, ddExporter.StatsProcessor())
+		recordDropped = true
+	}
+
+	var batcher = sdktrace.NewBatchSpanProcessor(exporter)
+	if len(rules) == 0 {
+		processors = append(processors, batcher)
+	} else {
+		processor, err := spansampling.New(batcher, spansampling.WithRules(rules))
+		if err != nil {
+			_ = batcher.Shutdown(context.Background())
+			return nil, nil, err
+		}
+		processors = append(processors, processor)
+		recordDropped = true
+	}
+
+	if recordDropped {
+		return processors, spansampling.NewSampler(obj.sampler), nil
+	}
+	return processors, obj.sampler, nil
+}
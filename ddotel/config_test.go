@@ -0,0 +1,84 @@
+package ddotel
+
+import (
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/samplers/ddsampler"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func setenv(t *testing.T, key, value string) {
+	prev, exists := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if exists {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func Test_Config_NewConfig(t *testing.T) {
+	assert := assert.New(t)
+	setenv(t, envTraceEnabled, "")
+	setenv(t, "DD_TRACE_SAMPLE_RATE", "")
+	setenv(t, envStartupLogs, "")
+
+	// Check default values applied
+	if conf, err := newConfig(); assert.NoError(err) {
+		assert.True(conf.enabled)
+		assert.NotNil(conf.rulesSampler)
+		assert.Equal("DatadogParentBased{root:DatadogRulesSampler}", conf.sampler.Description())
+		assert.Equal(tracecontext.NewDefault(), conf.propagator)
+		assert.Equal(DefaultShutdownTimeout, conf.shutdownTimeout)
+		assert.True(*conf.startupLogs)
+		assert.Equal(log.Default(), conf.logger)
+	}
+
+	// Check options applied
+	var propagator = propagation.TraceContext{}
+	if conf, err := newConfig(WithSampler(sdktrace.NeverSample()), WithPropagator(propagator), WithShutdownTimeout(time.Second),
+		WithService("s"), WithEnv("e"), WithVersion("v")); assert.NoError(err) {
+		assert.Equal(sdktrace.NeverSample(), conf.sampler)
+		assert.Equal(propagator, conf.propagator)
+		assert.Equal(time.Second, conf.shutdownTimeout)
+		assert.Equal([]string{"s", "e", "v"}, []string{conf.service, conf.env, conf.version})
+	}
+}
+
+func Test_Config_Env(t *testing.T) {
+	assert := assert.New(t)
+	setenv(t, envTraceEnabled, "false")
+	setenv(t, "DD_TRACE_SAMPLE_RATE", "0.25")
+
+	if conf, err := newConfig(); assert.NoError(err) {
+		assert.False(conf.enabled)
+		if assert.NotNil(conf.rulesSampler) {
+			assert.Equal([]float64{0.25}, []float64{conf.rulesSampler.Rules()[0].SampleRate})
+		}
+	}
+
+	// Check invalid sample rate
+	for _, value := range []string{"abc", "-1", "1.5"} {
+		setenv(t, "DD_TRACE_SAMPLE_RATE", value)
+		_, err := newConfig()
+		assert.ErrorIs(err, ErrInvalidSampleRate, value)
+	}
+
+	// Check invalid sampling rules
+	setenv(t, "DD_TRACE_SAMPLE_RATE", "")
+	setenv(t, "DD_TRACE_SAMPLING_RULES", "[")
+	_, err := newConfig()
+	assert.ErrorIs(err, ddsampler.ErrInvalidSamplingRules)
+
+	// Sampler option takes precedence
+	_, err = newConfig(WithSampler(sdktrace.AlwaysSample()))
+	assert.NoError(err)
+}
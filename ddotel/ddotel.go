@@ -0,0 +1,129 @@
+// Package ddotel sets up OpenTelemetry tracing for Datadog in one call: a tracer
+// provider exporting to the Datadog agent, with unified service tags, sampler
+// and Datadog propagator, configured from DD_* environment variables.
+package ddotel
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/detectors/unifiedtagging"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ErrAlreadyStarted = errors.New("tracing already started")
+
+var (
+	mu       sync.Mutex
+	provider *sdktrace.TracerProvider
+	conf     *config
+
+	prevPropagator propagation.TextMapPropagator // restored by Stop
+)
+
+// Start builds a tracer provider and registers it, with the propagator, as
+// OpenTelemetry globals. Nothing is registered when DD_TRACE_ENABLED is false.
+// Stop must be called before the application exits to flush spans.
+func Start(cfg ...configFn) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if provider != nil {
+		return ErrAlreadyStarted
+	}
+
+	startConf, err := newConfig(cfg...)
+	if err != nil {
+		return err
+	}
+	if !startConf.enabled {
+		return nil
+	}
+
+	res, err := resource.New(context.Background(),
+		resource.WithTelemetrySDK(),
+		resource.WithDetectors(unifiedtagging.New(
+			unifiedtagging.WithService(startConf.service),
+			unifiedtagging.WithEnv(startConf.env),
+			unifiedtagging.WithVersion(startConf.version),
+		)),
+	)
+	if err != nil {
+		// Partial resource is kept, invalid attributes being reported
+		otel.Handle(err)
+	}
+	if res == nil {
+		res = resource.Empty()
+	}
+
+	exporter, err := startConf.newExporter()
+	if err != nil {
+		return err
+	}
+	processors, sampler, err := startConf.newSpanProcessors(exporter)
+	if err != nil {
+		var ctx, cancel = context.WithTimeout(context.Background(), startConf.shutdownTimeout)
+		defer cancel()
+		if shutdownErr := exporter.Shutdown(ctx); shutdownErr != nil {
+			otel.Handle(shutdownErr)
+		}
+		return err
+	}
+
+	var opts = []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sampler),
+	}
+	for _, processor := range processors {
+		opts = append(opts, sdktrace.WithSpanProcessor(processor))
+	}
+	provider = sdktrace.NewTracerProvider(opts...)
+	conf = startConf
+	otel.SetTracerProvider(provider)
+	prevPropagator = otel.GetTextMapPropagator()
+	if len(prevPropagator.Fields()) == 0 {
+		// Default global propagator, delegating to the next one set: no-op until then
+		prevPropagator = propagation.NewCompositeTextMapPropagator()
+	}
+	otel.SetTextMapPropagator(conf.propagator)
+
+	if *conf.startupLogs {
+		logStartupReport(conf, newStartupReport(context.Background(), conf, res, exporter))
+	}
+	return nil
+}
+
+// Stop flushes the spans and shuts down the tracer provider started by Start,
+// within the context deadline or the shutdown timeout. The global tracer
+// provider is replaced by a no-op one, and the global propagator registered
+// before Start is restored.
+func Stop(ctx context.Context) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if provider == nil {
+		return nil
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, conf.shutdownTimeout)
+		defer cancel()
+	}
+
+	var err = provider.Shutdown(ctx)
+	otel.SetTracerProvider(trace.NewNoopTracerProvider())
+	otel.SetTextMapPropagator(prevPropagator)
+	provider, conf, prevPropagator = nil, nil, nil
+	return err
+}
+
+// TracerProvider returns the tracer provider started by Start, nil if not started.
+func TracerProvider() *sdktrace.TracerProvider {
+	mu.Lock()
+	defer mu.Unlock()
+	return provider
+}
@@ -0,0 +1,175 @@
+package ddotel
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/processors/spansampling"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// testExporter keeps exported spans after shutdown.
+type testExporter struct {
+	*tracetest.InMemoryExporter
+}
+
+func (obj testExporter) Shutdown(context.Context) error {
+	return nil
+}
+
+func Test_Start(t *testing.T) {
+	setenv(t, envTraceEnabled, "")
+	setenv(t, "DD_TRACE_SAMPLE_RATE", "")
+	var exporter = testExporter{tracetest.NewInMemoryExporter()}
+	otel.SetTextMapPropagator(propagation.Baggage{})
+
+	require.NoError(t, Start(WithExporter(exporter), WithService("test-service"), WithEnv("test")))
+	assert.ErrorIs(t, Start(), ErrAlreadyStarted)
+	assert.NotNil(t, TracerProvider())
+	assert.Equal(t, tracecontext.NewDefault(), otel.GetTextMapPropagator())
+
+	_, span := otel.Tracer("test").Start(context.Background(), "operation")
+	span.End()
+
+	// Spans flushed on stop
+	require.NoError(t, Stop(context.Background()))
+	if spans := exporter.GetSpans(); assert.Len(t, spans, 1) {
+		assert.Equal(t, "operation", spans[0].Name)
+		assert.Contains(t, spans[0].Resource.Attributes(), semconv.ServiceNameKey.String("test-service"))
+		assert.Contains(t, spans[0].Resource.Attributes(), semconv.DeploymentEnvironmentKey.String("test"))
+	}
+	assert.Nil(t, TracerProvider())
+	assert.IsType(t, trace.NewNoopTracerProvider(), otel.GetTracerProvider())
+	assert.Equal(t, propagation.Baggage{}, otel.GetTextMapPropagator())
+
+	// Stop without start
+	assert.NoError(t, Stop(context.Background()))
+}
+
+func Test_Start_Disabled(t *testing.T) {
+	setenv(t, envTraceEnabled, "false")
+	require.NoError(t, Start())
+	assert.Nil(t, TracerProvider())
+	assert.NoError(t, Stop(context.Background()))
+}
+
+func Test_Start_InvalidConfig(t *testing.T) {
+	setenv(t, envTraceEnabled, "")
+	setenv(t, "DD_TRACE_SAMPLE_RATE", "2")
+	assert.ErrorIs(t, Start(), ErrInvalidSampleRate)
+	assert.Nil(t, TracerProvider())
+}
+
+func Test_Start_SpanSampling(t *testing.T) {
+	setenv(t, envTraceEnabled, "")
+	setenv(t, "DD_SPAN_SAMPLING_RULES", `[{"name": "internal"}]`)
+	var exporter = testExporter{tracetest.NewInMemoryExporter()}
+
+	require.NoError(t, Start(WithExporter(exporter), WithSampler(sdktrace.NeverSample())))
+	ctx, root := otel.Tracer("test").Start(context.Background(), "root", trace.WithSpanKind(trace.SpanKindServer))
+	_, span := otel.Tracer("test").Start(ctx, "operation")
+	span.End()
+	root.End()
+	require.NoError(t, Stop(context.Background()))
+
+	// Span of the dropped trace kept by the rule
+	if spans := exporter.GetSpans(); assert.Len(t, spans, 1) {
+		assert.Equal(t, "operation", spans[0].Name)
+		assert.Contains(t, spans[0].Attributes, spansampling.AttributeMechanism.Int64(8))
+	}
+
+	// Invalid rules, exporter shut down
+	setenv(t, "DD_SPAN_SAMPLING_RULES", "[")
+	var failed = &test_shutdownExporter{}
+	assert.ErrorIs(t, Start(WithExporter(failed)), spansampling.ErrInvalidRules)
+	assert.Nil(t, TracerProvider())
+	assert.Equal(t, 1, failed.shutdowns)
+}
+
+// test_shutdownExporter counts its shutdowns.
+type test_shutdownExporter struct {
+	shutdowns int
+}
+
+func (obj *test_shutdownExporter) ExportSpans(context.Context, []sdktrace.ReadOnlySpan) error {
+	return nil
+}
+
+func (obj *test_shutdownExporter) Shutdown(context.Context) error {
+	obj.shutdowns++
+	return nil
+}
+
+func Test_Start_Agent(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths []string
+		agent = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = ioutil.ReadAll(r.Body)
+			mu.Lock()
+			paths = append(paths, r.URL.Path)
+			mu.Unlock()
+			if r.URL.Path == "/info" {
+				_, _ = w.Write([]byte(`{"endpoints":["/v0.4/traces"]}`))
+			}
+		}))
+	)
+	defer agent.Close()
+	setenv(t, envTraceEnabled, "")
+	setenv(t, "DD_TRACE_SAMPLE_RATE", "")
+	setenv(t, "DD_TRACE_AGENT_URL", agent.URL)
+
+	require.NoError(t, Start())
+	_, span := otel.Tracer("test").Start(context.Background(), "operation")
+	span.End()
+	require.NoError(t, Stop(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, paths, "/v0.4/traces")
+}
+
+func Test_Start_StatsComputation(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		paths = map[string]bool{}
+		agent = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = ioutil.ReadAll(r.Body)
+			mu.Lock()
+			paths[r.URL.Path] = true
+			mu.Unlock()
+			if r.URL.Path == "/info" {
+				_, _ = w.Write([]byte(`{"endpoints":["/v0.4/traces","/v0.6/stats"],"client_drop_p0s":true}`))
+			}
+		}))
+	)
+	defer agent.Close()
+	setenv(t, envTraceEnabled, "")
+	setenv(t, "DD_TRACE_SAMPLE_RATE", "0")
+	setenv(t, "DD_TRACE_AGENT_URL", agent.URL)
+	setenv(t, "DD_TRACE_STATS_COMPUTATION_ENABLED", "true")
+
+	// Rejected trace counted in stats, without being sent
+	require.NoError(t, Start(WithStartupLogs(false)))
+	_, span := otel.Tracer("test").Start(context.Background(), "operation", trace.WithSpanKind(trace.SpanKindServer))
+	span.End()
+	assert.False(t, span.SpanContext().IsSampled())
+	require.NoError(t, Stop(context.Background()))
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Contains(t, paths, "/v0.6/stats")
+	assert.NotContains(t, paths, "/v0.4/traces")
+}
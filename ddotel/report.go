@@ -0,0 +1,133 @@
+package ddotel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"runtime"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/version"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/samplers/ddsampler"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/log.go
+
+const (
+	startupReportPrefix = "DATADOG TRACER CONFIGURATION "
+
+	// startupCheckTimeout is the maximum duration of the agent connectivity check
+	startupCheckTimeout = 2 * time.Second
+
+	redacted = "<redacted>"
+)
+
+// expSecretKey matches tag keys holding secrets
+var expSecretKey = regexp.MustCompile(`(?i)(api[-_.]?key|app[-_.]?key|token|secret|passw(or)?d|credential|auth)`)
+
+// endpointExporter is implemented by exporters reporting their endpoint, as the
+// Datadog exporter does.
+type endpointExporter interface {
+	Endpoint() string
+	CheckConnectivity(ctx context.Context) error
+}
+
+// startupReport is the configuration logged when tracing starts.
+type startupReport struct {
+	Date                 string                   `json:"date"`
+	OSName               string                   `json:"os_name"`
+	Version              string                   `json:"version"`
+	Lang                 string                   `json:"lang"`
+	LangVersion          string                   `json:"lang_version"`
+	Env                  string                   `json:"env"`
+	Service              string                   `json:"service"`
+	AppVersion           string                   `json:"app_version"`
+	AgentURL             string                   `json:"agent_url"`
+	AgentError           string                   `json:"agent_error,omitempty"`
+	Exporter             string                   `json:"exporter"`
+	PropagationStyle     string                   `json:"propagation_style"`
+	HeaderKeys           map[string]string        `json:"header_keys,omitempty"`
+	HeaderValueConverter string                   `json:"header_value_converter,omitempty"`
+	Sampler              string                   `json:"sampler"`
+	SamplingRules        []ddsampler.SamplingRule `json:"sampling_rules,omitempty"`
+	Tags                 map[string]string        `json:"tags"`
+}
+
+// newStartupReport returns the report of the configuration, checking the agent
+// connectivity. Values of secret tags are redacted.
+func newStartupReport(ctx context.Context, conf *config, res *resource.Resource, exporter sdktrace.SpanExporter) startupReport {
+	var report = startupReport{
+		Date:        time.Now().Format(time.RFC3339),
+		OSName:      runtime.GOOS,
+		Version:     version.Tag,
+		Lang:        "Go",
+		LangVersion: runtime.Version(),
+		Exporter:    fmt.Sprintf("%T", exporter),
+		Sampler:     conf.sampler.Description(),
+		Tags:        map[string]string{},
+	}
+	if conf.rulesSampler != nil {
+		report.SamplingRules = conf.rulesSampler.Rules()
+	}
+
+	// Unified service tags and tags
+	for _, kv := range res.Attributes() {
+		switch kv.Key {
+		case semconv.ServiceNameKey:
+			report.Service = kv.Value.Emit()
+		case semconv.DeploymentEnvironmentKey:
+			report.Env = kv.Value.Emit()
+		case semconv.ServiceVersionKey:
+			report.AppVersion = kv.Value.Emit()
+		default:
+			report.Tags[string(kv.Key)] = redactTag(kv)
+		}
+	}
+
+	// Agent
+	if e, ok := exporter.(endpointExporter); ok {
+		report.AgentURL = e.Endpoint()
+		ctx, cancel := context.WithTimeout(ctx, startupCheckTimeout)
+		defer cancel()
+		if err := e.CheckConnectivity(ctx); err != nil {
+			report.AgentError = err.Error()
+		}
+	}
+
+	// Propagation
+	if headerKey, converter, ok := tracecontext.Describe(conf.propagator); ok {
+		report.PropagationStyle = "datadog"
+		report.HeaderKeys = map[string]string{
+			"trace_id":          headerKey.TraceID,
+			"parent_id":         headerKey.ParentID,
+			"sampling_priority": headerKey.SampledPriority,
+		}
+		report.HeaderValueConverter = converter
+	} else {
+		report.PropagationStyle = fmt.Sprintf("%T", conf.propagator)
+	}
+
+	return report
+}
+
+func redactTag(kv attribute.KeyValue) string {
+	if expSecretKey.MatchString(string(kv.Key)) {
+		return redacted
+	}
+	return kv.Value.Emit()
+}
+
+// logStartupReport logs the report as a single JSON line.
+func logStartupReport(conf *config, report startupReport) {
+	b, err := json.Marshal(report)
+	if err != nil {
+		return
+	}
+	conf.logger.Print(startupReportPrefix + string(b))
+}
@@ -0,0 +1,116 @@
+package ddotel
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/exporters/datadog"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/version"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+func Test_newStartupReport(t *testing.T) {
+	var agent = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer agent.Close()
+
+	exporter, err := datadog.New(datadog.WithAgentURL(agent.URL))
+	require.NoError(t, err)
+	prop, err := tracecontext.New(tracecontext.WithHeaderKey(tracecontext.HeaderKey{TraceID: "a", ParentID: "b", SampledPriority: "c"}))
+	require.NoError(t, err)
+	setenv(t, "DD_TRACE_SAMPLING_RULES", `[{"service": "api", "sample_rate": 0.5}]`)
+	setenv(t, "DD_TRACE_SAMPLE_RATE", "")
+	conf, err := newConfig(WithPropagator(prop))
+	require.NoError(t, err)
+	var res = resource.NewSchemaless(
+		semconv.ServiceNameKey.String("service"),
+		semconv.DeploymentEnvironmentKey.String("prod"),
+		semconv.ServiceVersionKey.String("1.0.0"),
+		attribute.String("team", "dd"),
+		attribute.String("dd.api_key", "secret-value"),
+		attribute.String("auth.token", "secret-value"),
+	)
+
+	var report = newStartupReport(context.Background(), conf, res, exporter)
+	assert.Equal(t, runtime.GOOS, report.OSName)
+	assert.Equal(t, version.Tag, report.Version)
+	assert.Equal(t, runtime.Version(), report.LangVersion)
+	assert.Equal(t, []string{"service", "prod", "1.0.0"}, []string{report.Service, report.Env, report.AppVersion})
+	assert.Equal(t, agent.URL, report.AgentURL)
+	assert.Empty(t, report.AgentError)
+	assert.Equal(t, "*datadog.Exporter", report.Exporter)
+	assert.Equal(t, "datadog", report.PropagationStyle)
+	assert.Equal(t, map[string]string{"trace_id": "a", "parent_id": "b", "sampling_priority": "c"}, report.HeaderKeys)
+	assert.Equal(t, "binary", report.HeaderValueConverter)
+	assert.Equal(t, conf.sampler.Description(), report.Sampler)
+	if assert.Len(t, report.SamplingRules, 1) {
+		assert.Equal(t, "api", report.SamplingRules[0].Service)
+	}
+	assert.Equal(t, map[string]string{"team": "dd", "dd.api_key": redacted, "auth.token": redacted}, report.Tags)
+
+	// Agent unreachable, other exporter and propagator
+	agent.Close()
+	report = newStartupReport(context.Background(), conf, res, exporter)
+	assert.NotEmpty(t, report.AgentError)
+
+	conf, err = newConfig(WithPropagator(propagation.TraceContext{}), WithSampler(sdktrace.AlwaysSample()))
+	require.NoError(t, err)
+	report = newStartupReport(context.Background(), conf, resource.Empty(), tracetest.NewInMemoryExporter())
+	assert.Empty(t, report.AgentURL)
+	assert.Equal(t, "*tracetest.InMemoryExporter", report.Exporter)
+	assert.Equal(t, "propagation.TraceContext", report.PropagationStyle)
+	assert.Nil(t, report.HeaderKeys)
+	assert.Nil(t, report.SamplingRules)
+}
+
+func Test_logStartupReport(t *testing.T) {
+	var buf bytes.Buffer
+	conf, err := newConfig(WithLogger(log.New(&buf, "", 0)))
+	require.NoError(t, err)
+
+	logStartupReport(conf, startupReport{Service: "service", Tags: map[string]string{}})
+	var line = buf.String()
+	assert.True(t, strings.HasPrefix(line, startupReportPrefix))
+	assert.Equal(t, 1, strings.Count(line, "\n"))
+
+	var report startupReport
+	assert.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, startupReportPrefix)), &report))
+	assert.Equal(t, "service", report.Service)
+}
+
+func Test_Start_StartupLogs(t *testing.T) {
+	setenv(t, envTraceEnabled, "")
+	setenv(t, "DD_TRACE_SAMPLE_RATE", "")
+	setenv(t, envStartupLogs, "")
+	var buf bytes.Buffer
+
+	// Logged by default
+	require.NoError(t, Start(WithExporter(tracetest.NewInMemoryExporter()), WithLogger(log.New(&buf, "", 0))))
+	require.NoError(t, Stop(context.Background()))
+	assert.Contains(t, buf.String(), startupReportPrefix)
+
+	// Disabled by option and environment
+	buf.Reset()
+	require.NoError(t, Start(WithExporter(tracetest.NewInMemoryExporter()), WithLogger(log.New(&buf, "", 0)), WithStartupLogs(false)))
+	require.NoError(t, Stop(context.Background()))
+	setenv(t, envStartupLogs, "false")
+	require.NoError(t, Start(WithExporter(tracetest.NewInMemoryExporter()), WithLogger(log.New(&buf, "", 0))))
+	require.NoError(t, Stop(context.Background()))
+	assert.Empty(t, buf.String())
+}
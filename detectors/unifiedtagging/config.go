@@ -0,0 +1,73 @@
+package unifiedtagging
+
+// _____________________ With option functions _____________________
+
+// WithService sets the service, taking precedence over environment variables.
+func WithService(value string) configFn {
+	return func(conf *config) {
+		conf.service = value
+	}
+}
+
+// WithEnv sets the environment, taking precedence over environment variables.
+func WithEnv(value string) configFn {
+	return func(conf *config) {
+		conf.env = value
+	}
+}
+
+// WithVersion sets the version, taking precedence over environment variables.
+func WithVersion(value string) configFn {
+	return func(conf *config) {
+		conf.version = value
+	}
+}
+
+// WithTags sets tags, taking precedence over environment variables. The
+// "service", "env" and "version" tags are mapped to unified service tags.
+func WithTags(tags map[string]string) configFn {
+	return func(conf *config) {
+		for k, v := range tags {
+			conf.tags[k] = v
+		}
+	}
+}
+
+// _____________________ Definition _____________________
+
+type configFn func(*config)
+
+// Ref https://docs.datadoghq.com/getting_started/tagging/unified_service_tagging/
+// Ref https://opentelemetry.io/docs/specs/otel/configuration/sdk-environment-variables/
+
+const (
+	envService = "DD_SERVICE"
+	envEnv     = "DD_ENV"
+	envVersion = "DD_VERSION"
+	envTags    = "DD_TAGS"
+
+	envOTelServiceName        = "OTEL_SERVICE_NAME"
+	envOTelResourceAttributes = "OTEL_RESOURCE_ATTRIBUTES"
+)
+
+// _____________________ Configuration _____________________
+
+func newConfig(cfg ...configFn) *config {
+	var conf = &config{tags: map[string]string{}}
+
+	// Apply configurations
+	for _, v := range cfg {
+		if v != nil {
+			v(conf)
+		}
+	}
+
+	return conf
+}
+
+type config struct {
+	service string
+	env     string
+	version string
+	tags    map[string]string
+}
@@ -0,0 +1,14 @@
+package unifiedtagging
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Config_NewConfig(t *testing.T) {
+	assert.Equal(t, &config{tags: map[string]string{}}, newConfig())
+
+	var conf = newConfig(WithService("s"), WithEnv("e"), WithVersion("v"), WithTags(map[string]string{"a": "1"}), WithTags(map[string]string{"b": "2"}))
+	assert.Equal(t, &config{service: "s", env: "e", version: "v", tags: map[string]string{"a": "1", "b": "2"}}, conf)
+}
@@ -0,0 +1,139 @@
+// Package unifiedtagging provides a resource detector building the Datadog
+// unified service tags (service, env and version) and tags from Datadog and
+// OpenTelemetry environment variables.
+package unifiedtagging
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+// Datadog tags of unified service tagging, mapped to OpenTelemetry resource attributes
+var unifiedTagKeys = map[string]attribute.Key{
+	"service": semconv.ServiceNameKey,
+	"env":     semconv.DeploymentEnvironmentKey,
+	"version": semconv.ServiceVersionKey,
+}
+
+// New returns a resource detector of unified service tags. Values are resolved
+// with the following precedence, highest first:
+//   - options (WithService, WithEnv, WithVersion, then WithTags)
+//   - DD_SERVICE, DD_ENV and DD_VERSION environment variables
+//   - DD_TAGS environment variable
+//   - OTEL_SERVICE_NAME environment variable
+//   - OTEL_RESOURCE_ATTRIBUTES environment variable
+func New(cfg ...configFn) resource.Detector {
+	return &detector{conf: newConfig(cfg...)}
+}
+
+type detector struct {
+	conf *config
+}
+
+// Detect returns a resource with the service.name, deployment.environment and
+// service.version attributes, and the other tags as attributes.
+func (obj *detector) Detect(context.Context) (*resource.Resource, error) {
+	var values = map[attribute.Key]string{}
+
+	// Lowest precedence first
+	otelAttrs, invalid := parseResourceAttributes(os.Getenv(envOTelResourceAttributes))
+	for k, v := range otelAttrs {
+		values[attribute.Key(k)] = v
+	}
+	setValue(values, semconv.ServiceNameKey, os.Getenv(envOTelServiceName))
+	setTags(values, parseTags(os.Getenv(envTags)))
+	setValue(values, semconv.ServiceNameKey, os.Getenv(envService))
+	setValue(values, semconv.DeploymentEnvironmentKey, os.Getenv(envEnv))
+	setValue(values, semconv.ServiceVersionKey, os.Getenv(envVersion))
+	setTags(values, obj.conf.tags)
+	setValue(values, semconv.ServiceNameKey, obj.conf.service)
+	setValue(values, semconv.DeploymentEnvironmentKey, obj.conf.env)
+	setValue(values, semconv.ServiceVersionKey, obj.conf.version)
+
+	var attrs = make([]attribute.KeyValue, 0, len(values))
+	for k, v := range values {
+		attrs = append(attrs, k.String(v))
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	var res = resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+
+	if len(invalid) != 0 {
+		return res, fmt.Errorf("%w: invalid %s: %v", resource.ErrPartialResource, envOTelResourceAttributes, invalid)
+	}
+	return res, nil
+}
+
+// setValue sets a non empty value.
+func setValue(values map[attribute.Key]string, key attribute.Key, value string) {
+	if value = strings.TrimSpace(value); value != "" {
+		values[key] = value
+	}
+}
+
+// setTags sets Datadog tags, unified service tags being mapped to resource attributes.
+func setTags(values map[attribute.Key]string, tags map[string]string) {
+	for k, v := range tags {
+		if key, ok := unifiedTagKeys[k]; ok {
+			setValue(values, key, v)
+			continue
+		}
+		values[attribute.Key(k)] = v
+	}
+}
+
+// parseTags parses Datadog tags, "key:value" pairs separated by commas or spaces.
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/internal/env.go (ParseTagString)
+func parseTags(value string) map[string]string {
+	var (
+		tags = map[string]string{}
+		sep  = " "
+	)
+	if strings.Contains(value, ",") {
+		sep = ","
+	}
+	for _, tag := range strings.Split(value, sep) {
+		var kv = strings.SplitN(tag, ":", 2)
+		var key = strings.TrimSpace(kv[0])
+		if key == "" {
+			continue
+		}
+		var v string
+		if len(kv) == 2 {
+			v = strings.TrimSpace(kv[1])
+		}
+		tags[key] = v
+	}
+	return tags
+}
+
+// parseResourceAttributes parses OpenTelemetry resource attributes, "key=value"
+// pairs separated by commas with percent-encoded values. Invalid pairs are returned.
+func parseResourceAttributes(value string) (attrs map[string]string, invalid []string) {
+	attrs = map[string]string{}
+	if strings.TrimSpace(value) == "" {
+		return attrs, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		var kv = strings.SplitN(pair, "=", 2)
+		var key = strings.TrimSpace(kv[0])
+		if len(kv) != 2 || key == "" {
+			invalid = append(invalid, pair)
+			continue
+		}
+		v, err := url.PathUnescape(strings.TrimSpace(kv[1]))
+		if err != nil {
+			invalid = append(invalid, pair)
+			continue
+		}
+		attrs[key] = v
+	}
+	return attrs, invalid
+}
@@ -0,0 +1,120 @@
+package unifiedtagging
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+func setenv(t *testing.T, key, value string) {
+	prev, exists := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if exists {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// test_setenv sets environment variables of the detector, others being empty.
+func test_setenv(t *testing.T, values map[string]string) {
+	for _, key := range []string{envService, envEnv, envVersion, envTags, envOTelServiceName, envOTelResourceAttributes} {
+		setenv(t, key, values[key])
+	}
+}
+
+func Test_detector_Detect(t *testing.T) {
+	// No environment
+	test_setenv(t, nil)
+	res, err := New().Detect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, res.Len())
+	assert.Equal(t, semconv.SchemaURL, res.SchemaURL())
+
+	// OpenTelemetry environment
+	test_setenv(t, map[string]string{
+		envOTelServiceName:        "otel-service",
+		envOTelResourceAttributes: "service.name=attr-service,deployment.environment=staging,team=a%20b",
+	})
+	res, err = New().Detect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []attribute.KeyValue{
+		semconv.DeploymentEnvironmentKey.String("staging"),
+		semconv.ServiceNameKey.String("otel-service"),
+		attribute.String("team", "a b"),
+	}, res.Attributes())
+
+	// Datadog environment takes precedence
+	test_setenv(t, map[string]string{
+		envService:                "dd-service",
+		envVersion:                "1.2.3",
+		envTags:                   "env:prod,team:dd,region:eu",
+		envOTelServiceName:        "otel-service",
+		envOTelResourceAttributes: "deployment.environment=staging,team=otel,host.name=h",
+	})
+	res, err = New().Detect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []attribute.KeyValue{
+		semconv.DeploymentEnvironmentKey.String("prod"),
+		semconv.HostNameKey.String("h"),
+		attribute.String("region", "eu"),
+		semconv.ServiceNameKey.String("dd-service"),
+		semconv.ServiceVersionKey.String("1.2.3"),
+		attribute.String("team", "dd"),
+	}, res.Attributes())
+
+	// DD_ENV takes precedence over DD_TAGS, options over environment
+	setenv(t, envEnv, "qa")
+	res, err = New(WithService("opt-service"), WithTags(map[string]string{"team": "opt", "version": "2.0.0"})).Detect(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []attribute.KeyValue{
+		semconv.DeploymentEnvironmentKey.String("qa"),
+		semconv.HostNameKey.String("h"),
+		attribute.String("region", "eu"),
+		semconv.ServiceNameKey.String("opt-service"),
+		semconv.ServiceVersionKey.String("2.0.0"),
+		attribute.String("team", "opt"),
+	}, res.Attributes())
+
+	res, err = New(WithEnv("dev"), WithVersion("3.0.0"), WithTags(map[string]string{"env": "ignored"})).Detect(context.Background())
+	assert.NoError(t, err)
+	assert.Contains(t, res.Attributes(), semconv.DeploymentEnvironmentKey.String("dev"))
+	assert.Contains(t, res.Attributes(), semconv.ServiceVersionKey.String("3.0.0"))
+}
+
+func Test_detector_Detect_Partial(t *testing.T) {
+	test_setenv(t, map[string]string{envOTelResourceAttributes: "team=a,invalid"})
+	res, err := New().Detect(context.Background())
+	assert.ErrorIs(t, err, resource.ErrPartialResource)
+	assert.Equal(t, []attribute.KeyValue{attribute.String("team", "a")}, res.Attributes())
+}
+
+func Test_detector_Merge(t *testing.T) {
+	test_setenv(t, map[string]string{envService: "dd-service"})
+	res, err := resource.New(context.Background(), resource.WithDetectors(New()))
+	assert.NoError(t, err)
+	assert.Equal(t, []attribute.KeyValue{semconv.ServiceNameKey.String("dd-service")}, res.Attributes())
+}
+
+func Test_parseTags(t *testing.T) {
+	assert.Equal(t, map[string]string{"env": "prod", "team": "a:b", "key": ""}, parseTags("env:prod, team:a:b ,key,"))
+	assert.Equal(t, map[string]string{"env": "prod", "team": "dd"}, parseTags("env:prod  team:dd"))
+	assert.Equal(t, map[string]string{}, parseTags(""))
+}
+
+func Test_parseResourceAttributes(t *testing.T) {
+	attrs, invalid := parseResourceAttributes(" a = 1 ,b=x%2Cy,c,=d,e=%zz")
+	assert.Equal(t, map[string]string{"a": "1", "b": "x,y"}, attrs)
+	assert.Equal(t, []string{"c", "=d", "e=%zz"}, invalid)
+
+	attrs, invalid = parseResourceAttributes("")
+	assert.Empty(t, attrs)
+	assert.Nil(t, invalid)
+}
@@ -0,0 +1,201 @@
+package datadog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/container"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/version"
+)
+
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/trace/api/endpoints.go
+
+const (
+	agentPathInfo     = "/info"
+	agentPathTraces04 = "/v0.4/traces"
+	agentPathStats06  = "/v0.6/stats"
+
+	// maxAgentPayloadSize is the maximum size of a trace payload sent to the agent
+	// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/writer.go (payloadSizeLimit)
+	maxAgentPayloadSize = int(9.5 * 1024 * 1024)
+)
+
+// agentFeatures are the capabilities advertised by the agent on its /info endpoint.
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/trace/api/info.go
+type agentFeatures struct {
+	Endpoints     []string `json:"endpoints"`
+	SpanEvents    bool     `json:"span_events"`
+	ClientDropP0s bool     `json:"client_drop_p0s"`
+	PeerTags      []string `json:"peer_tags"`
+}
+
+func (obj agentFeatures) hasEndpoint(path string) bool {
+	for _, v := range obj.Endpoints {
+		if v == path {
+			return true
+		}
+	}
+	return false
+}
+
+// agentClient sends requests to the Datadog agent API.
+type agentClient struct {
+	baseURL string
+	client  *http.Client
+	headers http.Header
+	onRates func(rates map[string]float64) // nil if rates are not used
+}
+
+func newAgentClient(conf *config) *agentClient {
+	return &agentClient{baseURL: conf.agentBaseURL, client: conf.httpClient, headers: metaHeaders(), onRates: conf.rateByServiceHandler}
+}
+
+// metaHeaders returns the headers identifying the tracer and its container,
+// used by the agent to tag traces.
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/transport.go
+func metaHeaders() http.Header {
+	var headers = http.Header{}
+	headers.Set("Datadog-Meta-Lang", "go")
+	headers.Set("Datadog-Meta-Lang-Version", strings.TrimPrefix(runtime.Version(), "go"))
+	headers.Set("Datadog-Meta-Lang-Interpreter", runtime.Compiler+"-"+runtime.GOARCH+"-"+runtime.GOOS)
+	headers.Set("Datadog-Meta-Tracer-Version", version.Tag)
+	if id := container.ID(); id != "" {
+		headers.Set("Datadog-Container-ID", id)
+	}
+	if id := container.EntityID(); id != "" {
+		headers.Set("Datadog-Entity-ID", id)
+	}
+	return headers
+}
+
+// info returns the features supported by the agent.
+func (obj *agentClient) info(ctx context.Context) (features agentFeatures, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, obj.baseURL+agentPathInfo, nil)
+	if err != nil {
+		return features, err
+	}
+
+	resp, err := obj.do(req)
+	if err != nil {
+		return features, err
+	}
+	defer resp.Body.Close()
+
+	err = json.NewDecoder(resp.Body).Decode(&features)
+	return features, err
+}
+
+// tracePayload is a msgpack encoded list of traces.
+type tracePayload struct {
+	data       []byte
+	traceCount int
+	spanCount  int
+	// clientComputedStats tells the agent stats are computed by the exporter
+	clientComputedStats bool
+	// Unsampled traces and spans dropped by the exporter since last payload
+	droppedP0Traces int
+	droppedP0Spans  int
+}
+
+// sendTraces posts a list of traces.
+func (obj *agentClient) sendTraces(ctx context.Context, payload tracePayload) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, obj.baseURL+agentPathTraces04, bytes.NewReader(payload.data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/msgpack")
+	req.Header.Set("X-Datadog-Trace-Count", strconv.Itoa(payload.traceCount))
+	// Top-level spans are always marked by the exporter
+	req.Header.Set("Datadog-Client-Computed-Top-Level", "yes")
+	if payload.clientComputedStats {
+		req.Header.Set("Datadog-Client-Computed-Stats", "yes")
+	}
+	if payload.droppedP0Traces != 0 || payload.droppedP0Spans != 0 {
+		req.Header.Set("Datadog-Client-Dropped-P0-Traces", strconv.Itoa(payload.droppedP0Traces))
+		req.Header.Set("Datadog-Client-Dropped-P0-Spans", strconv.Itoa(payload.droppedP0Spans))
+	}
+
+	resp, err := obj.do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Sample rates computed by the agent to keep its target throughput
+	if obj.onRates != nil {
+		var body tracesResponse
+		if err := json.NewDecoder(resp.Body).Decode(&body); err == nil && body.RateByService != nil {
+			obj.onRates(body.RateByService)
+		}
+	}
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// tracesResponse is the response of the agent to a trace payload.
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/trace/api/api.go (writeTracesResponse)
+type tracesResponse struct {
+	RateByService map[string]float64 `json:"rate_by_service"`
+}
+
+// sendStats posts a msgpack encoded stats payload.
+func (obj *agentClient) sendStats(ctx context.Context, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, obj.baseURL+agentPathStats06, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/msgpack")
+
+	return obj.send(req)
+}
+
+// send sends the request and discards the response.
+func (obj *agentClient) send(req *http.Request) error {
+	obj.setHeaders(req)
+	return sendRequest(obj.client, "agent", req)
+}
+
+// do sends the request and checks the response status code.
+func (obj *agentClient) do(req *http.Request) (*http.Response, error) {
+	obj.setHeaders(req)
+	return doRequest(obj.client, "agent", req)
+}
+
+func (obj *agentClient) setHeaders(req *http.Request) {
+	for k, v := range obj.headers {
+		req.Header[k] = v
+	}
+}
+
+// _____________________ HTTP helpers _____________________
+
+// sendRequest sends the request to target (agent or intake) and discards the response.
+func sendRequest(client *http.Client, target string, req *http.Request) error {
+	resp, err := doRequest(client, target, req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	return nil
+}
+
+// doRequest sends the request to target (agent or intake) and checks the response status code.
+func doRequest(client *http.Client, target string, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		resp.Body.Close()
+		return nil, newStatusError(target, req, resp)
+	}
+	return resp, nil
+}
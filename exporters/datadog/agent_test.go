@@ -0,0 +1,228 @@
+package datadog
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/container"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAgent is a stand-in Datadog agent recording received requests.
+type testAgent struct {
+	*httptest.Server
+
+	mu         sync.Mutex
+	features   agentFeatures
+	infoStatus int // status of info requests, features returned if 0
+	status     int
+	statuses   []int // next statuses, before status
+	retryAfter string
+	response   string // body of trace responses, "{}" by default
+	requests   []testAgentRequest
+}
+
+type testAgentRequest struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+func newTestAgent(t *testing.T, features agentFeatures) *testAgent {
+	var agent = &testAgent{features: features, status: http.StatusOK}
+	agent.Server = httptest.NewServer(http.HandlerFunc(agent.serveHTTP))
+	t.Cleanup(func() { agent.Close() })
+	return agent
+}
+
+// restart starts a new server on the address of the closed one.
+func (obj *testAgent) restart(t *testing.T) {
+	listener, err := net.Listen("tcp", obj.Listener.Addr().String())
+	require.NoError(t, err)
+	var server = httptest.NewUnstartedServer(http.HandlerFunc(obj.serveHTTP))
+	server.Listener.Close()
+	server.Listener = listener
+	server.Start()
+	obj.Server = server
+}
+
+func (obj *testAgent) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.requests = append(obj.requests, testAgentRequest{path: r.URL.Path, header: r.Header, body: body})
+
+	if r.URL.Path == agentPathInfo {
+		if obj.infoStatus != 0 {
+			w.WriteHeader(obj.infoStatus)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(obj.features)
+		return
+	}
+	var status = obj.status
+	if len(obj.statuses) != 0 {
+		status, obj.statuses = obj.statuses[0], obj.statuses[1:]
+	}
+	if obj.retryAfter != "" {
+		w.Header().Set("Retry-After", obj.retryAfter)
+	}
+	w.WriteHeader(status)
+	if obj.response != "" && r.URL.Path == agentPathTraces04 {
+		_, _ = w.Write([]byte(obj.response))
+		return
+	}
+	_, _ = w.Write([]byte("{}"))
+}
+
+// setNextStatuses sets the statuses of next requests.
+func (obj *testAgent) setNextStatuses(statuses ...int) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.statuses = statuses
+}
+
+func (obj *testAgent) setStatus(status int) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.status = status
+}
+
+// received returns requests received on path
+func (obj *testAgent) received(path string) []testAgentRequest {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	var requests []testAgentRequest
+	for _, v := range obj.requests {
+		if v.path == path {
+			requests = append(requests, v)
+		}
+	}
+	return requests
+}
+
+func test_newAgentClient(t *testing.T, agent *testAgent) *agentClient {
+	conf, err := newConfig(WithAgentURL(agent.URL))
+	require.NoError(t, err)
+	return newAgentClient(conf)
+}
+
+func Test_agentFeatures_hasEndpoint(t *testing.T) {
+	var features = agentFeatures{Endpoints: []string{"/v0.4/traces", "/v0.6/stats"}}
+	assert.True(t, features.hasEndpoint("/v0.6/stats"))
+	assert.False(t, features.hasEndpoint("/v0.7/config"))
+}
+
+func Test_agentClient_info(t *testing.T) {
+	var (
+		expected = agentFeatures{Endpoints: []string{agentPathTraces04}, SpanEvents: true, ClientDropP0s: true}
+		agent    = newTestAgent(t, expected)
+		client   = test_newAgentClient(t, agent)
+	)
+
+	features, err := client.info(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, expected, features)
+
+	// Check agent unreachable
+	agent.Close()
+	_, err = client.info(context.Background())
+	assert.Error(t, err)
+}
+
+func Test_agentClient_sendTraces(t *testing.T) {
+	var (
+		agent  = newTestAgent(t, agentFeatures{})
+		client = test_newAgentClient(t, agent)
+	)
+
+	assert.NoError(t, client.sendTraces(context.Background(), tracePayload{data: []byte{0x90}}))
+	assert.NoError(t, client.sendTraces(context.Background(), tracePayload{data: []byte{0x90}, traceCount: 2, clientComputedStats: true}))
+	requests := agent.received(agentPathTraces04)
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, []byte{0x90}, requests[0].body)
+		assert.Equal(t, "application/msgpack", requests[0].header.Get("Content-Type"))
+		assert.Equal(t, "0", requests[0].header.Get("X-Datadog-Trace-Count"))
+		assert.Equal(t, version.Tag, requests[0].header.Get("Datadog-Meta-Tracer-Version"))
+		assert.Empty(t, requests[0].header.Get("Datadog-Client-Computed-Stats"))
+		assert.Equal(t, "yes", requests[0].header.Get("Datadog-Client-Computed-Top-Level"))
+		assert.Equal(t, "2", requests[1].header.Get("X-Datadog-Trace-Count"))
+		assert.Equal(t, "yes", requests[1].header.Get("Datadog-Client-Computed-Stats"))
+		assert.Equal(t, "yes", requests[1].header.Get("Datadog-Client-Computed-Top-Level"))
+	}
+
+	// Check dropped P0 headers
+	assert.NoError(t, client.sendTraces(context.Background(), tracePayload{data: []byte{0x90}, droppedP0Traces: 2, droppedP0Spans: 5}))
+	requests = agent.received(agentPathTraces04)
+	if assert.Len(t, requests, 3) {
+		assert.Empty(t, requests[1].header.Get("Datadog-Client-Dropped-P0-Traces"))
+		assert.Equal(t, "2", requests[2].header.Get("Datadog-Client-Dropped-P0-Traces"))
+		assert.Equal(t, "5", requests[2].header.Get("Datadog-Client-Dropped-P0-Spans"))
+	}
+
+	// Check error status
+	agent.setStatus(http.StatusBadRequest)
+	assert.EqualError(t, client.sendTraces(context.Background(), tracePayload{data: []byte{0x90}}), "datadog agent POST /v0.4/traces: 400 Bad Request")
+}
+
+func Test_agentClient_sendTraces_RateByService(t *testing.T) {
+	var (
+		agent = newTestAgent(t, agentFeatures{})
+		rates []map[string]float64
+	)
+	conf, err := newConfig(WithAgentURL(agent.URL), WithRateByServiceHandler(func(value map[string]float64) {
+		rates = append(rates, value)
+	}))
+	require.NoError(t, err)
+	var client = newAgentClient(conf)
+
+	// Response without rates
+	assert.NoError(t, client.sendTraces(context.Background(), tracePayload{data: []byte{0x90}}))
+	assert.Empty(t, rates)
+
+	agent.response = `{"rate_by_service":{"service:,env:":0.8,"service:a,env:prod":0.5}}`
+	assert.NoError(t, client.sendTraces(context.Background(), tracePayload{data: []byte{0x90}}))
+	assert.Equal(t, []map[string]float64{{"service:,env:": 0.8, "service:a,env:prod": 0.5}}, rates)
+
+	// Invalid response ignored
+	agent.response = `OK`
+	assert.NoError(t, client.sendTraces(context.Background(), tracePayload{data: []byte{0x90}}))
+	assert.Len(t, rates, 1)
+}
+
+func Test_agentClient_sendStats(t *testing.T) {
+	var (
+		agent  = newTestAgent(t, agentFeatures{})
+		client = test_newAgentClient(t, agent)
+	)
+
+	assert.NoError(t, client.sendStats(context.Background(), []byte{0x80}))
+	requests := agent.received(agentPathStats06)
+	if assert.Len(t, requests, 1) {
+		assert.Equal(t, []byte{0x80}, requests[0].body)
+		assert.Equal(t, "application/msgpack", requests[0].header.Get("Content-Type"))
+		assert.Equal(t, "go", requests[0].header.Get("Datadog-Meta-Lang"))
+	}
+}
+
+func Test_metaHeaders(t *testing.T) {
+	var headers = metaHeaders()
+	assert.Equal(t, "go", headers.Get("Datadog-Meta-Lang"))
+	assert.Equal(t, strings.TrimPrefix(runtime.Version(), "go"), headers.Get("Datadog-Meta-Lang-Version"))
+	assert.Equal(t, runtime.Compiler+"-"+runtime.GOARCH+"-"+runtime.GOOS, headers.Get("Datadog-Meta-Lang-Interpreter"))
+	assert.Equal(t, version.Tag, headers.Get("Datadog-Meta-Tracer-Version"))
+	assert.Equal(t, container.ID(), headers.Get("Datadog-Container-ID"))
+	assert.Equal(t, container.EntityID(), headers.Get("Datadog-Entity-ID"))
+}
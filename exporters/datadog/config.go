@@ -0,0 +1,334 @@
+package datadog
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/agenturl"
+)
+
+// _____________________ With option functions _____________________
+
+// WithHTTPServerErrorStatuses sets the HTTP status codes of server spans considered
+// as errors, using Datadog format (ex: "500-599,404").
+// It defaults to DD_TRACE_HTTP_SERVER_ERROR_STATUSES environment variable or DefaultHTTPServerErrorStatuses.
+func WithHTTPServerErrorStatuses(value string) configFn {
+	return func(conf *config) {
+		conf.httpServerErrorStatuses = value
+	}
+}
+
+// WithHTTPClientErrorStatuses sets the HTTP status codes of client spans considered
+// as errors, using Datadog format (ex: "400-499,503").
+// It defaults to DD_TRACE_HTTP_CLIENT_ERROR_STATUSES environment variable or DefaultHTTPClientErrorStatuses.
+func WithHTTPClientErrorStatuses(value string) configFn {
+	return func(conf *config) {
+		conf.httpClientErrorStatuses = value
+	}
+}
+
+// WithAgentURL sets the URL of the Datadog agent trace API (ex: "http://localhost:8126"
+// or "unix:///var/run/datadog/apm.socket").
+// It defaults to DD_TRACE_AGENT_URL environment variable, or DD_AGENT_HOST and
+// DD_TRACE_AGENT_PORT, or DefaultAgentURL.
+func WithAgentURL(value string) configFn {
+	return func(conf *config) {
+		conf.agentURL = value
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests to the agent.
+func WithHTTPClient(value *http.Client) configFn {
+	return func(conf *config) {
+		conf.httpClient = value
+	}
+}
+
+// WithStatsComputation enables the computation of trace stats by the exporter
+// (Datadog client-side stats). Unsampled traces are then dropped by the exporter.
+// It defaults to DD_TRACE_STATS_COMPUTATION_ENABLED environment variable or false.
+func WithStatsComputation(enabled bool) configFn {
+	return func(conf *config) {
+		conf.statsComputation = &enabled
+	}
+}
+
+// WithAgentless sends traces directly to the Datadog intake instead of the agent.
+// An API key is then required. Trace stats are not computed in this mode.
+func WithAgentless(enabled bool) configFn {
+	return func(conf *config) {
+		conf.agentless = enabled
+	}
+}
+
+// WithAPIKey sets the Datadog API key used in agentless mode.
+// It defaults to DD_API_KEY environment variable.
+func WithAPIKey(value string) configFn {
+	return func(conf *config) {
+		conf.apiKey = value
+	}
+}
+
+// WithSite sets the Datadog site of the intake used in agentless mode (ex: "datadoghq.eu").
+// It defaults to DD_SITE environment variable or DefaultSite.
+func WithSite(value string) configFn {
+	return func(conf *config) {
+		conf.site = value
+	}
+}
+
+// WithIntakeURL sets the URL of the Datadog trace intake used in agentless mode.
+// It defaults to "https://trace.agent." followed by the site.
+func WithIntakeURL(value string) configFn {
+	return func(conf *config) {
+		conf.intakeURL = value
+	}
+}
+
+// WithRetry sets the retries of failed requests (connection errors, 5xx and 429
+// statuses), with a jittered exponential backoff between initialBackoff and maxBackoff.
+// The Retry-After header of 429 responses is honoured.
+// It defaults to DefaultMaxRetries, DefaultInitialBackoff and DefaultMaxBackoff.
+func WithRetry(maxRetries int, initialBackoff, maxBackoff time.Duration) configFn {
+	return func(conf *config) {
+		conf.retryPolicy = &retryPolicy{maxRetries: maxRetries, initialBackoff: initialBackoff, maxBackoff: maxBackoff}
+	}
+}
+
+// WithMaxQueuedBytes sets the maximum size of trace payloads kept for retry when
+// the agent is unavailable, oldest payloads being dropped first.
+// It defaults to DefaultMaxQueuedBytes.
+func WithMaxQueuedBytes(value int) configFn {
+	return func(conf *config) {
+		conf.maxQueuedBytes = value
+	}
+}
+
+// WithSpoolDir stores trace payloads waiting to be sent in segment files of dir,
+// instead of memory, so that they survive agent outages and process restarts.
+// The size of the directory is bounded by WithMaxQueuedBytes.
+func WithSpoolDir(dir string) configFn {
+	return func(conf *config) {
+		conf.spoolDir = dir
+	}
+}
+
+// WithSpoolMaxAge sets the age after which spooled payloads are discarded.
+// It defaults to DefaultSpoolMaxAge.
+func WithSpoolMaxAge(value time.Duration) configFn {
+	return func(conf *config) {
+		conf.spoolMaxAge = value
+	}
+}
+
+// WithRateByServiceHandler sets the function receiving the sample rates by service
+// computed by the agent, returned on each trace payload (ex: the UpdateRates method
+// of the Datadog priority sampler). Rates are keyed by "service:<service>,env:<env>".
+func WithRateByServiceHandler(fn func(rates map[string]float64)) configFn {
+	return func(conf *config) {
+		conf.rateByServiceHandler = fn
+	}
+}
+
+// _____________________ Definition _____________________
+
+type configFn func(*config)
+
+var (
+	ErrInvalidHTTPErrorStatuses = errors.New("invalid HTTP error statuses")
+	ErrInvalidAgentURL          = errors.New("invalid agent URL")
+	ErrInvalidIntakeURL         = errors.New("invalid intake URL")
+	ErrMissingAPIKey            = errors.New("missing API key")
+	ErrInvalidRetry             = errors.New("invalid retry configuration")
+)
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/internal/env.go
+// Ref https://docs.datadoghq.com/tracing/trace_collection/library_config/go/
+
+const (
+	envStatsComputation        = "DD_TRACE_STATS_COMPUTATION_ENABLED"
+	envHTTPServerErrorStatuses = "DD_TRACE_HTTP_SERVER_ERROR_STATUSES"
+	envHTTPClientErrorStatuses = "DD_TRACE_HTTP_CLIENT_ERROR_STATUSES"
+	envAPIKey                  = "DD_API_KEY"
+	envSite                    = "DD_SITE"
+)
+
+const (
+	// DefaultAgentURL specifies the URL of the agent trace API.
+	DefaultAgentURL = agenturl.DefaultURL
+
+	// defaultHTTPTimeout is the timeout of requests sent to the agent
+	defaultHTTPTimeout = 10 * time.Second
+
+	// DefaultHTTPServerErrorStatuses specifies the HTTP status codes of server spans
+	// considered as errors.
+	DefaultHTTPServerErrorStatuses = "500-599"
+
+	// DefaultHTTPClientErrorStatuses specifies the HTTP status codes of client spans
+	// considered as errors.
+	DefaultHTTPClientErrorStatuses = "400-499"
+
+	// DefaultSite specifies the Datadog site of the intake.
+	DefaultSite = "datadoghq.com"
+
+	intakeURLPrefix = "https://trace.agent."
+
+	// DefaultMaxRetries specifies the number of retries of a failed request.
+	DefaultMaxRetries = 3
+	// DefaultInitialBackoff specifies the delay before the first retry.
+	DefaultInitialBackoff = 100 * time.Millisecond
+	// DefaultMaxBackoff specifies the maximum delay between retries.
+	DefaultMaxBackoff = 5 * time.Second
+
+	// DefaultMaxQueuedBytes specifies the maximum size of payloads kept for retry.
+	DefaultMaxQueuedBytes = 32 * 1024 * 1024
+
+	// DefaultSpoolMaxAge specifies the age after which spooled payloads are discarded.
+	DefaultSpoolMaxAge = time.Hour
+)
+
+// _____________________ Configuration _____________________
+
+func newConfig(cfg ...configFn) (*config, error) {
+	var conf = &config{}
+
+	// Apply configurations
+	for _, v := range cfg {
+		if v != nil {
+			v(conf)
+		}
+	}
+
+	// Apply default value on empty
+	conf.applyDefault()
+
+	// Check configuration is valid
+	if err := conf.parse(); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+type config struct {
+	agentURL                string
+	httpClient              *http.Client
+	statsComputation        *bool
+	httpServerErrorStatuses string
+	httpClientErrorStatuses string
+	agentless               bool
+	apiKey                  string
+	site                    string
+	intakeURL               string
+	retryPolicy             *retryPolicy
+	maxQueuedBytes          int
+	spoolDir                string
+	spoolMaxAge             time.Duration
+	rateByServiceHandler    func(rates map[string]float64)
+
+	// Parsed values
+	agentBaseURL          string
+	intakeBaseURL         string
+	httpServerErrorRanges statusRanges
+	httpClientErrorRanges statusRanges
+}
+
+func (obj *config) applyDefault() {
+	// Set default agent URL
+	if obj.agentURL == "" {
+		obj.agentURL = agenturl.FromEnv()
+	}
+
+	// Set default stats computation
+	if obj.statsComputation == nil {
+		var enabled, _ = strconv.ParseBool(os.Getenv(envStatsComputation))
+		obj.statsComputation = &enabled
+	}
+
+	// Set default HTTP error statuses
+	obj.httpServerErrorStatuses = stringDefault(obj.httpServerErrorStatuses, os.Getenv(envHTTPServerErrorStatuses), DefaultHTTPServerErrorStatuses)
+	obj.httpClientErrorStatuses = stringDefault(obj.httpClientErrorStatuses, os.Getenv(envHTTPClientErrorStatuses), DefaultHTTPClientErrorStatuses)
+
+	// Set default intake
+	obj.apiKey = stringDefault(obj.apiKey, os.Getenv(envAPIKey))
+	obj.site = stringDefault(obj.site, os.Getenv(envSite), DefaultSite)
+	obj.intakeURL = stringDefault(obj.intakeURL, intakeURLPrefix+obj.site)
+
+	// Set default retries
+	if obj.retryPolicy == nil {
+		obj.retryPolicy = &retryPolicy{maxRetries: DefaultMaxRetries, initialBackoff: DefaultInitialBackoff, maxBackoff: DefaultMaxBackoff}
+	}
+	if obj.maxQueuedBytes <= 0 {
+		obj.maxQueuedBytes = DefaultMaxQueuedBytes
+	}
+	if obj.spoolMaxAge <= 0 {
+		obj.spoolMaxAge = DefaultSpoolMaxAge
+	}
+}
+
+func (obj *config) parse() (err error) {
+	if obj.agentless {
+		err = obj.parseIntakeURL()
+	} else {
+		err = obj.parseAgentURL()
+	}
+	if err != nil {
+		return err
+	}
+	if policy := obj.retryPolicy; policy.maxRetries < 0 || policy.initialBackoff < 0 || policy.maxBackoff < policy.initialBackoff {
+		return ErrInvalidRetry
+	}
+	if obj.httpServerErrorRanges, err = parseStatusRanges(obj.httpServerErrorStatuses); err != nil {
+		return fmt.Errorf("%w: server: %v", ErrInvalidHTTPErrorStatuses, err)
+	}
+	if obj.httpClientErrorRanges, err = parseStatusRanges(obj.httpClientErrorStatuses); err != nil {
+		return fmt.Errorf("%w: client: %v", ErrInvalidHTTPErrorStatuses, err)
+	}
+	return nil
+}
+
+// parseAgentURL computes the base URL of agent requests and the HTTP client to use.
+func (obj *config) parseAgentURL() (err error) {
+	obj.agentBaseURL, obj.httpClient, err = agenturl.Resolve(obj.agentURL, obj.httpClient, defaultHTTPTimeout)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAgentURL, err)
+	}
+	return nil
+}
+
+// parseIntakeURL computes the base URL of intake requests and the HTTP client to use.
+func (obj *config) parseIntakeURL() error {
+	if obj.apiKey == "" {
+		return ErrMissingAPIKey
+	}
+
+	intakeURL, err := url.Parse(obj.intakeURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidIntakeURL, err)
+	}
+	if intakeURL.Scheme != "http" && intakeURL.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrInvalidIntakeURL, intakeURL.Scheme)
+	}
+
+	obj.intakeBaseURL = strings.TrimSuffix(intakeURL.String(), "/")
+	if obj.httpClient == nil {
+		obj.httpClient = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	return nil
+}
+
+// stringDefault returns the first non empty value
+func stringDefault(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
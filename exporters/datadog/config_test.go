@@ -0,0 +1,204 @@
+package datadog
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/agenturl"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Config_NewConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	// Check default values applied
+	if conf, err := newConfig(); assert.NoError(err) {
+		assert.Equal(DefaultHTTPServerErrorStatuses, conf.httpServerErrorStatuses)
+		assert.Equal(DefaultHTTPClientErrorStatuses, conf.httpClientErrorStatuses)
+		assert.Equal(statusRanges{{500, 599}}, conf.httpServerErrorRanges)
+		assert.Equal(statusRanges{{400, 499}}, conf.httpClientErrorRanges)
+	}
+
+	// Check options
+	if conf, err := newConfig(WithHTTPServerErrorStatuses("502"), WithHTTPClientErrorStatuses("400-403,503")); assert.NoError(err) {
+		assert.Equal(statusRanges{{502, 502}}, conf.httpServerErrorRanges)
+		assert.Equal(statusRanges{{400, 403}, {503, 503}}, conf.httpClientErrorRanges)
+	}
+
+	// Check invalid configuration
+	_, err := newConfig(WithHTTPServerErrorStatuses("abc"))
+	assert.ErrorIs(err, ErrInvalidHTTPErrorStatuses)
+	_, err = newConfig(WithHTTPClientErrorStatuses("499-400"))
+	assert.ErrorIs(err, ErrInvalidHTTPErrorStatuses)
+}
+
+func Test_Config_Env(t *testing.T) {
+	setenv(t, envHTTPServerErrorStatuses, "500-503")
+	setenv(t, envHTTPClientErrorStatuses, "404")
+
+	// Check environment used as default value
+	if conf, err := newConfig(); assert.NoError(t, err) {
+		assert.Equal(t, statusRanges{{500, 503}}, conf.httpServerErrorRanges)
+		assert.Equal(t, statusRanges{{404, 404}}, conf.httpClientErrorRanges)
+	}
+
+	// Check option overrides environment
+	if conf, err := newConfig(WithHTTPServerErrorStatuses("599")); assert.NoError(t, err) {
+		assert.Equal(t, statusRanges{{599, 599}}, conf.httpServerErrorRanges)
+	}
+}
+
+func Test_Config_AgentURL(t *testing.T) {
+	assert := assert.New(t)
+
+	// Check default value
+	if conf, err := newConfig(); assert.NoError(err) {
+		assert.Equal(DefaultAgentURL, conf.agentBaseURL)
+		assert.NotNil(conf.httpClient)
+	}
+
+	// Check option
+	if conf, err := newConfig(WithAgentURL("https://agent:1234/")); assert.NoError(err) {
+		assert.Equal("https://agent:1234", conf.agentBaseURL)
+	}
+
+	// Check unix domain socket
+	if conf, err := newConfig(WithAgentURL("unix:///var/run/datadog/apm.socket")); assert.NoError(err) {
+		assert.Equal("http://UDS__var_run_datadog_apm.socket", conf.agentBaseURL)
+		assert.NotNil(conf.httpClient.Transport)
+	}
+
+	// Check invalid URL
+	_, err := newConfig(WithAgentURL("ftp://agent"))
+	assert.ErrorIs(err, ErrInvalidAgentURL)
+	_, err = newConfig(WithAgentURL(":"))
+	assert.ErrorIs(err, ErrInvalidAgentURL)
+}
+
+func Test_Config_AgentURL_Env(t *testing.T) {
+	setenv(t, agenturl.EnvHost, "agent")
+	setenv(t, agenturl.EnvPort, "1234")
+	if conf, err := newConfig(); assert.NoError(t, err) {
+		assert.Equal(t, "http://agent:1234", conf.agentBaseURL)
+	}
+
+	// Check URL takes precedence over host and port
+	setenv(t, agenturl.EnvURL, "http://other:8126")
+	if conf, err := newConfig(); assert.NoError(t, err) {
+		assert.Equal(t, "http://other:8126", conf.agentBaseURL)
+	}
+}
+
+func Test_Config_StatsComputation(t *testing.T) {
+	// Check default value
+	if conf, err := newConfig(); assert.NoError(t, err) {
+		assert.False(t, *conf.statsComputation)
+	}
+
+	// Check environment
+	setenv(t, envStatsComputation, "true")
+	if conf, err := newConfig(); assert.NoError(t, err) {
+		assert.True(t, *conf.statsComputation)
+	}
+
+	// Check option overrides environment
+	if conf, err := newConfig(WithStatsComputation(false)); assert.NoError(t, err) {
+		assert.False(t, *conf.statsComputation)
+	}
+}
+
+func Test_Config_Agentless(t *testing.T) {
+	assert := assert.New(t)
+
+	// Check API key required
+	_, err := newConfig(WithAgentless(true))
+	assert.ErrorIs(err, ErrMissingAPIKey)
+
+	// Check default intake
+	if conf, err := newConfig(WithAgentless(true), WithAPIKey("key")); assert.NoError(err) {
+		assert.Equal("https://trace.agent.datadoghq.com", conf.intakeBaseURL)
+		assert.Equal("key", conf.apiKey)
+		assert.NotNil(conf.httpClient)
+	}
+
+	// Check environment
+	setenv(t, envAPIKey, "env-key")
+	setenv(t, envSite, "datadoghq.eu")
+	if conf, err := newConfig(WithAgentless(true)); assert.NoError(err) {
+		assert.Equal("https://trace.agent.datadoghq.eu", conf.intakeBaseURL)
+		assert.Equal("env-key", conf.apiKey)
+	}
+
+	// Check options override environment
+	if conf, err := newConfig(WithAgentless(true), WithSite("us3.datadoghq.com"), WithAPIKey("key")); assert.NoError(err) {
+		assert.Equal("https://trace.agent.us3.datadoghq.com", conf.intakeBaseURL)
+		assert.Equal("key", conf.apiKey)
+	}
+	if conf, err := newConfig(WithAgentless(true), WithIntakeURL("https://localhost:1234/")); assert.NoError(err) {
+		assert.Equal("https://localhost:1234", conf.intakeBaseURL)
+	}
+
+	// Check agent URL ignored
+	_, err = newConfig(WithAgentless(true), WithAgentURL("ftp://agent"))
+	assert.NoError(err)
+
+	// Check invalid URL
+	_, err = newConfig(WithAgentless(true), WithIntakeURL("ftp://intake"))
+	assert.ErrorIs(err, ErrInvalidIntakeURL)
+	_, err = newConfig(WithAgentless(true), WithIntakeURL(":"))
+	assert.ErrorIs(err, ErrInvalidIntakeURL)
+}
+
+func Test_Config_Retry(t *testing.T) {
+	assert := assert.New(t)
+
+	// Check default values
+	if conf, err := newConfig(); assert.NoError(err) {
+		assert.Equal(&retryPolicy{maxRetries: DefaultMaxRetries, initialBackoff: DefaultInitialBackoff, maxBackoff: DefaultMaxBackoff}, conf.retryPolicy)
+		assert.Equal(DefaultMaxQueuedBytes, conf.maxQueuedBytes)
+	}
+
+	// Check options
+	if conf, err := newConfig(WithRetry(0, 0, 0), WithMaxQueuedBytes(1024)); assert.NoError(err) {
+		assert.Equal(&retryPolicy{}, conf.retryPolicy)
+		assert.Equal(1024, conf.maxQueuedBytes)
+	}
+
+	// Check invalid configuration
+	_, err := newConfig(WithRetry(-1, 0, 0))
+	assert.ErrorIs(err, ErrInvalidRetry)
+	_, err = newConfig(WithRetry(1, time.Second, time.Millisecond))
+	assert.ErrorIs(err, ErrInvalidRetry)
+}
+
+func Test_Config_Spool(t *testing.T) {
+	if conf, err := newConfig(); assert.NoError(t, err) {
+		assert.Empty(t, conf.spoolDir)
+		assert.Equal(t, DefaultSpoolMaxAge, conf.spoolMaxAge)
+	}
+	if conf, err := newConfig(WithSpoolDir("/tmp/spool"), WithSpoolMaxAge(time.Minute)); assert.NoError(t, err) {
+		assert.Equal(t, "/tmp/spool", conf.spoolDir)
+		assert.Equal(t, time.Minute, conf.spoolMaxAge)
+	}
+}
+
+func Test_stringDefault(t *testing.T) {
+	assert.Equal(t, "a", stringDefault("a", "b"))
+	assert.Equal(t, "b", stringDefault("", "b"))
+	assert.Equal(t, "", stringDefault("", ""))
+	assert.Equal(t, "", stringDefault())
+}
+
+// setenv sets an environment variable for the test duration (t.Setenv requires go 1.17)
+func setenv(t *testing.T, key, value string) {
+	prev, exists := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if exists {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
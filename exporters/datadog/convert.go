@@ -0,0 +1,175 @@
+package datadog
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/naming"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// converter transforms OpenTelemetry spans to Datadog spans.
+type converter struct {
+	conf *config
+}
+
+func newConverter(conf *config) *converter {
+	return &converter{conf: conf}
+}
+
+// convertSpan returns the Datadog representation of an OpenTelemetry span.
+func (obj *converter) convertSpan(src sdktrace.ReadOnlySpan) *span {
+	var dst = newSpan()
+
+	// Identifiers
+	var traceIDHigh uint64
+	dst.TraceID, traceIDHigh = tracecontext.TraceIDToUint64(src.SpanContext().TraceID())
+	dst.SpanID = tracecontext.SpanIDToUint64(src.SpanContext().SpanID())
+	if src.Parent().IsValid() {
+		dst.ParentID = tracecontext.SpanIDToUint64(src.Parent().SpanID())
+	}
+	if traceIDHigh != 0 && isLocalRoot(src) {
+		dst.Meta[keyTraceIDHigh] = fmt.Sprintf("%016x", traceIDHigh)
+	}
+
+	// Timing
+	dst.Start = src.StartTime().UnixNano()
+	dst.Duration = src.EndTime().Sub(src.StartTime()).Nanoseconds()
+
+	// Tags: resource first so that span attributes take precedence
+	if res := src.Resource(); res != nil {
+		setTags(dst, res.Attributes())
+		setUnifiedServiceTags(dst, res.Set())
+	}
+	setTags(dst, src.Attributes())
+	dst.Meta[keySpanKind] = trace.ValidateSpanKind(src.SpanKind()).String()
+
+	setOTelTags(src, dst)
+
+	// Naming
+	setNaming(src, dst)
+
+	// Errors
+	obj.mapError(src, dst)
+
+	// Trace metrics computed on top-level and measured spans
+	markLocalRootTopLevel(src, dst)
+	markMeasured(src, dst)
+
+	// Links and events
+	if links := convertLinks(src.Links()); len(links) != 0 {
+		dst.Meta[keySpanLinks] = encodeLinks(links)
+	}
+	dst.SpanEvents = convertEvents(src.Events())
+
+	// Sampling priority of the trace chunk, read by the agent on local root span
+	if _, ok := dst.Metrics[keySamplingPriority]; !ok && isLocalRoot(src) {
+		dst.Metrics[keySamplingPriority] = samplingPriority(src.SpanContext().TraceFlags())
+	}
+
+	return dst
+}
+
+// setChunkTags sets the trace level tags on the first span of a chunk, where the
+// agent reads them: sampling priority, 128 bit trace ID and propagated "_dd.p."
+// and origin tags found on other spans. A chunk may not hold the local root, when a trace
+// is exported in several batches or partially flushed.
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/spancontext.go (finishedOneLocked)
+func setChunkTags(chunk []*span, spanCtx trace.SpanContext) {
+	var first = chunk[0]
+	for _, s := range chunk[1:] {
+		copyChunkTags(first, s)
+	}
+
+	if _, ok := first.Metrics[keySamplingPriority]; !ok {
+		first.Metrics[keySamplingPriority] = samplingPriority(spanCtx.TraceFlags())
+	}
+	if _, traceIDHigh := tracecontext.TraceIDToUint64(spanCtx.TraceID()); traceIDHigh != 0 {
+		if _, ok := first.Meta[keyTraceIDHigh]; !ok {
+			first.Meta[keyTraceIDHigh] = fmt.Sprintf("%016x", traceIDHigh)
+		}
+	}
+}
+
+// copyChunkTags sets the trace level tags of src on dst, the first span of
+// another chunk of the trace, if not set.
+func copyChunkTags(dst, src *span) {
+	for k, v := range src.Meta {
+		if _, ok := dst.Meta[k]; !ok && isChunkTag(k) {
+			dst.Meta[k] = v
+		}
+	}
+	if v, ok := src.Metrics[keySamplingPriority]; ok {
+		if _, ok := dst.Metrics[keySamplingPriority]; !ok {
+			dst.Metrics[keySamplingPriority] = v
+		}
+	}
+}
+
+func isChunkTag(key string) bool {
+	return strings.HasPrefix(key, keyPropagatedPrefix) || key == keyOrigin
+}
+
+// isLocalRoot returns true if the span has no parent in the local process.
+func isLocalRoot(src sdktrace.ReadOnlySpan) bool {
+	return !src.Parent().IsValid() || src.Parent().IsRemote()
+}
+
+// samplingPriority returns Datadog AUTO_KEEP or AUTO_REJECT priority from the sampled flag.
+func samplingPriority(flags trace.TraceFlags) float64 {
+	if flags.IsSampled() {
+		return priorityAutoKeep
+	}
+	return priorityAutoReject
+}
+
+// setTags stores numeric attributes in metrics and others in meta, like the agent does.
+func setTags(dst *span, attrs []attribute.KeyValue) {
+	for _, kv := range attrs {
+		var key = string(kv.Key)
+		switch kv.Value.Type() {
+		case attribute.INT64:
+			dst.Metrics[key] = float64(kv.Value.AsInt64())
+		case attribute.FLOAT64:
+			dst.Metrics[key] = kv.Value.AsFloat64()
+		default:
+			dst.Meta[key] = naming.Truncate(kv.Value.Emit(), maxMetaValueLen)
+		}
+	}
+}
+
+// setUnifiedServiceTags maps OpenTelemetry resource to Datadog env and version tags.
+func setUnifiedServiceTags(dst *span, res *attribute.Set) {
+	if v, ok := res.Value(semconv.DeploymentEnvironmentKey); ok {
+		dst.Meta[keyEnv] = v.Emit()
+	}
+	if v, ok := res.Value(semconv.ServiceVersionKey); ok {
+		dst.Meta[keyVersion] = v.Emit()
+	}
+}
+
+// setOTelTags keeps OpenTelemetry specific information, like the agent OTLP ingest does.
+func setOTelTags(src sdktrace.ReadOnlySpan, dst *span) {
+	dst.Meta[keyOTelTraceID] = src.SpanContext().TraceID().String()
+	dst.Meta[keyOTelStatusCode] = src.Status().Code.String()
+	if description := src.Status().Description; description != "" {
+		dst.Meta[keyOTelStatusDescription] = description
+	}
+	if lib := src.InstrumentationLibrary(); lib.Name != "" {
+		dst.Meta[keyOTelLibraryName] = lib.Name
+		if lib.Version != "" {
+			dst.Meta[keyOTelLibraryVersion] = lib.Version
+		}
+	}
+
+	// Datadog HTTP status code from stable semantic conventions
+	if _, ok := dst.Metrics[keyHTTPStatusCode]; !ok {
+		if code, ok := httpStatusCode(src.Attributes()); ok {
+			dst.Metrics[keyHTTPStatusCode] = float64(code)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package datadog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/naming"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	test_traceID  = trace.TraceID{0xb8, 0x10, 0xdb, 0xa2, 0x98, 0x03, 0xee, 0x61, 0xe7, 0xc7, 0x1f, 0xf0, 0xc2, 0xc9, 0x5a, 0x9d}
+	test_spanID   = trace.SpanID{0xe7, 0xc7, 0x1f, 0xf0, 0xc2, 0xc9, 0x5a, 0x9d}
+	test_parentID = trace.SpanID{0, 0, 0, 0, 0, 0, 0, 0x01}
+)
+
+func Test_converter_convertSpan(t *testing.T) {
+	var (
+		conv  = test_newConverter(t)
+		start = time.Unix(10, 0)
+	)
+
+	dst := conv.convertSpan(tracetest.SpanStub{
+		Name:        "GET /users",
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: test_traceID, SpanID: test_spanID, TraceFlags: trace.FlagsSampled}),
+		Parent:      trace.NewSpanContext(trace.SpanContextConfig{TraceID: test_traceID, SpanID: test_parentID, Remote: true}),
+		SpanKind:    trace.SpanKindServer,
+		StartTime:   start,
+		EndTime:     start.Add(time.Second),
+		Attributes: []attribute.KeyValue{
+			semconv.HTTPMethodKey.String("GET"),
+			semconv.HTTPRouteKey.String("/users"),
+			semconv.HTTPStatusCodeKey.Int(500),
+			attribute.Float64("ratio", 0.5),
+			attribute.Bool("cached", true),
+			attribute.String("host", "span"),
+		},
+		Status: sdktrace.Status{Code: codes.Error},
+		Resource: resource.NewSchemaless(
+			semconv.ServiceNameKey.String("users"),
+			semconv.DeploymentEnvironmentKey.String("prod"),
+			semconv.ServiceVersionKey.String("1.2.3"),
+			attribute.String("host", "resource"),
+		),
+		InstrumentationLibrary: instrumentation.Library{Name: "go.opentelemetry.io/contrib/net/http", Version: "0.32.0"},
+	}.Snapshot())
+
+	assert.Equal(t, &span{
+		Name:     "http.server.request",
+		Service:  "users",
+		Resource: "GET /users",
+		Type:     "web",
+		Start:    start.UnixNano(),
+		Duration: int64(time.Second),
+		Meta: map[string]string{
+			"service.name":           "users",
+			"deployment.environment": "prod",
+			"service.version":        "1.2.3",
+			"env":                    "prod",
+			"version":                "1.2.3",
+			"http.method":            "GET",
+			"http.route":             "/users",
+			"cached":                 "true",
+			"host":                   "span",
+			keySpanKind:              "server",
+			keyTraceIDHigh:           "b810dba29803ee61",
+			keyErrorMessage:          "500: Internal Server Error",
+			keyOTelTraceID:           "b810dba29803ee61e7c71ff0c2c95a9d",
+			keyOTelStatusCode:        "Error",
+			keyOTelLibraryName:       "go.opentelemetry.io/contrib/net/http",
+			keyOTelLibraryVersion:    "0.32.0",
+		},
+		Metrics: map[string]float64{
+			"http.status_code":  500,
+			"ratio":             0.5,
+			keySamplingPriority: priorityAutoKeep,
+			keyTopLevel:         1,
+			keyMeasured:         1,
+		},
+		SpanID:   16701352862047361693,
+		TraceID:  16701352862047361693,
+		ParentID: 1,
+		Error:    1,
+	}, dst)
+}
+
+func Test_converter_convertSpan_Child(t *testing.T) {
+	var conv = test_newConverter(t)
+
+	dst := conv.convertSpan(tracetest.SpanStub{
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: test_traceID, SpanID: test_spanID}),
+		Parent:      trace.NewSpanContext(trace.SpanContextConfig{TraceID: test_traceID, SpanID: test_parentID}),
+	}.Snapshot())
+
+	// Check trace ID high and priority only set on local root
+	assert.NotContains(t, dst.Meta, keyTraceIDHigh)
+	assert.NotContains(t, dst.Metrics, keySamplingPriority)
+	assert.Equal(t, uint64(1), dst.ParentID)
+	assert.Equal(t, naming.DefaultServiceName, dst.Service)
+	assert.Equal(t, "internal", dst.Name)
+	assert.Equal(t, "custom", dst.Type)
+}
+
+func Test_converter_convertSpan_LinksEvents(t *testing.T) {
+	var conv = test_newConverter(t)
+
+	dst := conv.convertSpan(tracetest.SpanStub{
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: test_traceID, SpanID: test_spanID}),
+		Attributes:  []attribute.KeyValue{attribute.Int(keySamplingPriority, priorityUserKeep)},
+		Links: []sdktrace.Link{{
+			SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{15: 1}, SpanID: trace.SpanID{7: 2}}),
+		}},
+		Events: []sdktrace.Event{{Name: "message", Time: time.Unix(0, 1000)}},
+	}.Snapshot())
+
+	assert.Equal(t, `[{"trace_id":1,"span_id":2,"flags":2147483648}]`, dst.Meta[keySpanLinks])
+	assert.Equal(t, []spanEvent{{Name: "message", TimeUnixNano: 1000}}, dst.SpanEvents)
+	// Check priority set by attribute kept
+	assert.Equal(t, float64(priorityUserKeep), dst.Metrics[keySamplingPriority])
+}
+
+func Test_samplingPriority(t *testing.T) {
+	assert.Equal(t, float64(priorityAutoKeep), samplingPriority(trace.FlagsSampled))
+	assert.Equal(t, float64(priorityAutoReject), samplingPriority(0))
+}
+
+func Test_setChunkTags(t *testing.T) {
+	var spanCtx = trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{0: 1, 15: 1}, SpanID: trace.SpanID{7: 1}})
+
+	// Chunk without local root: tags taken from other spans
+	var first, other = newSpan(), newSpan()
+	other.Meta["_dd.p.dm"] = "-3"
+	other.Meta[keyOrigin] = "synthetics"
+	other.Meta["other"] = "value"
+	other.Metrics[keySamplingPriority] = priorityUserKeep
+	setChunkTags([]*span{first, other}, spanCtx)
+	assert.Equal(t, map[string]string{"_dd.p.dm": "-3", keyOrigin: "synthetics", keyTraceIDHigh: "0100000000000000"}, first.Meta)
+	assert.Equal(t, map[string]float64{keySamplingPriority: priorityUserKeep}, first.Metrics)
+
+	// Priority from sampled flag, first span tags kept
+	first = newSpan()
+	first.Meta[keyTraceIDHigh] = "kept"
+	setChunkTags([]*span{first}, spanCtx)
+	assert.Equal(t, map[string]string{keyTraceIDHigh: "kept"}, first.Meta)
+	assert.Equal(t, map[string]float64{keySamplingPriority: priorityAutoReject}, first.Metrics)
+}
+
+func Test_setOTelTags(t *testing.T) {
+	var dst = newSpan()
+	setOTelTags(tracetest.SpanStub{
+		Attributes: []attribute.KeyValue{httpResponseStatusCodeKey.Int(404)},
+		Status:     sdktrace.Status{Code: codes.Error, Description: "not found"},
+	}.Snapshot(), dst)
+
+	assert.Equal(t, map[string]string{
+		keyOTelTraceID:           "00000000000000000000000000000000",
+		keyOTelStatusCode:        "Error",
+		keyOTelStatusDescription: "not found",
+	}, dst.Meta)
+	assert.Equal(t, map[string]float64{keyHTTPStatusCode: 404}, dst.Metrics)
+}
@@ -0,0 +1,149 @@
+package datadog
+
+import (
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/msgpack"
+)
+
+// encoder serializes Datadog traces in the agent v0.4 msgpack format.
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/trace/api/version.go
+type encoder struct {
+	// nativeSpanEvents encodes span events in the span_events field when the
+	// agent supports them, otherwise in the events meta.
+	nativeSpanEvents bool
+}
+
+// encode returns the payload of a list of traces, a trace being a list of spans.
+func (obj encoder) encode(traces [][]*span) []byte {
+	var b = msgpack.AppendArrayHeader(nil, len(traces))
+	for _, trace := range traces {
+		b = obj.appendTrace(b, trace)
+	}
+	return b
+}
+
+// encodePayloads returns the payloads of a list of traces, of at most maxSize
+// bytes when possible. Traces larger than maxSize are split in several chunks.
+func (obj encoder) encodePayloads(traces [][]*span, maxSize int) []tracePayload {
+	var (
+		payloads   []tracePayload
+		current    tracePayload
+		chunks     []byte
+		chunkCount int
+	)
+	var flush = func() {
+		current.data = append(msgpack.AppendArrayHeader(nil, chunkCount), chunks...)
+		payloads = append(payloads, current)
+		current, chunks, chunkCount = tracePayload{}, nil, 0
+	}
+
+	// A split trace is counted once, in the payload of its first chunk
+	for _, trace := range traces {
+		for i, chunk := range obj.encodeChunks(trace, maxSize) {
+			if chunkCount != 0 && len(chunks)+len(chunk.data)+arrayHeaderMaxSize > maxSize {
+				flush()
+			}
+			chunks = append(chunks, chunk.data...)
+			chunkCount++
+			if i == 0 {
+				current.traceCount++
+			}
+			current.spanCount += chunk.spanCount
+		}
+	}
+	if chunkCount != 0 {
+		flush()
+	}
+	return payloads
+}
+
+// arrayHeaderMaxSize is the maximum size of a msgpack array header
+const arrayHeaderMaxSize = 5
+
+type encodedChunk struct {
+	data      []byte
+	spanCount int
+}
+
+// encodeChunks encodes spans in one chunk, or several ones if larger than maxSize,
+// the chunk tags being copied to the first span of each chunk.
+func (obj encoder) encodeChunks(trace []*span, maxSize int) []encodedChunk {
+	var b = obj.appendTrace(nil, trace)
+	if len(b)+arrayHeaderMaxSize <= maxSize || len(trace) == 1 {
+		return []encodedChunk{{data: b, spanCount: len(trace)}}
+	}
+	var half = len(trace) / 2
+	copyChunkTags(trace[half], trace[0])
+	return append(obj.encodeChunks(trace[:half], maxSize), obj.encodeChunks(trace[half:], maxSize)...)
+}
+
+func (obj encoder) appendTrace(b []byte, trace []*span) []byte {
+	b = msgpack.AppendArrayHeader(b, len(trace))
+	for _, s := range trace {
+		b = obj.appendSpan(b, s)
+	}
+	return b
+}
+
+func (obj encoder) appendSpan(b []byte, s *span) []byte {
+	var (
+		meta         = s.Meta
+		nativeEvents = obj.nativeSpanEvents && len(s.SpanEvents) != 0
+		fieldCount   = 12
+	)
+	if nativeEvents {
+		fieldCount++
+	} else {
+		meta = metaWithEvents(s)
+	}
+
+	b = msgpack.AppendMapHeader(b, fieldCount)
+	b = msgpack.AppendString(b, "name")
+	b = msgpack.AppendString(b, s.Name)
+	b = msgpack.AppendString(b, "service")
+	b = msgpack.AppendString(b, s.Service)
+	b = msgpack.AppendString(b, "resource")
+	b = msgpack.AppendString(b, s.Resource)
+	b = msgpack.AppendString(b, "type")
+	b = msgpack.AppendString(b, s.Type)
+	b = msgpack.AppendString(b, "start")
+	b = msgpack.AppendInt64(b, s.Start)
+	b = msgpack.AppendString(b, "duration")
+	b = msgpack.AppendInt64(b, s.Duration)
+	b = msgpack.AppendString(b, "meta")
+	b = msgpack.AppendMapHeader(b, len(meta))
+	for k, v := range meta {
+		b = msgpack.AppendString(msgpack.AppendString(b, k), v)
+	}
+	b = msgpack.AppendString(b, "metrics")
+	b = msgpack.AppendMapHeader(b, len(s.Metrics))
+	for k, v := range s.Metrics {
+		b = msgpack.AppendFloat64(msgpack.AppendString(b, k), v)
+	}
+	b = msgpack.AppendString(b, "span_id")
+	b = msgpack.AppendUint64(b, s.SpanID)
+	b = msgpack.AppendString(b, "trace_id")
+	b = msgpack.AppendUint64(b, s.TraceID)
+	b = msgpack.AppendString(b, "parent_id")
+	b = msgpack.AppendUint64(b, s.ParentID)
+	b = msgpack.AppendString(b, "error")
+	b = msgpack.AppendInt64(b, int64(s.Error))
+	if nativeEvents {
+		b = msgpack.AppendString(b, "span_events")
+		b = appendEventsMsgpack(b, s.SpanEvents)
+	}
+	return b
+}
+
+// metaWithEvents returns the span meta with span events JSON encoded in the
+// events meta, the span being kept unchanged.
+func metaWithEvents(s *span) map[string]string {
+	if len(s.SpanEvents) == 0 {
+		return s.Meta
+	}
+	var meta = make(map[string]string, len(s.Meta)+1)
+	for k, v := range s.Meta {
+		meta[k] = v
+	}
+	meta[keySpanEvents] = encodeEventsJSON(s.SpanEvents)
+	return meta
+}
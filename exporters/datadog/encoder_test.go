@@ -0,0 +1,128 @@
+package datadog
+
+import (
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/msgpack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func test_decodeTraces(t *testing.T, payload []byte) []interface{} {
+	value, remaining, err := msgpack.Decode(payload)
+	require.NoError(t, err)
+	require.Empty(t, remaining)
+	return value.([]interface{})
+}
+
+func test_span() *span {
+	return &span{
+		Name:     "name",
+		Service:  "service",
+		Resource: "resource",
+		Type:     "web",
+		Start:    10,
+		Duration: 20,
+		Meta:     map[string]string{"key": "value"},
+		Metrics:  map[string]float64{"metric": 1.5},
+		SpanID:   1,
+		TraceID:  2,
+		ParentID: 3,
+		Error:    1,
+	}
+}
+
+func Test_encoder_encode(t *testing.T) {
+	var expected = map[string]interface{}{
+		"name":      "name",
+		"service":   "service",
+		"resource":  "resource",
+		"type":      "web",
+		"start":     uint64(10),
+		"duration":  uint64(20),
+		"meta":      map[string]interface{}{"key": "value"},
+		"metrics":   map[string]interface{}{"metric": 1.5},
+		"span_id":   uint64(1),
+		"trace_id":  uint64(2),
+		"parent_id": uint64(3),
+		"error":     uint64(1),
+	}
+
+	traces := test_decodeTraces(t, encoder{}.encode([][]*span{{test_span(), test_span()}, {test_span()}}))
+	assert.Equal(t, []interface{}{
+		[]interface{}{expected, expected},
+		[]interface{}{expected},
+	}, traces)
+
+	assert.Equal(t, []interface{}{}, test_decodeTraces(t, encoder{}.encode(nil)))
+}
+
+func Test_encoder_encode_SpanEvents(t *testing.T) {
+	var s = test_span()
+	s.SpanEvents = []spanEvent{{Name: "message", TimeUnixNano: 1000}}
+
+	// Check events in meta
+	traces := test_decodeTraces(t, encoder{}.encode([][]*span{{s}}))
+	ddSpan := traces[0].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{
+		"key":         "value",
+		keySpanEvents: `[{"name":"message","time_unix_nano":1000}]`,
+	}, ddSpan["meta"])
+	assert.NotContains(t, ddSpan, "span_events")
+	// Check span unchanged
+	assert.NotContains(t, s.Meta, keySpanEvents)
+
+	// Check native events
+	traces = test_decodeTraces(t, encoder{nativeSpanEvents: true}.encode([][]*span{{s}}))
+	ddSpan = traces[0].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"key": "value"}, ddSpan["meta"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"name":           "message",
+		"time_unix_nano": uint64(1000),
+		"attributes":     map[string]interface{}{},
+	}}, ddSpan["span_events"])
+}
+
+func Test_encoder_encodePayloads(t *testing.T) {
+	var (
+		enc      = encoder{}
+		spanSize = len(enc.appendSpan(nil, test_span()))
+		maxSize  = 3*spanSize + 10
+		small    = []*span{test_span()}
+		large    = []*span{test_span(), test_span(), test_span(), test_span(), test_span()}
+	)
+
+	// Check traces grouped up to maximum size
+	payloads := enc.encodePayloads([][]*span{small, small, small, small}, maxSize)
+	require.Len(t, payloads, 2)
+	for _, payload := range payloads {
+		assert.LessOrEqual(t, len(payload.data), maxSize)
+		assert.Len(t, test_decodeTraces(t, payload.data), payload.traceCount)
+	}
+	assert.Equal(t, 3, payloads[0].traceCount)
+	assert.Equal(t, 3, payloads[0].spanCount)
+	assert.Equal(t, 1, payloads[1].traceCount)
+
+	// Check large trace split in chunks, counted once, with chunk tags on each chunk
+	large[0].Meta["_dd.p.dm"] = "-3"
+	large[0].Metrics[keySamplingPriority] = priorityUserKeep
+	payloads = enc.encodePayloads([][]*span{large}, maxSize)
+	var spanCount, traceCount int
+	for _, payload := range payloads {
+		assert.LessOrEqual(t, len(payload.data), maxSize)
+		spanCount += payload.spanCount
+		traceCount += payload.traceCount
+		for _, chunk := range test_decodeTraces(t, payload.data) {
+			var first = chunk.([]interface{})[0].(map[string]interface{})
+			assert.Equal(t, "-3", first["meta"].(map[string]interface{})["_dd.p.dm"])
+			assert.Equal(t, float64(priorityUserKeep), first["metrics"].(map[string]interface{})[keySamplingPriority])
+		}
+	}
+	assert.Greater(t, len(payloads), 1)
+	assert.Equal(t, len(large), spanCount)
+	assert.Equal(t, 1, traceCount)
+	assert.Equal(t, 1, payloads[0].traceCount)
+
+	// Check no trace, no payload
+	assert.Empty(t, enc.encodePayloads(nil, maxSize))
+}
@@ -0,0 +1,161 @@
+package datadog
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/naming"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// _____________________ Error mapping _____________________
+
+// Ref https://docs.datadoghq.com/tracing/error_tracking/#use-span-tags-to-track-error-spans
+
+// mapError sets Datadog error flag and error tags from OpenTelemetry span status
+// and exception events.
+func (obj *converter) mapError(src sdktrace.ReadOnlySpan, dst *span) {
+	statusCode, isHTTP := httpStatusCode(src.Attributes())
+	if !obj.isError(src, statusCode, isHTTP) {
+		return
+	}
+	dst.Error = 1
+
+	// Last exception event is the one which terminated the span
+	if event, ok := lastExceptionEvent(src.Events()); ok {
+		for _, kv := range event.Attributes {
+			switch kv.Key {
+			case semconv.ExceptionTypeKey:
+				dst.Meta[keyErrorType] = kv.Value.Emit()
+			case semconv.ExceptionMessageKey:
+				dst.Meta[keyErrorMessage] = kv.Value.Emit()
+			case semconv.ExceptionStacktraceKey:
+				dst.Meta[keyErrorStack] = naming.Truncate(kv.Value.Emit(), maxMetaValueLen)
+			}
+		}
+	}
+
+	// Error message fallback when no exception recorded
+	if _, ok := dst.Meta[keyErrorMessage]; !ok {
+		if desc := src.Status().Description; desc != "" {
+			dst.Meta[keyErrorMessage] = desc
+		} else if isHTTP {
+			// Same format as dd-trace-go HTTP integrations
+			dst.Meta[keyErrorMessage] = fmt.Sprintf("%d: %s", statusCode, http.StatusText(statusCode))
+		}
+	}
+}
+
+// isError decides whether the span is an error for Datadog. An Error status
+// always wins, HTTP server and client spans being errors too if their status
+// code is in the configured ranges.
+func (obj *converter) isError(src sdktrace.ReadOnlySpan, statusCode int, isHTTP bool) bool {
+	if src.Status().Code == codes.Error {
+		return true
+	}
+	if isHTTP {
+		switch src.SpanKind() {
+		case trace.SpanKindServer:
+			return obj.conf.httpServerErrorRanges.contains(statusCode)
+		case trace.SpanKindClient:
+			return obj.conf.httpClientErrorRanges.contains(statusCode)
+		}
+	}
+	return false
+}
+
+func lastExceptionEvent(events []sdktrace.Event) (sdktrace.Event, bool) {
+	for i := len(events) - 1; i >= 0; i-- {
+		if events[i].Name == semconv.ExceptionEventName {
+			return events[i], true
+		}
+	}
+	return sdktrace.Event{}, false
+}
+
+// httpResponseStatusCodeKey is the stable HTTP semantic convention replacing http.status_code
+const httpResponseStatusCodeKey = attribute.Key("http.response.status_code")
+
+func httpStatusCode(attrs []attribute.KeyValue) (int, bool) {
+	for _, kv := range attrs {
+		if kv.Key != semconv.HTTPStatusCodeKey && kv.Key != httpResponseStatusCodeKey {
+			continue
+		}
+		switch kv.Value.Type() {
+		case attribute.INT64:
+			return int(kv.Value.AsInt64()), true
+		case attribute.STRING:
+			if code, err := strconv.Atoi(kv.Value.AsString()); err == nil {
+				return code, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// _____________________ Status ranges _____________________
+
+type statusRange struct {
+	min, max int
+}
+
+type statusRanges []statusRange
+
+func (obj statusRanges) contains(code int) bool {
+	for _, v := range obj {
+		if code >= v.min && code <= v.max {
+			return true
+		}
+	}
+	return false
+}
+
+// parseStatusRanges parses Datadog HTTP statuses format: comma separated list of
+// status codes or inclusive ranges (ex: "400-403,405,500-599").
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/internal/env.go
+func parseStatusRanges(value string) (statusRanges, error) {
+	var ranges statusRanges
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		var (
+			bounds = strings.SplitN(item, "-", 2)
+			r      statusRange
+			err    error
+		)
+		if r.min, err = parseStatusCode(bounds[0]); err != nil {
+			return nil, err
+		}
+		r.max = r.min
+		if len(bounds) == 2 {
+			if r.max, err = parseStatusCode(bounds[1]); err != nil {
+				return nil, err
+			}
+		}
+		if r.min > r.max {
+			return nil, fmt.Errorf("invalid range %q", item)
+		}
+		ranges = append(ranges, r)
+	}
+	return ranges, nil
+}
+
+func parseStatusCode(value string) (int, error) {
+	code, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return 0, err
+	}
+	if code < 100 || code > 999 {
+		return 0, fmt.Errorf("invalid status code %d", code)
+	}
+	return code, nil
+}
@@ -0,0 +1,145 @@
+package datadog
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func test_newConverter(t *testing.T, cfg ...configFn) *converter {
+	conf, err := newConfig(cfg...)
+	require.NoError(t, err)
+	return newConverter(conf)
+}
+
+func test_exceptionEvent(typ, msg, stack string) sdktrace.Event {
+	return sdktrace.Event{
+		Name: semconv.ExceptionEventName,
+		Attributes: []attribute.KeyValue{
+			semconv.ExceptionTypeKey.String(typ),
+			semconv.ExceptionMessageKey.String(msg),
+			semconv.ExceptionStacktraceKey.String(stack),
+		},
+	}
+}
+
+func Test_converter_mapError(t *testing.T) {
+	var conv = test_newConverter(t)
+
+	// Check no error
+	dst := newSpan()
+	conv.mapError(tracetest.SpanStub{}.Snapshot(), dst)
+	assert.Zero(t, dst.Error)
+	assert.Empty(t, dst.Meta)
+
+	// Check last exception event used
+	dst = newSpan()
+	conv.mapError(tracetest.SpanStub{
+		Status: sdktrace.Status{Code: codes.Error, Description: "failure"},
+		Events: []sdktrace.Event{
+			test_exceptionEvent("first", "first message", "first stack"),
+			test_exceptionEvent("*errors.errorString", "last message", "last stack"),
+			{Name: "other"},
+		},
+	}.Snapshot(), dst)
+	assert.Equal(t, int32(1), dst.Error)
+	assert.Equal(t, map[string]string{
+		keyErrorType:    "*errors.errorString",
+		keyErrorMessage: "last message",
+		keyErrorStack:   "last stack",
+	}, dst.Meta)
+
+	// Check status description used without exception
+	dst = newSpan()
+	conv.mapError(tracetest.SpanStub{Status: sdktrace.Status{Code: codes.Error, Description: "failure"}}.Snapshot(), dst)
+	assert.Equal(t, int32(1), dst.Error)
+	assert.Equal(t, map[string]string{keyErrorMessage: "failure"}, dst.Meta)
+
+	// Check stack truncated
+	dst = newSpan()
+	conv.mapError(tracetest.SpanStub{
+		Status: sdktrace.Status{Code: codes.Error},
+		Events: []sdktrace.Event{test_exceptionEvent("type", "msg", strings.Repeat("s", maxMetaValueLen+10))},
+	}.Snapshot(), dst)
+	assert.Len(t, dst.Meta[keyErrorStack], maxMetaValueLen)
+}
+
+func Test_converter_mapError_HTTP(t *testing.T) {
+	var conv = test_newConverter(t, WithHTTPClientErrorStatuses("400-403"))
+
+	var test = func(kind trace.SpanKind, statusCode int, code codes.Code) *span {
+		dst := newSpan()
+		conv.mapError(tracetest.SpanStub{
+			SpanKind:   kind,
+			Status:     sdktrace.Status{Code: code},
+			Attributes: []attribute.KeyValue{semconv.HTTPStatusCodeKey.Int(statusCode)},
+		}.Snapshot(), dst)
+		return dst
+	}
+
+	// Check server statuses
+	dst := test(trace.SpanKindServer, 503, codes.Unset)
+	assert.Equal(t, int32(1), dst.Error)
+	assert.Equal(t, "503: Service Unavailable", dst.Meta[keyErrorMessage])
+	assert.Zero(t, test(trace.SpanKindServer, 404, codes.Unset).Error)
+	assert.Zero(t, test(trace.SpanKindServer, 404, codes.Ok).Error)
+
+	// Check client statuses
+	assert.Equal(t, int32(1), test(trace.SpanKindClient, 403, codes.Unset).Error)
+	assert.Zero(t, test(trace.SpanKindClient, 404, codes.Unset).Error)
+
+	// Check explicit Error status always wins over ranges
+	assert.Equal(t, int32(1), test(trace.SpanKindServer, 404, codes.Error).Error)
+	dst = test(trace.SpanKindClient, 200, codes.Error)
+	assert.Equal(t, int32(1), dst.Error)
+	assert.Equal(t, "200: OK", dst.Meta[keyErrorMessage])
+
+	// Check other kinds rely on status
+	assert.Equal(t, int32(1), test(trace.SpanKindInternal, 200, codes.Error).Error)
+	assert.Zero(t, test(trace.SpanKindInternal, 500, codes.Unset).Error)
+}
+
+func Test_httpStatusCode(t *testing.T) {
+	_, ok := httpStatusCode(nil)
+	assert.False(t, ok)
+
+	code, ok := httpStatusCode([]attribute.KeyValue{semconv.HTTPStatusCodeKey.Int(404)})
+	assert.True(t, ok)
+	assert.Equal(t, 404, code)
+
+	code, ok = httpStatusCode([]attribute.KeyValue{httpResponseStatusCodeKey.String("502")})
+	assert.True(t, ok)
+	assert.Equal(t, 502, code)
+
+	_, ok = httpStatusCode([]attribute.KeyValue{semconv.HTTPStatusCodeKey.String("abc")})
+	assert.False(t, ok)
+}
+
+func Test_parseStatusRanges(t *testing.T) {
+	ranges, err := parseStatusRanges("400-403, 405,500-599,")
+	if assert.NoError(t, err) {
+		assert.Equal(t, statusRanges{{400, 403}, {405, 405}, {500, 599}}, ranges)
+		assert.True(t, ranges.contains(400))
+		assert.True(t, ranges.contains(405))
+		assert.True(t, ranges.contains(599))
+		assert.False(t, ranges.contains(404))
+		assert.False(t, ranges.contains(200))
+	}
+
+	ranges, err = parseStatusRanges("")
+	assert.NoError(t, err)
+	assert.Empty(t, ranges)
+
+	for _, v := range []string{"abc", "500-abc", "99", "1000", "599-500"} {
+		_, err = parseStatusRanges(v)
+		assert.Error(t, err, v)
+	}
+}
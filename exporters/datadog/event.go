@@ -0,0 +1,150 @@
+package datadog
+
+import (
+	"encoding/json"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/msgpack"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// spanEvent is the Datadog representation of a span event.
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/span_event.go
+type spanEvent struct {
+	Name         string
+	TimeUnixNano uint64
+	Attributes   []attribute.KeyValue
+}
+
+// keySpanEvents is the meta used to store span events when agent does not support them natively
+const keySpanEvents = "events"
+
+func convertEvents(events []sdktrace.Event) []spanEvent {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var dst = make([]spanEvent, 0, len(events))
+	for _, event := range events {
+		dst = append(dst, spanEvent{
+			Name:         event.Name,
+			TimeUnixNano: uint64(event.Time.UnixNano()),
+			Attributes:   event.Attributes,
+		})
+	}
+	return dst
+}
+
+// _____________________ JSON format (meta) _____________________
+
+type spanEventJSON struct {
+	Name         string                 `json:"name"`
+	TimeUnixNano uint64                 `json:"time_unix_nano"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+}
+
+func encodeEventsJSON(events []spanEvent) string {
+	var dst = make([]spanEventJSON, 0, len(events))
+	for _, event := range events {
+		var v = spanEventJSON{Name: event.Name, TimeUnixNano: event.TimeUnixNano}
+		if len(event.Attributes) != 0 {
+			v.Attributes = make(map[string]interface{}, len(event.Attributes))
+			for _, kv := range event.Attributes {
+				v.Attributes[string(kv.Key)] = kv.Value.AsInterface()
+			}
+		}
+		dst = append(dst, v)
+	}
+
+	// No error can happen since only basic types are used
+	data, _ := json.Marshal(dst)
+	return string(data)
+}
+
+// _____________________ Native format (msgpack) _____________________
+
+// Attribute types of native span events
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/proto/datadog/trace/span.proto
+const (
+	eventAttrString = 0
+	eventAttrBool   = 1
+	eventAttrInt    = 2
+	eventAttrDouble = 3
+	eventAttrArray  = 4
+)
+
+func appendEventsMsgpack(b []byte, events []spanEvent) []byte {
+	b = msgpack.AppendArrayHeader(b, len(events))
+	for _, event := range events {
+		b = msgpack.AppendMapHeader(b, 3)
+		b = msgpack.AppendString(b, "name")
+		b = msgpack.AppendString(b, event.Name)
+		b = msgpack.AppendString(b, "time_unix_nano")
+		b = msgpack.AppendUint64(b, event.TimeUnixNano)
+		b = msgpack.AppendString(b, "attributes")
+		b = msgpack.AppendMapHeader(b, len(event.Attributes))
+		for _, kv := range event.Attributes {
+			b = msgpack.AppendString(b, string(kv.Key))
+			b = appendEventAttributeMsgpack(b, kv.Value)
+		}
+	}
+	return b
+}
+
+func appendEventAttributeMsgpack(b []byte, value attribute.Value) []byte {
+	switch value.Type() {
+	case attribute.BOOL:
+		return appendEventScalarMsgpack(b, eventAttrBool, "bool_value", msgpack.AppendBool(nil, value.AsBool()))
+	case attribute.INT64:
+		return appendEventScalarMsgpack(b, eventAttrInt, "int_value", msgpack.AppendInt64(nil, value.AsInt64()))
+	case attribute.FLOAT64:
+		return appendEventScalarMsgpack(b, eventAttrDouble, "double_value", msgpack.AppendFloat64(nil, value.AsFloat64()))
+	case attribute.BOOLSLICE:
+		var values = value.AsBoolSlice()
+		b = appendEventArrayHeaderMsgpack(b, len(values))
+		for _, v := range values {
+			b = appendEventScalarMsgpack(b, eventAttrBool, "bool_value", msgpack.AppendBool(nil, v))
+		}
+		return b
+	case attribute.INT64SLICE:
+		var values = value.AsInt64Slice()
+		b = appendEventArrayHeaderMsgpack(b, len(values))
+		for _, v := range values {
+			b = appendEventScalarMsgpack(b, eventAttrInt, "int_value", msgpack.AppendInt64(nil, v))
+		}
+		return b
+	case attribute.FLOAT64SLICE:
+		var values = value.AsFloat64Slice()
+		b = appendEventArrayHeaderMsgpack(b, len(values))
+		for _, v := range values {
+			b = appendEventScalarMsgpack(b, eventAttrDouble, "double_value", msgpack.AppendFloat64(nil, v))
+		}
+		return b
+	case attribute.STRINGSLICE:
+		var values = value.AsStringSlice()
+		b = appendEventArrayHeaderMsgpack(b, len(values))
+		for _, v := range values {
+			b = appendEventScalarMsgpack(b, eventAttrString, "string_value", msgpack.AppendString(nil, v))
+		}
+		return b
+	}
+	return appendEventScalarMsgpack(b, eventAttrString, "string_value", msgpack.AppendString(nil, value.Emit()))
+}
+
+func appendEventScalarMsgpack(b []byte, typ int64, key string, encodedValue []byte) []byte {
+	b = msgpack.AppendMapHeader(b, 2)
+	b = msgpack.AppendString(b, "type")
+	b = msgpack.AppendInt64(b, typ)
+	b = msgpack.AppendString(b, key)
+	return append(b, encodedValue...)
+}
+
+func appendEventArrayHeaderMsgpack(b []byte, size int) []byte {
+	b = msgpack.AppendMapHeader(b, 2)
+	b = msgpack.AppendString(b, "type")
+	b = msgpack.AppendInt64(b, eventAttrArray)
+	b = msgpack.AppendString(b, "array_value")
+	b = msgpack.AppendMapHeader(b, 1)
+	b = msgpack.AppendString(b, "values")
+	return msgpack.AppendArrayHeader(b, size)
+}
@@ -0,0 +1,70 @@
+package datadog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/msgpack"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+var test_events = []spanEvent{{
+	Name:         "message",
+	TimeUnixNano: 1000,
+	Attributes: []attribute.KeyValue{
+		attribute.String("string", "value"),
+		attribute.Bool("bool", true),
+		attribute.Int("int", 1),
+		attribute.Float64("float", 1.5),
+		attribute.StringSlice("strings", []string{"a"}),
+	},
+}, {
+	Name:         "empty",
+	TimeUnixNano: 2000,
+}}
+
+func Test_convertEvents(t *testing.T) {
+	assert.Nil(t, convertEvents(nil))
+	assert.Equal(t, []spanEvent{{
+		Name:         "message",
+		TimeUnixNano: 1000,
+		Attributes:   []attribute.KeyValue{attribute.String("key", "value")},
+	}}, convertEvents([]sdktrace.Event{{
+		Name:       "message",
+		Time:       time.Unix(0, 1000),
+		Attributes: []attribute.KeyValue{attribute.String("key", "value")},
+	}}))
+}
+
+func Test_encodeEventsJSON(t *testing.T) {
+	assert.Equal(t,
+		`[{"name":"message","time_unix_nano":1000,"attributes":{"bool":true,"float":1.5,"int":1,"string":"value","strings":["a"]}},{"name":"empty","time_unix_nano":2000}]`,
+		encodeEventsJSON(test_events))
+}
+
+func Test_appendEventsMsgpack(t *testing.T) {
+	value, _, err := msgpack.Decode(appendEventsMsgpack(nil, test_events))
+	assert.NoError(t, err)
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{
+			"name":           "message",
+			"time_unix_nano": uint64(1000),
+			"attributes": map[string]interface{}{
+				"string": map[string]interface{}{"type": uint64(eventAttrString), "string_value": "value"},
+				"bool":   map[string]interface{}{"type": uint64(eventAttrBool), "bool_value": true},
+				"int":    map[string]interface{}{"type": uint64(eventAttrInt), "int_value": uint64(1)},
+				"float":  map[string]interface{}{"type": uint64(eventAttrDouble), "double_value": 1.5},
+				"strings": map[string]interface{}{"type": uint64(eventAttrArray), "array_value": map[string]interface{}{
+					"values": []interface{}{map[string]interface{}{"type": uint64(eventAttrString), "string_value": "a"}},
+				}},
+			},
+		},
+		map[string]interface{}{
+			"name":           "empty",
+			"time_unix_nano": uint64(2000),
+			"attributes":     map[string]interface{}{},
+		},
+	}, value)
+}
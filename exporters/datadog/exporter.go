@@ -0,0 +1,326 @@
+package datadog
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporter exports OpenTelemetry spans to the Datadog agent, or to the Datadog
+// intake in agentless mode.
+type Exporter struct {
+	conf   *config
+	conv   *converter
+	agent  *agentClient
+	intake *intakeClient // set in agentless mode
+	writer *traceWriter
+	stats  *concentrator
+
+	metrics *exporterMetrics
+
+	featuresMu       sync.Mutex
+	features         *agentFeatures
+	featuresLoading  bool      // request in progress
+	featuresFailures int       // consecutive failed requests
+	featuresRetryAt  time.Time // next request after a failure
+
+	statsProcessor int32 // set once StatsProcessor is called
+	statsOnce      sync.Once
+	stop           chan struct{}
+	wg             sync.WaitGroup
+	stopped        int32
+}
+
+var _ sdktrace.SpanExporter = (*Exporter)(nil)
+
+// New returns a new exporter sending spans to the Datadog agent.
+// To use the defaults, call with nothing.
+func New(cfg ...configFn) (*Exporter, error) {
+	conf, err := newConfig(cfg...)
+	if err != nil {
+		return nil, err
+	}
+
+	var exporter = &Exporter{
+		conf:    conf,
+		conv:    newConverter(conf),
+		agent:   newAgentClient(conf),
+		stats:   newConcentrator(),
+		metrics: &exporterMetrics{},
+		stop:    make(chan struct{}),
+	}
+	if exporter.writer, err = newTraceWriter(conf, exporter.agent, exporter.metrics); err != nil {
+		return nil, err
+	}
+	if conf.agentless {
+		exporter.intake = newIntakeClient(conf, exporter.metrics)
+	}
+	return exporter, nil
+}
+
+// ExportSpans converts spans to Datadog format and sends them to the agent.
+func (obj *Exporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	if atomic.LoadInt32(&obj.stopped) != 0 || len(spans) == 0 {
+		return nil
+	}
+
+	// Agentless, no agent to sample traces
+	if obj.intake != nil {
+		var traces = obj.convertTraces(spans)
+		var traceCount, spanCount = len(traces), countSpans(traces)
+		traces = dropUnsampledTraces(traces)
+		obj.metrics.droppedP0(traceCount-len(traces), spanCount-countSpans(traces))
+		if len(traces) == 0 {
+			return nil
+		}
+		return obj.intake.sendTraces(ctx, traces)
+	}
+
+	var (
+		features            = obj.agentFeatures(ctx)
+		traces              = obj.convertTraces(spans)
+		clientComputedStats bool
+	)
+
+	// Client-side stats computed by the stats processor, unsampled traces being dropped
+	if obj.computesStats(features) {
+		var traceCount, spanCount = len(traces), countSpans(traces)
+		traces = dropUnsampledTraces(traces)
+		obj.writer.addDroppedP0(traceCount-len(traces), spanCount-countSpans(traces))
+		clientComputedStats = true
+	}
+
+	// Queued payloads are sent even without new traces
+	var payloads = encoder{nativeSpanEvents: features.SpanEvents}.encodePayloads(traces, maxAgentPayloadSize)
+	for i := range payloads {
+		payloads[i].clientComputedStats = clientComputedStats
+	}
+	return obj.writer.write(ctx, payloads)
+}
+
+// InternalMetrics returns the counters of the exporter activity.
+func (obj *Exporter) InternalMetrics() InternalMetrics {
+	var metrics = obj.metrics.snapshot()
+	count, size := obj.writer.stats()
+	metrics.QueuedPayloads, metrics.QueuedBytes = count, size
+	return metrics
+}
+
+// Endpoint returns the URL of the agent, or of the intake in agentless mode,
+// password being redacted.
+func (obj *Exporter) Endpoint() string {
+	var endpoint = obj.conf.agentURL
+	if obj.intake != nil {
+		endpoint = obj.conf.intakeURL
+	}
+	if u, err := url.Parse(endpoint); err == nil {
+		return u.Redacted()
+	}
+	return endpoint
+}
+
+// CheckConnectivity requests the agent info endpoint, returning an error when the
+// agent is unreachable. The intake is not checked in agentless mode.
+func (obj *Exporter) CheckConnectivity(ctx context.Context) error {
+	if obj.intake != nil {
+		return nil
+	}
+	_, err := obj.agent.info(ctx)
+	return err
+}
+
+// Shutdown stops the exporter and flushes stats, spans exported after are dropped.
+func (obj *Exporter) Shutdown(ctx context.Context) error {
+	if !atomic.CompareAndSwapInt32(&obj.stopped, 0, 1) {
+		return nil
+	}
+	close(obj.stop)
+	obj.wg.Wait()
+
+	// Send queued payloads and all stats, including incomplete buckets
+	var err = obj.writer.flush(ctx)
+	if statsErr := obj.flushStats(ctx, time.Now(), true); statsErr != nil {
+		err = statsErr
+	}
+	return err
+}
+
+// featuresRetry is the backoff between failed requests of the agent capabilities.
+var featuresRetry = retryPolicy{initialBackoff: time.Second, maxBackoff: time.Minute}
+
+// agentFeatures returns the agent capabilities, loaded on first successful call.
+// Meanwhile, empty capabilities are returned: failed requests are retried after
+// a backoff, only the first failure being reported.
+func (obj *Exporter) agentFeatures(ctx context.Context) agentFeatures {
+	obj.featuresMu.Lock()
+	if obj.features != nil {
+		defer obj.featuresMu.Unlock()
+		return *obj.features
+	}
+	if obj.featuresLoading || time.Now().Before(obj.featuresRetryAt) {
+		obj.featuresMu.Unlock()
+		return agentFeatures{}
+	}
+	obj.featuresLoading = true
+	obj.featuresMu.Unlock()
+
+	// Requested outside the lock, concurrent exports not waiting for the agent
+	features, err := obj.agent.info(ctx)
+
+	obj.featuresMu.Lock()
+	defer obj.featuresMu.Unlock()
+	obj.featuresLoading = false
+	if err != nil {
+		// Agent could be older or not yet started
+		if obj.featuresFailures == 0 {
+			otel.Handle(err)
+		}
+		obj.featuresRetryAt = time.Now().Add(featuresRetry.backoff(obj.featuresFailures))
+		obj.featuresFailures++
+		return agentFeatures{}
+	}
+	obj.features, obj.featuresFailures = &features, 0
+	return features
+}
+
+// cachedFeatures returns the agent capabilities if already loaded, without requesting the agent.
+func (obj *Exporter) cachedFeatures() agentFeatures {
+	obj.featuresMu.Lock()
+	defer obj.featuresMu.Unlock()
+
+	if obj.features == nil {
+		return agentFeatures{}
+	}
+	return *obj.features
+}
+
+// convertTraces converts spans and groups them by trace, keeping spans order.
+func (obj *Exporter) convertTraces(spans []sdktrace.ReadOnlySpan) [][]*span {
+	var (
+		traces  [][]*span
+		firsts  []sdktrace.ReadOnlySpan
+		indexes = map[trace.TraceID]int{}
+	)
+	for _, s := range spans {
+		var traceID = s.SpanContext().TraceID()
+		index, ok := indexes[traceID]
+		if !ok {
+			index = len(traces)
+			indexes[traceID] = index
+			traces = append(traces, nil)
+			firsts = append(firsts, s)
+		}
+		traces[index] = append(traces[index], obj.conv.convertSpan(s))
+	}
+	for i, trace := range traces {
+		markTopLevel(trace)
+		setChunkTags(trace, firsts[i].SpanContext())
+	}
+	return traces
+}
+
+// _____________________ Stats _____________________
+
+// canComputeStats returns true if stats computation is enabled and supported by the agent.
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/option.go (canComputeStats)
+func (obj *Exporter) canComputeStats(features agentFeatures) bool {
+	return *obj.conf.statsComputation && features.ClientDropP0s && features.hasEndpoint(agentPathStats06)
+}
+
+// computesStats returns true if stats can be computed and the stats processor
+// is registered, unsampled spans being only seen by the processor.
+func (obj *Exporter) computesStats(features agentFeatures) bool {
+	return atomic.LoadInt32(&obj.statsProcessor) != 0 && obj.canComputeStats(features)
+}
+
+// startStatsFlush flushes complete stats buckets periodically until shutdown.
+func (obj *Exporter) startStatsFlush() {
+	obj.wg.Add(1)
+	go func() {
+		defer obj.wg.Done()
+
+		var ticker = time.NewTicker(statsBucketDuration)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				if err := obj.flushStats(context.Background(), now, false); err != nil {
+					otel.Handle(err)
+				}
+			case <-obj.stop:
+				return
+			}
+		}
+	}()
+}
+
+// flushStats sends stats buckets, dropped if the agent does not support them,
+// its traces being then sent without the Datadog-Client-Computed-Stats header.
+func (obj *Exporter) flushStats(ctx context.Context, now time.Time, force bool) error {
+	var payloads = obj.stats.flush(now, force)
+	// Features loaded if no trace was exported yet (ex: all traces rejected)
+	if len(payloads) == 0 || !obj.canComputeStats(obj.agentFeatures(ctx)) {
+		return nil
+	}
+
+	var lastErr error
+	for _, payload := range payloads {
+		var payload = payload
+		retries, err := obj.conf.retryPolicy.do(ctx, func() error {
+			return obj.agent.sendStats(ctx, payload)
+		})
+		atomic.AddUint64(&obj.metrics.retries, uint64(retries))
+		if err != nil {
+			lastErr = err
+		}
+	}
+	return lastErr
+}
+
+// dropUnsampledTraces removes traces with a sampling priority lower or equal to
+// AUTO_REJECT, except their spans kept by single span sampling.
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/spancontext.go (finishedOneLocked)
+func dropUnsampledTraces(traces [][]*span) [][]*span {
+	var kept = traces[:0]
+	for _, trace := range traces {
+		if tracePriority(trace) > priorityAutoReject {
+			kept = append(kept, trace)
+			continue
+		}
+		var singleSpans []*span
+		for _, s := range trace {
+			if _, ok := s.Metrics[keySpanSamplingMechanism]; ok {
+				singleSpans = append(singleSpans, s)
+			}
+		}
+		if len(singleSpans) > 0 {
+			kept = append(kept, singleSpans)
+		}
+	}
+	return kept
+}
+
+// countSpans returns the number of spans of traces.
+func countSpans(traces [][]*span) int {
+	var count int
+	for _, trace := range traces {
+		count += len(trace)
+	}
+	return count
+}
+
+// tracePriority returns the sampling priority of a trace chunk, AUTO_KEEP if not set.
+func tracePriority(trace []*span) float64 {
+	for _, s := range trace {
+		if priority, ok := s.Metrics[keySamplingPriority]; ok {
+			return priority
+		}
+	}
+	return priorityAutoKeep
+}
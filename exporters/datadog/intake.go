@@ -0,0 +1,82 @@
+package datadog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"os"
+)
+
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/trace/writer/trace.go
+
+const (
+	intakePathTraces = "/api/v0.2/traces"
+
+	// maxIntakePayloadSize is the maximum size of an uncompressed payload accepted by the intake
+	maxIntakePayloadSize = 3200000
+)
+
+// intakeClient sends traces to the Datadog intake, without agent.
+type intakeClient struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+	encoder intakeEncoder
+	policy  retryPolicy
+	metrics *exporterMetrics
+}
+
+func newIntakeClient(conf *config, metrics *exporterMetrics) *intakeClient {
+	var hostname, _ = os.Hostname()
+	return &intakeClient{
+		baseURL: conf.intakeBaseURL,
+		apiKey:  conf.apiKey,
+		client:  conf.httpClient,
+		encoder: intakeEncoder{hostname: hostname, maxSize: maxIntakePayloadSize},
+		policy:  *conf.retryPolicy,
+		metrics: metrics,
+	}
+}
+
+// sendTraces posts a list of traces, split in several payloads if needed.
+func (obj *intakeClient) sendTraces(ctx context.Context, traces [][]*span) error {
+	var lastErr error
+	for _, payload := range obj.encoder.encode(traces) {
+		var payload = payload
+		retries, err := obj.policy.do(ctx, func() error {
+			return obj.send(ctx, intakePathTraces, payload.data)
+		})
+		if err != nil {
+			obj.metrics.failed(retries)
+			obj.metrics.dropped(payload.traceCount, payload.spanCount)
+			lastErr = err
+			continue
+		}
+		obj.metrics.sent(payload.traceCount, payload.spanCount, retries)
+	}
+	return lastErr
+}
+
+// send posts a gzip compressed protobuf payload.
+func (obj *intakeClient) send(ctx context.Context, path string, payload []byte) error {
+	var body bytes.Buffer
+	var gz = gzip.NewWriter(&body)
+	if _, err := gz.Write(payload); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, obj.baseURL+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("DD-Api-Key", obj.apiKey)
+	req.Header.Set("X-Datadog-Reported-Languages", "go")
+
+	return sendRequest(obj.client, "intake", req)
+}
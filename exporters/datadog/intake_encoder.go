@@ -0,0 +1,204 @@
+package datadog
+
+import (
+	"runtime"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/protowire"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/version"
+)
+
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/proto/datadog/trace/agent_payload.proto
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/proto/datadog/trace/tracer_payload.proto
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/proto/datadog/trace/span.proto
+
+const (
+	fieldAgentPayloadHostName       = 1
+	fieldAgentPayloadEnv            = 2
+	fieldAgentPayloadTracerPayloads = 5
+
+	fieldTracerPayloadLanguageName    = 2
+	fieldTracerPayloadLanguageVersion = 3
+	fieldTracerPayloadTracerVersion   = 4
+	fieldTracerPayloadRuntimeID       = 5
+	fieldTracerPayloadChunks          = 6
+	fieldTracerPayloadEnv             = 8
+	fieldTracerPayloadAppVersion      = 10
+
+	fieldChunkPriority = 1
+	fieldChunkOrigin   = 2
+	fieldChunkSpans    = 3
+
+	fieldSpanService  = 1
+	fieldSpanName     = 2
+	fieldSpanResource = 3
+	fieldSpanTraceID  = 4
+	fieldSpanSpanID   = 5
+	fieldSpanParentID = 6
+	fieldSpanStart    = 7
+	fieldSpanDuration = 8
+	fieldSpanError    = 9
+	fieldSpanMeta     = 10
+	fieldSpanMetrics  = 11
+	fieldSpanType     = 12
+
+	fieldMapEntryKey   = 1
+	fieldMapEntryValue = 2
+)
+
+const (
+	// chunkOverhead is the maximum size of the field header of a chunk
+	chunkOverhead = 16
+	// tracerPayloadOverhead is the maximum size of a tracer payload without chunks,
+	// env and version
+	tracerPayloadOverhead = 256
+)
+
+// intakeEncoder serializes Datadog traces in the AgentPayload protobuf format of
+// the trace intake, in payloads of at most maxSize bytes when possible.
+type intakeEncoder struct {
+	hostname string
+	maxSize  int
+}
+
+// tracerPayloadKey groups chunks sharing the same tracer payload.
+type tracerPayloadKey struct {
+	env     string
+	version string
+}
+
+// intakeBatch is the content of an agent payload being built.
+type intakeBatch struct {
+	keys       []tracerPayloadKey
+	chunks     map[tracerPayloadKey][][]byte
+	size       int
+	chunkCount int
+	spanCount  int
+}
+
+func newIntakeBatch() *intakeBatch {
+	return &intakeBatch{chunks: map[tracerPayloadKey][][]byte{}}
+}
+
+// sizeWith returns the size of the batch with chunk added.
+func (obj *intakeBatch) sizeWith(key tracerPayloadKey, chunk []byte) int {
+	var size = obj.size + len(chunk) + chunkOverhead
+	if _, ok := obj.chunks[key]; !ok {
+		size += tracerPayloadOverhead + len(key.env) + len(key.version)
+	}
+	return size
+}
+
+func (obj *intakeBatch) add(key tracerPayloadKey, chunk encodedChunk) {
+	obj.size = obj.sizeWith(key, chunk.data)
+	obj.chunkCount++
+	obj.spanCount += chunk.spanCount
+	if _, ok := obj.chunks[key]; !ok {
+		obj.keys = append(obj.keys, key)
+	}
+	obj.chunks[key] = append(obj.chunks[key], chunk.data)
+}
+
+// encode returns the payloads of a list of traces, a trace being a list of spans.
+// Traces are split in several chunks when larger than maxSize.
+func (obj intakeEncoder) encode(traces [][]*span) []tracePayload {
+	var (
+		payloads []tracePayload
+		batch    = newIntakeBatch()
+	)
+	for _, trace := range traces {
+		var (
+			key      = tracerPayloadKey{env: trace[0].Meta[keyEnv], version: trace[0].Meta[keyVersion]}
+			priority = int64(tracePriority(trace))
+			origin   = trace[0].Meta[keyOrigin]
+		)
+		for _, chunk := range obj.encodeChunks(trace, priority, origin) {
+			if batch.size != 0 && batch.sizeWith(key, chunk.data) > obj.maxSize {
+				payloads = append(payloads, obj.encodePayload(batch))
+				batch = newIntakeBatch()
+			}
+			batch.add(key, chunk)
+		}
+	}
+	if batch.size != 0 {
+		payloads = append(payloads, obj.encodePayload(batch))
+	}
+	return payloads
+}
+
+// encodeChunks encodes spans in one chunk, or several ones if larger than maxSize.
+func (obj intakeEncoder) encodeChunks(trace []*span, priority int64, origin string) []encodedChunk {
+	var b = appendProtoVarint(nil, fieldChunkPriority, uint64(priority))
+	b = appendProtoString(b, fieldChunkOrigin, origin)
+	for _, s := range trace {
+		b = appendProtoMessage(b, fieldChunkSpans, appendSpanProto(nil, s))
+	}
+
+	if len(b)+chunkOverhead+tracerPayloadOverhead <= obj.maxSize || len(trace) == 1 {
+		return []encodedChunk{{data: b, spanCount: len(trace)}}
+	}
+	var half = len(trace) / 2
+	return append(obj.encodeChunks(trace[:half], priority, origin), obj.encodeChunks(trace[half:], priority, origin)...)
+}
+
+func (obj intakeEncoder) encodePayload(batch *intakeBatch) tracePayload {
+	var b = appendProtoString(nil, fieldAgentPayloadHostName, obj.hostname)
+	if len(batch.keys) == 1 {
+		b = appendProtoString(b, fieldAgentPayloadEnv, batch.keys[0].env)
+	}
+	for _, key := range batch.keys {
+		var tp = appendProtoString(nil, fieldTracerPayloadLanguageName, "go")
+		tp = appendProtoString(tp, fieldTracerPayloadLanguageVersion, runtime.Version())
+		tp = appendProtoString(tp, fieldTracerPayloadTracerVersion, version.Tag)
+		tp = appendProtoString(tp, fieldTracerPayloadRuntimeID, runtimeID)
+		for _, chunk := range batch.chunks[key] {
+			tp = appendProtoMessage(tp, fieldTracerPayloadChunks, chunk)
+		}
+		tp = appendProtoString(tp, fieldTracerPayloadEnv, key.env)
+		tp = appendProtoString(tp, fieldTracerPayloadAppVersion, key.version)
+		b = appendProtoMessage(b, fieldAgentPayloadTracerPayloads, tp)
+	}
+	return tracePayload{data: b, traceCount: batch.chunkCount, spanCount: batch.spanCount}
+}
+
+func appendSpanProto(b []byte, s *span) []byte {
+	b = appendProtoString(b, fieldSpanService, s.Service)
+	b = appendProtoString(b, fieldSpanName, s.Name)
+	b = appendProtoString(b, fieldSpanResource, s.Resource)
+	b = appendProtoVarint(b, fieldSpanTraceID, s.TraceID)
+	b = appendProtoVarint(b, fieldSpanSpanID, s.SpanID)
+	b = appendProtoVarint(b, fieldSpanParentID, s.ParentID)
+	b = appendProtoVarint(b, fieldSpanStart, uint64(s.Start))
+	b = appendProtoVarint(b, fieldSpanDuration, uint64(s.Duration))
+	b = appendProtoVarint(b, fieldSpanError, uint64(s.Error))
+	for k, v := range metaWithEvents(s) {
+		var entry = appendProtoString(nil, fieldMapEntryKey, k)
+		b = appendProtoMessage(b, fieldSpanMeta, appendProtoString(entry, fieldMapEntryValue, v))
+	}
+	for k, v := range s.Metrics {
+		var entry = appendProtoString(nil, fieldMapEntryKey, k)
+		b = appendProtoMessage(b, fieldSpanMetrics, protowire.AppendDouble(entry, fieldMapEntryValue, v))
+	}
+	return appendProtoString(b, fieldSpanType, s.Type)
+}
+
+// _____________________ Protobuf helpers _____________________
+
+// appendProtoString appends a string field, omitted when empty.
+func appendProtoString(b []byte, num int, value string) []byte {
+	if value == "" {
+		return b
+	}
+	return protowire.AppendString(protowire.AppendTag(b, num, protowire.BytesType), value)
+}
+
+// appendProtoVarint appends a varint field, omitted when zero.
+func appendProtoVarint(b []byte, num int, value uint64) []byte {
+	if value == 0 {
+		return b
+	}
+	return protowire.AppendVarint(protowire.AppendTag(b, num, protowire.VarintType), value)
+}
+
+func appendProtoMessage(b []byte, num int, message []byte) []byte {
+	return protowire.AppendBytes(protowire.AppendTag(b, num, protowire.BytesType), message)
+}
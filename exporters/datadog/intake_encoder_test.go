@@ -0,0 +1,157 @@
+package datadog
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/protowire"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// test_protoFields maps field numbers of a protobuf message to their values.
+type test_protoFields map[int][]test_protoField
+
+type test_protoField struct {
+	value uint64 // varint and fixed64
+	data  []byte // length-delimited
+}
+
+func test_decodeProto(t *testing.T, b []byte) test_protoFields {
+	var fields = test_protoFields{}
+	for len(b) > 0 {
+		num, typ, n, err := protowire.ConsumeTag(b)
+		require.NoError(t, err)
+		b = b[n:]
+		value, data, n, err := protowire.ConsumeFieldValue(b, typ)
+		require.NoError(t, err)
+		b = b[n:]
+		fields[num] = append(fields[num], test_protoField{value: value, data: data})
+	}
+	return fields
+}
+
+func (obj test_protoFields) string(num int) string {
+	if len(obj[num]) == 0 {
+		return ""
+	}
+	return string(obj[num][0].data)
+}
+
+func (obj test_protoFields) varint(num int) uint64 {
+	if len(obj[num]) == 0 {
+		return 0
+	}
+	return obj[num][0].value
+}
+
+// messages decodes the repeated message field num.
+func (obj test_protoFields) messages(t *testing.T, num int) []test_protoFields {
+	var messages []test_protoFields
+	for _, field := range obj[num] {
+		messages = append(messages, test_decodeProto(t, field.data))
+	}
+	return messages
+}
+
+func Test_intakeEncoder_encode(t *testing.T) {
+	var (
+		root = &span{Name: "root", Service: "users", Resource: "GET", Type: "web", TraceID: 1, SpanID: 1, Start: 10, Duration: 5, Error: 1,
+			Meta:    map[string]string{keyEnv: "prod", keyVersion: "1.0", keyOrigin: "synthetics"},
+			Metrics: map[string]float64{keySamplingPriority: priorityUserKeep},
+		}
+		child = &span{Name: "child", TraceID: 1, SpanID: 2, ParentID: 1, Meta: map[string]string{}, Metrics: map[string]float64{},
+			SpanEvents: []spanEvent{{Name: "message"}}}
+		other = &span{Name: "other", TraceID: 2, SpanID: 3, Meta: map[string]string{keyEnv: "dev"}, Metrics: map[string]float64{}}
+	)
+
+	payloads := intakeEncoder{hostname: "host", maxSize: maxIntakePayloadSize}.encode([][]*span{{root, child}, {other}})
+	require.Len(t, payloads, 1)
+
+	var payload = test_decodeProto(t, payloads[0].data)
+	assert.Equal(t, "host", payload.string(fieldAgentPayloadHostName))
+	tracerPayloads := payload.messages(t, fieldAgentPayloadTracerPayloads)
+	require.Len(t, tracerPayloads, 2)
+
+	// Check tracer payloads grouped by env and version
+	var tp = tracerPayloads[0]
+	assert.Equal(t, "go", tp.string(fieldTracerPayloadLanguageName))
+	assert.Equal(t, version.Tag, tp.string(fieldTracerPayloadTracerVersion))
+	assert.Equal(t, runtimeID, tp.string(fieldTracerPayloadRuntimeID))
+	assert.Equal(t, "prod", tp.string(fieldTracerPayloadEnv))
+	assert.Equal(t, "1.0", tp.string(fieldTracerPayloadAppVersion))
+	assert.Equal(t, "dev", tracerPayloads[1].string(fieldTracerPayloadEnv))
+
+	// Check chunk
+	chunks := tp.messages(t, fieldTracerPayloadChunks)
+	require.Len(t, chunks, 1)
+	assert.Equal(t, uint64(priorityUserKeep), chunks[0].varint(fieldChunkPriority))
+	assert.Equal(t, "synthetics", chunks[0].string(fieldChunkOrigin))
+
+	// Check spans
+	spans := chunks[0].messages(t, fieldChunkSpans)
+	require.Len(t, spans, 2)
+	var s = spans[0]
+	assert.Equal(t, "users", s.string(fieldSpanService))
+	assert.Equal(t, "root", s.string(fieldSpanName))
+	assert.Equal(t, "GET", s.string(fieldSpanResource))
+	assert.Equal(t, "web", s.string(fieldSpanType))
+	assert.Equal(t, uint64(1), s.varint(fieldSpanTraceID))
+	assert.Equal(t, uint64(1), s.varint(fieldSpanSpanID))
+	assert.Equal(t, uint64(10), s.varint(fieldSpanStart))
+	assert.Equal(t, uint64(5), s.varint(fieldSpanDuration))
+	assert.Equal(t, uint64(1), s.varint(fieldSpanError))
+	assert.Len(t, s[fieldSpanMeta], 3)
+	metric := test_decodeProto(t, s[fieldSpanMetrics][0].data)
+	assert.Equal(t, keySamplingPriority, metric.string(fieldMapEntryKey))
+	assert.Equal(t, float64(priorityUserKeep), math.Float64frombits(metric.varint(fieldMapEntryValue)))
+
+	// Check events in meta
+	meta := test_decodeProto(t, spans[1][fieldSpanMeta][0].data)
+	assert.Equal(t, keySpanEvents, meta.string(fieldMapEntryKey))
+	assert.Equal(t, uint64(1), spans[1].varint(fieldSpanParentID))
+
+	// Check reject priority
+	other.Metrics[keySamplingPriority] = priorityUserReject
+	payloads = intakeEncoder{maxSize: maxIntakePayloadSize}.encode([][]*span{{other}})
+	chunk := test_decodeProto(t, payloads[0].data).messages(t, fieldAgentPayloadTracerPayloads)[0].messages(t, fieldTracerPayloadChunks)[0]
+	assert.Equal(t, int64(priorityUserReject), int64(chunk.varint(fieldChunkPriority)))
+}
+
+func Test_intakeEncoder_encode_Split(t *testing.T) {
+	var (
+		encoder = intakeEncoder{maxSize: 2048}
+		newSpan = func(id uint64) *span {
+			return &span{TraceID: id / 10, SpanID: id, Meta: map[string]string{"data": strings.Repeat("a", 400)}, Metrics: map[string]float64{}}
+		}
+		small = []*span{newSpan(10)}
+		large = []*span{newSpan(20), newSpan(21), newSpan(22), newSpan(23), newSpan(24), newSpan(25)}
+	)
+
+	// Check payloads split by trace
+	payloads := encoder.encode([][]*span{small, small, small, small, small})
+	assert.Len(t, payloads, 2)
+	for _, payload := range payloads {
+		assert.LessOrEqual(t, len(payload.data), encoder.maxSize)
+	}
+
+	// Check large trace split in chunks, all spans kept
+	payloads = encoder.encode([][]*span{large})
+	assert.Len(t, payloads, 2)
+	var spanCount int
+	for _, payload := range payloads {
+		assert.LessOrEqual(t, len(payload.data), encoder.maxSize)
+		for _, tp := range test_decodeProto(t, payload.data).messages(t, fieldAgentPayloadTracerPayloads) {
+			for _, chunk := range tp.messages(t, fieldTracerPayloadChunks) {
+				spanCount += len(chunk[fieldChunkSpans])
+			}
+		}
+	}
+	assert.Equal(t, len(large), spanCount)
+
+	// Check span larger than maximum size still sent
+	var huge = &span{Meta: map[string]string{"data": strings.Repeat("a", 4096)}}
+	assert.Len(t, encoder.encode([][]*span{{huge}}), 1)
+}
@@ -0,0 +1,93 @@
+package datadog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testIntake is a stand-in HTTPS Datadog intake recording received payloads.
+type testIntake struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	status   int
+	requests []testAgentRequest
+}
+
+func newTestIntake(t *testing.T) *testIntake {
+	var intake = &testIntake{status: http.StatusAccepted}
+	intake.Server = httptest.NewTLSServer(http.HandlerFunc(intake.serveHTTP))
+	t.Cleanup(intake.Close)
+	return intake
+}
+
+func (obj *testIntake) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var body []byte
+	if gz, err := gzip.NewReader(r.Body); err == nil {
+		body, _ = ioutil.ReadAll(gz)
+	}
+
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.requests = append(obj.requests, testAgentRequest{path: r.URL.Path, header: r.Header, body: body})
+	w.WriteHeader(obj.status)
+}
+
+func (obj *testIntake) setStatus(status int) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.status = status
+}
+
+func (obj *testIntake) received() []testAgentRequest {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	return append([]testAgentRequest{}, obj.requests...)
+}
+
+// test_agentlessOptions configures an exporter sending to the stand-in intake.
+func test_agentlessOptions(intake *testIntake) []configFn {
+	return []configFn{
+		WithAgentless(true),
+		WithAPIKey("api-key"),
+		WithIntakeURL(intake.URL),
+		WithHTTPClient(intake.Client()),
+	}
+}
+
+func Test_intakeClient_sendTraces(t *testing.T) {
+	var intake = newTestIntake(t)
+	conf, err := newConfig(test_agentlessOptions(intake)...)
+	require.NoError(t, err)
+	var client = newIntakeClient(conf, &exporterMetrics{})
+
+	// Check request
+	assert.NoError(t, client.sendTraces(context.Background(), [][]*span{{test_span()}}))
+	requests := intake.received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, intakePathTraces, requests[0].path)
+	assert.Equal(t, "api-key", requests[0].header.Get("DD-Api-Key"))
+	assert.Equal(t, "gzip", requests[0].header.Get("Content-Encoding"))
+	assert.Equal(t, "application/x-protobuf", requests[0].header.Get("Content-Type"))
+	assert.Len(t, test_decodeProto(t, requests[0].body).messages(t, fieldAgentPayloadTracerPayloads), 1)
+
+	// Check payloads split
+	client.encoder.maxSize = 1024
+	var big = test_span()
+	big.Meta["data"] = string(bytes.Repeat([]byte("a"), 600))
+	assert.NoError(t, client.sendTraces(context.Background(), [][]*span{{big}, {big}, {big}}))
+	assert.Len(t, intake.received(), 4)
+
+	// Check error status
+	intake.setStatus(http.StatusForbidden)
+	assert.EqualError(t, client.sendTraces(context.Background(), [][]*span{{test_span()}}), "datadog intake POST /api/v0.2/traces: 403 Forbidden")
+}
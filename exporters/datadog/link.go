@@ -0,0 +1,86 @@
+package datadog
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// spanLink is the Datadog representation of a span link, JSON encoded in _dd.span_links meta.
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/span_link.go
+type spanLink struct {
+	TraceID     uint64            `json:"trace_id"`
+	TraceIDHigh uint64            `json:"trace_id_high,omitempty"`
+	SpanID      uint64            `json:"span_id"`
+	Attributes  map[string]string `json:"attributes,omitempty"`
+	Tracestate  string            `json:"tracestate,omitempty"`
+	Flags       uint32            `json:"flags,omitempty"`
+}
+
+// keySpanLinks is the meta used to store span links
+const keySpanLinks = "_dd.span_links"
+
+// flagsSet is set on link flags to tell flags are defined (0 is a valid value)
+const flagsSet = uint32(1) << 31
+
+func convertLinks(links []sdktrace.Link) []spanLink {
+	if len(links) == 0 {
+		return nil
+	}
+
+	var dst = make([]spanLink, 0, len(links))
+	for _, link := range links {
+		var ddLink = spanLink{
+			SpanID:     tracecontext.SpanIDToUint64(link.SpanContext.SpanID()),
+			Tracestate: link.SpanContext.TraceState().String(),
+			Flags:      uint32(link.SpanContext.TraceFlags()) | flagsSet,
+			Attributes: flattenAttributes(link.Attributes),
+		}
+		ddLink.TraceID, ddLink.TraceIDHigh = tracecontext.TraceIDToUint64(link.SpanContext.TraceID())
+		dst = append(dst, ddLink)
+	}
+	return dst
+}
+
+func encodeLinks(links []spanLink) string {
+	// No error can happen since only basic types are used
+	data, _ := json.Marshal(links)
+	return string(data)
+}
+
+// flattenAttributes converts attributes to string values, array elements use
+// a "key.index" key like dd-trace-go does.
+func flattenAttributes(attrs []attribute.KeyValue) map[string]string {
+	if len(attrs) == 0 {
+		return nil
+	}
+
+	var dst = make(map[string]string, len(attrs))
+	for _, kv := range attrs {
+		var key = string(kv.Key)
+		switch kv.Value.Type() {
+		case attribute.BOOLSLICE:
+			for i, v := range kv.Value.AsBoolSlice() {
+				dst[key+"."+strconv.Itoa(i)] = strconv.FormatBool(v)
+			}
+		case attribute.INT64SLICE:
+			for i, v := range kv.Value.AsInt64Slice() {
+				dst[key+"."+strconv.Itoa(i)] = strconv.FormatInt(v, 10)
+			}
+		case attribute.FLOAT64SLICE:
+			for i, v := range kv.Value.AsFloat64Slice() {
+				dst[key+"."+strconv.Itoa(i)] = strconv.FormatFloat(v, 'g', -1, 64)
+			}
+		case attribute.STRINGSLICE:
+			for i, v := range kv.Value.AsStringSlice() {
+				dst[key+"."+strconv.Itoa(i)] = v
+			}
+		default:
+			dst[key] = kv.Value.Emit()
+		}
+	}
+	return dst
+}
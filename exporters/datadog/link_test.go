@@ -0,0 +1,66 @@
+package datadog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_convertLinks(t *testing.T) {
+	assert.Nil(t, convertLinks(nil))
+
+	traceState, err := trace.ParseTraceState("dd=s:2,other=value")
+	assert.NoError(t, err)
+
+	links := convertLinks([]sdktrace.Link{{
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    test_traceID,
+			SpanID:     test_spanID,
+			TraceFlags: trace.FlagsSampled,
+			TraceState: traceState,
+		}),
+		Attributes: []attribute.KeyValue{attribute.String("reason", "retry")},
+	}})
+	assert.Equal(t, []spanLink{{
+		TraceID:     16701352862047361693,
+		TraceIDHigh: 0xb810dba29803ee61,
+		SpanID:      16701352862047361693,
+		Attributes:  map[string]string{"reason": "retry"},
+		Tracestate:  "dd=s:2,other=value",
+		Flags:       0x80000001,
+	}}, links)
+
+	assert.Equal(t,
+		`[{"trace_id":16701352862047361693,"trace_id_high":13263342393987690081,"span_id":16701352862047361693,"attributes":{"reason":"retry"},"tracestate":"dd=s:2,other=value","flags":2147483649}]`,
+		encodeLinks(links))
+
+	// Check empty values omitted
+	assert.Equal(t, `[{"trace_id":1,"span_id":2,"flags":2147483648}]`, encodeLinks(convertLinks([]sdktrace.Link{{
+		SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: trace.TraceID{15: 1}, SpanID: trace.SpanID{7: 2}}),
+	}})))
+}
+
+func Test_flattenAttributes(t *testing.T) {
+	assert.Nil(t, flattenAttributes(nil))
+	assert.Equal(t, map[string]string{
+		"string":    "value",
+		"int":       "1",
+		"bools.0":   "true",
+		"bools.1":   "false",
+		"ints.0":    "1",
+		"ints.1":    "-2",
+		"floats.0":  "1.5",
+		"strings.0": "a",
+		"strings.1": "b",
+	}, flattenAttributes([]attribute.KeyValue{
+		attribute.String("string", "value"),
+		attribute.Int("int", 1),
+		attribute.BoolSlice("bools", []bool{true, false}),
+		attribute.Int64Slice("ints", []int64{1, -2}),
+		attribute.Float64Slice("floats", []float64{1.5}),
+		attribute.StringSlice("strings", []string{"a", "b"}),
+	}))
+}
@@ -0,0 +1,76 @@
+package datadog
+
+import "sync/atomic"
+
+// InternalMetrics are the counters of the exporter activity since its creation.
+type InternalMetrics struct {
+	TracesSent uint64 // traces accepted by the agent or the intake
+	SpansSent  uint64
+
+	// Unsampled traces dropped by the exporter, when stats are computed by the
+	// exporter or in agentless mode
+	TracesDroppedP0 uint64
+	SpansDroppedP0  uint64
+
+	// Traces lost after send failures or queue overflow
+	TracesDropped uint64
+	SpansDropped  uint64
+
+	PayloadsSent   uint64
+	SendErrors     uint64 // failed attempts, including retried ones
+	Retries        uint64
+	QueuedBytes    int64 // size of payloads waiting for retry
+	QueuedPayloads int64
+}
+
+// exporterMetrics are updated atomically by the exporter.
+type exporterMetrics struct {
+	tracesSent      uint64
+	spansSent       uint64
+	tracesDroppedP0 uint64
+	spansDroppedP0  uint64
+	tracesDropped   uint64
+	spansDropped    uint64
+	payloadsSent    uint64
+	sendErrors      uint64
+	retries         uint64
+}
+
+func (obj *exporterMetrics) snapshot() InternalMetrics {
+	return InternalMetrics{
+		TracesSent:      atomic.LoadUint64(&obj.tracesSent),
+		SpansSent:       atomic.LoadUint64(&obj.spansSent),
+		TracesDroppedP0: atomic.LoadUint64(&obj.tracesDroppedP0),
+		SpansDroppedP0:  atomic.LoadUint64(&obj.spansDroppedP0),
+		TracesDropped:   atomic.LoadUint64(&obj.tracesDropped),
+		SpansDropped:    atomic.LoadUint64(&obj.spansDropped),
+		PayloadsSent:    atomic.LoadUint64(&obj.payloadsSent),
+		SendErrors:      atomic.LoadUint64(&obj.sendErrors),
+		Retries:         atomic.LoadUint64(&obj.retries),
+	}
+}
+
+// sent records a payload accepted after retries.
+func (obj *exporterMetrics) sent(traceCount, spanCount, retries int) {
+	atomic.AddUint64(&obj.payloadsSent, 1)
+	atomic.AddUint64(&obj.tracesSent, uint64(traceCount))
+	atomic.AddUint64(&obj.spansSent, uint64(spanCount))
+	atomic.AddUint64(&obj.retries, uint64(retries))
+	atomic.AddUint64(&obj.sendErrors, uint64(retries))
+}
+
+// failed records a payload not accepted after retries.
+func (obj *exporterMetrics) failed(retries int) {
+	atomic.AddUint64(&obj.retries, uint64(retries))
+	atomic.AddUint64(&obj.sendErrors, uint64(retries+1))
+}
+
+func (obj *exporterMetrics) dropped(traceCount, spanCount int) {
+	atomic.AddUint64(&obj.tracesDropped, uint64(traceCount))
+	atomic.AddUint64(&obj.spansDropped, uint64(spanCount))
+}
+
+func (obj *exporterMetrics) droppedP0(traceCount, spanCount int) {
+	atomic.AddUint64(&obj.tracesDroppedP0, uint64(traceCount))
+	atomic.AddUint64(&obj.spansDroppedP0, uint64(spanCount))
+}
@@ -0,0 +1,27 @@
+package datadog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_exporterMetrics(t *testing.T) {
+	var metrics = &exporterMetrics{}
+	metrics.sent(2, 5, 1)
+	metrics.failed(2)
+	metrics.dropped(1, 3)
+	metrics.droppedP0(4, 6)
+
+	assert.Equal(t, InternalMetrics{
+		TracesSent:      2,
+		SpansSent:       5,
+		TracesDroppedP0: 4,
+		SpansDroppedP0:  6,
+		TracesDropped:   1,
+		SpansDropped:    3,
+		PayloadsSent:    1,
+		SendErrors:      4,
+		Retries:         3,
+	}, metrics.snapshot())
+}
@@ -0,0 +1,41 @@
+package datadog
+
+import (
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/naming"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Naming follows the Datadog OTLP ingest rules.
+// Ref https://docs.datadoghq.com/opentelemetry/schema_semantics/semantic_mapping/
+
+// Attributes overriding Datadog span fields
+const (
+	// AttributeOperationName overrides the operation name of the span.
+	AttributeOperationName = naming.AttributeOperationName
+	// AttributeResourceName overrides the resource name of the span.
+	AttributeResourceName = naming.AttributeResourceName
+	// AttributeSpanType overrides the type of the span.
+	AttributeSpanType = naming.AttributeSpanType
+)
+
+// setNaming sets service, name, resource and type of the span. Override
+// attributes are removed from meta.
+func setNaming(src sdktrace.ReadOnlySpan, dst *span) {
+	var (
+		attrs   = naming.NewAttributes(src.Attributes())
+		resAttr naming.Attributes
+	)
+	if res := src.Resource(); res != nil {
+		resAttr = naming.NewAttributes(res.Attributes())
+	}
+
+	dst.Service = naming.ServiceName(resAttr)
+	dst.Name = naming.OperationName(src.SpanKind(), attrs)
+	dst.Resource = naming.ResourceName(src.Name(), src.SpanKind(), attrs)
+	dst.Type = naming.SpanType(src.SpanKind(), attrs, resAttr)
+
+	for _, key := range []attribute.Key{AttributeOperationName, AttributeResourceName, AttributeSpanType} {
+		delete(dst.Meta, string(key))
+	}
+}
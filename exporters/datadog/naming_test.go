@@ -0,0 +1,44 @@
+package datadog
+
+import (
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/naming"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_setNaming(t *testing.T) {
+	var (
+		src = tracetest.SpanStub{
+			Name:     "span",
+			SpanKind: trace.SpanKindServer,
+			Attributes: []attribute.KeyValue{
+				AttributeOperationName.String("custom.operation"),
+				AttributeResourceName.String("custom resource"),
+				AttributeSpanType.String("worker"),
+			},
+			Resource: resource.NewSchemaless(semconv.ServiceNameKey.String("jobs")),
+		}.Snapshot()
+		dst = newSpan()
+	)
+	setTags(dst, src.Attributes())
+	setNaming(src, dst)
+
+	assert.Equal(t, "jobs", dst.Service)
+	assert.Equal(t, "custom.operation", dst.Name)
+	assert.Equal(t, "custom resource", dst.Resource)
+	assert.Equal(t, "worker", dst.Type)
+	assert.Empty(t, dst.Meta)
+
+	// Check defaults
+	setNaming(tracetest.SpanStub{Name: "span"}.Snapshot(), dst)
+	assert.Equal(t, naming.DefaultServiceName, dst.Service)
+	assert.Equal(t, "internal", dst.Name)
+	assert.Equal(t, "span", dst.Resource)
+	assert.Equal(t, "custom", dst.Type)
+}
@@ -0,0 +1,61 @@
+package datadog
+
+// payloadQueue keeps trace payloads waiting to be sent, in order. Payloads
+// dropped by the queue (overflow, age) are given to the drop function of the queue.
+type payloadQueue interface {
+	// push appends a payload.
+	push(payload tracePayload) error
+	// peek returns the oldest payload, kept until removed.
+	peek() (tracePayload, bool, error)
+	// remove removes the oldest payload.
+	remove() error
+	// stats returns the number of payloads and their size in bytes.
+	stats() (count, size int)
+}
+
+// memoryQueue is a payloadQueue bounded to maxBytes, oldest payloads being dropped
+// on overflow. The newest payload is always kept.
+type memoryQueue struct {
+	payloads []tracePayload
+	size     int
+	maxBytes int
+	drop     func(tracePayload)
+}
+
+func newMemoryQueue(maxBytes int, drop func(tracePayload)) *memoryQueue {
+	return &memoryQueue{maxBytes: maxBytes, drop: drop}
+}
+
+func (obj *memoryQueue) push(payload tracePayload) error {
+	obj.payloads = append(obj.payloads, payload)
+	obj.size += len(payload.data)
+	for obj.size > obj.maxBytes && len(obj.payloads) > 1 {
+		obj.drop(obj.payloads[0])
+		obj.removeFirst()
+	}
+	return nil
+}
+
+func (obj *memoryQueue) peek() (tracePayload, bool, error) {
+	if len(obj.payloads) == 0 {
+		return tracePayload{}, false, nil
+	}
+	return obj.payloads[0], true, nil
+}
+
+func (obj *memoryQueue) remove() error {
+	if len(obj.payloads) != 0 {
+		obj.removeFirst()
+	}
+	return nil
+}
+
+func (obj *memoryQueue) stats() (count, size int) {
+	return len(obj.payloads), obj.size
+}
+
+func (obj *memoryQueue) removeFirst() {
+	obj.size -= len(obj.payloads[0].data)
+	obj.payloads[0] = tracePayload{}
+	obj.payloads = obj.payloads[1:]
+}
@@ -0,0 +1,65 @@
+package datadog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// test_queueContent pops all payloads of a queue.
+func test_queueContent(t *testing.T, queue payloadQueue) []tracePayload {
+	var payloads []tracePayload
+	for {
+		payload, ok, err := queue.peek()
+		require.NoError(t, err)
+		if !ok {
+			return payloads
+		}
+		payloads = append(payloads, payload)
+		require.NoError(t, queue.remove())
+	}
+}
+
+func Test_memoryQueue(t *testing.T) {
+	var (
+		dropped []tracePayload
+		queue   = newMemoryQueue(10, func(p tracePayload) { dropped = append(dropped, p) })
+		p1      = tracePayload{data: make([]byte, 4), traceCount: 1}
+		p2      = tracePayload{data: make([]byte, 4), traceCount: 2}
+		p3      = tracePayload{data: make([]byte, 4), traceCount: 3}
+		big     = tracePayload{data: make([]byte, 20), traceCount: 4}
+	)
+
+	// Check oldest payloads dropped on overflow
+	assert.NoError(t, queue.push(p1))
+	assert.NoError(t, queue.push(p2))
+	assert.Empty(t, dropped)
+	assert.NoError(t, queue.push(p3))
+	assert.Equal(t, []tracePayload{p1}, dropped)
+	count, size := queue.stats()
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 8, size)
+
+	// Check payload kept until removed
+	payload, ok, err := queue.peek()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, p2, payload)
+	payload, _, _ = queue.peek()
+	assert.Equal(t, p2, payload)
+
+	// Check order
+	assert.Equal(t, []tracePayload{p2, p3}, test_queueContent(t, queue))
+	assert.NoError(t, queue.remove())
+
+	// Check newest payload kept even if larger than maximum size
+	dropped = nil
+	assert.NoError(t, queue.push(p1))
+	assert.NoError(t, queue.push(big))
+	assert.Equal(t, []tracePayload{p1}, dropped)
+	assert.Equal(t, []tracePayload{big}, test_queueContent(t, queue))
+	count, size = queue.stats()
+	assert.Equal(t, 0, count)
+	assert.Equal(t, 0, size)
+}
@@ -0,0 +1,129 @@
+package datadog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// statusError is returned when the agent or the intake answers with an error status.
+type statusError struct {
+	target     string
+	method     string
+	path       string
+	status     string
+	statusCode int
+	retryAfter time.Duration // from Retry-After header, 0 if not set
+}
+
+func newStatusError(target string, req *http.Request, resp *http.Response) *statusError {
+	return &statusError{
+		target:     target,
+		method:     req.Method,
+		path:       req.URL.Path,
+		status:     resp.Status,
+		statusCode: resp.StatusCode,
+		retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+func (obj *statusError) Error() string {
+	return fmt.Sprintf("datadog %s %s %s: %s", obj.target, obj.method, obj.path, obj.status)
+}
+
+// parseRetryAfter returns the delay of a Retry-After header, in seconds or HTTP date.
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// isRetryable returns true for connection errors, server errors and throttling.
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.statusCode >= http.StatusInternalServerError ||
+			statusErr.statusCode == http.StatusTooManyRequests ||
+			statusErr.statusCode == http.StatusRequestTimeout
+	}
+	return true
+}
+
+// _____________________ Backoff _____________________
+
+// retryPolicy retries failed requests with a jittered exponential backoff.
+type retryPolicy struct {
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+var (
+	jitterMu   sync.Mutex
+	jitterRand = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// backoff returns the delay before the retry number attempt (0 based): half of
+// the exponential delay plus a random part up to the other half.
+func (obj retryPolicy) backoff(attempt int) time.Duration {
+	var delay = obj.initialBackoff
+	for i := 0; i < attempt && delay < obj.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > obj.maxBackoff {
+		delay = obj.maxBackoff
+	}
+	if delay <= 1 {
+		return delay
+	}
+
+	jitterMu.Lock()
+	defer jitterMu.Unlock()
+	return delay/2 + time.Duration(jitterRand.Int63n(int64(delay/2)+1))
+}
+
+// do calls send until it succeeds, fails with a non retryable error, the retries
+// are exhausted or the context is done. A retry is not attempted if its delay
+// exceeds the context deadline, the caller not waiting for a request it cannot
+// make. It returns the number of retries.
+func (obj retryPolicy) do(ctx context.Context, send func() error) (retries int, err error) {
+	for {
+		if err = send(); !isRetryable(err) || retries >= obj.maxRetries {
+			return retries, err
+		}
+
+		// Throttled by server: honour the requested delay
+		var delay = obj.backoff(retries)
+		var statusErr *statusError
+		if errors.As(err, &statusErr) && statusErr.retryAfter > delay {
+			delay = statusErr.retryAfter
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return retries, err
+		}
+
+		var timer = time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return retries, err
+		case <-timer.C:
+		}
+		retries++
+	}
+}
@@ -0,0 +1,109 @@
+package datadog
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseRetryAfter(t *testing.T) {
+	var now = time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	assert.Equal(t, 3*time.Second, parseRetryAfter("3", now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("-1", now))
+	assert.Equal(t, 30*time.Second, parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter(now.Add(-time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
+
+func Test_isRetryable(t *testing.T) {
+	assert.False(t, isRetryable(nil))
+	assert.False(t, isRetryable(context.Canceled))
+	assert.False(t, isRetryable(context.DeadlineExceeded))
+	assert.True(t, isRetryable(errors.New("connection refused")))
+	assert.True(t, isRetryable(&statusError{statusCode: http.StatusServiceUnavailable}))
+	assert.True(t, isRetryable(&statusError{statusCode: http.StatusTooManyRequests}))
+	assert.True(t, isRetryable(&statusError{statusCode: http.StatusRequestTimeout}))
+	assert.False(t, isRetryable(&statusError{statusCode: http.StatusBadRequest}))
+	assert.False(t, isRetryable(&statusError{statusCode: http.StatusRequestEntityTooLarge}))
+}
+
+func Test_retryPolicy_backoff(t *testing.T) {
+	var policy = retryPolicy{initialBackoff: 100 * time.Millisecond, maxBackoff: time.Second}
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			var delay = policy.backoff(attempt)
+			assert.GreaterOrEqual(t, int64(delay), int64(max/2))
+			assert.LessOrEqual(t, int64(delay), int64(max))
+		}
+	}
+	assert.Equal(t, time.Duration(0), retryPolicy{}.backoff(3))
+}
+
+func Test_retryPolicy_do(t *testing.T) {
+	var (
+		policy = retryPolicy{maxRetries: 2, initialBackoff: time.Millisecond, maxBackoff: time.Millisecond}
+		calls  int
+		errs   []error
+	)
+	var send = func() error {
+		calls++
+		if len(errs) == 0 {
+			return nil
+		}
+		var err = errs[0]
+		errs = errs[1:]
+		return err
+	}
+
+	// Check retried until success
+	errs = []error{errors.New("refused"), &statusError{statusCode: http.StatusBadGateway}}
+	retries, err := policy.do(context.Background(), send)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, retries)
+	assert.Equal(t, 3, calls)
+
+	// Check retries exhausted
+	calls, errs = 0, []error{errors.New("1"), errors.New("2"), errors.New("3"), errors.New("4")}
+	retries, err = policy.do(context.Background(), send)
+	assert.EqualError(t, err, "3")
+	assert.Equal(t, 2, retries)
+
+	// Check non retryable error
+	calls, errs = 0, []error{&statusError{statusCode: http.StatusBadRequest}}
+	retries, err = policy.do(context.Background(), send)
+	assert.Error(t, err)
+	assert.Equal(t, 0, retries)
+	assert.Equal(t, 1, calls)
+
+	// Check Retry-After honoured
+	calls, errs = 0, []error{&statusError{statusCode: http.StatusTooManyRequests, retryAfter: 50 * time.Millisecond}}
+	var start = time.Now()
+	_, err = policy.do(context.Background(), send)
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, int64(time.Since(start)), int64(50*time.Millisecond))
+
+	// Check no retry after the context deadline
+	deadlineCtx, deadlineCancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer deadlineCancel()
+	calls, errs = 0, []error{&statusError{statusCode: http.StatusTooManyRequests, retryAfter: time.Minute}}
+	start = time.Now()
+	retries, err = policy.do(deadlineCtx, send)
+	assert.Error(t, err)
+	assert.Equal(t, 0, retries)
+	assert.Equal(t, 1, calls)
+	assert.Less(t, int64(time.Since(start)), int64(20*time.Millisecond))
+
+	// Check context done stops retries
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	calls, errs = 0, []error{errors.New("refused"), errors.New("refused")}
+	retries, err = policy.do(ctx, send)
+	assert.EqualError(t, err, "refused")
+	assert.Equal(t, 0, retries)
+}
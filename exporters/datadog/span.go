@@ -0,0 +1,82 @@
+package datadog
+
+// span is the Datadog representation of a span sent to the agent.
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1.38.1/ddtrace/tracer/span.go#L63-L81
+type span struct {
+	Name     string             // operation name
+	Service  string             // service name
+	Resource string             // resource name
+	Type     string             // protocol associated with the span
+	Start    int64              // span start time expressed in nanoseconds since epoch
+	Duration int64              // duration of the span expressed in nanoseconds
+	Meta     map[string]string  // arbitrary map of metadata
+	Metrics  map[string]float64 // arbitrary map of numeric metrics
+	SpanID   uint64             // identifier of this span
+	TraceID  uint64             // lower 64-bits of the root span identifier
+	ParentID uint64             // identifier of the span's direct parent
+	Error    int32              // error status of the span; 0 means no errors
+
+	SpanEvents []spanEvent // encoded natively or in events meta depending on agent
+}
+
+func newSpan() *span {
+	return &span{
+		Meta:    map[string]string{},
+		Metrics: map[string]float64{},
+	}
+}
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/ext/tags.go
+
+const (
+	// Error tags used by Datadog Error Tracking
+	keyErrorType    = "error.type"
+	keyErrorMessage = "error.message"
+	keyErrorStack   = "error.stack"
+
+	// keyPropagatedPrefix prefixes the trace level tags propagated between services
+	keyPropagatedPrefix = "_dd.p."
+	// keyTraceIDHigh stores the higher 64-bits of a 128-bits trace ID (hexadecimal)
+	keyTraceIDHigh = "_dd.p.tid"
+
+	// keySamplingPriority stores the sampling priority of the trace chunk
+	keySamplingPriority = "_sampling_priority_v1"
+
+	// keySpanSamplingMechanism marks spans of dropped traces kept by single span sampling
+	keySpanSamplingMechanism = "_dd.span_sampling.mechanism"
+
+	// keyTopLevel marks service entry spans, keyMeasured spans with computed stats
+	keyTopLevel = "_top_level"
+	keyMeasured = "_dd.measured"
+
+	// keyOrigin stores the origin of the trace (ex: synthetics)
+	keyOrigin = "_dd.origin"
+
+	// OpenTelemetry information kept by the agent OTLP ingest
+	keyOTelTraceID           = "otel.trace_id"
+	keyOTelStatusCode        = "otel.status_code"
+	keyOTelStatusDescription = "otel.status_description"
+	keyOTelLibraryName       = "otel.library.name"
+	keyOTelLibraryVersion    = "otel.library.version"
+
+	keyHTTPStatusCode = "http.status_code"
+	keySpanKind       = "span.kind"
+	keyEnv            = "env"
+	keyVersion        = "version"
+)
+
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/trace/traceutil/truncate.go
+
+const (
+	// maxMetaValueLen is the maximum length of a meta value accepted by the agent
+	maxMetaValueLen = 25000
+)
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/ext/priority.go
+
+const (
+	priorityUserReject = -1
+	priorityAutoReject = 0
+	priorityAutoKeep   = 1
+	priorityUserKeep   = 2
+)
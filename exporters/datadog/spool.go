@@ -0,0 +1,265 @@
+package datadog
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// diskQueue is a payloadQueue storing payloads in segment files of a directory,
+// bounded to maxBytes, payloads older than maxAge being dropped. Segments are
+// written in a temporary file then renamed, a crash never leaving a partial
+// segment. Segments left by a previous process are replayed first.
+type diskQueue struct {
+	dir      string
+	maxBytes int
+	maxAge   time.Duration
+	drop     func(tracePayload)
+	now      func() time.Time
+
+	segments []diskSegment // oldest first
+	size     int
+	nextSeq  uint64
+}
+
+type diskSegment struct {
+	seq     uint64
+	size    int
+	created time.Time
+}
+
+var ErrCorruptedSegment = errors.New("corrupted spool segment")
+
+const (
+	segmentExt     = ".seg"
+	segmentTempExt = ".tmp"
+
+	// Segment header: magic, version, flags, trace count, span count, creation
+	// time, data checksum and data length
+	segmentMagic      = "DDSP"
+	segmentVersion    = 1
+	segmentHeaderSize = 4 + 1 + 1 + 4 + 4 + 8 + 4 + 4
+
+	segmentFlagClientComputedStats = 1
+)
+
+func newDiskQueue(dir string, maxBytes int, maxAge time.Duration, drop func(tracePayload)) (*diskQueue, error) {
+	var queue = &diskQueue{dir: dir, maxBytes: maxBytes, maxAge: maxAge, drop: drop, now: time.Now}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	if err := queue.load(); err != nil {
+		return nil, err
+	}
+	return queue, nil
+}
+
+// load indexes the segments of the directory, removing temporary and corrupted files.
+func (obj *diskQueue) load() error {
+	files, err := ioutil.ReadDir(obj.dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		var name = file.Name()
+		switch {
+		case file.IsDir():
+			continue
+		case strings.HasSuffix(name, segmentTempExt):
+			// Interrupted write
+			_ = os.Remove(filepath.Join(obj.dir, name))
+			continue
+		case !strings.HasSuffix(name, segmentExt):
+			continue
+		}
+
+		seq, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		_, created, err := obj.read(seq, true)
+		if err != nil {
+			_ = os.Remove(obj.path(seq))
+			continue
+		}
+		obj.segments = append(obj.segments, diskSegment{seq: seq, size: int(file.Size()), created: created})
+		obj.size += int(file.Size())
+	}
+
+	sort.Slice(obj.segments, func(i, j int) bool { return obj.segments[i].seq < obj.segments[j].seq })
+	if n := len(obj.segments); n != 0 {
+		obj.nextSeq = obj.segments[n-1].seq + 1
+	}
+	return nil
+}
+
+func (obj *diskQueue) push(payload tracePayload) error {
+	var (
+		seq     = obj.nextSeq
+		created = obj.now()
+		b       = encodeSegment(payload, created)
+	)
+	if err := writeFileSync(obj.path(seq), b); err != nil {
+		return err
+	}
+	obj.nextSeq++
+	obj.segments = append(obj.segments, diskSegment{seq: seq, size: len(b), created: created})
+	obj.size += len(b)
+
+	// Drop oldest segments on overflow
+	for obj.size > obj.maxBytes && len(obj.segments) > 1 {
+		obj.dropFirst()
+	}
+	return nil
+}
+
+func (obj *diskQueue) peek() (tracePayload, bool, error) {
+	for len(obj.segments) != 0 {
+		var segment = obj.segments[0]
+		if obj.maxAge > 0 && obj.now().Sub(segment.created) > obj.maxAge {
+			obj.dropFirst()
+			continue
+		}
+
+		payload, _, err := obj.read(segment.seq, false)
+		if err != nil {
+			// Unreadable segment is skipped
+			_ = obj.removeFirst()
+			return tracePayload{}, false, err
+		}
+		return payload, true, nil
+	}
+	return tracePayload{}, false, nil
+}
+
+func (obj *diskQueue) remove() error {
+	if len(obj.segments) == 0 {
+		return nil
+	}
+	return obj.removeFirst()
+}
+
+func (obj *diskQueue) stats() (count, size int) {
+	return len(obj.segments), obj.size
+}
+
+// dropFirst removes the oldest segment, given to the drop function.
+func (obj *diskQueue) dropFirst() {
+	if payload, _, err := obj.read(obj.segments[0].seq, true); err == nil {
+		obj.drop(payload)
+	}
+	_ = obj.removeFirst()
+}
+
+func (obj *diskQueue) removeFirst() error {
+	var segment = obj.segments[0]
+	obj.segments = obj.segments[1:]
+	obj.size -= segment.size
+	if err := os.Remove(obj.path(segment.seq)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (obj *diskQueue) path(seq uint64) string {
+	return filepath.Join(obj.dir, fmt.Sprintf("%020d%s", seq, segmentExt))
+}
+
+// read returns the payload of a segment and its creation time. Payload data
+// is not read when headerOnly is set.
+func (obj *diskQueue) read(seq uint64, headerOnly bool) (tracePayload, time.Time, error) {
+	f, err := os.Open(obj.path(seq))
+	if err != nil {
+		return tracePayload{}, time.Time{}, err
+	}
+	defer f.Close()
+
+	var header [segmentHeaderSize]byte
+	if _, err = io.ReadFull(f, header[:]); err != nil {
+		return tracePayload{}, time.Time{}, fmt.Errorf("%w: %v", ErrCorruptedSegment, err)
+	}
+	payload, created, checksum, size, err := decodeSegmentHeader(header[:])
+	if err != nil || headerOnly {
+		return payload, created, err
+	}
+
+	payload.data = make([]byte, size)
+	if _, err = io.ReadFull(f, payload.data); err != nil {
+		return tracePayload{}, time.Time{}, fmt.Errorf("%w: %v", ErrCorruptedSegment, err)
+	}
+	if crc32.ChecksumIEEE(payload.data) != checksum {
+		return tracePayload{}, time.Time{}, fmt.Errorf("%w: checksum mismatch", ErrCorruptedSegment)
+	}
+	return payload, created, nil
+}
+
+// _____________________ Segment format _____________________
+
+func encodeSegment(payload tracePayload, created time.Time) []byte {
+	var flags byte
+	if payload.clientComputedStats {
+		flags |= segmentFlagClientComputedStats
+	}
+
+	var b = make([]byte, segmentHeaderSize, segmentHeaderSize+len(payload.data))
+	copy(b, segmentMagic)
+	b[4], b[5] = segmentVersion, flags
+	binary.BigEndian.PutUint32(b[6:], uint32(payload.traceCount))
+	binary.BigEndian.PutUint32(b[10:], uint32(payload.spanCount))
+	binary.BigEndian.PutUint64(b[14:], uint64(created.UnixNano()))
+	binary.BigEndian.PutUint32(b[22:], crc32.ChecksumIEEE(payload.data))
+	binary.BigEndian.PutUint32(b[26:], uint32(len(payload.data)))
+	return append(b, payload.data...)
+}
+
+func decodeSegmentHeader(b []byte) (payload tracePayload, created time.Time, checksum uint32, size int, err error) {
+	if string(b[:4]) != segmentMagic || b[4] != segmentVersion {
+		return payload, created, 0, 0, fmt.Errorf("%w: invalid header", ErrCorruptedSegment)
+	}
+	payload.clientComputedStats = b[5]&segmentFlagClientComputedStats != 0
+	payload.traceCount = int(binary.BigEndian.Uint32(b[6:]))
+	payload.spanCount = int(binary.BigEndian.Uint32(b[10:]))
+	created = time.Unix(0, int64(binary.BigEndian.Uint64(b[14:])))
+	checksum = binary.BigEndian.Uint32(b[22:])
+	size = int(binary.BigEndian.Uint32(b[26:]))
+	return payload, created, checksum, size, nil
+}
+
+// writeFileSync writes a file atomically: data is synced in a temporary file
+// renamed once complete.
+func writeFileSync(path string, data []byte) error {
+	var temp = path + segmentTempExt
+	f, err := os.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(temp, path)
+	}
+	if err != nil {
+		_ = os.Remove(temp)
+		return err
+	}
+
+	// Persist the rename, not supported on all platforms
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
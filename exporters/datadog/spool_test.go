@@ -0,0 +1,140 @@
+package datadog
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func test_newDiskQueue(t *testing.T, dir string, maxBytes int, dropped *[]tracePayload) *diskQueue {
+	queue, err := newDiskQueue(dir, maxBytes, time.Hour, func(p tracePayload) { *dropped = append(*dropped, p) })
+	require.NoError(t, err)
+	return queue
+}
+
+func Test_diskQueue(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		dropped []tracePayload
+		queue   = test_newDiskQueue(t, dir, 1024, &dropped)
+		p1      = tracePayload{data: []byte{0x91, 0x90}, traceCount: 1, spanCount: 2, clientComputedStats: true}
+		p2      = tracePayload{data: []byte{0x92, 0x90, 0x90}, traceCount: 2, spanCount: 3}
+	)
+
+	assert.NoError(t, queue.push(p1))
+	assert.NoError(t, queue.push(p2))
+	count, size := queue.stats()
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 2*segmentHeaderSize+5, size)
+
+	// Check payload kept until removed
+	payload, ok, err := queue.peek()
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, p1, payload)
+	assert.NoError(t, queue.remove())
+
+	// Check payloads replayed after restart, in order
+	assert.NoError(t, queue.push(p1))
+	queue = test_newDiskQueue(t, dir, 1024, &dropped)
+	assert.Equal(t, []tracePayload{p2, p1}, test_queueContent(t, queue))
+	assert.NoError(t, queue.remove())
+
+	// Check sequence continues after restart
+	assert.NoError(t, queue.push(p2))
+	queue = test_newDiskQueue(t, dir, 1024, &dropped)
+	assert.NoError(t, queue.push(p1))
+	assert.Equal(t, []tracePayload{p2, p1}, test_queueContent(t, queue))
+	assert.Empty(t, dropped)
+
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func Test_diskQueue_Overflow(t *testing.T) {
+	var (
+		dropped []tracePayload
+		queue   = test_newDiskQueue(t, t.TempDir(), 2*segmentHeaderSize+10, &dropped)
+		p1      = tracePayload{data: make([]byte, 5), traceCount: 1, spanCount: 1}
+		p2      = tracePayload{data: make([]byte, 5), traceCount: 2, spanCount: 2}
+		p3      = tracePayload{data: make([]byte, 5), traceCount: 3, spanCount: 3}
+	)
+
+	assert.NoError(t, queue.push(p1))
+	assert.NoError(t, queue.push(p2))
+	assert.NoError(t, queue.push(p3))
+
+	// Check oldest dropped, without data
+	assert.Equal(t, []tracePayload{{traceCount: 1, spanCount: 1}}, dropped)
+	assert.Equal(t, []tracePayload{p2, p3}, test_queueContent(t, queue))
+}
+
+func Test_diskQueue_MaxAge(t *testing.T) {
+	var (
+		dropped []tracePayload
+		queue   = test_newDiskQueue(t, t.TempDir(), 1024, &dropped)
+		now     = time.Now()
+		p1      = tracePayload{data: []byte{0x90}, traceCount: 1}
+		p2      = tracePayload{data: []byte{0x90}, traceCount: 2}
+	)
+
+	queue.now = func() time.Time { return now.Add(-2 * time.Hour) }
+	assert.NoError(t, queue.push(p1))
+	queue.now = func() time.Time { return now }
+	assert.NoError(t, queue.push(p2))
+
+	// Check expired payload discarded
+	assert.Equal(t, []tracePayload{p2}, test_queueContent(t, queue))
+	assert.Equal(t, []tracePayload{{traceCount: 1}}, dropped)
+}
+
+func Test_diskQueue_Corrupted(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		dropped []tracePayload
+		queue   = test_newDiskQueue(t, dir, 1024, &dropped)
+		payload = tracePayload{data: []byte{0x91, 0x90}, traceCount: 1}
+	)
+	require.NoError(t, queue.push(payload))
+	require.NoError(t, queue.push(payload))
+
+	// Interrupted write, invalid header and unknown files
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "00000000000000000005.seg.tmp"), []byte("partial"), 0o600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "00000000000000000006.seg"), []byte("bad"), 0o600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "other"), []byte("other"), 0o600))
+
+	// Check corrupted data detected by checksum
+	var first = queue.path(0)
+	b, err := ioutil.ReadFile(first)
+	require.NoError(t, err)
+	b[len(b)-1] ^= 0xff
+	require.NoError(t, ioutil.WriteFile(first, b, 0o600))
+
+	queue = test_newDiskQueue(t, dir, 1024, &dropped)
+	count, _ := queue.stats()
+	assert.Equal(t, 2, count)
+	_, _, err = queue.peek()
+	assert.ErrorIs(t, err, ErrCorruptedSegment)
+	assert.Equal(t, []tracePayload{payload}, test_queueContent(t, queue))
+
+	// Check only unknown file left
+	files, err := ioutil.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+	assert.Equal(t, "other", files[0].Name())
+}
+
+func Test_newDiskQueue_Error(t *testing.T) {
+	var file = filepath.Join(t.TempDir(), "file")
+	require.NoError(t, ioutil.WriteFile(file, nil, 0o600))
+	_, err := newDiskQueue(file, 1024, time.Hour, func(tracePayload) {})
+	assert.Error(t, err)
+	_, err = os.Stat(file)
+	assert.NoError(t, err)
+}
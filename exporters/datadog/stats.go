@@ -0,0 +1,242 @@
+package datadog
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/ddsketch"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/msgpack"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/runtimeid"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/version"
+)
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/stats.go
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/trace/stats/concentrator.go
+
+// statsBucketDuration is the time range aggregated in a stats bucket
+const statsBucketDuration = 10 * time.Second
+
+// runtimeID identifies this process in stats payloads
+var runtimeID = runtimeid.ID()
+
+// statsKey identifies a group of aggregated spans.
+type statsKey struct {
+	// Payload
+	env     string
+	version string
+	// Group
+	service     string
+	name        string
+	resource    string
+	typ         string
+	spanKind    string
+	statusCode  uint32
+	synthetics  bool
+	peerTags    string
+	isTraceRoot bool
+}
+
+// groupedStats are the stats of a group of spans.
+type groupedStats struct {
+	hits         uint64
+	topLevelHits uint64
+	errors       uint64
+	duration     uint64
+	okSummary    *ddsketch.DDSketch
+	errorSummary *ddsketch.DDSketch
+}
+
+// concentrator aggregates spans stats in time buckets, flushed once complete.
+type concentrator struct {
+	mu       sync.Mutex
+	buckets  map[int64]map[statsKey]*groupedStats
+	sequence uint64
+}
+
+func newConcentrator() *concentrator {
+	return &concentrator{buckets: map[int64]map[statsKey]*groupedStats{}}
+}
+
+// add aggregates top-level and measured spans of a trace.
+func (obj *concentrator) add(trace []*span, peerTagKeys []string) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	for _, s := range trace {
+		var isTopLevel = s.Metrics[keyTopLevel] == 1
+		if !isTopLevel && s.Metrics[keyMeasured] != 1 {
+			continue
+		}
+
+		// Bucket of span end time
+		var end = s.Start + s.Duration
+		var bucketStart = end - end%int64(statsBucketDuration)
+		bucket, ok := obj.buckets[bucketStart]
+		if !ok {
+			bucket = map[statsKey]*groupedStats{}
+			obj.buckets[bucketStart] = bucket
+		}
+
+		var key = newStatsKey(s, peerTagKeys)
+		group, ok := bucket[key]
+		if !ok {
+			group = &groupedStats{okSummary: ddsketch.NewDefault(), errorSummary: ddsketch.NewDefault()}
+			bucket[key] = group
+		}
+
+		group.hits++
+		group.duration += uint64(s.Duration)
+		if isTopLevel {
+			group.topLevelHits++
+		}
+		if s.Error != 0 {
+			group.errors++
+			_ = group.errorSummary.Add(float64(s.Duration))
+		} else {
+			_ = group.okSummary.Add(float64(s.Duration))
+		}
+	}
+}
+
+func newStatsKey(s *span, peerTagKeys []string) statsKey {
+	var key = statsKey{
+		env:         s.Meta[keyEnv],
+		version:     s.Meta[keyVersion],
+		service:     s.Service,
+		name:        s.Name,
+		resource:    s.Resource,
+		typ:         s.Type,
+		spanKind:    s.Meta[keySpanKind],
+		synthetics:  strings.HasPrefix(s.Meta[keyOrigin], "synthetics"),
+		isTraceRoot: s.ParentID == 0,
+	}
+
+	if code, ok := s.Metrics["http.status_code"]; ok {
+		key.statusCode = uint32(code)
+	} else if code, err := strconv.ParseUint(s.Meta["http.status_code"], 10, 32); err == nil {
+		key.statusCode = uint32(code)
+	}
+
+	// Peer tags identify the remote service of outgoing requests
+	switch key.spanKind {
+	case "client", "producer", "consumer":
+		var peerTags []string
+		for _, k := range peerTagKeys {
+			if v := s.Meta[k]; v != "" {
+				peerTags = append(peerTags, k+":"+v)
+			}
+		}
+		key.peerTags = strings.Join(peerTags, ",")
+	}
+	return key
+}
+
+// flush returns the msgpack encoded payloads of complete buckets, or all of them if force.
+func (obj *concentrator) flush(now time.Time, force bool) [][]byte {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	// Group flushed buckets by payload (env, version)
+	type payloadKey struct{ env, version string }
+	var payloads = map[payloadKey]map[int64][]statsKey{}
+	var buckets = map[int64]map[statsKey]*groupedStats{}
+	for start, bucket := range obj.buckets {
+		if !force && start+int64(statsBucketDuration) > now.UnixNano() {
+			continue
+		}
+		buckets[start] = bucket
+		delete(obj.buckets, start)
+
+		for key := range bucket {
+			var pk = payloadKey{key.env, key.version}
+			if payloads[pk] == nil {
+				payloads[pk] = map[int64][]statsKey{}
+			}
+			payloads[pk][start] = append(payloads[pk][start], key)
+		}
+	}
+
+	var encoded [][]byte
+	for pk, payloadBuckets := range payloads {
+		obj.sequence++
+		encoded = append(encoded, encodeStatsPayload(pk.env, pk.version, obj.sequence, payloadBuckets, buckets))
+	}
+	return encoded
+}
+
+// _____________________ Encoding _____________________
+
+// encodeStatsPayload encodes a ClientStatsPayload in msgpack.
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/proto/datadog/trace/stats.proto
+func encodeStatsPayload(env, ver string, sequence uint64, keys map[int64][]statsKey, buckets map[int64]map[statsKey]*groupedStats) []byte {
+	// Sort buckets by start time for deterministic payloads
+	var starts = make([]int64, 0, len(keys))
+	for start := range keys {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	var b = msgpack.AppendMapHeader(nil, 7)
+	b = appendStringField(b, "Env", env)
+	b = appendStringField(b, "Version", ver)
+	b = appendStringField(b, "Lang", "go")
+	b = appendStringField(b, "TracerVersion", version.Tag)
+	b = appendStringField(b, "RuntimeID", runtimeID)
+	b = msgpack.AppendUint64(msgpack.AppendString(b, "Sequence"), sequence)
+	b = msgpack.AppendArrayHeader(msgpack.AppendString(b, "Stats"), len(starts))
+	for _, start := range starts {
+		b = msgpack.AppendMapHeader(b, 3)
+		b = msgpack.AppendUint64(msgpack.AppendString(b, "Start"), uint64(start))
+		b = msgpack.AppendUint64(msgpack.AppendString(b, "Duration"), uint64(statsBucketDuration))
+		b = msgpack.AppendArrayHeader(msgpack.AppendString(b, "Stats"), len(keys[start]))
+		for _, key := range keys[start] {
+			b = appendGroupedStats(b, key, buckets[start][key])
+		}
+	}
+	return b
+}
+
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/proto/datadog/trace/stats.proto (Trilean)
+const (
+	trileanTrue  = 1
+	trileanFalse = 2
+)
+
+func appendGroupedStats(b []byte, key statsKey, group *groupedStats) []byte {
+	var isTraceRoot int64 = trileanFalse
+	if key.isTraceRoot {
+		isTraceRoot = trileanTrue
+	}
+	var peerTags []string
+	if key.peerTags != "" {
+		peerTags = strings.Split(key.peerTags, ",")
+	}
+
+	b = msgpack.AppendMapHeader(b, 15)
+	b = appendStringField(b, "Service", key.service)
+	b = appendStringField(b, "Name", key.name)
+	b = appendStringField(b, "Resource", key.resource)
+	b = appendStringField(b, "Type", key.typ)
+	b = appendStringField(b, "SpanKind", key.spanKind)
+	b = msgpack.AppendUint64(msgpack.AppendString(b, "HTTPStatusCode"), uint64(key.statusCode))
+	b = msgpack.AppendBool(msgpack.AppendString(b, "Synthetics"), key.synthetics)
+	b = msgpack.AppendInt64(msgpack.AppendString(b, "IsTraceRoot"), isTraceRoot)
+	b = msgpack.AppendArrayHeader(msgpack.AppendString(b, "PeerTags"), len(peerTags))
+	for _, v := range peerTags {
+		b = msgpack.AppendString(b, v)
+	}
+	b = msgpack.AppendUint64(msgpack.AppendString(b, "Hits"), group.hits)
+	b = msgpack.AppendUint64(msgpack.AppendString(b, "TopLevelHits"), group.topLevelHits)
+	b = msgpack.AppendUint64(msgpack.AppendString(b, "Errors"), group.errors)
+	b = msgpack.AppendUint64(msgpack.AppendString(b, "Duration"), group.duration)
+	b = msgpack.AppendBytes(msgpack.AppendString(b, "OkSummary"), group.okSummary.MarshalProto())
+	b = msgpack.AppendBytes(msgpack.AppendString(b, "ErrorSummary"), group.errorSummary.MarshalProto())
+	return b
+}
+
+func appendStringField(b []byte, key, value string) []byte {
+	return msgpack.AppendString(msgpack.AppendString(b, key), value)
+}
@@ -0,0 +1,160 @@
+package datadog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/msgpack"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func test_statsSpan(start time.Time, metrics map[string]float64, meta map[string]string) *span {
+	var s = &span{
+		Name:     "name",
+		Service:  "service",
+		Resource: "resource",
+		Type:     "web",
+		Start:    start.UnixNano(),
+		Duration: int64(time.Second),
+		Meta:     map[string]string{keyEnv: "prod", keyVersion: "1.0"},
+		Metrics:  map[string]float64{},
+	}
+	for k, v := range metrics {
+		s.Metrics[k] = v
+	}
+	for k, v := range meta {
+		s.Meta[k] = v
+	}
+	return s
+}
+
+func test_decodeStatsPayload(t *testing.T, payload []byte) map[string]interface{} {
+	value, remaining, err := msgpack.Decode(payload)
+	require.NoError(t, err)
+	require.Empty(t, remaining)
+	return value.(map[string]interface{})
+}
+
+func Test_concentrator_add(t *testing.T) {
+	var (
+		stats = newConcentrator()
+		start = time.Unix(100, 0)
+	)
+
+	// Check only top-level and measured spans aggregated
+	stats.add([]*span{
+		test_statsSpan(start, map[string]float64{keyTopLevel: 1}, nil),
+		test_statsSpan(start, map[string]float64{keyMeasured: 1}, nil),
+		test_statsSpan(start, nil, nil),
+	}, nil)
+	require.Len(t, stats.buckets, 1)
+
+	// Check bucket aligned on span end time
+	bucket, ok := stats.buckets[int64(100*time.Second)]
+	require.True(t, ok)
+	require.Len(t, bucket, 1)
+	for _, group := range bucket {
+		assert.Equal(t, uint64(2), group.hits)
+		assert.Equal(t, uint64(1), group.topLevelHits)
+		assert.Equal(t, uint64(0), group.errors)
+		assert.Equal(t, uint64(2*time.Second), group.duration)
+		assert.Equal(t, 2.0, group.okSummary.Count())
+		assert.True(t, group.errorSummary.IsEmpty())
+	}
+
+	// Check error and new bucket
+	var errorSpan = test_statsSpan(start.Add(9*time.Second), map[string]float64{keyTopLevel: 1}, nil)
+	errorSpan.Error = 1
+	stats.add([]*span{errorSpan}, nil)
+	require.Len(t, stats.buckets, 2)
+	for _, group := range stats.buckets[int64(110*time.Second)] {
+		assert.Equal(t, uint64(1), group.errors)
+		assert.Equal(t, 1.0, group.errorSummary.Count())
+	}
+}
+
+func Test_newStatsKey(t *testing.T) {
+	var s = test_statsSpan(time.Unix(0, 0), map[string]float64{"http.status_code": 404}, map[string]string{
+		keySpanKind:    "client",
+		keyOrigin:      "synthetics-browser",
+		"peer.service": "db",
+		"out.host":     "host",
+	})
+	s.ParentID = 1
+
+	assert.Equal(t, statsKey{
+		env:        "prod",
+		version:    "1.0",
+		service:    "service",
+		name:       "name",
+		resource:   "resource",
+		typ:        "web",
+		spanKind:   "client",
+		statusCode: 404,
+		synthetics: true,
+		peerTags:   "peer.service:db,out.host:host",
+	}, newStatsKey(s, []string{"peer.service", "db.instance", "out.host"}))
+
+	// Check peer tags only for outgoing spans, status code from meta
+	s = test_statsSpan(time.Unix(0, 0), nil, map[string]string{keySpanKind: "server", "http.status_code": "200", "peer.service": "db"})
+	key := newStatsKey(s, []string{"peer.service"})
+	assert.Empty(t, key.peerTags)
+	assert.Equal(t, uint32(200), key.statusCode)
+	assert.True(t, key.isTraceRoot)
+	assert.False(t, key.synthetics)
+}
+
+func Test_concentrator_flush(t *testing.T) {
+	var (
+		stats = newConcentrator()
+		start = time.Unix(100, 0)
+	)
+	stats.add([]*span{
+		test_statsSpan(start, map[string]float64{keyTopLevel: 1}, map[string]string{keySpanKind: "client", "peer.service": "db"}),
+		test_statsSpan(start.Add(10*time.Second), map[string]float64{keyTopLevel: 1}, nil),
+	}, []string{"peer.service"})
+
+	// Check current bucket kept
+	payloads := stats.flush(start.Add(15*time.Second), false)
+	require.Len(t, payloads, 1)
+	assert.Len(t, stats.buckets, 1)
+
+	payload := test_decodeStatsPayload(t, payloads[0])
+	assert.Equal(t, "prod", payload["Env"])
+	assert.Equal(t, "1.0", payload["Version"])
+	assert.Equal(t, "go", payload["Lang"])
+	assert.Equal(t, version.Tag, payload["TracerVersion"])
+	assert.Equal(t, runtimeID, payload["RuntimeID"])
+	assert.Equal(t, uint64(1), payload["Sequence"])
+
+	buckets := payload["Stats"].([]interface{})
+	require.Len(t, buckets, 1)
+	bucket := buckets[0].(map[string]interface{})
+	assert.Equal(t, uint64(100*time.Second), bucket["Start"])
+	assert.Equal(t, uint64(statsBucketDuration), bucket["Duration"])
+
+	groups := bucket["Stats"].([]interface{})
+	require.Len(t, groups, 1)
+	group := groups[0].(map[string]interface{})
+	assert.Equal(t, "service", group["Service"])
+	assert.Equal(t, "name", group["Name"])
+	assert.Equal(t, "resource", group["Resource"])
+	assert.Equal(t, "client", group["SpanKind"])
+	assert.Equal(t, uint64(trileanTrue), group["IsTraceRoot"])
+	assert.Equal(t, []interface{}{"peer.service:db"}, group["PeerTags"])
+	assert.Equal(t, uint64(1), group["Hits"])
+	assert.Equal(t, uint64(1), group["TopLevelHits"])
+	assert.Equal(t, uint64(0), group["Errors"])
+	assert.Equal(t, uint64(time.Second), group["Duration"])
+	assert.NotEmpty(t, group["OkSummary"])
+	assert.NotEmpty(t, group["ErrorSummary"])
+
+	// Check force flush
+	payloads = stats.flush(start.Add(15*time.Second), true)
+	require.Len(t, payloads, 1)
+	assert.Equal(t, uint64(2), test_decodeStatsPayload(t, payloads[0])["Sequence"])
+	assert.Empty(t, stats.buckets)
+	assert.Empty(t, stats.flush(start, true))
+}
@@ -0,0 +1,84 @@
+package datadog
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/naming"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// statsProcessor computes the client-side stats of every ended span, sampled
+// or not, spans of unsampled traces never reaching the exporter.
+type statsProcessor struct {
+	exporter *Exporter
+	// topLevel holds the IDs of spans whose local parent belongs to another service
+	topLevel sync.Map
+}
+
+var _ sdktrace.SpanProcessor = (*statsProcessor)(nil)
+
+// StatsProcessor returns the span processor computing client-side stats, to
+// register on the tracer provider next to the exporter span processor. Stats
+// are only computed, and unsampled traces dropped, once it is registered.
+// Unsampled spans being ended only when recorded, the sampler must return
+// RecordOnly instead of Drop (ex: spansampling.NewSampler).
+func (obj *Exporter) StatsProcessor() sdktrace.SpanProcessor {
+	atomic.StoreInt32(&obj.statsProcessor, 1)
+	return &statsProcessor{exporter: obj}
+}
+
+// StatsComputation returns true if client-side stats are computed, enabled by
+// WithStatsComputation and not in agentless mode. The stats processor and a
+// sampler recording unsampled traces must then be registered.
+func (obj *Exporter) StatsComputation() bool {
+	return obj.intake == nil && *obj.conf.statsComputation
+}
+
+// OnStart marks the span as top-level if its local parent belongs to another service.
+func (obj *statsProcessor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	parentSpan, ok := trace.SpanFromContext(parent).(sdktrace.ReadOnlySpan)
+	if !ok || parentSpan.SpanContext().TraceID() != s.SpanContext().TraceID() {
+		return
+	}
+	if serviceName(parentSpan.Resource()) != serviceName(s.Resource()) {
+		obj.topLevel.Store(s.SpanContext().SpanID(), struct{}{})
+	}
+}
+
+// OnEnd aggregates the span in stats, whatever its sampling decision.
+func (obj *statsProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	var (
+		exporter    = obj.exporter
+		_, topLevel = obj.topLevel.LoadAndDelete(s.SpanContext().SpanID())
+	)
+	if atomic.LoadInt32(&exporter.stopped) != 0 || !exporter.StatsComputation() {
+		return
+	}
+
+	exporter.statsOnce.Do(exporter.startStatsFlush)
+
+	var dst = exporter.conv.convertSpan(s)
+	if topLevel {
+		dst.Metrics[keyTopLevel] = 1
+	}
+	exporter.stats.add([]*span{dst}, exporter.cachedFeatures().PeerTags)
+}
+
+// Shutdown does nothing, stats being flushed on exporter shutdown.
+func (obj *statsProcessor) Shutdown(context.Context) error { return nil }
+
+// ForceFlush does nothing, stats being flushed by time buckets.
+func (obj *statsProcessor) ForceFlush(context.Context) error { return nil }
+
+// serviceName returns the Datadog service of a resource.
+func serviceName(res *resource.Resource) string {
+	var resAttr naming.Attributes
+	if res != nil {
+		resAttr = naming.NewAttributes(res.Attributes())
+	}
+	return naming.ServiceName(resAttr)
+}
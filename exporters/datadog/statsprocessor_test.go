@@ -0,0 +1,115 @@
+package datadog
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/samplers/ddsampler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// test_recordOnly records all spans without sampling them.
+type test_recordOnly struct{}
+
+func (test_recordOnly) ShouldSample(sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return sdktrace.SamplingResult{Decision: sdktrace.RecordOnly}
+}
+
+func (test_recordOnly) Description() string { return "RecordOnly" }
+
+// test_statsGroups returns the stats groups aggregated by the exporter, by service.
+func test_statsGroups(exporter *Exporter) map[string]*groupedStats {
+	exporter.stats.mu.Lock()
+	defer exporter.stats.mu.Unlock()
+
+	var groups = map[string]*groupedStats{}
+	for _, bucket := range exporter.stats.buckets {
+		for key, group := range bucket {
+			groups[key.service] = group
+		}
+	}
+	return groups
+}
+
+func Test_statsProcessor(t *testing.T) {
+	exporter, err := New(WithStatsComputation(true))
+	require.NoError(t, err)
+	defer exporter.Shutdown(context.Background())
+	assert.True(t, exporter.StatsComputation())
+
+	var (
+		ctx       = context.Background()
+		processor = exporter.StatsProcessor()
+		frontend  = sdktrace.NewTracerProvider(
+			sdktrace.WithSampler(test_recordOnly{}),
+			sdktrace.WithSpanProcessor(processor),
+			sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceNameKey.String("frontend"))),
+		)
+		backend = sdktrace.NewTracerProvider(
+			sdktrace.WithSampler(test_recordOnly{}),
+			sdktrace.WithSpanProcessor(processor),
+			sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceNameKey.String("backend"))),
+		)
+	)
+
+	// Unsampled spans aggregated, child of another service being top-level
+	ctx, root := frontend.Tracer("test").Start(ctx, "root", trace.WithSpanKind(trace.SpanKindServer))
+	_, child := backend.Tracer("test").Start(ctx, "child")
+	_, internal := frontend.Tracer("test").Start(ctx, "internal")
+	assert.False(t, root.SpanContext().IsSampled())
+	child.End()
+	internal.End()
+	root.End()
+
+	var groups = test_statsGroups(exporter)
+	require.Len(t, groups, 2)
+	assert.Equal(t, uint64(1), groups["frontend"].hits)
+	assert.Equal(t, uint64(1), groups["frontend"].topLevelHits)
+	assert.Equal(t, uint64(1), groups["backend"].topLevelHits)
+
+	// Marks removed once spans ended
+	var marks int
+	processor.(*statsProcessor).topLevel.Range(func(_, _ interface{}) bool {
+		marks++
+		return true
+	})
+	assert.Zero(t, marks)
+}
+
+func Test_statsProcessor_RejectedTrace(t *testing.T) {
+	exporter, err := New(WithStatsComputation(true))
+	require.NoError(t, err)
+	defer exporter.Shutdown(context.Background())
+	sampler, err := ddsampler.NewRulesSampler(ddsampler.WithService("api"), ddsampler.WithSampleRate(0), ddsampler.WithStatsComputation(true))
+	require.NoError(t, err)
+
+	var provider = sdktrace.NewTracerProvider(
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithSpanProcessor(exporter.StatsProcessor()),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceNameKey.String("api"))),
+	)
+
+	// Trace rejected by the sampler still counted
+	_, root := provider.Tracer("test").Start(context.Background(), "root", trace.WithSpanKind(trace.SpanKindServer))
+	root.End()
+	assert.False(t, root.SpanContext().IsSampled())
+	var groups = test_statsGroups(exporter)
+	require.Contains(t, groups, "api")
+	assert.Equal(t, uint64(1), groups["api"].hits)
+}
+
+func Test_statsProcessor_disabled(t *testing.T) {
+	exporter, err := New()
+	require.NoError(t, err)
+	assert.False(t, exporter.StatsComputation())
+
+	var provider = sdktrace.NewTracerProvider(sdktrace.WithSampler(test_recordOnly{}), sdktrace.WithSpanProcessor(exporter.StatsProcessor()))
+	_, span := provider.Tracer("test").Start(context.Background(), "root", trace.WithSpanKind(trace.SpanKindServer))
+	span.End()
+	assert.Empty(t, test_statsGroups(exporter))
+}
@@ -0,0 +1,73 @@
+package datadog
+
+import (
+	"strconv"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AttributeMeasured is the span attribute forcing (true) or preventing (false)
+// the computation of trace metrics for a span.
+const AttributeMeasured = attribute.Key(keyMeasured)
+
+// Measured returns the attribute forcing the computation of trace metrics for a span.
+func Measured() attribute.KeyValue {
+	return AttributeMeasured.Bool(true)
+}
+
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/trace/api/otlp.go (computeTopLevelAndMeasured)
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/trace/traceutil/span.go (ComputeTopLevel)
+
+// markTopLevel marks service entry spans: local roots are marked on conversion,
+// spans whose parent belongs to another service are marked here.
+func markTopLevel(trace []*span) {
+	var services = make(map[uint64]string, len(trace))
+	for _, s := range trace {
+		services[s.SpanID] = s.Service
+	}
+	for _, s := range trace {
+		if service, ok := services[s.ParentID]; ok && service != s.Service {
+			s.Metrics[keyTopLevel] = 1
+		}
+	}
+}
+
+// markLocalRootTopLevel marks spans without local parent, the parent service
+// being in another process.
+func markLocalRootTopLevel(src sdktrace.ReadOnlySpan, dst *span) {
+	if isLocalRoot(src) {
+		dst.Metrics[keyTopLevel] = 1
+	}
+}
+
+// markMeasured marks server, client, producer and consumer spans as measured,
+// unless AttributeMeasured is set on the span.
+func markMeasured(src sdktrace.ReadOnlySpan, dst *span) {
+	// Attribute value set by user (meta for bool, metrics for numbers)
+	if value, ok := dst.Meta[keyMeasured]; ok {
+		delete(dst.Meta, keyMeasured)
+		if measured, err := strconv.ParseBool(value); err == nil {
+			setMeasured(dst, measured)
+			return
+		}
+	}
+	if value, ok := dst.Metrics[keyMeasured]; ok {
+		setMeasured(dst, value != 0)
+		return
+	}
+
+	switch src.SpanKind() {
+	case trace.SpanKindServer, trace.SpanKindClient, trace.SpanKindProducer, trace.SpanKindConsumer:
+		setMeasured(dst, true)
+	}
+}
+
+func setMeasured(dst *span, measured bool) {
+	if measured {
+		dst.Metrics[keyMeasured] = 1
+	} else {
+		delete(dst.Metrics, keyMeasured)
+	}
+}
@@ -0,0 +1,89 @@
+package datadog
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_markTopLevel(t *testing.T) {
+	var (
+		root   = &span{SpanID: 1, Service: "front", Metrics: map[string]float64{keyTopLevel: 1}}
+		child  = &span{SpanID: 2, ParentID: 1, Service: "front", Metrics: map[string]float64{}}
+		other  = &span{SpanID: 3, ParentID: 2, Service: "db", Metrics: map[string]float64{}}
+		orphan = &span{SpanID: 4, ParentID: 9, Service: "db", Metrics: map[string]float64{}}
+	)
+	markTopLevel([]*span{other, child, root, orphan})
+
+	assert.Equal(t, float64(1), root.Metrics[keyTopLevel])
+	assert.NotContains(t, child.Metrics, keyTopLevel)
+	assert.Equal(t, float64(1), other.Metrics[keyTopLevel])
+	// Local parent not in chunk: same process, same service
+	assert.NotContains(t, orphan.Metrics, keyTopLevel)
+}
+
+func Test_markLocalRootTopLevel(t *testing.T) {
+	var (
+		traceID = trace.TraceID{15: 1}
+		local   = trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: trace.SpanID{7: 1}})
+		remote  = local.WithRemote(true)
+	)
+	for name, tt := range map[string]struct {
+		parent   trace.SpanContext
+		topLevel bool
+	}{
+		"root":          {topLevel: true},
+		"remote parent": {parent: remote, topLevel: true},
+		"local parent":  {parent: local},
+	} {
+		var src = tracetest.SpanStub{
+			SpanContext: trace.NewSpanContext(trace.SpanContextConfig{TraceID: traceID, SpanID: trace.SpanID{7: 2}}),
+			Parent:      tt.parent,
+		}.Snapshot()
+		var dst = newSpan()
+		markLocalRootTopLevel(src, dst)
+		if tt.topLevel {
+			assert.Equal(t, float64(1), dst.Metrics[keyTopLevel], name)
+		} else {
+			assert.NotContains(t, dst.Metrics, keyTopLevel, name)
+		}
+	}
+}
+
+func Test_markMeasured(t *testing.T) {
+	for name, tt := range map[string]struct {
+		kind     trace.SpanKind
+		attr     *attribute.KeyValue
+		measured bool
+	}{
+		"server":          {kind: trace.SpanKindServer, measured: true},
+		"client":          {kind: trace.SpanKindClient, measured: true},
+		"producer":        {kind: trace.SpanKindProducer, measured: true},
+		"consumer":        {kind: trace.SpanKindConsumer, measured: true},
+		"internal":        {kind: trace.SpanKindInternal},
+		"forced":          {kind: trace.SpanKindInternal, attr: test_attr(Measured()), measured: true},
+		"forced int":      {kind: trace.SpanKindInternal, attr: test_attr(AttributeMeasured.Int(1)), measured: true},
+		"prevented":       {kind: trace.SpanKindServer, attr: test_attr(AttributeMeasured.Bool(false))},
+		"prevented int":   {kind: trace.SpanKindClient, attr: test_attr(AttributeMeasured.Int(0))},
+		"invalid ignored": {kind: trace.SpanKindClient, attr: test_attr(AttributeMeasured.String("maybe")), measured: true},
+	} {
+		var stub = tracetest.SpanStub{SpanKind: tt.kind}
+		if tt.attr != nil {
+			stub.Attributes = []attribute.KeyValue{*tt.attr}
+		}
+		var dst = test_newConverter(t).convertSpan(stub.Snapshot())
+		if tt.measured {
+			assert.Equal(t, float64(1), dst.Metrics[keyMeasured], name)
+		} else {
+			assert.NotContains(t, dst.Metrics, keyMeasured, name)
+		}
+		assert.NotContains(t, dst.Meta, keyMeasured, name)
+	}
+}
+
+func test_attr(kv attribute.KeyValue) *attribute.KeyValue {
+	return &kv
+}
@@ -0,0 +1,132 @@
+package datadog
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+)
+
+// traceWriter sends trace payloads to the agent in order, with retries. Payloads
+// not sent are queued and sent first on next write.
+type traceWriter struct {
+	agent   *agentClient
+	policy  retryPolicy
+	metrics *exporterMetrics
+
+	mu    sync.Mutex // one write at a time, queue is not safe for concurrent use
+	queue payloadQueue
+	// Unsampled traces and spans dropped by the exporter, not yet reported to the agent
+	droppedP0Traces int
+	droppedP0Spans  int
+
+	// Queue stats, readable while writing
+	queuedCount int64
+	queuedBytes int64
+}
+
+func newTraceWriter(conf *config, agent *agentClient, metrics *exporterMetrics) (*traceWriter, error) {
+	var writer = &traceWriter{
+		agent:   agent,
+		policy:  *conf.retryPolicy,
+		metrics: metrics,
+	}
+
+	// Disk queue if enabled, payloads of previous process sent first
+	if conf.spoolDir == "" {
+		writer.queue = newMemoryQueue(conf.maxQueuedBytes, writer.drop)
+	} else {
+		queue, err := newDiskQueue(conf.spoolDir, conf.maxQueuedBytes, conf.spoolMaxAge, writer.drop)
+		if err != nil {
+			return nil, err
+		}
+		writer.queue = queue
+	}
+	writer.updateStats()
+	return writer, nil
+}
+
+// addDroppedP0 records unsampled traces dropped by the exporter, reported with
+// the next payload.
+func (obj *traceWriter) addDroppedP0(traceCount, spanCount int) {
+	if traceCount == 0 {
+		return
+	}
+	obj.metrics.droppedP0(traceCount, spanCount)
+
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.droppedP0Traces += traceCount
+	obj.droppedP0Spans += spanCount
+}
+
+// write queues payloads and sends all queued payloads.
+func (obj *traceWriter) write(ctx context.Context, payloads []tracePayload) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	for _, payload := range payloads {
+		if err := obj.queue.push(payload); err != nil {
+			otel.Handle(err)
+			obj.drop(payload)
+		}
+	}
+	obj.updateStats()
+	return obj.flushLocked(ctx)
+}
+
+// flush sends queued payloads in order, and stops at first failure.
+func (obj *traceWriter) flush(ctx context.Context) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	return obj.flushLocked(ctx)
+}
+
+func (obj *traceWriter) flushLocked(ctx context.Context) error {
+	defer obj.updateStats()
+	for {
+		payload, ok, err := obj.queue.peek()
+		if err != nil || !ok {
+			return err
+		}
+
+		payload.droppedP0Traces, payload.droppedP0Spans = obj.droppedP0Traces, obj.droppedP0Spans
+		retries, err := obj.policy.do(ctx, func() error {
+			return obj.agent.sendTraces(ctx, payload)
+		})
+		if err != nil {
+			obj.metrics.failed(retries)
+			if !isRetryable(err) && ctx.Err() == nil {
+				// Payload rejected by agent
+				obj.drop(payload)
+				if removeErr := obj.queue.remove(); removeErr != nil {
+					otel.Handle(removeErr)
+				}
+			}
+			// Otherwise agent unavailable, payload kept for next write
+			return err
+		}
+
+		obj.metrics.sent(payload.traceCount, payload.spanCount, retries)
+		obj.droppedP0Traces, obj.droppedP0Spans = 0, 0
+		if err = obj.queue.remove(); err != nil {
+			return err
+		}
+	}
+}
+
+// stats returns the number of queued payloads and their size in bytes.
+func (obj *traceWriter) stats() (count, size int64) {
+	return atomic.LoadInt64(&obj.queuedCount), atomic.LoadInt64(&obj.queuedBytes)
+}
+
+func (obj *traceWriter) updateStats() {
+	count, size := obj.queue.stats()
+	atomic.StoreInt64(&obj.queuedCount, int64(count))
+	atomic.StoreInt64(&obj.queuedBytes, int64(size))
+}
+
+func (obj *traceWriter) drop(payload tracePayload) {
+	obj.metrics.dropped(payload.traceCount, payload.spanCount)
+}
@@ -0,0 +1,118 @@
+package datadog
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func test_newTraceWriter(t *testing.T, agent *testAgent, cfg ...configFn) *traceWriter {
+	conf, err := newConfig(append([]configFn{WithAgentURL(agent.URL), WithRetry(2, time.Millisecond, time.Millisecond)}, cfg...)...)
+	require.NoError(t, err)
+	writer, err := newTraceWriter(conf, newAgentClient(conf), &exporterMetrics{})
+	require.NoError(t, err)
+	return writer
+}
+
+func Test_traceWriter_write(t *testing.T) {
+	var (
+		agent   = newTestAgent(t, agentFeatures{})
+		writer  = test_newTraceWriter(t, agent)
+		payload = tracePayload{data: []byte{0x90}, traceCount: 1, spanCount: 2}
+	)
+
+	// Check retried on server errors
+	agent.setNextStatuses(http.StatusServiceUnavailable, http.StatusTooManyRequests)
+	assert.NoError(t, writer.write(context.Background(), []tracePayload{payload}))
+	assert.Len(t, agent.received(agentPathTraces04), 3)
+	assert.Equal(t, InternalMetrics{TracesSent: 1, SpansSent: 2, PayloadsSent: 1, Retries: 2, SendErrors: 2}, writer.metrics.snapshot())
+
+	// Check payload kept when retries exhausted, sent first on next write
+	agent.setNextStatuses(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	assert.Error(t, writer.write(context.Background(), []tracePayload{payload}))
+	count, _ := writer.stats()
+	assert.Equal(t, int64(1), count)
+	assert.NoError(t, writer.write(context.Background(), []tracePayload{{data: []byte{0x91}, traceCount: 1}}))
+	requests := agent.received(agentPathTraces04)
+	require.Len(t, requests, 8)
+	assert.Equal(t, []byte{0x90}, requests[6].body)
+	assert.Equal(t, []byte{0x91}, requests[7].body)
+
+	// Check payload rejected by agent dropped
+	agent.setNextStatuses(http.StatusBadRequest)
+	assert.Error(t, writer.write(context.Background(), []tracePayload{payload}))
+	count, _ = writer.stats()
+	assert.Equal(t, int64(0), count)
+	assert.Equal(t, uint64(1), writer.metrics.snapshot().TracesDropped)
+	assert.Equal(t, uint64(2), writer.metrics.snapshot().SpansDropped)
+}
+
+func Test_traceWriter_write_AgentDown(t *testing.T) {
+	var (
+		agent   = newTestAgent(t, agentFeatures{})
+		writer  = test_newTraceWriter(t, agent, WithMaxQueuedBytes(2))
+		payload = tracePayload{data: []byte{0x90}, traceCount: 1, spanCount: 1}
+	)
+	agent.Close()
+
+	// Check queued bytes bounded
+	assert.Error(t, writer.write(context.Background(), []tracePayload{payload}))
+	assert.Error(t, writer.write(context.Background(), []tracePayload{payload}))
+	assert.Error(t, writer.write(context.Background(), []tracePayload{payload}))
+	count, size := writer.stats()
+	assert.Equal(t, int64(2), count)
+	assert.Equal(t, int64(2), size)
+	assert.Equal(t, uint64(1), writer.metrics.snapshot().TracesDropped)
+}
+
+func Test_traceWriter_write_Deadline(t *testing.T) {
+	var (
+		agent   = newTestAgent(t, agentFeatures{})
+		writer  = test_newTraceWriter(t, agent, WithRetry(2, time.Minute, time.Minute))
+		payload = tracePayload{data: []byte{0x90}, traceCount: 1, spanCount: 1}
+	)
+
+	// Check write not blocked past the deadline, payload kept for next write
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	agent.setNextStatuses(http.StatusServiceUnavailable)
+	var start = time.Now()
+	assert.Error(t, writer.write(ctx, []tracePayload{payload}))
+	assert.Less(t, int64(time.Since(start)), int64(50*time.Millisecond))
+	count, _ := writer.stats()
+	assert.Equal(t, int64(1), count)
+
+	assert.NoError(t, writer.flush(context.Background()))
+	assert.Len(t, agent.received(agentPathTraces04), 2)
+}
+
+func Test_traceWriter_addDroppedP0(t *testing.T) {
+	var (
+		agent  = newTestAgent(t, agentFeatures{})
+		writer = test_newTraceWriter(t, agent)
+	)
+
+	writer.addDroppedP0(0, 0)
+	writer.addDroppedP0(2, 3)
+	writer.addDroppedP0(1, 1)
+
+	// Check counts kept on failure, reported once sent
+	agent.setNextStatuses(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	assert.Error(t, writer.write(context.Background(), []tracePayload{{data: []byte{0x90}}}))
+	assert.NoError(t, writer.flush(context.Background()))
+	assert.NoError(t, writer.write(context.Background(), []tracePayload{{data: []byte{0x90}}}))
+
+	requests := agent.received(agentPathTraces04)
+	require.Len(t, requests, 5)
+	assert.Equal(t, "3", requests[3].header.Get("Datadog-Client-Dropped-P0-Traces"))
+	assert.Equal(t, "4", requests[3].header.Get("Datadog-Client-Dropped-P0-Spans"))
+	assert.Empty(t, requests[4].header.Get("Datadog-Client-Dropped-P0-Traces"))
+
+	var metrics = writer.metrics.snapshot()
+	assert.Equal(t, uint64(3), metrics.TracesDroppedP0)
+	assert.Equal(t, uint64(4), metrics.SpansDroppedP0)
+}
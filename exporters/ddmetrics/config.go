@@ -0,0 +1,178 @@
+package ddmetrics
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// _____________________ With option functions _____________________
+
+// WithAPIKey sets the Datadog API key.
+// It defaults to DD_API_KEY environment variable.
+func WithAPIKey(value string) configFn {
+	return func(conf *config) {
+		conf.apiKey = value
+	}
+}
+
+// WithSite sets the Datadog site (ex: "datadoghq.eu").
+// It defaults to DD_SITE environment variable or DefaultSite.
+func WithSite(value string) configFn {
+	return func(conf *config) {
+		conf.site = value
+	}
+}
+
+// WithURL sets the base URL of the Datadog API (ex: "https://api.datadoghq.eu").
+// It defaults to DD_DD_URL environment variable or "https://api." + site.
+func WithURL(value string) configFn {
+	return func(conf *config) {
+		conf.url = value
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send metrics.
+func WithHTTPClient(value *http.Client) configFn {
+	return func(conf *config) {
+		conf.httpClient = value
+	}
+}
+
+// WithHostname sets the host of the metrics.
+// It defaults to the host of the resource attributes, see README.
+func WithHostname(value string) configFn {
+	return func(conf *config) {
+		conf.hostname = value
+	}
+}
+
+// WithTags sets the tags added to all metrics (ex: "team:core").
+func WithTags(tags ...string) configFn {
+	return func(conf *config) {
+		conf.tags = tags
+	}
+}
+
+// WithStreamTTL sets the number of exports a metric stream is kept without new
+// point, its previous point being forgotten after: a stream seen again is then
+// converted as restarted.
+// It defaults to DefaultStreamTTL.
+func WithStreamTTL(exports int) configFn {
+	return func(conf *config) {
+		conf.streamTTL = exports
+	}
+}
+
+// _____________________ Definition _____________________
+
+type configFn func(*config)
+
+var (
+	ErrMissingAPIKey    = errors.New("missing API key")
+	ErrInvalidURL       = errors.New("invalid API URL")
+	ErrInvalidStreamTTL = errors.New("invalid stream TTL")
+)
+
+// Ref https://docs.datadoghq.com/api/latest/metrics/
+// Ref https://docs.datadoghq.com/agent/configuration/agent-configuration-files/ (dd_url)
+
+const (
+	envAPIKey = "DD_API_KEY"
+	envSite   = "DD_SITE"
+	envURL    = "DD_DD_URL"
+)
+
+const (
+	// DefaultSite specifies the Datadog site.
+	DefaultSite = "datadoghq.com"
+
+	// DefaultStreamTTL specifies the number of exports a metric stream is kept without new point.
+	DefaultStreamTTL = 5
+
+	apiURLPrefix = "https://api."
+
+	// defaultHTTPTimeout is the timeout of requests sent to the API
+	defaultHTTPTimeout = 10 * time.Second
+)
+
+// _____________________ Configuration _____________________
+
+func newConfig(cfg ...configFn) (*config, error) {
+	var conf = &config{}
+
+	// Apply configurations
+	for _, v := range cfg {
+		if v != nil {
+			v(conf)
+		}
+	}
+
+	// Apply default value on empty
+	conf.applyDefault()
+
+	// Check configuration is valid
+	if err := conf.parse(); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+type config struct {
+	apiKey     string
+	site       string
+	url        string
+	httpClient *http.Client
+	hostname   string
+	tags       []string
+	streamTTL  int
+
+	// Parsed values
+	baseURL string
+}
+
+func (obj *config) applyDefault() {
+	obj.apiKey = stringDefault(obj.apiKey, os.Getenv(envAPIKey))
+	obj.site = stringDefault(obj.site, os.Getenv(envSite), DefaultSite)
+	obj.url = stringDefault(obj.url, os.Getenv(envURL), apiURLPrefix+obj.site)
+	if obj.httpClient == nil {
+		obj.httpClient = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	if obj.streamTTL == 0 {
+		obj.streamTTL = DefaultStreamTTL
+	}
+}
+
+func (obj *config) parse() error {
+	if obj.apiKey == "" {
+		return ErrMissingAPIKey
+	}
+	if obj.streamTTL < 0 {
+		return ErrInvalidStreamTTL
+	}
+
+	apiURL, err := url.Parse(obj.url)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	if apiURL.Scheme != "http" && apiURL.Scheme != "https" {
+		return fmt.Errorf("%w: unsupported scheme %q", ErrInvalidURL, apiURL.Scheme)
+	}
+	obj.baseURL = strings.TrimSuffix(apiURL.String(), "/")
+	return nil
+}
+
+// stringDefault returns the first non empty value
+func stringDefault(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package ddmetrics
+
+import (
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newConfig(t *testing.T) {
+	for _, key := range []string{envAPIKey, envSite, envURL} {
+		setenv(t, key, "")
+	}
+
+	_, err := newConfig()
+	assert.ErrorIs(t, err, ErrMissingAPIKey)
+
+	// Defaults
+	setenv(t, envAPIKey, "key")
+	conf, err := newConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "key", conf.apiKey)
+	assert.Equal(t, "https://api.datadoghq.com", conf.baseURL)
+	assert.NotNil(t, conf.httpClient)
+	assert.Equal(t, DefaultStreamTTL, conf.streamTTL)
+
+	// Environment
+	setenv(t, envSite, "datadoghq.eu")
+	conf, err = newConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.datadoghq.eu", conf.baseURL)
+	setenv(t, envURL, "https://proxy.example.com/")
+	conf, err = newConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "https://proxy.example.com", conf.baseURL)
+
+	// Options over environment
+	var client = &http.Client{}
+	conf, err = newConfig(WithAPIKey("other"), WithURL("http://localhost:8080"), WithHTTPClient(client), WithHostname("host"), WithTags("team:core"), WithStreamTTL(3))
+	require.NoError(t, err)
+	assert.Equal(t, 3, conf.streamTTL)
+	assert.Equal(t, "other", conf.apiKey)
+	assert.Equal(t, "http://localhost:8080", conf.baseURL)
+	assert.Same(t, client, conf.httpClient)
+	assert.Equal(t, "host", conf.hostname)
+	assert.Equal(t, []string{"team:core"}, conf.tags)
+
+	setenv(t, envURL, "")
+	conf, err = newConfig(WithSite("us3.datadoghq.com"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.us3.datadoghq.com", conf.baseURL)
+
+	// Invalid
+	for _, value := range []string{"ftp://api.datadoghq.com", "://api"} {
+		_, err = newConfig(WithURL(value))
+		assert.ErrorIs(t, err, ErrInvalidURL, value)
+	}
+	_, err = newConfig(WithStreamTTL(-1))
+	assert.ErrorIs(t, err, ErrInvalidStreamTTL)
+}
+
+func setenv(t *testing.T, key, value string) {
+	old, exists := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if exists {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
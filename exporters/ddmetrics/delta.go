@@ -0,0 +1,131 @@
+package ddmetrics
+
+import (
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Ref https://github.com/DataDog/opentelemetry-mapping-go/blob/main/pkg/otlp/metrics/ttlcache.go
+
+// streamKey identifies the points of a metric stream.
+type streamKey struct {
+	name  string
+	attrs attribute.Distinct
+}
+
+type cumulativePoint struct {
+	seen    uint64 // export of the point
+	evicted bool   // start time kept only
+	start   time.Time
+	end     time.Time
+	value   float64 // sums
+	count   uint64  // histograms
+	sum     float64
+	counts  []uint64
+
+	exponential *ExponentialHistogramPoint // exponential histograms
+}
+
+// deltaConverter converts cumulative points to deltas, keeping the previous
+// point of each stream. A stream restarts (ex: process restart of a remote
+// source, counter reset) when its start time changes or when its value
+// decreases: the delta is then the value since the new start. Points of
+// streams not seen for several exports are evicted, their start time being
+// kept: the first point of an evicted stream seen again with the same start
+// time is a new baseline, exported as no value, the values since its last
+// export being unknown. The SDK keeping cumulative streams forever, kept start
+// times are bounded by its own memory.
+type deltaConverter struct {
+	points map[streamKey]cumulativePoint
+	export uint64 // number of exports
+}
+
+func newDeltaConverter() *deltaConverter {
+	return &deltaConverter{points: make(map[streamKey]cumulativePoint)}
+}
+
+// sum returns the delta of a monotonic sum and the interval it covers, false
+// for a baseline.
+func (obj *deltaConverter) sum(key streamKey, start, end time.Time, value float64) (float64, time.Duration, bool) {
+	var prev, found = obj.points[key]
+	obj.points[key] = cumulativePoint{seen: obj.export, start: start, end: end, value: value}
+
+	if isBaseline(prev, found, start) {
+		return 0, 0, false
+	}
+	if !found || prev.evicted || !start.Equal(prev.start) || value < prev.value {
+		return value, end.Sub(start), true
+	}
+	return value - prev.value, end.Sub(prev.end), true
+}
+
+// histogram returns the delta of a histogram: bucket counts, count and sum.
+func (obj *deltaConverter) histogram(key streamKey, start, end time.Time, counts []uint64, count uint64, sum float64) ([]uint64, uint64, float64) {
+	var prev, found = obj.points[key]
+	obj.points[key] = cumulativePoint{seen: obj.export, start: start, end: end, count: count, sum: sum, counts: append([]uint64(nil), counts...)}
+
+	if isBaseline(prev, found, start) {
+		return nil, 0, 0
+	}
+	if !found || prev.evicted || !start.Equal(prev.start) || count < prev.count || len(counts) != len(prev.counts) {
+		return counts, count, sum
+	}
+	var delta = make([]uint64, len(counts))
+	for i := range counts {
+		if counts[i] < prev.counts[i] {
+			return counts, count, sum
+		}
+		delta[i] = counts[i] - prev.counts[i]
+	}
+	return delta, count - prev.count, sum - prev.sum
+}
+
+// exponentialHistogram returns the delta of an exponential histogram: point,
+// count and sum. The previous point is downscaled when the scale of the stream
+// decreased, a scale increase being a restart.
+func (obj *deltaConverter) exponentialHistogram(key streamKey, start, end time.Time, point ExponentialHistogramPoint, count uint64, sum float64) (ExponentialHistogramPoint, uint64, float64) {
+	var prev, found = obj.points[key]
+	var copied = copyExponential(point)
+	obj.points[key] = cumulativePoint{seen: obj.export, start: start, end: end, count: count, sum: sum, exponential: &copied}
+
+	if isBaseline(prev, found, start) {
+		return ExponentialHistogramPoint{}, 0, 0
+	}
+	if !found || prev.exponential == nil || !start.Equal(prev.start) || count < prev.count || point.Scale > prev.exponential.Scale || point.ZeroCount < prev.exponential.ZeroCount {
+		return point, count, sum
+	}
+	var by = prev.exponential.Scale - point.Scale
+	positive, ok := subtractBuckets(point.Positive, downscale(prev.exponential.Positive, by))
+	if !ok {
+		return point, count, sum
+	}
+	negative, ok := subtractBuckets(point.Negative, downscale(prev.exponential.Negative, by))
+	if !ok {
+		return point, count, sum
+	}
+	var delta = ExponentialHistogramPoint{
+		Scale:     point.Scale,
+		ZeroCount: point.ZeroCount - prev.exponential.ZeroCount,
+		Positive:  positive,
+		Negative:  negative,
+	}
+	return delta, count - prev.count, sum - prev.sum
+}
+
+// evict ends an export, forgetting the points of streams not seen for more
+// than ttl exports but their start time.
+func (obj *deltaConverter) evict(ttl int) {
+	obj.export++
+	for key, point := range obj.points {
+		if !point.evicted && obj.export-point.seen > uint64(ttl) {
+			obj.points[key] = cumulativePoint{evicted: true, start: point.start}
+		}
+	}
+}
+
+// isBaseline returns true if the point is the first one of an evicted stream
+// seen again with the same start time.
+func isBaseline(prev cumulativePoint, found bool, start time.Time) bool {
+	return found && prev.evicted && start.Equal(prev.start)
+}
@@ -0,0 +1,155 @@
+package ddmetrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func Test_deltaConverter_sum(t *testing.T) {
+	var (
+		conv   = newDeltaConverter()
+		start  = time.Unix(1000, 0)
+		ok     = attribute.NewSet(attribute.String("status", "ok"))
+		failed = attribute.NewSet(attribute.String("status", "error"))
+		key    = streamKey{name: "requests", attrs: ok.Equivalent()}
+		other  = streamKey{name: "requests", attrs: failed.Equivalent()}
+	)
+
+	// First point since start
+	delta, interval, exported := conv.sum(key, start, start.Add(10*time.Second), 5)
+	assert.True(t, exported)
+	assert.Equal(t, 5.0, delta)
+	assert.Equal(t, 10*time.Second, interval)
+
+	delta, interval, _ = conv.sum(key, start, start.Add(20*time.Second), 8)
+	assert.Equal(t, 3.0, delta)
+	assert.Equal(t, 10*time.Second, interval)
+	delta, _, _ = conv.sum(other, start, start.Add(20*time.Second), 1)
+	assert.Equal(t, 1.0, delta)
+
+	// Restart detected from value decrease or start time change
+	delta, interval, _ = conv.sum(key, start, start.Add(30*time.Second), 2)
+	assert.Equal(t, 2.0, delta)
+	assert.Equal(t, 30*time.Second, interval)
+	delta, interval, _ = conv.sum(key, start.Add(35*time.Second), start.Add(40*time.Second), 4)
+	assert.Equal(t, 4.0, delta)
+	assert.Equal(t, 5*time.Second, interval)
+}
+
+func Test_deltaConverter_histogram(t *testing.T) {
+	var (
+		conv  = newDeltaConverter()
+		start = time.Unix(1000, 0)
+		key   = streamKey{name: "latency"}
+	)
+
+	counts, count, sum := conv.histogram(key, start, start.Add(time.Second), []uint64{1, 2, 0}, 3, 10)
+	assert.Equal(t, []uint64{1, 2, 0}, counts)
+	assert.Equal(t, uint64(3), count)
+	assert.Equal(t, 10.0, sum)
+
+	counts, count, sum = conv.histogram(key, start, start.Add(2*time.Second), []uint64{1, 4, 1}, 6, 25)
+	assert.Equal(t, []uint64{0, 2, 1}, counts)
+	assert.Equal(t, uint64(3), count)
+	assert.Equal(t, 15.0, sum)
+
+	// Restart: bucket decrease, boundaries change
+	counts, count, _ = conv.histogram(key, start, start.Add(3*time.Second), []uint64{0, 5, 1}, 6, 30)
+	assert.Equal(t, []uint64{0, 5, 1}, counts)
+	assert.Equal(t, uint64(6), count)
+	counts, _, _ = conv.histogram(key, start, start.Add(4*time.Second), []uint64{1, 6}, 7, 31)
+	assert.Equal(t, []uint64{1, 6}, counts)
+}
+
+func Test_deltaConverter_exponentialHistogram(t *testing.T) {
+	var (
+		conv  = newDeltaConverter()
+		start = time.Unix(1000, 0)
+		key   = streamKey{name: "latency"}
+		first = ExponentialHistogramPoint{Scale: 1, ZeroCount: 1, Positive: ExponentialBuckets{Counts: []uint64{1, 2}}}
+	)
+
+	point, count, sum := conv.exponentialHistogram(key, start, start.Add(time.Second), first, 4, 10)
+	assert.Equal(t, first, point)
+	assert.Equal(t, uint64(4), count)
+	assert.Equal(t, 10.0, sum)
+
+	// Previous point downscaled to the new scale
+	var second = ExponentialHistogramPoint{
+		ZeroCount: 2,
+		Positive:  ExponentialBuckets{Counts: []uint64{4}},
+		Negative:  ExponentialBuckets{Counts: []uint64{1}},
+	}
+	point, count, sum = conv.exponentialHistogram(key, start, start.Add(2*time.Second), second, 7, 20)
+	assert.Equal(t, ExponentialHistogramPoint{
+		ZeroCount: 1,
+		Positive:  ExponentialBuckets{Counts: []uint64{1}},
+		Negative:  ExponentialBuckets{Counts: []uint64{1}},
+	}, point)
+	assert.Equal(t, uint64(3), count)
+	assert.Equal(t, 10.0, sum)
+
+	// Restart: scale increase, count decrease
+	point, count, _ = conv.exponentialHistogram(key, start, start.Add(3*time.Second), first, 8, 30)
+	assert.Equal(t, first, point)
+	assert.Equal(t, uint64(8), count)
+	point, count, _ = conv.exponentialHistogram(key, start, start.Add(4*time.Second), first, 4, 10)
+	assert.Equal(t, first, point)
+	assert.Equal(t, uint64(4), count)
+}
+
+func Test_deltaConverter_evict(t *testing.T) {
+	var (
+		conv  = newDeltaConverter()
+		start = time.Unix(1000, 0)
+		set   = attribute.NewSet(attribute.String("status", "ok"))
+		key   = streamKey{name: "requests", attrs: set.Equivalent()}
+		other = streamKey{name: "errors", attrs: set.Equivalent()}
+	)
+	conv.sum(key, start, start.Add(10*time.Second), 5)
+	conv.sum(other, start, start.Add(10*time.Second), 1)
+	conv.evict(2)
+
+	// Streams seen within the TTL kept, start time only of evicted ones
+	conv.sum(key, start, start.Add(20*time.Second), 6)
+	conv.evict(2)
+	conv.evict(2)
+	assert.False(t, conv.points[key].evicted)
+	assert.Equal(t, cumulativePoint{evicted: true, start: start}, conv.points[other])
+
+	// Evicted stream seen again: new baseline exported as no value, then deltas
+	_, _, ok := conv.sum(other, start, start.Add(40*time.Second), 3)
+	assert.False(t, ok)
+	delta, interval, ok := conv.sum(other, start, start.Add(50*time.Second), 5)
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, delta)
+	assert.Equal(t, 10*time.Second, interval)
+
+	// Evicted stream restarted
+	conv.evict(2)
+	conv.evict(2)
+	conv.evict(2)
+	assert.True(t, conv.points[key].evicted)
+	delta, _, ok = conv.sum(key, start.Add(60*time.Second), start.Add(70*time.Second), 4)
+	assert.True(t, ok)
+	assert.Equal(t, 4.0, delta)
+
+	// Histograms of evicted streams
+	var latency, sizes = streamKey{name: "latency"}, streamKey{name: "sizes"}
+	conv.histogram(latency, start, start.Add(10*time.Second), []uint64{1, 2}, 3, 10)
+	conv.exponentialHistogram(sizes, start, start.Add(10*time.Second), ExponentialHistogramPoint{ZeroCount: 1}, 1, 0)
+	for i := 0; i < 3; i++ {
+		conv.evict(2)
+	}
+	_, count, _ := conv.histogram(latency, start, start.Add(50*time.Second), []uint64{2, 3}, 5, 20)
+	assert.Zero(t, count)
+	counts, count, sum := conv.histogram(latency, start, start.Add(60*time.Second), []uint64{2, 4}, 6, 22)
+	assert.Equal(t, []uint64{0, 1}, counts)
+	assert.Equal(t, uint64(1), count)
+	assert.Equal(t, 2.0, sum)
+	_, count, _ = conv.exponentialHistogram(sizes, start, start.Add(50*time.Second), ExponentialHistogramPoint{ZeroCount: 2}, 2, 0)
+	assert.Zero(t, count)
+}
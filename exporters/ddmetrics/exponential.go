@@ -0,0 +1,70 @@
+package ddmetrics
+
+import (
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/ddsketch"
+	"go.opentelemetry.io/otel/sdk/metric/export/aggregation"
+	"go.opentelemetry.io/otel/sdk/metric/number"
+)
+
+// Ref https://opentelemetry.io/docs/specs/otel/metrics/data-model/#exponentialhistogram
+
+type (
+	// ExponentialHistogramPoint is the point of an exponential bucket histogram:
+	// scale, zero count, positive and negative buckets.
+	ExponentialHistogramPoint = ddsketch.ExponentialHistogram
+	// ExponentialBuckets are the buckets of an exponential histogram point, the
+	// bucket of index i holding the values in (base^i, base^(i+1)].
+	ExponentialBuckets = ddsketch.ExponentialBuckets
+)
+
+// ExponentialHistogram is the aggregation of an exponential bucket histogram,
+// exported as a distribution (sketch). The OpenTelemetry SDK of this module has
+// no exponential histogram aggregator: it is implemented by the aggregators of
+// a custom aggregator selector.
+type ExponentialHistogram interface {
+	aggregation.Aggregation
+	Count() (uint64, error)
+	Sum() (number.Number, error)
+	ExponentialHistogram() (ExponentialHistogramPoint, error)
+}
+
+// downscale returns the buckets merged by 2^by, as for a scale lowered by by.
+func downscale(buckets ExponentialBuckets, by int32) ExponentialBuckets {
+	if by <= 0 || len(buckets.Counts) == 0 {
+		return buckets
+	}
+	var (
+		offset = buckets.Offset >> by
+		last   = (buckets.Offset + int32(len(buckets.Counts)) - 1) >> by
+		counts = make([]uint64, last-offset+1)
+	)
+	for i, count := range buckets.Counts {
+		counts[(buckets.Offset+int32(i))>>by-offset] += count
+	}
+	return ExponentialBuckets{Offset: offset, Counts: counts}
+}
+
+// subtractBuckets returns the counts of buckets minus the ones of prev, false
+// if a count of prev is greater (stream restart).
+func subtractBuckets(buckets, prev ExponentialBuckets) (ExponentialBuckets, bool) {
+	var delta = ExponentialBuckets{Offset: buckets.Offset, Counts: append([]uint64(nil), buckets.Counts...)}
+	for i, count := range prev.Counts {
+		if count == 0 {
+			continue
+		}
+		var index = int(prev.Offset) + i - int(buckets.Offset)
+		if index < 0 || index >= len(delta.Counts) || delta.Counts[index] < count {
+			return buckets, false
+		}
+		delta.Counts[index] -= count
+	}
+	return delta, true
+}
+
+// copyExponential returns a copy of the point, its counts being reused by the
+// aggregator.
+func copyExponential(point ExponentialHistogramPoint) ExponentialHistogramPoint {
+	point.Positive.Counts = append([]uint64(nil), point.Positive.Counts...)
+	point.Negative.Counts = append([]uint64(nil), point.Negative.Counts...)
+	return point
+}
@@ -0,0 +1,101 @@
+package ddmetrics
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/unit"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/export"
+	"go.opentelemetry.io/otel/sdk/metric/export/aggregation"
+	"go.opentelemetry.io/otel/sdk/metric/number"
+	"go.opentelemetry.io/otel/sdk/metric/sdkapi"
+)
+
+// test_exponential is the aggregation of a custom exponential histogram aggregator.
+type test_exponential struct {
+	point ExponentialHistogramPoint
+	sum   float64
+}
+
+func (obj test_exponential) Kind() aggregation.Kind { return "ExponentialHistogram" }
+
+func (obj test_exponential) Count() (uint64, error) {
+	var count = obj.point.ZeroCount
+	for _, c := range append(append([]uint64(nil), obj.point.Positive.Counts...), obj.point.Negative.Counts...) {
+		count += c
+	}
+	return count, nil
+}
+
+func (obj test_exponential) Sum() (number.Number, error) {
+	return number.NewFloat64Number(obj.sum), nil
+}
+
+func (obj test_exponential) ExponentialHistogram() (ExponentialHistogramPoint, error) {
+	return obj.point, nil
+}
+
+// test_reader is an export.Reader of records, read as a single
+// instrumentation library.
+type test_reader struct {
+	sync.RWMutex
+	records []export.Record
+}
+
+func (obj *test_reader) ForEach(_ aggregation.TemporalitySelector, fn func(export.Record) error) error {
+	for _, record := range obj.records {
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type test_libraryReader struct{ reader *test_reader }
+
+func (obj test_libraryReader) ForEach(fn func(instrumentation.Library, export.Reader) error) error {
+	return fn(instrumentation.Library{Name: "test"}, obj.reader)
+}
+
+// test_exponentialReader returns a reader of an exponential histogram record
+// of the latency histogram.
+func test_exponentialReader(start, end time.Time, agg test_exponential, attrs ...attribute.KeyValue) export.InstrumentationLibraryReader {
+	var (
+		desc = sdkapi.NewDescriptor("latency", sdkapi.HistogramInstrumentKind, number.Float64Kind, "", unit.Milliseconds)
+		set  = attribute.NewSet(attrs...)
+	)
+	return test_libraryReader{reader: &test_reader{records: []export.Record{export.NewRecord(&desc, &set, agg, start, end)}}}
+}
+
+func Test_downscale(t *testing.T) {
+	var buckets = ExponentialBuckets{Offset: -3, Counts: []uint64{1, 2, 3, 4, 5}}
+	assert.Equal(t, buckets, downscale(buckets, 0))
+	// Indexes -3..1 merged by 2: -2 (-3), -1 (-2, -1), 0 (0, 1)
+	assert.Equal(t, ExponentialBuckets{Offset: -2, Counts: []uint64{1, 5, 9}}, downscale(buckets, 1))
+	assert.Equal(t, ExponentialBuckets{Offset: -1, Counts: []uint64{6, 9}}, downscale(buckets, 2))
+	assert.Equal(t, ExponentialBuckets{}, downscale(ExponentialBuckets{}, 1))
+}
+
+func Test_subtractBuckets(t *testing.T) {
+	var buckets = ExponentialBuckets{Offset: 2, Counts: []uint64{3, 4, 5}}
+
+	delta, ok := subtractBuckets(buckets, ExponentialBuckets{Offset: 3, Counts: []uint64{1, 5}})
+	assert.True(t, ok)
+	assert.Equal(t, ExponentialBuckets{Offset: 2, Counts: []uint64{3, 3, 0}}, delta)
+	assert.Equal(t, []uint64{3, 4, 5}, buckets.Counts)
+
+	// Empty buckets out of range ignored
+	delta, ok = subtractBuckets(buckets, ExponentialBuckets{Offset: 0, Counts: []uint64{0, 0, 3}})
+	assert.True(t, ok)
+	assert.Equal(t, []uint64{0, 4, 5}, delta.Counts)
+
+	// Restart: count decrease, bucket out of range
+	_, ok = subtractBuckets(buckets, ExponentialBuckets{Offset: 2, Counts: []uint64{4}})
+	assert.False(t, ok)
+	_, ok = subtractBuckets(buckets, ExponentialBuckets{Offset: 5, Counts: []uint64{1}})
+	assert.False(t, ok)
+}
@@ -0,0 +1,269 @@
+// Package ddmetrics exports OpenTelemetry metrics to the Datadog API, without
+// Datadog agent.
+package ddmetrics
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/ddsketch"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/export"
+	"go.opentelemetry.io/otel/sdk/metric/export/aggregation"
+	"go.opentelemetry.io/otel/sdk/metric/sdkapi"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+const (
+	pathSeries   = "/api/v2/series"
+	pathSketches = "/api/beta/sketches"
+
+	// maxPayloadSize is the maximum size of an uncompressed payload, the API
+	// accepting 5 MB uncompressed and 512 KB compressed
+	maxPayloadSize = 2 * 1024 * 1024
+)
+
+// Exporter exports OpenTelemetry metrics to the Datadog API:
+//   - counters as counts of the interval delta
+//   - up down counters and gauges as gauges
+//   - histograms and exponential histograms as distributions (sketches)
+//
+// The exporter asks for cumulative temporality and computes deltas itself, so
+// that restarts of a stream are detected.
+type Exporter struct {
+	conf *config
+
+	mu     sync.Mutex
+	deltas *deltaConverter
+}
+
+var _ export.Exporter = (*Exporter)(nil)
+
+// New returns a new exporter sending metrics to the Datadog API.
+// To use the defaults, call with nothing.
+func New(cfg ...configFn) (*Exporter, error) {
+	conf, err := newConfig(cfg...)
+	if err != nil {
+		return nil, err
+	}
+	return &Exporter{conf: conf, deltas: newDeltaConverter()}, nil
+}
+
+// TemporalityFor returns the cumulative temporality, deltas being computed by
+// the exporter.
+func (obj *Exporter) TemporalityFor(*sdkapi.Descriptor, aggregation.Kind) aggregation.Temporality {
+	return aggregation.CumulativeTemporality
+}
+
+// Export sends the metrics of the reader to the Datadog API.
+func (obj *Exporter) Export(ctx context.Context, res *resource.Resource, reader export.InstrumentationLibraryReader) error {
+	obj.mu.Lock()
+	var batch = metricBatch{
+		host: obj.conf.hostname,
+		tags: append(append([]string(nil), obj.conf.tags...), resourceTagsOf(res)...),
+	}
+	if batch.host == "" {
+		batch.host = resourceHost(res)
+	}
+	err := reader.ForEach(func(_ instrumentation.Library, r export.Reader) error {
+		return r.ForEach(obj, func(record export.Record) error {
+			return obj.convert(&batch, record)
+		})
+	})
+	obj.deltas.evict(obj.conf.streamTTL)
+	obj.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	var firstErr error
+	if len(batch.series) > 0 {
+		payloads, err := encodeSeries(batch.series, maxPayloadSize)
+		if err != nil {
+			return err
+		}
+		for _, payload := range payloads {
+			if err := obj.send(ctx, pathSeries, "application/json", payload); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+	}
+	for _, payload := range encodeSketches(batch.sketches, maxPayloadSize) {
+		if err := obj.send(ctx, pathSketches, "application/x-protobuf", payload); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// metricBatch holds the metrics of an export.
+type metricBatch struct {
+	host     string
+	tags     []string
+	series   []series
+	sketches []sketchSeries
+}
+
+// convert adds the record to the batch, mu being locked.
+func (obj *Exporter) convert(batch *metricBatch, record export.Record) error {
+	var (
+		desc = record.Descriptor()
+		key  = streamKey{name: desc.Name(), attrs: record.Attributes().Equivalent()}
+		tags = make([]string, len(batch.tags), len(batch.tags)+record.Attributes().Len())
+	)
+	copy(tags, batch.tags)
+	for iter := record.Attributes().Iter(); iter.Next(); {
+		tags = append(tags, attributeTag(iter.Attribute()))
+	}
+	var newSeries = func(typ int, timestamp time.Time, value float64) series {
+		var s = series{
+			Metric: desc.Name(),
+			Type:   typ,
+			Points: []point{{Timestamp: timestamp.Unix(), Value: value}},
+			Tags:   tags,
+			Unit:   datadogUnit(string(desc.Unit())),
+		}
+		if batch.host != "" {
+			s.Resources = []seriesResource{{Name: batch.host, Type: "host"}}
+		}
+		return s
+	}
+
+	var newSketch = func(sketch *agentSketch) sketchSeries {
+		return sketchSeries{
+			metric:    desc.Name(),
+			host:      batch.host,
+			tags:      tags,
+			timestamp: record.EndTime().Unix(),
+			sketch:    sketch,
+		}
+	}
+
+	switch value := record.Aggregation().(type) {
+	case ExponentialHistogram:
+		point, err := value.ExponentialHistogram()
+		if err != nil {
+			return err
+		}
+		sumNumber, err := value.Sum()
+		if err != nil {
+			return err
+		}
+		count, err := value.Count()
+		if err != nil {
+			return err
+		}
+		var sum = sumNumber.CoerceToFloat64(desc.NumberKind())
+		point, count, sum = obj.deltas.exponentialHistogram(key, record.StartTime(), record.EndTime(), point, count, sum)
+		if count == 0 {
+			return nil
+		}
+		var sketch = newAgentSketch()
+		ddsketch.ForEachExponentialBucket(point, sketch.insert)
+		sketch.sum = sum
+		batch.sketches = append(batch.sketches, newSketch(sketch))
+	case aggregation.Histogram:
+		buckets, err := value.Histogram()
+		if err != nil {
+			return err
+		}
+		sumNumber, err := value.Sum()
+		if err != nil {
+			return err
+		}
+		count, err := value.Count()
+		if err != nil {
+			return err
+		}
+		var sum = sumNumber.CoerceToFloat64(desc.NumberKind())
+		counts, count, sum := obj.deltas.histogram(key, record.StartTime(), record.EndTime(), buckets.Counts, count, sum)
+		if count == 0 {
+			return nil
+		}
+		var sketch = newAgentSketch()
+		ddsketch.ForEachExplicitBucket(buckets.Boundaries, counts, sum, sketch.insert)
+		sketch.sum = sum
+		batch.sketches = append(batch.sketches, newSketch(sketch))
+	case aggregation.LastValue:
+		last, timestamp, err := value.LastValue()
+		if err != nil {
+			return err
+		}
+		if v := last.CoerceToFloat64(desc.NumberKind()); isFinite(v) {
+			batch.series = append(batch.series, newSeries(seriesTypeGauge, timestamp, v))
+		}
+	case aggregation.Sum:
+		sum, err := value.Sum()
+		if err != nil {
+			return err
+		}
+		var v = sum.CoerceToFloat64(desc.NumberKind())
+		if !isFinite(v) {
+			return nil
+		}
+		if !desc.InstrumentKind().Monotonic() {
+			batch.series = append(batch.series, newSeries(seriesTypeGauge, record.EndTime(), v))
+			return nil
+		}
+		delta, interval, ok := obj.deltas.sum(key, record.StartTime(), record.EndTime(), v)
+		if !ok {
+			return nil
+		}
+		var s = newSeries(seriesTypeCount, record.EndTime(), delta)
+		s.Interval = int64(interval.Round(time.Second) / time.Second)
+		batch.series = append(batch.series, s)
+	}
+	return nil
+}
+
+// send posts a gzip compressed payload.
+func (obj *Exporter) send(ctx context.Context, path, contentType string, payload []byte) error {
+	var body bytes.Buffer
+	var gz = gzip.NewWriter(&body)
+	if _, err := gz.Write(payload); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, obj.conf.baseURL+path, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Content-Encoding", "gzip")
+	req.Header.Set("DD-API-KEY", obj.conf.apiKey)
+
+	resp, err := obj.conf.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s: unexpected status %s", path, resp.Status)
+	}
+	return nil
+}
+
+// attributeTag returns the tag "key:value" of an attribute.
+func attributeTag(kv attribute.KeyValue) string {
+	if value := kv.Value.Emit(); value != "" {
+		return string(kv.Key) + ":" + value
+	}
+	return string(kv.Key)
+}
+
+func isFinite(value float64) bool {
+	return !math.IsNaN(value) && !math.IsInf(value, 0)
+}
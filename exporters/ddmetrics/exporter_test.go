@@ -0,0 +1,242 @@
+package ddmetrics
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/unit"
+	"go.opentelemetry.io/otel/sdk/metric/aggregator/histogram"
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+// test_api is a stand-in of the Datadog metrics API, decoding payloads.
+type test_api struct {
+	*httptest.Server
+	status int
+
+	mu       sync.Mutex
+	series   []series
+	sketches []test_sketch
+}
+
+func test_newAPI(t *testing.T) *test_api {
+	var api = &test_api{status: http.StatusAccepted}
+	api.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key", r.Header.Get("DD-API-KEY"))
+		assert.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(gz)
+		require.NoError(t, err)
+
+		api.mu.Lock()
+		defer api.mu.Unlock()
+		switch r.URL.Path {
+		case pathSeries:
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			var payload struct{ Series []series }
+			require.NoError(t, json.Unmarshal(body, &payload))
+			api.series = append(api.series, payload.Series...)
+		case pathSketches:
+			assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+			api.sketches = append(api.sketches, test_decodeSketchPayload(t, body)...)
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+		w.WriteHeader(api.status)
+	}))
+	t.Cleanup(api.Close)
+	return api
+}
+
+// received returns then clears the series and sketches received.
+func (obj *test_api) received() (map[string]series, map[string]test_sketch) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	var seriesByName, sketchesByName = make(map[string]series), make(map[string]test_sketch)
+	for _, s := range obj.series {
+		seriesByName[s.Metric] = s
+	}
+	for _, s := range obj.sketches {
+		sketchesByName[s.Metric] = s
+	}
+	obj.series, obj.sketches = nil, nil
+	return seriesByName, sketchesByName
+}
+
+func test_pipeline(exporter *Exporter) *controller.Controller {
+	return controller.New(
+		processor.NewFactory(simple.NewWithHistogramDistribution(histogram.WithExplicitBoundaries([]float64{1, 10})), exporter),
+		controller.WithResource(resource.NewSchemaless(
+			semconv.ServiceNameKey.String("api"),
+			semconv.DeploymentEnvironmentKey.String("prod"),
+			semconv.HostNameKey.String("host"),
+		)),
+		controller.WithCollectPeriod(0),
+	)
+}
+
+func test_export(t *testing.T, exporter *Exporter, ctrl *controller.Controller) error {
+	require.NoError(t, ctrl.Collect(context.Background()))
+	return exporter.Export(context.Background(), ctrl.Resource(), ctrl)
+}
+
+func Test_Exporter_Export(t *testing.T) {
+	var api = test_newAPI(t)
+	exporter, err := New(WithAPIKey("key"), WithURL(api.URL), WithTags("team:core"))
+	require.NoError(t, err)
+
+	var (
+		ctx   = context.Background()
+		ctrl  = test_pipeline(exporter)
+		meter = ctrl.Meter("test")
+	)
+	counter, err := meter.SyncInt64().Counter("requests", instrument.WithUnit("{request}"))
+	require.NoError(t, err)
+	upDown, err := meter.SyncInt64().UpDownCounter("connections")
+	require.NoError(t, err)
+	latency, err := meter.SyncFloat64().Histogram("latency", instrument.WithUnit(unit.Milliseconds))
+	require.NoError(t, err)
+	heap, err := meter.AsyncInt64().Gauge("heap", instrument.WithUnit(unit.Bytes))
+	require.NoError(t, err)
+	require.NoError(t, meter.RegisterCallback([]instrument.Asynchronous{heap}, func(ctx context.Context) {
+		heap.Observe(ctx, 1024)
+	}))
+
+	counter.Add(ctx, 3, attribute.String("status", "ok"))
+	upDown.Add(ctx, 5)
+	for _, v := range []float64{0.5, 2, 4, 20} {
+		latency.Record(ctx, v)
+	}
+	require.NoError(t, test_export(t, exporter, ctrl))
+
+	var tags = []string{"team:core", "service:api", "env:prod"}
+	seriesByName, sketchesByName := api.received()
+	require.Len(t, seriesByName, 3)
+	var requests = seriesByName["requests"]
+	assert.Equal(t, seriesTypeCount, requests.Type)
+	assert.Equal(t, 3.0, requests.Points[0].Value)
+	assert.Equal(t, append(tags, "status:ok"), requests.Tags)
+	assert.Equal(t, "request", requests.Unit)
+	assert.Equal(t, []seriesResource{{Name: "host", Type: "host"}}, requests.Resources)
+	assert.InDelta(t, time.Now().Unix(), requests.Points[0].Timestamp, 5)
+	assert.Equal(t, seriesTypeGauge, seriesByName["connections"].Type)
+	assert.Equal(t, 5.0, seriesByName["connections"].Points[0].Value)
+	assert.Equal(t, 1024.0, seriesByName["heap"].Points[0].Value)
+	assert.Equal(t, "byte", seriesByName["heap"].Unit)
+
+	require.Len(t, sketchesByName, 1)
+	var sketch = sketchesByName["latency"]
+	assert.Equal(t, "host", sketch.Host)
+	assert.Equal(t, tags, sketch.Tags)
+	if assert.Len(t, sketch.Dogsketches, 1) {
+		var d = sketch.Dogsketches[0]
+		assert.Equal(t, int64(4), d.Cnt)
+		assert.Equal(t, 26.5, d.Sum)
+		assert.Equal(t, 26.5/4, d.Avg)
+		assert.Equal(t, 1.0, d.Min)
+		assert.Equal(t, 10.0, d.Max)
+		assert.Equal(t, []int{sketchKey(1), sketchKey(math.Sqrt(10)), sketchKey(10)}, d.K)
+		assert.Equal(t, []uint64{1, 2, 1}, d.N)
+	}
+
+	// Deltas of cumulative values
+	counter.Add(ctx, 2, attribute.String("status", "ok"))
+	latency.Record(ctx, 3)
+	require.NoError(t, test_export(t, exporter, ctrl))
+	seriesByName, sketchesByName = api.received()
+	assert.Equal(t, 2.0, seriesByName["requests"].Points[0].Value)
+	if d := sketchesByName["latency"].Dogsketches; assert.Len(t, d, 1) {
+		assert.Equal(t, int64(1), d[0].Cnt)
+		assert.Equal(t, 3.0, d[0].Sum)
+	}
+
+	// API error
+	api.status = http.StatusForbidden
+	counter.Add(ctx, 1, attribute.String("status", "ok"))
+	assert.Error(t, test_export(t, exporter, ctrl))
+}
+
+func Test_Exporter_Export_exponentialHistogram(t *testing.T) {
+	var api = test_newAPI(t)
+	exporter, err := New(WithAPIKey("key"), WithURL(api.URL), WithHostname("host"))
+	require.NoError(t, err)
+
+	var (
+		ctx   = context.Background()
+		res   = resource.NewSchemaless(semconv.ServiceNameKey.String("api"))
+		start = time.Now().Add(-time.Minute)
+		route = attribute.String("route", "/users")
+	)
+	// Values 0, 1.5 (bucket 0: (1, 2]) and 3, 3.5 (bucket 1: (2, 4])
+	var agg = test_exponential{
+		point: ExponentialHistogramPoint{ZeroCount: 1, Positive: ExponentialBuckets{Counts: []uint64{1, 2}}},
+		sum:   8,
+	}
+	require.NoError(t, exporter.Export(ctx, res, test_exponentialReader(start, start.Add(10*time.Second), agg, route)))
+
+	_, sketchesByName := api.received()
+	require.Len(t, sketchesByName, 1)
+	var sketch = sketchesByName["latency"]
+	assert.Equal(t, "host", sketch.Host)
+	assert.Equal(t, []string{"service:api", "route:/users"}, sketch.Tags)
+	if assert.Len(t, sketch.Dogsketches, 1) {
+		var d = sketch.Dogsketches[0]
+		assert.Equal(t, int64(4), d.Cnt)
+		assert.Equal(t, 8.0, d.Sum)
+		assert.Equal(t, []int{0, sketchKey(math.Sqrt2), sketchKey(2 * math.Sqrt2)}, d.K)
+		assert.Equal(t, []uint64{1, 1, 2}, d.N)
+	}
+
+	// Deltas of cumulative points, downscaled to scale -1 (bucket 0: (1, 4])
+	agg = test_exponential{
+		point: ExponentialHistogramPoint{Scale: -1, ZeroCount: 1, Positive: ExponentialBuckets{Counts: []uint64{5}}},
+		sum:   13,
+	}
+	require.NoError(t, exporter.Export(ctx, res, test_exponentialReader(start, start.Add(20*time.Second), agg, route)))
+	_, sketchesByName = api.received()
+	if d := sketchesByName["latency"].Dogsketches; assert.Len(t, d, 1) {
+		assert.Equal(t, int64(2), d[0].Cnt)
+		assert.Equal(t, 5.0, d[0].Sum)
+		assert.Equal(t, []int{sketchKey(2)}, d[0].K)
+	}
+
+	// No sketch without new value
+	require.NoError(t, exporter.Export(ctx, res, test_exponentialReader(start, start.Add(30*time.Second), agg, route)))
+	_, sketchesByName = api.received()
+	assert.Empty(t, sketchesByName)
+}
+
+func Test_Exporter_Export_hostname(t *testing.T) {
+	var api = test_newAPI(t)
+	exporter, err := New(WithAPIKey("key"), WithURL(api.URL), WithHostname("custom"))
+	require.NoError(t, err)
+
+	var ctrl = test_pipeline(exporter)
+	gauge, err := ctrl.Meter("test").AsyncFloat64().Gauge("temperature")
+	require.NoError(t, err)
+	require.NoError(t, ctrl.Meter("test").RegisterCallback([]instrument.Asynchronous{gauge}, func(ctx context.Context) {
+		gauge.Observe(ctx, 21.5)
+	}))
+	require.NoError(t, test_export(t, exporter, ctrl))
+
+	seriesByName, sketchesByName := api.received()
+	assert.Empty(t, sketchesByName)
+	assert.Equal(t, []seriesResource{{Name: "custom", Type: "host"}}, seriesByName["temperature"].Resources)
+}
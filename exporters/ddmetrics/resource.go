@@ -0,0 +1,83 @@
+package ddmetrics
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+// Ref https://docs.datadoghq.com/opentelemetry/schema_semantics/hostname/
+// Ref https://github.com/DataDog/opentelemetry-mapping-go/blob/main/pkg/otlp/attributes/source/source_provider.go
+
+// hostNameKey is the resource attribute overriding the host of all telemetry.
+const hostNameKey = attribute.Key("datadog.host.name")
+
+// resourceTags maps resource attributes to Datadog tags.
+// Ref https://docs.datadoghq.com/opentelemetry/schema_semantics/semantic_mapping/
+var resourceTags = []struct {
+	key attribute.Key
+	tag string
+}{
+	{semconv.ServiceNameKey, "service"},
+	{semconv.DeploymentEnvironmentKey, "env"},
+	{semconv.ServiceVersionKey, "version"},
+	{semconv.CloudProviderKey, "cloud_provider"},
+	{semconv.CloudRegionKey, "region"},
+	{semconv.CloudAvailabilityZoneKey, "zone"},
+	{semconv.ContainerIDKey, "container_id"},
+	{semconv.ContainerNameKey, "container_name"},
+	{semconv.ContainerImageNameKey, "image_name"},
+	{semconv.ContainerImageTagKey, "image_tag"},
+	{semconv.K8SClusterNameKey, "kube_cluster_name"},
+	{semconv.K8SNamespaceNameKey, "kube_namespace"},
+	{semconv.K8SPodNameKey, "pod_name"},
+	{semconv.K8SDeploymentNameKey, "kube_deployment"},
+	{semconv.AWSECSTaskFamilyKey, "task_family"},
+}
+
+// resourceHost returns the host of the resource attributes: datadog.host.name,
+// the instance ID on AWS EC2, the node name on Kubernetes or host.name.
+func resourceHost(res *resource.Resource) string {
+	var set = res.Set()
+	if value := setValue(set, hostNameKey); value != "" {
+		return value
+	}
+	if setValue(set, semconv.CloudPlatformKey) == semconv.CloudPlatformAWSEC2.Value.AsString() {
+		if value := setValue(set, semconv.HostIDKey); value != "" {
+			return value
+		}
+	}
+	if value := setValue(set, semconv.K8SNodeNameKey); value != "" {
+		if cluster := setValue(set, semconv.K8SClusterNameKey); cluster != "" {
+			return value + "-" + cluster
+		}
+		return value
+	}
+	return setValue(set, semconv.HostNameKey)
+}
+
+// resourceTagsOf returns the Datadog tags of the resource attributes.
+func resourceTagsOf(res *resource.Resource) []string {
+	var (
+		set  = res.Set()
+		tags []string
+	)
+	for _, v := range resourceTags {
+		if value := setValue(set, v.key); value != "" {
+			tags = append(tags, v.tag+":"+value)
+		}
+	}
+	return tags
+}
+
+// setValue returns the value of key, empty if not set.
+func setValue(set *attribute.Set, key attribute.Key) string {
+	if set.Len() == 0 {
+		// Value panics on the set of an empty resource
+		return ""
+	}
+	if value, ok := set.Value(key); ok {
+		return value.Emit()
+	}
+	return ""
+}
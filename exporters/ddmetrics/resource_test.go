@@ -0,0 +1,31 @@
+package ddmetrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+func Test_resourceHost(t *testing.T) {
+	var host = semconv.HostNameKey.String("host")
+	assert.Equal(t, "", resourceHost(nil))
+	assert.Equal(t, "host", resourceHost(resource.NewSchemaless(host)))
+	assert.Equal(t, "custom", resourceHost(resource.NewSchemaless(host, hostNameKey.String("custom"))))
+	assert.Equal(t, "i-123", resourceHost(resource.NewSchemaless(host, semconv.CloudPlatformAWSEC2, semconv.HostIDKey.String("i-123"))))
+	assert.Equal(t, "host", resourceHost(resource.NewSchemaless(host, semconv.CloudPlatformGCPComputeEngine, semconv.HostIDKey.String("123"))))
+	assert.Equal(t, "node-prod", resourceHost(resource.NewSchemaless(host, semconv.K8SNodeNameKey.String("node"), semconv.K8SClusterNameKey.String("prod"))))
+	assert.Equal(t, "node", resourceHost(resource.NewSchemaless(semconv.K8SNodeNameKey.String("node"))))
+}
+
+func Test_resourceTagsOf(t *testing.T) {
+	assert.Empty(t, resourceTagsOf(nil))
+	assert.Equal(t, []string{"service:api", "env:prod", "region:eu-west-1", "kube_namespace:default"}, resourceTagsOf(resource.NewSchemaless(
+		semconv.K8SNamespaceNameKey.String("default"),
+		semconv.ServiceNameKey.String("api"),
+		semconv.CloudRegionKey.String("eu-west-1"),
+		semconv.DeploymentEnvironmentKey.String("prod"),
+		semconv.HostNameKey.String("host"),
+	)))
+}
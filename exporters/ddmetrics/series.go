@@ -0,0 +1,92 @@
+package ddmetrics
+
+import (
+	"encoding/json"
+)
+
+// Ref https://docs.datadoghq.com/api/latest/metrics/#submit-metrics
+
+// Series types
+const (
+	seriesTypeCount = 1
+	seriesTypeGauge = 3
+)
+
+// series is a metric of the v2 series API.
+type series struct {
+	Metric    string           `json:"metric"`
+	Type      int              `json:"type"`
+	Interval  int64            `json:"interval,omitempty"`
+	Points    []point          `json:"points"`
+	Resources []seriesResource `json:"resources,omitempty"`
+	Tags      []string         `json:"tags,omitempty"`
+	Unit      string           `json:"unit,omitempty"`
+}
+
+type point struct {
+	Timestamp int64   `json:"timestamp"`
+	Value     float64 `json:"value"`
+}
+
+type seriesResource struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// splitPayloads returns payloads of encoded items, each one being smaller than
+// maxSize unless an item alone is larger.
+func splitPayloads(items [][]byte, maxSize int, encode func([][]byte) []byte) [][]byte {
+	var (
+		payloads [][]byte
+		start    int
+		size     int
+	)
+	for i, item := range items {
+		if i > start && size+len(item) > maxSize {
+			payloads = append(payloads, encode(items[start:i]))
+			start, size = i, 0
+		}
+		size += len(item) + 1
+	}
+	if start < len(items) {
+		payloads = append(payloads, encode(items[start:]))
+	}
+	return payloads
+}
+
+// encodeSeries returns the JSON payloads of the series.
+func encodeSeries(list []series, maxSize int) ([][]byte, error) {
+	var items = make([][]byte, 0, len(list))
+	for i := range list {
+		item, err := json.Marshal(&list[i])
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return splitPayloads(items, maxSize, func(items [][]byte) []byte {
+		var b = append([]byte(nil), `{"series":[`...)
+		for i, item := range items {
+			if i > 0 {
+				b = append(b, ',')
+			}
+			b = append(b, item...)
+		}
+		return append(b, "]}"...)
+	}), nil
+}
+
+// encodeSketches returns the protobuf SketchPayload payloads of the sketches.
+func encodeSketches(list []sketchSeries, maxSize int) [][]byte {
+	var items = make([][]byte, 0, len(list))
+	for i := range list {
+		items = append(items, list[i].appendProto(nil))
+	}
+	return splitPayloads(items, maxSize, func(items [][]byte) []byte {
+		var b []byte
+		for _, item := range items {
+			b = append(b, item...)
+		}
+		return b
+	})
+}
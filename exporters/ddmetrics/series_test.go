@@ -0,0 +1,55 @@
+package ddmetrics
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_splitPayloads(t *testing.T) {
+	var join = func(items [][]byte) []byte { return bytes.TrimSpace(bytes.Join(items, nil)) }
+	var items = [][]byte{[]byte("aaa "), []byte("bbb "), []byte("cccccccccc "), []byte("d ")}
+	assert.Equal(t, [][]byte{[]byte("aaa bbb"), []byte("cccccccccc"), []byte("d")}, splitPayloads(items, 10, join))
+	assert.Empty(t, splitPayloads(nil, 10, join))
+}
+
+func Test_encodeSeries(t *testing.T) {
+	var list = []series{
+		{Metric: "requests", Type: seriesTypeCount, Interval: 10, Points: []point{{Timestamp: 1000, Value: 3}}, Tags: []string{"env:prod"}},
+		{Metric: "heap", Type: seriesTypeGauge, Points: []point{{Timestamp: 1000, Value: 1024}}, Unit: "byte",
+			Resources: []seriesResource{{Name: "host", Type: "host"}}},
+	}
+	payloads, err := encodeSeries(list, maxPayloadSize)
+	require.NoError(t, err)
+	require.Len(t, payloads, 1)
+	assert.JSONEq(t, `{"series":[
+		{"metric":"requests","type":1,"interval":10,"points":[{"timestamp":1000,"value":3}],"tags":["env:prod"]},
+		{"metric":"heap","type":3,"points":[{"timestamp":1000,"value":1024}],"resources":[{"name":"host","type":"host"}],"unit":"byte"}
+	]}`, string(payloads[0]))
+
+	// Split, each payload being valid
+	payloads, err = encodeSeries(list, 10)
+	require.NoError(t, err)
+	require.Len(t, payloads, 2)
+	for _, payload := range payloads {
+		var decoded struct{ Series []series }
+		require.NoError(t, json.Unmarshal(payload, &decoded))
+		assert.Len(t, decoded.Series, 1)
+	}
+}
+
+func Test_encodeSketches(t *testing.T) {
+	var sketch = newAgentSketch()
+	sketch.insert(1, 1)
+	var list = []sketchSeries{{metric: "a", sketch: sketch}, {metric: "b", sketch: sketch}}
+
+	payloads := encodeSketches(list, maxPayloadSize)
+	if assert.Len(t, payloads, 1) {
+		assert.Len(t, test_decodeSketchPayload(t, payloads[0]), 2)
+	}
+	assert.Len(t, encodeSketches(list, 10), 2)
+	assert.Empty(t, encodeSketches(nil, 10))
+}
@@ -0,0 +1,181 @@
+package ddmetrics
+
+import (
+	"math"
+	"sort"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/protowire"
+)
+
+// The sketches endpoint expects the sketches of the Datadog agent, which index
+// bins with a logarithmic mapping of 1/128 relative accuracy.
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/quantile/config.go
+const (
+	sketchRelativeAccuracy = 1.0 / 128
+	sketchMinValue         = 1e-9
+	sketchBinLimit         = 4096
+	sketchMaxKey           = math.MaxInt16
+)
+
+var (
+	sketchGamma   = 1 + 2*sketchRelativeAccuracy
+	sketchLnGamma = math.Log(sketchGamma)
+	sketchBias    = 1 - int(math.Floor(math.Log(sketchMinValue)/sketchLnGamma))
+)
+
+// sketchKey returns the key of the bin of a value, 0 for zero and values
+// smaller than sketchMinValue, negative for negative values.
+func sketchKey(value float64) int {
+	switch {
+	case value < 0:
+		return -sketchKey(-value)
+	case value < sketchMinValue:
+		return 0
+	}
+	var key = int(math.Ceil(math.Log(value)/sketchLnGamma)) + sketchBias
+	switch {
+	case key > sketchMaxKey:
+		return sketchMaxKey
+	case key < 1:
+		return 1
+	}
+	return key
+}
+
+// sketchValue returns the value of the bin of a key, within the relative
+// accuracy of all values of the bin.
+func sketchValue(key int) float64 {
+	switch {
+	case key < 0:
+		return -sketchValue(-key)
+	case key == 0:
+		return 0
+	}
+	return math.Pow(sketchGamma, float64(key-sketchBias)) * (2 / (1 + sketchGamma))
+}
+
+// agentSketch is a sketch in the format of the Datadog agent.
+type agentSketch struct {
+	bins  map[int]uint64
+	count uint64
+	sum   float64
+	min   float64
+	max   float64
+}
+
+func newAgentSketch() *agentSketch {
+	return &agentSketch{bins: make(map[int]uint64), min: math.Inf(1), max: math.Inf(-1)}
+}
+
+// insert adds count occurrences of value, its sum being set apart.
+func (obj *agentSketch) insert(value float64, count uint64) {
+	if count == 0 {
+		return
+	}
+	obj.bins[sketchKey(value)] += count
+	obj.count += count
+	obj.min = math.Min(obj.min, value)
+	obj.max = math.Max(obj.max, value)
+}
+
+// keys returns the sorted keys and their counts, the lowest bins being
+// collapsed above the bin limit.
+func (obj *agentSketch) keys() ([]int, []uint64) {
+	var keys = make([]int, 0, len(obj.bins))
+	for k := range obj.bins {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	var counts = make([]uint64, len(keys))
+	for i, k := range keys {
+		counts[i] = obj.bins[k]
+	}
+	if excess := len(keys) - sketchBinLimit; excess > 0 {
+		for i := 0; i < excess; i++ {
+			counts[excess] += counts[i]
+		}
+		keys, counts = keys[excess:], counts[excess:]
+	}
+	return keys, counts
+}
+
+// _____________________ Protobuf _____________________
+
+// Ref https://github.com/DataDog/agent-payload/blob/master/proto/metrics/agent_payload.proto (SketchPayload)
+const (
+	fieldPayloadSketches = 1
+
+	fieldSketchMetric      = 1
+	fieldSketchHost        = 2
+	fieldSketchTags        = 4
+	fieldSketchDogsketches = 7
+
+	fieldDogsketchTs  = 1
+	fieldDogsketchCnt = 2
+	fieldDogsketchMin = 3
+	fieldDogsketchMax = 4
+	fieldDogsketchAvg = 5
+	fieldDogsketchSum = 6
+	fieldDogsketchK   = 7
+	fieldDogsketchN   = 8
+)
+
+// sketchSeries is the sketch of a metric for an interval.
+type sketchSeries struct {
+	metric    string
+	host      string
+	tags      []string
+	timestamp int64
+	sketch    *agentSketch
+}
+
+// appendProto appends the Sketch message of the series, as sketches field of
+// a SketchPayload.
+func (obj *sketchSeries) appendProto(b []byte) []byte {
+	var sketch []byte
+	sketch = appendString(sketch, fieldSketchMetric, obj.metric)
+	if obj.host != "" {
+		sketch = appendString(sketch, fieldSketchHost, obj.host)
+	}
+	for _, tag := range obj.tags {
+		sketch = appendString(sketch, fieldSketchTags, tag)
+	}
+	sketch = appendMessage(sketch, fieldSketchDogsketches, obj.appendDogsketch(nil))
+	return appendMessage(b, fieldPayloadSketches, sketch)
+}
+
+func (obj *sketchSeries) appendDogsketch(b []byte) []byte {
+	var s = obj.sketch
+	b = protowire.AppendVarint(protowire.AppendTag(b, fieldDogsketchTs, protowire.VarintType), uint64(obj.timestamp))
+	b = protowire.AppendVarint(protowire.AppendTag(b, fieldDogsketchCnt, protowire.VarintType), s.count)
+	b = protowire.AppendDouble(b, fieldDogsketchMin, s.min)
+	b = protowire.AppendDouble(b, fieldDogsketchMax, s.max)
+	b = protowire.AppendDouble(b, fieldDogsketchAvg, s.sum/float64(s.count))
+	b = protowire.AppendDouble(b, fieldDogsketchSum, s.sum)
+
+	// Packed repeated sint32 and uint32, counts above uint32 being split
+	var packedK, packedN []byte
+	var keys, counts = s.keys()
+	for i, k := range keys {
+		for n := counts[i]; n > 0; {
+			var c = n
+			if c > math.MaxUint32 {
+				c = math.MaxUint32
+			}
+			packedK = protowire.AppendVarint(packedK, protowire.EncodeZigZag(int64(k)))
+			packedN = protowire.AppendVarint(packedN, c)
+			n -= c
+		}
+	}
+	b = appendMessage(b, fieldDogsketchK, packedK)
+	return appendMessage(b, fieldDogsketchN, packedN)
+}
+
+func appendString(b []byte, num int, value string) []byte {
+	return protowire.AppendString(protowire.AppendTag(b, num, protowire.BytesType), value)
+}
+
+func appendMessage(b []byte, num int, message []byte) []byte {
+	return protowire.AppendBytes(protowire.AppendTag(b, num, protowire.BytesType), message)
+}
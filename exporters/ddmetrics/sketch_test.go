@@ -0,0 +1,151 @@
+package ddmetrics
+
+import (
+	"math"
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/protowire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_sketchKey(t *testing.T) {
+	assert.Equal(t, 0, sketchKey(0))
+	assert.Equal(t, 0, sketchKey(sketchMinValue/2))
+	assert.Positive(t, sketchKey(sketchMinValue))
+	assert.Equal(t, sketchMaxKey, sketchKey(math.MaxFloat64))
+	assert.Equal(t, -sketchKey(2), sketchKey(-2))
+
+	// Values within the relative accuracy of their bin value
+	for _, value := range []float64{1e-6, 0.001, 0.5, 1, 3, 42, 1e3, 1e9, -7.5} {
+		var approx = sketchValue(sketchKey(value))
+		assert.InEpsilon(t, value, approx, sketchRelativeAccuracy, value)
+	}
+	assert.Equal(t, 0.0, sketchValue(0))
+}
+
+func Test_agentSketch(t *testing.T) {
+	var sketch = newAgentSketch()
+	sketch.insert(1, 2)
+	sketch.insert(-3, 1)
+	sketch.insert(0.999, 3)
+	sketch.insert(0, 1)
+	sketch.insert(5, 0)
+	assert.Equal(t, uint64(7), sketch.count)
+	assert.Equal(t, -3.0, sketch.min)
+	assert.Equal(t, 1.0, sketch.max)
+
+	keys, counts := sketch.keys()
+	assert.Equal(t, []int{sketchKey(-3), 0, sketchKey(1)}, keys)
+	assert.Equal(t, []uint64{1, 1, 5}, counts)
+
+	// Lowest bins collapsed above the limit
+	sketch = newAgentSketch()
+	for i := 0; i < sketchBinLimit+2; i++ {
+		sketch.insert(math.Pow(sketchGamma, float64(i)+0.5), 1)
+	}
+	keys, counts = sketch.keys()
+	assert.Len(t, keys, sketchBinLimit)
+	assert.Equal(t, uint64(3), counts[0])
+}
+
+// test_dogsketch is a decoded Dogsketch message.
+type test_dogsketch struct {
+	Ts, Cnt            int64
+	Min, Max, Avg, Sum float64
+	K                  []int
+	N                  []uint64
+}
+
+// test_sketch is a decoded Sketch message.
+type test_sketch struct {
+	Metric, Host string
+	Tags         []string
+	Dogsketches  []test_dogsketch
+}
+
+// test_decodeSketchPayload decodes a SketchPayload message.
+func test_decodeSketchPayload(t *testing.T, b []byte) []test_sketch {
+	var sketches []test_sketch
+	test_consumeFields(t, b, func(num int, value uint64, data []byte) {
+		require.Equal(t, fieldPayloadSketches, num)
+		var sketch test_sketch
+		test_consumeFields(t, data, func(num int, value uint64, data []byte) {
+			switch num {
+			case fieldSketchMetric:
+				sketch.Metric = string(data)
+			case fieldSketchHost:
+				sketch.Host = string(data)
+			case fieldSketchTags:
+				sketch.Tags = append(sketch.Tags, string(data))
+			case fieldSketchDogsketches:
+				sketch.Dogsketches = append(sketch.Dogsketches, test_decodeDogsketch(t, data))
+			}
+		})
+		sketches = append(sketches, sketch)
+	})
+	return sketches
+}
+
+func test_decodeDogsketch(t *testing.T, b []byte) test_dogsketch {
+	var d test_dogsketch
+	test_consumeFields(t, b, func(num int, value uint64, data []byte) {
+		switch num {
+		case fieldDogsketchTs:
+			d.Ts = int64(value)
+		case fieldDogsketchCnt:
+			d.Cnt = int64(value)
+		case fieldDogsketchMin:
+			d.Min = math.Float64frombits(value)
+		case fieldDogsketchMax:
+			d.Max = math.Float64frombits(value)
+		case fieldDogsketchAvg:
+			d.Avg = math.Float64frombits(value)
+		case fieldDogsketchSum:
+			d.Sum = math.Float64frombits(value)
+		case fieldDogsketchK, fieldDogsketchN:
+			for len(data) > 0 {
+				v, n, err := protowire.ConsumeVarint(data)
+				require.NoError(t, err)
+				data = data[n:]
+				if num == fieldDogsketchK {
+					d.K = append(d.K, int(protowire.DecodeZigZag(v)))
+				} else {
+					d.N = append(d.N, v)
+				}
+			}
+		}
+	})
+	return d
+}
+
+func test_consumeFields(t *testing.T, b []byte, fn func(num int, value uint64, data []byte)) {
+	for len(b) > 0 {
+		num, typ, n, err := protowire.ConsumeTag(b)
+		require.NoError(t, err)
+		b = b[n:]
+		value, data, n, err := protowire.ConsumeFieldValue(b, typ)
+		require.NoError(t, err)
+		b = b[n:]
+		fn(num, value, data)
+	}
+}
+
+func Test_sketchSeries_appendProto(t *testing.T) {
+	var sketch = newAgentSketch()
+	sketch.insert(2, 3)
+	sketch.insert(8, math.MaxUint32+1)
+	sketch.sum = 100
+
+	var b = (&sketchSeries{metric: "latency", host: "host", tags: []string{"env:prod"}, timestamp: 1000, sketch: sketch}).appendProto(nil)
+	assert.Equal(t, []test_sketch{{
+		Metric: "latency",
+		Host:   "host",
+		Tags:   []string{"env:prod"},
+		Dogsketches: []test_dogsketch{{
+			Ts: 1000, Cnt: math.MaxUint32 + 4, Min: 2, Max: 8, Avg: 100 / float64(math.MaxUint32+4), Sum: 100,
+			K: []int{sketchKey(2), sketchKey(8), sketchKey(8)},
+			N: []uint64{3, math.MaxUint32, 1},
+		}},
+	}}, test_decodeSketchPayload(t, b))
+}
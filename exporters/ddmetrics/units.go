@@ -0,0 +1,52 @@
+package ddmetrics
+
+import "strings"
+
+// Ref https://unitsofmeasure.org/ucum (OpenTelemetry units)
+// Ref https://docs.datadoghq.com/metrics/units/ (Datadog units)
+
+// units maps UCUM units to Datadog units.
+var units = map[string]string{
+	// Bytes
+	"bit":  "bit",
+	"By":   "byte",
+	"KBy":  "kilobyte",
+	"MBy":  "megabyte",
+	"GBy":  "gigabyte",
+	"TBy":  "terabyte",
+	"KiBy": "kibibyte",
+	"MiBy": "mebibyte",
+	"GiBy": "gibibyte",
+	"TiBy": "tebibyte",
+
+	// Time
+	"ns":  "nanosecond",
+	"us":  "microsecond",
+	"ms":  "millisecond",
+	"s":   "second",
+	"min": "minute",
+	"h":   "hour",
+	"d":   "day",
+	"wk":  "week",
+
+	// Others
+	"%":   "percent",
+	"Hz":  "hertz",
+	"Cel": "degree celsius",
+	"W":   "watt",
+	"J":   "joule",
+	"V":   "volt",
+	"A":   "ampere",
+}
+
+// datadogUnit returns the Datadog unit of a UCUM unit, empty if unknown or
+// dimensionless ("1"). Annotations (ex: "{request}") are used as units.
+func datadogUnit(unit string) string {
+	if value, ok := units[unit]; ok {
+		return value
+	}
+	if strings.HasPrefix(unit, "{") && strings.HasSuffix(unit, "}") && !strings.ContainsAny(unit, "/ ") {
+		return strings.TrimSuffix(strings.TrimPrefix(unit, "{"), "}")
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+package ddmetrics
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_datadogUnit(t *testing.T) {
+	for unit, expected := range map[string]string{
+		"By":        "byte",
+		"MiBy":      "mebibyte",
+		"ms":        "millisecond",
+		"s":         "second",
+		"%":         "percent",
+		"1":         "",
+		"":          "",
+		"{request}": "request",
+		"{req}/s":   "",
+		"By/s":      "",
+	} {
+		assert.Equal(t, expected, datadogUnit(unit), unit)
+	}
+}
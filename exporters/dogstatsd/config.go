@@ -0,0 +1,212 @@
+package dogstatsd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// _____________________ With option functions _____________________
+
+// WithAddress sets the address of the DogStatsD server: "udp://host:port",
+// "unix:///path/to/dsd.socket" or "host:port".
+// It defaults to DD_DOGSTATSD_URL environment variable, or DD_AGENT_HOST and
+// DD_DOGSTATSD_PORT, or DefaultAddress.
+func WithAddress(value string) configFn {
+	return func(conf *config) {
+		conf.address = value
+	}
+}
+
+// WithNamespace sets the prefix of all metric names (ex: "myapp.").
+func WithNamespace(value string) configFn {
+	return func(conf *config) {
+		conf.namespace = value
+	}
+}
+
+// WithTags sets the tags added to all metrics (ex: "team:core").
+func WithTags(tags ...string) configFn {
+	return func(conf *config) {
+		conf.tags = tags
+	}
+}
+
+// WithEntityID sets the entity ID used by the agent for origin detection
+// (container tags).
+// It defaults to DD_ENTITY_ID environment variable.
+func WithEntityID(value string) configFn {
+	return func(conf *config) {
+		conf.entityID = value
+	}
+}
+
+// WithMaxPacketSize sets the maximum size of the packets sent, metrics being
+// buffered up to this size.
+// It defaults to DefaultUDPPacketSize for UDP and DefaultUDSPacketSize for UDS.
+func WithMaxPacketSize(value int) configFn {
+	return func(conf *config) {
+		conf.maxPacketSize = value
+	}
+}
+
+// WithHistogramType sets the DogStatsD type of histograms: TypeDistribution,
+// aggregated by Datadog across hosts, or TypeHistogram, aggregated by the agent.
+// It defaults to TypeDistribution.
+func WithHistogramType(value string) configFn {
+	return func(conf *config) {
+		conf.histogramType = value
+	}
+}
+
+// _____________________ Definition _____________________
+
+type configFn func(*config)
+
+var (
+	ErrInvalidAddress       = errors.New("invalid DogStatsD address")
+	ErrInvalidPacketSize    = errors.New("invalid packet size")
+	ErrInvalidHistogramType = errors.New("invalid histogram type")
+)
+
+// Ref https://docs.datadoghq.com/developers/dogstatsd/
+// Ref https://github.com/DataDog/datadog-go/blob/master/statsd/options.go
+
+const (
+	envDogStatsDURL  = "DD_DOGSTATSD_URL"
+	envAgentHost     = "DD_AGENT_HOST"
+	envDogStatsDPort = "DD_DOGSTATSD_PORT"
+	envEntityID      = "DD_ENTITY_ID"
+)
+
+const (
+	// DefaultAddress specifies the address of the DogStatsD server.
+	DefaultAddress = "localhost:8125"
+
+	// DefaultUDPPacketSize specifies the maximum size of UDP packets, fitting
+	// an Ethernet MTU (1500) minus IP and UDP headers.
+	DefaultUDPPacketSize = 1432
+
+	// DefaultUDSPacketSize specifies the maximum size of Unix domain socket
+	// datagrams.
+	DefaultUDSPacketSize = 8192
+)
+
+const (
+	schemeUDP  = "udp"
+	schemeUnix = "unix"
+)
+
+// _____________________ Configuration _____________________
+
+func newConfig(cfg ...configFn) (*config, error) {
+	var conf = &config{}
+
+	// Apply configurations
+	for _, v := range cfg {
+		if v != nil {
+			v(conf)
+		}
+	}
+
+	// Apply default value on empty
+	conf.applyDefault()
+
+	// Check configuration is valid
+	if err := conf.parse(); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+type config struct {
+	address       string
+	namespace     string
+	tags          []string
+	entityID      string
+	maxPacketSize int
+	histogramType string
+
+	// Parsed values
+	network string // "udp" or "unixgram"
+	addr    string // host:port or socket path
+}
+
+func (obj *config) applyDefault() {
+	// Set default address
+	if obj.address == "" {
+		obj.address = os.Getenv(envDogStatsDURL)
+	}
+	if obj.address == "" {
+		if host, port := os.Getenv(envAgentHost), os.Getenv(envDogStatsDPort); host != "" || port != "" {
+			if host == "" {
+				host = "localhost"
+			}
+			if port == "" {
+				port = "8125"
+			}
+			obj.address = net.JoinHostPort(host, port)
+		}
+	}
+	if obj.address == "" {
+		obj.address = DefaultAddress
+	}
+
+	if obj.entityID == "" {
+		obj.entityID = os.Getenv(envEntityID)
+	}
+	if obj.histogramType == "" {
+		obj.histogramType = TypeDistribution
+	}
+}
+
+func (obj *config) parse() error {
+	if err := obj.parseAddress(); err != nil {
+		return err
+	}
+
+	if obj.maxPacketSize == 0 {
+		obj.maxPacketSize = DefaultUDPPacketSize
+		if obj.network == "unixgram" {
+			obj.maxPacketSize = DefaultUDSPacketSize
+		}
+	}
+	if obj.maxPacketSize < 0 {
+		return ErrInvalidPacketSize
+	}
+
+	if obj.histogramType != TypeDistribution && obj.histogramType != TypeHistogram {
+		return fmt.Errorf("%w: %q", ErrInvalidHistogramType, obj.histogramType)
+	}
+	return nil
+}
+
+func (obj *config) parseAddress() error {
+	if !strings.Contains(obj.address, "://") {
+		obj.address = schemeUDP + "://" + obj.address
+	}
+	address, err := url.Parse(obj.address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAddress, err)
+	}
+
+	switch address.Scheme {
+	case schemeUDP:
+		if address.Hostname() == "" || address.Port() == "" {
+			return fmt.Errorf("%w: %q, host and port expected", ErrInvalidAddress, obj.address)
+		}
+		obj.network, obj.addr = "udp", address.Host
+	case schemeUnix:
+		if address.Path == "" {
+			return fmt.Errorf("%w: %q, socket path expected", ErrInvalidAddress, obj.address)
+		}
+		obj.network, obj.addr = "unixgram", address.Path
+	default:
+		return fmt.Errorf("%w: unsupported scheme %q", ErrInvalidAddress, address.Scheme)
+	}
+	return nil
+}
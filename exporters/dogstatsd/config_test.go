@@ -0,0 +1,72 @@
+package dogstatsd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newConfig(t *testing.T) {
+	for _, key := range []string{envDogStatsDURL, envAgentHost, envDogStatsDPort, envEntityID} {
+		setenv(t, key, "")
+	}
+
+	// Defaults
+	conf, err := newConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "udp", conf.network)
+	assert.Equal(t, "localhost:8125", conf.addr)
+	assert.Equal(t, DefaultUDPPacketSize, conf.maxPacketSize)
+	assert.Equal(t, TypeDistribution, conf.histogramType)
+	assert.Empty(t, conf.entityID)
+
+	// Environment
+	setenv(t, envAgentHost, "agent")
+	conf, err = newConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "agent:8125", conf.addr)
+
+	setenv(t, envDogStatsDURL, "unix:///var/run/datadog/dsd.socket")
+	setenv(t, envEntityID, "pod-uid")
+	conf, err = newConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "unixgram", conf.network)
+	assert.Equal(t, "/var/run/datadog/dsd.socket", conf.addr)
+	assert.Equal(t, DefaultUDSPacketSize, conf.maxPacketSize)
+	assert.Equal(t, "pod-uid", conf.entityID)
+
+	// Options over environment
+	conf, err = newConfig(WithAddress("udp://127.0.0.1:9125"), WithEntityID("other"), WithMaxPacketSize(512), WithHistogramType(TypeHistogram))
+	require.NoError(t, err)
+	assert.Equal(t, "udp", conf.network)
+	assert.Equal(t, "127.0.0.1:9125", conf.addr)
+	assert.Equal(t, 512, conf.maxPacketSize)
+	assert.Equal(t, TypeHistogram, conf.histogramType)
+	assert.Equal(t, "other", conf.entityID)
+
+	// Invalid
+	for _, address := range []string{"tcp://localhost:8125", "localhost", "unix://", "udp://:8125", "udp://[::1"} {
+		_, err = newConfig(WithAddress(address))
+		assert.ErrorIs(t, err, ErrInvalidAddress, address)
+	}
+	_, err = newConfig(WithMaxPacketSize(-1))
+	assert.ErrorIs(t, err, ErrInvalidPacketSize)
+	_, err = newConfig(WithHistogramType(TypeGauge))
+	assert.ErrorIs(t, err, ErrInvalidHistogramType)
+}
+
+func setenv(t *testing.T, key, value string) {
+	old, exists := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if exists {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
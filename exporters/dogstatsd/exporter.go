@@ -0,0 +1,200 @@
+// Package dogstatsd exports OpenTelemetry metrics to the DogStatsD server of
+// the Datadog agent.
+package dogstatsd
+
+import (
+	"context"
+	"sync"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/ddsketch"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/export"
+	"go.opentelemetry.io/otel/sdk/metric/export/aggregation"
+	"go.opentelemetry.io/otel/sdk/metric/sdkapi"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+// Exporter exports OpenTelemetry metrics to DogStatsD:
+//   - counters as counts (c) of the interval delta
+//   - up down counters and gauges as gauges (g)
+//   - histograms as distributions (d) or histograms (h)
+//
+// Metrics of identical contexts (name, type and tags) are aggregated, then
+// buffered into packets of the maximum packet size.
+type Exporter struct {
+	conf *config
+
+	mu     sync.Mutex
+	writer *packetWriter
+}
+
+var _ export.Exporter = (*Exporter)(nil)
+
+// New returns a new exporter sending metrics to DogStatsD.
+// To use the defaults, call with nothing.
+func New(cfg ...configFn) (*Exporter, error) {
+	conf, err := newConfig(cfg...)
+	if err != nil {
+		return nil, err
+	}
+	return &Exporter{conf: conf, writer: newPacketWriter(conf)}, nil
+}
+
+// TemporalityFor returns the delta temporality, except for up down counters
+// exported as gauges of the cumulative value.
+func (obj *Exporter) TemporalityFor(desc *sdkapi.Descriptor, kind aggregation.Kind) aggregation.Temporality {
+	if kind == aggregation.SumKind && !desc.InstrumentKind().Monotonic() {
+		return aggregation.CumulativeTemporality
+	}
+	return aggregation.DeltaTemporality
+}
+
+// Export sends the metrics of the reader to DogStatsD. Metrics failing to be
+// sent are dropped, the first error being returned.
+func (obj *Exporter) Export(ctx context.Context, res *resource.Resource, reader export.InstrumentationLibraryReader) error {
+	var (
+		agg        = newAggregator()
+		commonTags = obj.commonTags(res)
+	)
+	err := reader.ForEach(func(_ instrumentation.Library, r export.Reader) error {
+		return r.ForEach(obj, func(record export.Record) error {
+			return obj.aggregate(agg, commonTags, record)
+		})
+	})
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return obj.send(agg)
+}
+
+// Gauge is a gauge sent out of the OpenTelemetry pipeline.
+type Gauge struct {
+	Name  string
+	Value float64
+}
+
+// SendGauges sends gauges out of the OpenTelemetry pipeline, with the tags of
+// all metrics and the given tags. Gauges failing to be sent are dropped, the
+// first error being returned.
+func (obj *Exporter) SendGauges(res *resource.Resource, tags []string, gauges []Gauge) error {
+	var (
+		agg     = newAggregator()
+		allTags = obj.commonTags(res)
+	)
+	for _, tag := range tags {
+		allTags = append(allTags, tagReplacer.Replace(tag))
+	}
+	for _, v := range gauges {
+		agg.gauge(obj.conf.namespace+v.Name, allTags, v.Value)
+	}
+	return obj.send(agg)
+}
+
+// send writes the metrics of the aggregator, returning the first error.
+func (obj *Exporter) send(agg *aggregator) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	var firstErr error
+	_ = agg.forEachLine(obj.conf.maxPacketSize, func(line []byte) error {
+		if err := obj.writer.write(line); err != nil && firstErr == nil {
+			firstErr = err
+		}
+		return nil
+	})
+	if err := obj.writer.flush(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
+
+// Close closes the connection to DogStatsD.
+func (obj *Exporter) Close() error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	return obj.writer.close()
+}
+
+// unifiedTags are the unified service tags of resource attributes.
+var unifiedTags = []struct {
+	tag string
+	key attribute.Key
+}{
+	{"service", semconv.ServiceNameKey},
+	{"env", semconv.DeploymentEnvironmentKey},
+	{"version", semconv.ServiceVersionKey},
+}
+
+// commonTags returns the tags of all metrics: configured tags, unified
+// service tags of the resource and entity ID.
+func (obj *Exporter) commonTags(res *resource.Resource) []string {
+	var tags = make([]string, 0, len(obj.conf.tags)+4)
+	for _, tag := range obj.conf.tags {
+		tags = append(tags, tagReplacer.Replace(tag))
+	}
+	// Value panics on the set of an empty resource
+	if set := res.Set(); set.Len() > 0 {
+		for _, v := range unifiedTags {
+			if value, ok := set.Value(v.key); ok && value.Emit() != "" {
+				tags = append(tags, formatTag(v.tag, value.Emit()))
+			}
+		}
+	}
+	if obj.conf.entityID != "" {
+		tags = append(tags, formatTag(entityIDTag, obj.conf.entityID))
+	}
+	return tags
+}
+
+// aggregate adds the record to the aggregator.
+func (obj *Exporter) aggregate(agg *aggregator, commonTags []string, record export.Record) error {
+	var (
+		desc = record.Descriptor()
+		name = obj.conf.namespace + desc.Name()
+		tags = make([]string, len(commonTags), len(commonTags)+record.Attributes().Len())
+	)
+	copy(tags, commonTags)
+	for iter := record.Attributes().Iter(); iter.Next(); {
+		var kv = iter.Attribute()
+		tags = append(tags, formatTag(string(kv.Key), kv.Value.Emit()))
+	}
+
+	switch value := record.Aggregation().(type) {
+	case aggregation.Histogram:
+		buckets, err := value.Histogram()
+		if err != nil {
+			return err
+		}
+		sum, err := value.Sum()
+		if err != nil {
+			return err
+		}
+		// Values of a bucket known within the bucket width only, see README (Histogram accuracy)
+		ddsketch.ForEachExplicitBucket(buckets.Boundaries, buckets.Counts, sum.CoerceToFloat64(desc.NumberKind()), func(value float64, count uint64) {
+			agg.sample(name, obj.conf.histogramType, tags, value, float64(count))
+		})
+	case aggregation.LastValue:
+		last, _, err := value.LastValue()
+		if err != nil {
+			return err
+		}
+		agg.gauge(name, tags, last.CoerceToFloat64(desc.NumberKind()))
+	case aggregation.Sum:
+		sum, err := value.Sum()
+		if err != nil {
+			return err
+		}
+		if desc.InstrumentKind().Monotonic() {
+			agg.count(name, tags, sum.CoerceToFloat64(desc.NumberKind()))
+		} else {
+			agg.gauge(name, tags, sum.CoerceToFloat64(desc.NumberKind()))
+		}
+	}
+	return nil
+}
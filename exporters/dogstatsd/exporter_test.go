@@ -0,0 +1,192 @@
+package dogstatsd
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/sdk/metric/aggregator/histogram"
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+// test_pipeline returns a controller collecting metrics exported by exporter.
+func test_pipeline(exporter *Exporter) *controller.Controller {
+	return controller.New(
+		processor.NewFactory(simple.NewWithHistogramDistribution(histogram.WithExplicitBoundaries([]float64{1, 10})), exporter),
+		controller.WithResource(resource.NewSchemaless(
+			semconv.ServiceNameKey.String("api"),
+			semconv.DeploymentEnvironmentKey.String("prod"),
+			semconv.HostNameKey.String("host"),
+		)),
+		controller.WithCollectPeriod(0),
+	)
+}
+
+// test_export collects then exports the metrics of the pipeline.
+func test_export(t *testing.T, exporter *Exporter, ctrl *controller.Controller) {
+	require.NoError(t, ctrl.Collect(context.Background()))
+	require.NoError(t, exporter.Export(context.Background(), ctrl.Resource(), ctrl))
+}
+
+func test_instruments(t *testing.T, meter metric.Meter) {
+	var ctx = context.Background()
+	counter, err := meter.SyncInt64().Counter("requests")
+	require.NoError(t, err)
+	upDown, err := meter.SyncInt64().UpDownCounter("connections")
+	require.NoError(t, err)
+	latency, err := meter.SyncFloat64().Histogram("latency")
+	require.NoError(t, err)
+	heap, err := meter.AsyncFloat64().Gauge("heap")
+	require.NoError(t, err)
+	require.NoError(t, meter.RegisterCallback([]instrument.Asynchronous{heap}, func(ctx context.Context) {
+		heap.Observe(ctx, 1024)
+	}))
+
+	counter.Add(ctx, 2, attribute.String("status", "ok"))
+	counter.Add(ctx, 1, attribute.String("status", "ok"))
+	upDown.Add(ctx, 5)
+	upDown.Add(ctx, -2)
+	for _, v := range []float64{0.5, 2, 4, 20, 30} {
+		latency.Record(ctx, v)
+	}
+}
+
+func Test_Exporter_Export(t *testing.T) {
+	var server = test_listen(t, "udp", "127.0.0.1:0")
+	exporter, err := New(
+		WithAddress(server.LocalAddr().String()),
+		WithNamespace("app."),
+		WithTags("team:core"),
+		WithEntityID("pod-uid"),
+	)
+	require.NoError(t, err)
+	defer exporter.Close()
+
+	var ctrl = test_pipeline(exporter)
+	test_instruments(t, ctrl.Meter("test"))
+	test_export(t, exporter, ctrl)
+
+	const tags = "team:core,service:api,env:prod,dd.internal.entity_id:pod-uid"
+	if packets := test_readPackets(t, server); assert.Len(t, packets, 1) {
+		assert.ElementsMatch(t, []string{
+			"app.requests:3|c|#" + tags + ",status:ok",
+			"app.connections:3|g|#" + tags,
+			"app.latency:3.1622776601683795|d|@0.5|#" + tags,
+			"app.latency:10|d|@0.5|#" + tags,
+			"app.latency:1|d|#" + tags,
+			"app.heap:1024|g|#" + tags,
+		}, splitLines(packets[0]))
+	}
+
+	// Counters delta, unchanged metrics not exported
+	ctx := context.Background()
+	counter, _ := ctrl.Meter("test").SyncInt64().Counter("requests")
+	counter.Add(ctx, 4, attribute.String("status", "ok"))
+	test_export(t, exporter, ctrl)
+	if packets := test_readPackets(t, server); assert.Len(t, packets, 1) {
+		assert.ElementsMatch(t, []string{
+			"app.requests:4|c|#" + tags + ",status:ok",
+			"app.heap:1024|g|#" + tags,
+		}, splitLines(packets[0]))
+	}
+}
+
+func Test_Exporter_Export_histogram(t *testing.T) {
+	var server = test_listen(t, "udp", "127.0.0.1:0")
+	exporter, err := New(WithAddress(server.LocalAddr().String()), WithHistogramType(TypeHistogram), WithEntityID("-"))
+	require.NoError(t, err)
+	defer exporter.Close()
+
+	var ctrl = test_pipeline(exporter)
+	latency, err := ctrl.Meter("test").SyncInt64().Histogram("latency")
+	require.NoError(t, err)
+	latency.Record(context.Background(), 3)
+	test_export(t, exporter, ctrl)
+	assert.Equal(t, []string{"latency:3.1622776601683795|h|#service:api,env:prod,dd.internal.entity_id:-\n"}, test_readPackets(t, server))
+}
+
+func Test_Exporter_Export_packetSize(t *testing.T) {
+	var server = test_listen(t, "udp", "127.0.0.1:0")
+	exporter, err := New(WithAddress(server.LocalAddr().String()), WithEntityID("-"))
+	require.NoError(t, err)
+	defer exporter.Close()
+
+	// Values of 400 buckets, sampled once, overflowing a packet
+	var boundaries = make([]float64, 400)
+	for i := range boundaries {
+		boundaries[i] = float64(i + 1)
+	}
+	var ctrl = controller.New(
+		processor.NewFactory(simple.NewWithHistogramDistribution(histogram.WithExplicitBoundaries(boundaries)), exporter),
+		controller.WithCollectPeriod(0),
+	)
+	latency, err := ctrl.Meter("test").SyncFloat64().Histogram("latency")
+	require.NoError(t, err)
+	for i := range boundaries {
+		latency.Record(context.Background(), float64(i)+1.5)
+	}
+	test_export(t, exporter, ctrl)
+
+	var values int
+	var packets = test_readPackets(t, server)
+	assert.Greater(t, len(packets), 1)
+	for _, packet := range packets {
+		assert.LessOrEqual(t, len(packet), DefaultUDPPacketSize)
+		for _, line := range splitLines(packet) {
+			values += strings.Count(line[:strings.Index(line, "|")], ":")
+		}
+	}
+	assert.Equal(t, len(boundaries), values)
+}
+
+func Test_Exporter_Export_error(t *testing.T) {
+	exporter, err := New(WithAddress("unix://" + t.TempDir() + "/dsd.socket"))
+	require.NoError(t, err)
+	defer exporter.Close()
+
+	var ctrl = test_pipeline(exporter)
+	test_instruments(t, ctrl.Meter("test"))
+	require.NoError(t, ctrl.Collect(context.Background()))
+	assert.Error(t, exporter.Export(context.Background(), ctrl.Resource(), ctrl))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, exporter.Export(ctx, ctrl.Resource(), ctrl), context.Canceled)
+}
+
+func Test_Exporter_SendGauges(t *testing.T) {
+	var server = test_listen(t, "udp", "127.0.0.1:0")
+	exporter, err := New(WithAddress(server.LocalAddr().String()), WithNamespace("app."), WithEntityID("-"))
+	require.NoError(t, err)
+	defer exporter.Close()
+
+	var res = resource.NewSchemaless(semconv.ServiceNameKey.String("api"))
+	require.NoError(t, exporter.SendGauges(res, []string{"lang:go"}, []Gauge{
+		{Name: "runtime.go.num_goroutine", Value: 12},
+		{Name: "runtime.go.num_cpu", Value: 4},
+	}))
+	assert.Equal(t, []string{
+		"app.runtime.go.num_goroutine:12|g|#service:api,dd.internal.entity_id:-,lang:go\n" +
+			"app.runtime.go.num_cpu:4|g|#service:api,dd.internal.entity_id:-,lang:go\n",
+	}, test_readPackets(t, server))
+}
+
+func Test_Exporter_commonTags(t *testing.T) {
+	exporter, err := New(WithTags("team:core"), WithEntityID("pod-uid"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team:core", "dd.internal.entity_id:pod-uid"}, exporter.commonTags(nil))
+	assert.Equal(t, []string{"team:core", "version:1.0", "dd.internal.entity_id:pod-uid"}, exporter.commonTags(resource.NewSchemaless(semconv.ServiceVersionKey.String("1.0"))))
+}
+
+func splitLines(packet string) []string {
+	return strings.Split(strings.TrimSuffix(packet, "\n"), "\n")
+}
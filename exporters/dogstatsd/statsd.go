@@ -0,0 +1,184 @@
+package dogstatsd
+
+import (
+	"strconv"
+	"strings"
+)
+
+// DogStatsD metric types
+// Ref https://docs.datadoghq.com/developers/dogstatsd/datagram_shell/
+const (
+	// TypeCount is the type of counts, the value being the delta of the interval.
+	TypeCount = "c"
+	// TypeGauge is the type of gauges, the value being the last one.
+	TypeGauge = "g"
+	// TypeDistribution is the type of distributions, aggregated by Datadog
+	// across hosts.
+	TypeDistribution = "d"
+	// TypeHistogram is the type of histograms, aggregated by the agent.
+	TypeHistogram = "h"
+)
+
+// entityIDTag is the tag of the entity ID, used by the agent for origin detection.
+// Ref https://github.com/DataDog/datadog-go/blob/master/statsd/statsd.go (entityIDTagName)
+const entityIDTag = "dd.internal.entity_id"
+
+// nameReplacer replaces the characters of the datagram format in metric names.
+var nameReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", "#", "_", ",", "_", " ", "_", "\n", "_", "\r", "_")
+
+// tagReplacer replaces the characters of the datagram format in tags.
+var tagReplacer = strings.NewReplacer("|", "_", ",", "_", "\n", "_", "\r", "_")
+
+// formatTag returns the tag "key:value", the key alone if value is empty.
+func formatTag(key, value string) string {
+	if value == "" {
+		return tagReplacer.Replace(key)
+	}
+	return tagReplacer.Replace(key) + ":" + tagReplacer.Replace(value)
+}
+
+// _____________________ Aggregation _____________________
+
+// contextKey identifies the metrics aggregated together.
+type contextKey struct {
+	name string
+	typ  string
+	tags string // tags joined by ","
+}
+
+type metricContext struct {
+	contextKey
+	value   float64   // count and gauge
+	values  []float64 // distribution and histogram, with counts
+	counts  []float64
+	indexes map[float64]int
+}
+
+// aggregator aggregates the metrics of identical contexts (name, type and
+// tags), so that each one is sent once per export: counts are added, the last
+// gauge value is kept and distribution values are merged.
+type aggregator struct {
+	contexts map[contextKey]*metricContext
+	ordered  []*metricContext
+}
+
+func newAggregator() *aggregator {
+	return &aggregator{contexts: make(map[contextKey]*metricContext)}
+}
+
+func (obj *aggregator) context(name, typ string, tags []string) *metricContext {
+	var key = contextKey{name: nameReplacer.Replace(name), typ: typ, tags: strings.Join(tags, ",")}
+	if ctx, ok := obj.contexts[key]; ok {
+		return ctx
+	}
+	var ctx = &metricContext{contextKey: key}
+	obj.contexts[key] = ctx
+	obj.ordered = append(obj.ordered, ctx)
+	return ctx
+}
+
+func (obj *aggregator) count(name string, tags []string, value float64) {
+	obj.context(name, TypeCount, tags).value += value
+}
+
+func (obj *aggregator) gauge(name string, tags []string, value float64) {
+	obj.context(name, TypeGauge, tags).value = value
+}
+
+// sample adds count values to a distribution or histogram.
+func (obj *aggregator) sample(name, typ string, tags []string, value, count float64) {
+	var ctx = obj.context(name, typ, tags)
+	if ctx.indexes == nil {
+		ctx.indexes = make(map[float64]int)
+	}
+	if i, ok := ctx.indexes[value]; ok {
+		ctx.counts[i] += count
+		return
+	}
+	ctx.indexes[value] = len(ctx.values)
+	ctx.values = append(ctx.values, value)
+	ctx.counts = append(ctx.counts, count)
+}
+
+// forEachLine calls fn with the datagram of each metric, in order of first
+// aggregation. Values sampled once are packed in datagrams of at most maxSize
+// bytes (newline included), values sampled n times are sent with a 1/n sample
+// rate.
+func (obj *aggregator) forEachLine(maxSize int, fn func(line []byte) error) error {
+	var line []byte
+	for _, ctx := range obj.ordered {
+		if ctx.values == nil {
+			line = appendLine(line[:0], ctx.contextKey, "", ctx.value)
+			if err := fn(line); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var once []float64
+		for i, value := range ctx.values {
+			if ctx.counts[i] == 1 {
+				once = append(once, value)
+				continue
+			}
+			if ctx.counts[i] <= 0 {
+				continue
+			}
+			line = appendLine(line[:0], ctx.contextKey, formatFloat(1/ctx.counts[i]), value)
+			if err := fn(line); err != nil {
+				return err
+			}
+		}
+
+		// Packed values split in datagrams fitting in a packet
+		var (
+			empty  = len(appendLine(line[:0], ctx.contextKey, "")) + 1
+			size   = empty
+			packed []float64
+		)
+		for _, value := range once {
+			var valueSize = 1 + len(strconv.AppendFloat(line[:0], value, 'f', -1, 64))
+			if len(packed) > 0 && size+valueSize > maxSize {
+				line = appendLine(line[:0], ctx.contextKey, "", packed...)
+				if err := fn(line); err != nil {
+					return err
+				}
+				packed, size = packed[:0], empty
+			}
+			packed = append(packed, value)
+			size += valueSize
+		}
+		if len(packed) > 0 {
+			line = appendLine(line[:0], ctx.contextKey, "", packed...)
+			if err := fn(line); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// appendLine appends the datagram "name:value[:value...]|type[|@rate][|#tags]".
+// Ref https://docs.datadoghq.com/developers/dogstatsd/datagram_shell/
+func appendLine(b []byte, key contextKey, rate string, values ...float64) []byte {
+	b = append(b, key.name...)
+	for _, value := range values {
+		b = append(b, ':')
+		b = strconv.AppendFloat(b, value, 'f', -1, 64)
+	}
+	b = append(b, '|')
+	b = append(b, key.typ...)
+	if rate != "" {
+		b = append(b, "|@"...)
+		b = append(b, rate...)
+	}
+	if key.tags != "" {
+		b = append(b, "|#"...)
+		b = append(b, key.tags...)
+	}
+	return b
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
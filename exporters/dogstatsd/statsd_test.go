@@ -0,0 +1,82 @@
+package dogstatsd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func test_lines(agg *aggregator) []string {
+	var lines []string
+	_ = agg.forEachLine(DefaultUDPPacketSize, func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	})
+	return lines
+}
+
+func Test_formatTag(t *testing.T) {
+	assert.Equal(t, "env:prod", formatTag("env", "prod"))
+	assert.Equal(t, "debug", formatTag("debug", ""))
+	assert.Equal(t, "path:/a_b_c_d", formatTag("path", "/a|b,c\nd"))
+}
+
+func Test_aggregator(t *testing.T) {
+	var agg = newAggregator()
+	agg.count("requests", []string{"env:prod"}, 2)
+	agg.gauge("queue.size", nil, 3)
+	agg.count("requests", []string{"env:prod"}, 1.5)
+	agg.count("requests", []string{"env:dev"}, 1)
+	agg.gauge("queue.size", nil, 4)
+	agg.sample("latency", TypeDistribution, []string{"env:prod"}, 0.5, 1)
+	agg.sample("latency", TypeDistribution, []string{"env:prod"}, 2, 4)
+	agg.sample("latency", TypeDistribution, []string{"env:prod"}, 1, 1)
+	agg.sample("latency", TypeDistribution, []string{"env:prod"}, 0.5, 1)
+	agg.sample("latency", TypeDistribution, []string{"env:prod"}, 1, 0)
+	agg.count("bad|name:x", nil, 1)
+
+	assert.Equal(t, []string{
+		"requests:3.5|c|#env:prod",
+		"queue.size:4|g",
+		"requests:1|c|#env:dev",
+		"latency:0.5|d|@0.5|#env:prod",
+		"latency:2|d|@0.25|#env:prod",
+		"latency:1|d|#env:prod",
+		"bad_name_x:1|c",
+	}, test_lines(agg))
+
+	agg = newAggregator()
+	agg.sample("latency", TypeHistogram, nil, 1, 1)
+	agg.sample("latency", TypeHistogram, nil, 1.25, 1)
+	assert.Equal(t, []string{"latency:1:1.25|h"}, test_lines(agg))
+}
+
+func Test_aggregator_forEachLine_packetSize(t *testing.T) {
+	var agg = newAggregator()
+	for i := 0; i < 500; i++ {
+		agg.sample("latency", TypeDistribution, []string{"env:prod"}, 1000.125+float64(i), 1)
+	}
+
+	var values int
+	var lines = test_lines(agg)
+	assert.Greater(t, len(lines), 1)
+	for _, line := range lines {
+		assert.LessOrEqual(t, len(line)+1, DefaultUDPPacketSize)
+		assert.True(t, strings.HasPrefix(line, "latency:1"), line)
+		assert.True(t, strings.HasSuffix(line, "|d|#env:prod"), line)
+		values += strings.Count(line[:strings.Index(line, "|")], ":")
+	}
+	assert.Equal(t, 500, values)
+
+	// Values larger than the packet size sent alone
+	agg = newAggregator()
+	agg.sample("latency", TypeDistribution, nil, 1.5, 1)
+	agg.sample("latency", TypeDistribution, nil, 2.5, 1)
+	lines = nil
+	_ = agg.forEachLine(10, func(line []byte) error {
+		lines = append(lines, string(line))
+		return nil
+	})
+	assert.Equal(t, []string{"latency:1.5|d", "latency:2.5|d"}, lines)
+}
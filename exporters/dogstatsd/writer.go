@@ -0,0 +1,84 @@
+package dogstatsd
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+var ErrMetricTooLarge = errors.New("metric larger than the maximum packet size")
+
+// writeTimeout is the timeout of a packet write, a full Unix domain socket
+// buffer blocking writes
+const writeTimeout = 100 * time.Millisecond
+
+// packetWriter buffers datagrams into packets of the maximum size, sent over
+// UDP or a Unix domain socket.
+type packetWriter struct {
+	network       string
+	addr          string
+	maxPacketSize int
+
+	conn   net.Conn // dialed on first write, closed on error
+	buffer []byte
+}
+
+func newPacketWriter(conf *config) *packetWriter {
+	return &packetWriter{
+		network:       conf.network,
+		addr:          conf.addr,
+		maxPacketSize: conf.maxPacketSize,
+		buffer:        make([]byte, 0, conf.maxPacketSize),
+	}
+}
+
+// write buffers a datagram, the buffered packet being sent first if the
+// datagram does not fit in.
+func (obj *packetWriter) write(line []byte) error {
+	var size = len(line) + 1 // newline separated
+	if size > obj.maxPacketSize {
+		return fmt.Errorf("%w: %d bytes", ErrMetricTooLarge, size)
+	}
+	if len(obj.buffer)+size > obj.maxPacketSize {
+		if err := obj.flush(); err != nil {
+			return err
+		}
+	}
+	obj.buffer = append(obj.buffer, line...)
+	obj.buffer = append(obj.buffer, '\n')
+	return nil
+}
+
+// flush sends the buffered packet, dropped if the send fails.
+func (obj *packetWriter) flush() error {
+	if len(obj.buffer) == 0 {
+		return nil
+	}
+	defer func() { obj.buffer = obj.buffer[:0] }()
+
+	if obj.conn == nil {
+		conn, err := net.Dial(obj.network, obj.addr)
+		if err != nil {
+			return err
+		}
+		obj.conn = conn
+	}
+	if err := obj.conn.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	if _, err := obj.conn.Write(obj.buffer); err != nil {
+		obj.close()
+		return err
+	}
+	return nil
+}
+
+func (obj *packetWriter) close() error {
+	if obj.conn == nil {
+		return nil
+	}
+	var err = obj.conn.Close()
+	obj.conn = nil
+	return err
+}
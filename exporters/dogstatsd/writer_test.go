@@ -0,0 +1,74 @@
+package dogstatsd
+
+import (
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// test_listen returns a DogStatsD stand-in listening on network.
+func test_listen(t *testing.T, network, addr string) net.PacketConn {
+	conn, err := net.ListenPacket(network, addr)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// test_readPackets returns the packets received until none for a while.
+func test_readPackets(t *testing.T, conn net.PacketConn) []string {
+	var (
+		packets []string
+		buffer  = make([]byte, 65536)
+	)
+	for {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(200*time.Millisecond)))
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return packets
+		}
+		packets = append(packets, string(buffer[:n]))
+	}
+}
+
+func Test_packetWriter(t *testing.T) {
+	var (
+		server = test_listen(t, "udp", "127.0.0.1:0")
+		writer = newPacketWriter(&config{network: "udp", addr: server.LocalAddr().String(), maxPacketSize: 16})
+	)
+	defer writer.close()
+
+	// Buffered up to the maximum packet size
+	assert.NoError(t, writer.write([]byte("a:1|c")))
+	assert.NoError(t, writer.write([]byte("b:1|c")))
+	assert.NoError(t, writer.write([]byte("c:1|c")))
+	assert.ErrorIs(t, writer.write([]byte("too.long:1|c|#ab")), ErrMetricTooLarge)
+	assert.NoError(t, writer.write([]byte("d:1|c")))
+	assert.NoError(t, writer.flush())
+	assert.NoError(t, writer.flush())
+	assert.Equal(t, []string{"a:1|c\nb:1|c\n", "c:1|c\nd:1|c\n"}, test_readPackets(t, server))
+}
+
+func Test_packetWriter_UDS(t *testing.T) {
+	var (
+		path   = filepath.Join(t.TempDir(), "dsd.socket")
+		writer = newPacketWriter(&config{network: "unixgram", addr: path, maxPacketSize: DefaultUDSPacketSize})
+	)
+	defer writer.close()
+
+	// Server not listening, packet dropped
+	assert.NoError(t, writer.write([]byte("a:1|c")))
+	assert.Error(t, writer.flush())
+
+	var server = test_listen(t, "unixgram", path)
+	assert.NoError(t, writer.write([]byte(strings.Repeat("b", 2000)+":1|c")))
+	assert.NoError(t, writer.write([]byte("c:1|c")))
+	assert.NoError(t, writer.flush())
+	if packets := test_readPackets(t, server); assert.Len(t, packets, 1) {
+		assert.True(t, strings.HasSuffix(packets[0], ":1|c\nc:1|c\n"))
+	}
+}
@@ -0,0 +1,96 @@
+package metricmapping
+
+import (
+	"errors"
+	"fmt"
+)
+
+// _____________________ With option functions _____________________
+
+// WithMappings sets the mappings of metrics by OpenTelemetry name, replacing
+// the built-in ones. To extend them, add to DefaultMappings. Two metrics can
+// not be mapped to the same name, their points being mixed by exporters.
+// It defaults to DefaultMappings.
+func WithMappings(value map[string]Mapping) configFn {
+	return func(conf *config) {
+		conf.mappings = value
+	}
+}
+
+// WithAttributeRenames sets the renames of the attributes of all metrics,
+// replacing the built-in ones. An attribute renamed to "" is dropped.
+// It defaults to DefaultAttributeRenames.
+func WithAttributeRenames(value map[string]string) configFn {
+	return func(conf *config) {
+		conf.attributeRenames = value
+	}
+}
+
+// _____________________ Definition _____________________
+
+type configFn func(*config)
+
+var (
+	ErrInvalidUnit   = errors.New("invalid mapping unit")
+	ErrDuplicateName = errors.New("duplicate mapping name")
+)
+
+// _____________________ Configuration _____________________
+
+func newConfig(cfg ...configFn) (*config, error) {
+	var conf = &config{}
+
+	// Apply configurations
+	for _, v := range cfg {
+		if v != nil {
+			v(conf)
+		}
+	}
+
+	// Apply default value on empty
+	if err := conf.applyDefault(); err != nil {
+		return nil, err
+	}
+
+	// Check configuration is valid
+	if err := conf.parse(); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+type config struct {
+	mappings         map[string]Mapping
+	attributeRenames map[string]string
+}
+
+func (obj *config) applyDefault() error {
+	if obj.mappings == nil {
+		obj.mappings = DefaultMappings()
+	}
+	if obj.attributeRenames == nil {
+		obj.attributeRenames = DefaultAttributeRenames()
+	}
+	return nil
+}
+
+func (obj *config) parse() error {
+	var sources = make(map[string]string, len(obj.mappings))
+	for name, mapping := range obj.mappings {
+		if _, ok := unitScales[mapping.Unit]; mapping.Unit != "" && !ok {
+			return fmt.Errorf("%w: %q of %s", ErrInvalidUnit, mapping.Unit, name)
+		}
+
+		// Metrics mapped to the same name would share their streams
+		var target = mapping.targetName(name)
+		if other, ok := sources[target]; ok {
+			if other > name {
+				other, name = name, other
+			}
+			return fmt.Errorf("%w: %q of %s and %s", ErrDuplicateName, target, other, name)
+		}
+		sources[target] = name
+	}
+	return nil
+}
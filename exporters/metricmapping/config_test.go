@@ -0,0 +1,50 @@
+package metricmapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_newConfig(t *testing.T) {
+	// Defaults
+	conf, err := newConfig()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultMappings(), conf.mappings)
+	assert.Equal(t, DefaultAttributeRenames(), conf.attributeRenames)
+
+	// Options, empty ones disabling the built-in mappings
+	var mappings = map[string]Mapping{"latency": {Name: "app.latency", Unit: "ms"}}
+	conf, err = newConfig(WithMappings(mappings), WithAttributeRenames(map[string]string{}))
+	require.NoError(t, err)
+	assert.Equal(t, mappings, conf.mappings)
+	assert.Empty(t, conf.attributeRenames)
+
+	// Invalid
+	_, err = newConfig(WithMappings(map[string]Mapping{"latency": {Unit: "{request}"}}))
+	assert.ErrorIs(t, err, ErrInvalidUnit)
+}
+
+func Test_newConfig_DuplicateName(t *testing.T) {
+	// Two metrics mapped to the same name
+	_, err := newConfig(WithMappings(map[string]Mapping{
+		"http.server.duration":         {Name: "trace.http.server.request.duration"},
+		"http.server.request.duration": {Name: "trace.http.server.request.duration", Unit: "s"},
+	}))
+	assert.ErrorIs(t, err, ErrDuplicateName)
+	assert.EqualError(t, err, `duplicate mapping name: "trace.http.server.request.duration" of http.server.duration and http.server.request.duration`)
+
+	// Metric mapped to the name of a metric keeping its name
+	_, err = newConfig(WithMappings(map[string]Mapping{
+		"latency":     {Name: "app.latency"},
+		"app.latency": {Unit: "ms"},
+	}))
+	assert.ErrorIs(t, err, ErrDuplicateName)
+
+	// Built-in mappings extended with a colliding one
+	var mappings = DefaultMappings()
+	mappings["http.server.request.duration"] = Mapping{Name: "trace.http.server.request.duration", Unit: "s"}
+	_, err = New(&test_exporter{}, WithMappings(mappings))
+	assert.ErrorIs(t, err, ErrDuplicateName)
+}
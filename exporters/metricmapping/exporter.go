@@ -0,0 +1,221 @@
+// Package metricmapping maps OpenTelemetry metrics to the names, units and
+// tags of Datadog metrics, before a Datadog metric exporter.
+package metricmapping
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/unit"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/export"
+	"go.opentelemetry.io/otel/sdk/metric/export/aggregation"
+	"go.opentelemetry.io/otel/sdk/metric/number"
+	"go.opentelemetry.io/otel/sdk/metric/sdkapi"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// Exporter maps the metrics exported to the exporter it wraps:
+//   - metric names, with the mapping of the OpenTelemetry name
+//   - values, converted to the unit of the mapping (ex: seconds to milliseconds)
+//   - attributes, renamed for all metrics then with the mapping
+//
+// Metrics without mapping keep their name and unit.
+type Exporter struct {
+	conf     *config
+	exporter export.Exporter
+}
+
+var _ export.Exporter = (*Exporter)(nil)
+
+// New returns an exporter mapping metrics before exporting them with exporter
+// (ex: the DogStatsD or Datadog metrics exporter).
+// To use the built-in mappings, call with the exporter only.
+func New(exporter export.Exporter, cfg ...configFn) (*Exporter, error) {
+	conf, err := newConfig(cfg...)
+	if err != nil {
+		return nil, err
+	}
+	return &Exporter{conf: conf, exporter: exporter}, nil
+}
+
+// TemporalityFor returns the temporality of the wrapped exporter.
+func (obj *Exporter) TemporalityFor(desc *sdkapi.Descriptor, kind aggregation.Kind) aggregation.Temporality {
+	return obj.exporter.TemporalityFor(desc, kind)
+}
+
+// Export exports the mapped metrics of the reader with the wrapped exporter.
+func (obj *Exporter) Export(ctx context.Context, res *resource.Resource, reader export.InstrumentationLibraryReader) error {
+	return obj.exporter.Export(ctx, res, &libraryReader{conf: obj.conf, reader: reader})
+}
+
+// _____________________ Readers _____________________
+
+type libraryReader struct {
+	conf   *config
+	reader export.InstrumentationLibraryReader
+}
+
+func (obj *libraryReader) ForEach(fn func(instrumentation.Library, export.Reader) error) error {
+	return obj.reader.ForEach(func(lib instrumentation.Library, r export.Reader) error {
+		return fn(lib, &recordReader{Reader: r, conf: obj.conf})
+	})
+}
+
+// recordReader maps the records of the reader, locks being the ones of the reader.
+type recordReader struct {
+	export.Reader
+	conf *config
+}
+
+func (obj *recordReader) ForEach(selector aggregation.TemporalitySelector, fn func(export.Record) error) error {
+	return obj.Reader.ForEach(selector, func(record export.Record) error {
+		return fn(obj.conf.mapRecord(record))
+	})
+}
+
+// _____________________ Mapping _____________________
+
+// mapRecord returns the record mapped to its Datadog name, unit and tags.
+func (obj *config) mapRecord(record export.Record) export.Record {
+	var (
+		desc       = record.Descriptor()
+		mapping    = obj.mappings[desc.Name()]
+		name       = mapping.targetName(desc.Name())
+		unitName   = desc.Unit()
+		numberKind = desc.NumberKind()
+		agg        = record.Aggregation()
+	)
+	if factor, ok := unitFactor(string(desc.Unit()), mapping.Unit); ok {
+		unitName = unit.Unit(mapping.Unit)
+		if factor != 1 {
+			agg = scale(agg, desc.NumberKind(), factor)
+			numberKind = number.Float64Kind
+		}
+	}
+
+	var mapped = sdkapi.NewDescriptor(name, desc.InstrumentKind(), numberKind, desc.Description(), unitName)
+	var attrs = obj.mapAttributes(record.Attributes(), mapping.Attributes)
+	return export.NewRecord(&mapped, attrs, agg, record.StartTime(), record.EndTime())
+}
+
+// mapAttributes returns the attributes renamed by the renames of all metrics,
+// then by the renames of the mapping, attrs if unchanged.
+func (obj *config) mapAttributes(attrs *attribute.Set, renames map[string]string) *attribute.Set {
+	if attrs.Len() == 0 || (len(obj.attributeRenames) == 0 && len(renames) == 0) {
+		return attrs
+	}
+
+	var (
+		kvs     = make([]attribute.KeyValue, 0, attrs.Len())
+		changed bool
+	)
+	for iter := attrs.Iter(); iter.Next(); {
+		var kv = iter.Attribute()
+		var key, renamed = rename(string(kv.Key), obj.attributeRenames)
+		if key2, ok := rename(key, renames); ok {
+			key, renamed = key2, true
+		}
+		changed = changed || renamed
+		if key != "" {
+			kvs = append(kvs, attribute.KeyValue{Key: attribute.Key(key), Value: kv.Value})
+		}
+	}
+	if !changed {
+		return attrs
+	}
+	var set = attribute.NewSet(kvs...)
+	return &set
+}
+
+// rename returns the new name of key, true if renamed.
+func rename(key string, renames map[string]string) (string, bool) {
+	if value, ok := renames[key]; ok {
+		return value, true
+	}
+	return key, false
+}
+
+// _____________________ Unit conversion _____________________
+
+// scale returns the aggregation with values multiplied by factor, as float64.
+func scale(agg aggregation.Aggregation, kind number.Kind, factor float64) aggregation.Aggregation {
+	var base = scaled{kind: kind, factor: factor}
+	switch value := agg.(type) {
+	case aggregation.Histogram:
+		return &scaledHistogram{scaled: base, agg: value}
+	case aggregation.LastValue:
+		return &scaledLastValue{scaled: base, agg: value}
+	case aggregation.Sum:
+		return &scaledSum{scaled: base, agg: value}
+	}
+	return agg
+}
+
+type scaled struct {
+	kind   number.Kind
+	factor float64
+}
+
+func (obj scaled) number(value number.Number) number.Number {
+	return number.NewFloat64Number(value.CoerceToFloat64(obj.kind) * obj.factor)
+}
+
+type scaledSum struct {
+	scaled
+	agg aggregation.Sum
+}
+
+func (obj *scaledSum) Kind() aggregation.Kind {
+	return obj.agg.Kind()
+}
+
+func (obj *scaledSum) Sum() (number.Number, error) {
+	sum, err := obj.agg.Sum()
+	return obj.number(sum), err
+}
+
+type scaledLastValue struct {
+	scaled
+	agg aggregation.LastValue
+}
+
+func (obj *scaledLastValue) Kind() aggregation.Kind {
+	return obj.agg.Kind()
+}
+
+func (obj *scaledLastValue) LastValue() (number.Number, time.Time, error) {
+	last, timestamp, err := obj.agg.LastValue()
+	return obj.number(last), timestamp, err
+}
+
+type scaledHistogram struct {
+	scaled
+	agg aggregation.Histogram
+}
+
+func (obj *scaledHistogram) Kind() aggregation.Kind {
+	return obj.agg.Kind()
+}
+
+func (obj *scaledHistogram) Count() (uint64, error) {
+	return obj.agg.Count()
+}
+
+func (obj *scaledHistogram) Sum() (number.Number, error) {
+	sum, err := obj.agg.Sum()
+	return obj.number(sum), err
+}
+
+func (obj *scaledHistogram) Histogram() (aggregation.Buckets, error) {
+	buckets, err := obj.agg.Histogram()
+	if err != nil {
+		return buckets, err
+	}
+	var boundaries = make([]float64, len(buckets.Boundaries))
+	for i, boundary := range buckets.Boundaries {
+		boundaries[i] = boundary * obj.factor
+	}
+	return aggregation.Buckets{Boundaries: boundaries, Counts: buckets.Counts}, nil
+}
@@ -0,0 +1,182 @@
+package metricmapping
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/unit"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	"go.opentelemetry.io/otel/sdk/metric/aggregator/histogram"
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	"go.opentelemetry.io/otel/sdk/metric/export"
+	"go.opentelemetry.io/otel/sdk/metric/export/aggregation"
+	"go.opentelemetry.io/otel/sdk/metric/number"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	"go.opentelemetry.io/otel/sdk/metric/sdkapi"
+	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// test_record is an exported record
+type test_record struct {
+	unit       unit.Unit
+	numberKind number.Kind
+	attrs      map[attribute.Key]string
+	value      float64 // sum or last value
+	boundaries []float64
+	counts     []uint64
+}
+
+// test_exporter records the metrics exported
+type test_exporter struct {
+	aggregation.TemporalitySelector
+
+	mu      sync.Mutex
+	records map[string]test_record
+}
+
+func (obj *test_exporter) Export(_ context.Context, _ *resource.Resource, reader export.InstrumentationLibraryReader) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.records = map[string]test_record{}
+	return reader.ForEach(func(_ instrumentation.Library, r export.Reader) error {
+		return r.ForEach(obj, func(record export.Record) error {
+			var (
+				desc   = record.Descriptor()
+				result = test_record{unit: desc.Unit(), numberKind: desc.NumberKind(), attrs: map[attribute.Key]string{}}
+			)
+			for iter := record.Attributes().Iter(); iter.Next(); {
+				result.attrs[iter.Attribute().Key] = iter.Attribute().Value.Emit()
+			}
+			switch value := record.Aggregation().(type) {
+			case aggregation.Histogram:
+				sum, _ := value.Sum()
+				buckets, _ := value.Histogram()
+				result.value = sum.CoerceToFloat64(desc.NumberKind())
+				result.boundaries, result.counts = buckets.Boundaries, buckets.Counts
+			case aggregation.LastValue:
+				last, _, _ := value.LastValue()
+				result.value = last.CoerceToFloat64(desc.NumberKind())
+			case aggregation.Sum:
+				sum, _ := value.Sum()
+				result.value = sum.CoerceToFloat64(desc.NumberKind())
+			}
+			obj.records[desc.Name()] = result
+			return nil
+		})
+	})
+}
+
+// test_pipeline returns a controller exporting through the mapping exporter to
+// the test exporter.
+func test_pipeline(t *testing.T, cfg ...configFn) (*controller.Controller, *Exporter, *test_exporter) {
+	var inner = &test_exporter{TemporalitySelector: aggregation.CumulativeTemporalitySelector()}
+	exporter, err := New(inner, cfg...)
+	require.NoError(t, err)
+	var ctrl = controller.New(
+		processor.NewFactory(simple.NewWithHistogramDistribution(histogram.WithExplicitBoundaries([]float64{100, 1000})), exporter),
+		controller.WithCollectPeriod(0),
+	)
+	return ctrl, exporter, inner
+}
+
+func test_export(t *testing.T, exporter *Exporter, ctrl *controller.Controller) {
+	require.NoError(t, ctrl.Collect(context.Background()))
+	require.NoError(t, exporter.Export(context.Background(), ctrl.Resource(), ctrl))
+}
+
+func Test_Exporter_Export(t *testing.T) {
+	var (
+		ctx                     = context.Background()
+		ctrl, mapping, exporter = test_pipeline(t)
+		meter                   = ctrl.Meter("test")
+		attrs                   = []attribute.KeyValue{attribute.Int("http.response.status_code", 200), attribute.String("http.route", "/users")}
+	)
+	duration, err := meter.SyncFloat64().Histogram("http.server.duration", instrument.WithUnit(unit.Milliseconds))
+	require.NoError(t, err)
+	seconds, err := meter.SyncInt64().Histogram("rpc.client.duration", instrument.WithUnit(unit.Unit("s")))
+	require.NoError(t, err)
+	connections, err := meter.SyncInt64().UpDownCounter("db.client.connections.usage")
+	require.NoError(t, err)
+	requests, err := meter.SyncInt64().Counter("requests")
+	require.NoError(t, err)
+
+	duration.Record(ctx, 50, attrs...)
+	duration.Record(ctx, 500, attrs...)
+	seconds.Record(ctx, 2)
+	connections.Add(ctx, 3, attribute.String("state", "idle"), attribute.String("pool.name", "main"))
+	requests.Add(ctx, 2, attribute.String("url.scheme", "https"))
+	test_export(t, mapping, ctrl)
+
+	// Name, unit and attributes mapped, milliseconds to seconds
+	var record = exporter.records["trace.http.server.request.duration"]
+	assert.Equal(t, unit.Unit("s"), record.unit)
+	assert.Equal(t, number.Float64Kind, record.numberKind)
+	assert.Equal(t, map[attribute.Key]string{"http.status_code": "200", "http.route": "/users"}, record.attrs)
+	assert.InDelta(t, 0.55, record.value, 1e-9)
+	assert.InDeltaSlice(t, []float64{0.1, 1}, record.boundaries, 1e-9)
+	assert.Equal(t, []uint64{1, 1, 0}, record.counts)
+
+	// Already in seconds: unchanged values
+	record = exporter.records["trace.grpc.client.request.duration"]
+	assert.Equal(t, unit.Unit("s"), record.unit)
+	assert.Equal(t, number.Int64Kind, record.numberKind)
+	assert.Equal(t, 2.0, record.value)
+	assert.Equal(t, []float64{100, 1000}, record.boundaries)
+
+	// Name of the Datadog integration
+	record = exporter.records["datadog.tracer.sql.db.connections.open"]
+	assert.Equal(t, map[attribute.Key]string{"state": "idle", "pool.name": "main"}, record.attrs)
+	assert.Equal(t, 3.0, record.value)
+
+	// Without mapping, attributes of all metrics renamed
+	record = exporter.records["requests"]
+	assert.Equal(t, map[attribute.Key]string{"http.scheme": "https"}, record.attrs)
+	assert.Equal(t, 2.0, record.value)
+	assert.Len(t, exporter.records, 4)
+}
+
+func Test_Exporter_Export_options(t *testing.T) {
+	var (
+		ctx                     = context.Background()
+		ctrl, mapping, exporter = test_pipeline(t,
+			WithMappings(map[string]Mapping{
+				"queue.wait": {Name: "queue.wait_time", Unit: "ms", Attributes: map[string]string{"internal": ""}},
+				"heap":       {Unit: "MiBy"},
+			}),
+			WithAttributeRenames(map[string]string{"queue": "queue_name"}),
+		)
+		meter = ctrl.Meter("test")
+	)
+	wait, err := meter.SyncInt64().Counter("queue.wait", instrument.WithUnit(unit.Unit("s")))
+	require.NoError(t, err)
+	heap, err := meter.AsyncInt64().Gauge("heap", instrument.WithUnit(unit.Bytes))
+	require.NoError(t, err)
+	require.NoError(t, meter.RegisterCallback([]instrument.Asynchronous{heap}, func(ctx context.Context) {
+		heap.Observe(ctx, 3<<20)
+	}))
+	wait.Add(ctx, 2, attribute.String("queue", "jobs"), attribute.Bool("internal", true))
+	test_export(t, mapping, ctrl)
+
+	var record = exporter.records["queue.wait_time"]
+	assert.Equal(t, map[attribute.Key]string{"queue_name": "jobs"}, record.attrs)
+	assert.Equal(t, 2000.0, record.value)
+	assert.Equal(t, unit.Milliseconds, record.unit)
+
+	record = exporter.records["heap"]
+	assert.Equal(t, 3.0, record.value)
+	assert.Equal(t, unit.Unit("MiBy"), record.unit)
+	assert.Len(t, exporter.records, 2)
+}
+
+func Test_Exporter_TemporalityFor(t *testing.T) {
+	exporter, err := New(&test_exporter{TemporalitySelector: aggregation.DeltaTemporalitySelector()})
+	require.NoError(t, err)
+	var desc = sdkapi.NewDescriptor("requests", sdkapi.CounterInstrumentKind, number.Int64Kind, "", "")
+	assert.Equal(t, aggregation.DeltaTemporality, exporter.TemporalityFor(&desc, aggregation.SumKind))
+}
@@ -0,0 +1,107 @@
+package metricmapping
+
+// Mapping maps an OpenTelemetry metric to a Datadog metric.
+type Mapping struct {
+	// Name is the Datadog metric name, the OpenTelemetry one if empty.
+	Name string
+	// Unit is the UCUM unit of the Datadog metric (ex: "ms"), values being
+	// converted from the unit of the instrument. Values are unchanged if empty
+	// or if the instrument unit can not be converted.
+	Unit string
+	// Attributes renames attributes to Datadog tags, after the attribute
+	// renames of all metrics. An attribute renamed to "" is dropped.
+	Attributes map[string]string
+}
+
+// Ref https://docs.datadoghq.com/tracing/metrics/metrics_namespace/
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/contrib/database/sql/metrics.go
+// Ref https://docs.datadoghq.com/opentelemetry/integrations/runtime_metrics/
+
+// DefaultMappings returns the built-in mappings of the metrics of the v1.10
+// semantic conventions (the conventions of this module) to the metrics of the
+// Datadog integrations: trace metrics of the operations of HTTP and gRPC spans
+// (durations in seconds), connection pool metrics of the Datadog database/sql
+// integration, and runtime metrics of the Datadog tracer.
+func DefaultMappings() map[string]Mapping {
+	return map[string]Mapping{
+		// HTTP
+		"http.server.duration": {Name: "trace.http.server.request.duration", Unit: "s"},
+		"http.client.duration": {Name: "trace.http.client.request.duration", Unit: "s"},
+
+		// RPC
+		"rpc.server.duration": {Name: "trace.grpc.server.request.duration", Unit: "s"},
+		"rpc.client.duration": {Name: "trace.grpc.client.request.duration", Unit: "s"},
+
+		// Database
+		"db.client.connections.usage":     {Name: "datadog.tracer.sql.db.connections.open"},
+		"db.client.connections.max":       {Name: "datadog.tracer.sql.db.connections.max_open"},
+		"db.client.connections.wait_time": {Name: "datadog.tracer.sql.db.connections.wait_duration", Unit: "ns"},
+
+		// Go runtime
+		"process.runtime.go.goroutines":        {Name: "runtime.go.num_goroutine"},
+		"process.runtime.go.gc.count":          {Name: "runtime.go.mem_stats.num_gc"},
+		"process.runtime.go.gc.pause_total_ns": {Name: "runtime.go.mem_stats.pause_total_ns"},
+		"process.runtime.go.mem.lookups":       {Name: "runtime.go.mem_stats.lookups"},
+		"process.runtime.go.mem.heap_alloc":    {Name: "runtime.go.mem_stats.heap_alloc"},
+		"process.runtime.go.mem.heap_idle":     {Name: "runtime.go.mem_stats.heap_idle"},
+		"process.runtime.go.mem.heap_inuse":    {Name: "runtime.go.mem_stats.heap_inuse"},
+		"process.runtime.go.mem.heap_objects":  {Name: "runtime.go.mem_stats.heap_objects"},
+		"process.runtime.go.mem.heap_released": {Name: "runtime.go.mem_stats.heap_released"},
+		"process.runtime.go.mem.heap_sys":      {Name: "runtime.go.mem_stats.heap_sys"},
+	}
+}
+
+// targetName returns the name of the mapped metric.
+func (obj Mapping) targetName(name string) string {
+	if obj.Name != "" {
+		return obj.Name
+	}
+	return name
+}
+
+// DefaultAttributeRenames returns the built-in renames of the attributes of
+// all metrics: attributes of recent semantic conventions to the Datadog tags
+// of the tracer.
+func DefaultAttributeRenames() map[string]string {
+	return map[string]string{
+		"http.response.status_code": "http.status_code",
+		"http.request.method":       "http.method",
+		"url.scheme":                "http.scheme",
+	}
+}
+
+// _____________________ Units _____________________
+
+// unitScales are the convertible UCUM units, in the base unit of their
+// dimension: nanosecond or byte, so that factors are exact.
+// Ref https://unitsofmeasure.org/ucum
+var unitScales = map[string]struct {
+	dimension string
+	scale     float64
+}{
+	"ns":   {"time", 1},
+	"us":   {"time", 1e3},
+	"ms":   {"time", 1e6},
+	"s":    {"time", 1e9},
+	"min":  {"time", 60e9},
+	"h":    {"time", 3600e9},
+	"d":    {"time", 86400e9},
+	"By":   {"bytes", 1},
+	"KBy":  {"bytes", 1e3},
+	"MBy":  {"bytes", 1e6},
+	"GBy":  {"bytes", 1e9},
+	"KiBy": {"bytes", 1 << 10},
+	"MiBy": {"bytes", 1 << 20},
+	"GiBy": {"bytes", 1 << 30},
+}
+
+// unitFactor returns the factor converting values of the unit from to the
+// unit to, false if not convertible.
+func unitFactor(from, to string) (float64, bool) {
+	var src, srcOk = unitScales[from]
+	var dst, dstOk = unitScales[to]
+	if !srcOk || !dstOk || src.dimension != dst.dimension {
+		return 1, false
+	}
+	return src.scale / dst.scale, true
+}
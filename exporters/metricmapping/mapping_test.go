@@ -0,0 +1,53 @@
+package metricmapping
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_unitFactor(t *testing.T) {
+	for _, v := range []struct {
+		from, to string
+		factor   float64
+		ok       bool
+	}{
+		{"s", "ms", 1000, true},
+		{"ms", "ms", 1, true},
+		{"ns", "ms", 1e-6, true},
+		{"min", "s", 60, true},
+		{"KiBy", "By", 1024, true},
+		{"By", "ms", 1, false},
+		{"", "ms", 1, false},
+		{"ms", "", 1, false},
+		{"{request}", "ms", 1, false},
+	} {
+		factor, ok := unitFactor(v.from, v.to)
+		assert.Equal(t, v.ok, ok, "%s to %s", v.from, v.to)
+		assert.Equal(t, v.factor, factor, "%s to %s", v.from, v.to)
+	}
+}
+
+func Test_DefaultMappings(t *testing.T) {
+	var mappings = DefaultMappings()
+	for name, mapping := range mappings {
+		if mapping.Unit != "" {
+			assert.Contains(t, unitScales, mapping.Unit, name)
+		}
+		// Mapped names are not mapped again
+		_, ok := mappings[mapping.Name]
+		assert.False(t, ok, name)
+	}
+	assert.Equal(t, Mapping{Name: "trace.http.server.request.duration", Unit: "s"}, mappings["http.server.duration"])
+
+	// Copies
+	mappings["http.server.duration"] = Mapping{Name: "changed"}
+	assert.Equal(t, "trace.http.server.request.duration", DefaultMappings()["http.server.duration"].Name)
+	DefaultAttributeRenames()["url.scheme"] = "changed"
+	assert.Equal(t, "http.scheme", DefaultAttributeRenames()["url.scheme"])
+}
+
+func Test_Mapping_targetName(t *testing.T) {
+	assert.Equal(t, "app.latency", Mapping{Name: "app.latency"}.targetName("latency"))
+	assert.Equal(t, "latency", Mapping{Unit: "ms"}.targetName("latency"))
+}
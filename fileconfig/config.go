@@ -0,0 +1,140 @@
+package fileconfig
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/samplers/ddsampler"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// _____________________ With option functions _____________________
+
+// WithSampler sets the sampler receiving the sample rate and the sampling
+// rules of the file.
+func WithSampler(value *ddsampler.RulesSampler) configFn {
+	return func(conf *config) {
+		conf.sampler = value
+	}
+}
+
+// WithPropagator sets the Datadog propagator receiving the header keys and the
+// header tags of the file. It must be returned by tracecontext.New.
+// It defaults to a propagator returned by tracecontext.New.
+func WithPropagator(value propagation.TextMapPropagator) configFn {
+	return func(conf *config) {
+		conf.datadog = value
+	}
+}
+
+// WithPropagationStyles sets the propagation styles applied when the file
+// sets none (ex: StyleDatadog, StyleTraceContext).
+// It defaults to DD_TRACE_PROPAGATION_STYLE environment variable or StyleDatadog.
+func WithPropagationStyles(styles ...string) configFn {
+	return func(conf *config) {
+		conf.styles = styles
+	}
+}
+
+// WithCheckInterval sets the interval between checks of the file content.
+// It defaults to DefaultCheckInterval.
+func WithCheckInterval(value time.Duration) configFn {
+	return func(conf *config) {
+		conf.checkInterval = value
+	}
+}
+
+// WithReloadHandler sets the function called after each load of the file,
+// with the configuration applied or the error of an invalid file.
+func WithReloadHandler(fn func(ReloadEvent)) configFn {
+	return func(conf *config) {
+		conf.reloadHandler = fn
+	}
+}
+
+// _____________________ Definition _____________________
+
+type configFn func(*config)
+
+var (
+	ErrInvalidConfig           = errors.New("invalid configuration file")
+	ErrInvalidPropagationStyle = errors.New("invalid propagation style")
+	ErrInvalidCheckInterval    = errors.New("invalid check interval")
+)
+
+// Ref https://docs.datadoghq.com/tracing/trace_collection/trace_context_propagation/
+
+const envPropagationStyle = "DD_TRACE_PROPAGATION_STYLE"
+
+const (
+	// DefaultCheckInterval specifies the interval between checks of the file content.
+	DefaultCheckInterval = 5 * time.Second
+)
+
+// _____________________ Configuration _____________________
+
+func newConfig(cfg ...configFn) (*config, error) {
+	var conf = &config{}
+
+	// Apply configurations
+	for _, v := range cfg {
+		if v != nil {
+			v(conf)
+		}
+	}
+
+	// Apply default value on empty
+	if err := conf.applyDefault(); err != nil {
+		return nil, err
+	}
+
+	// Check configuration is valid
+	if err := conf.parse(); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+type config struct {
+	sampler       *ddsampler.RulesSampler
+	datadog       propagation.TextMapPropagator
+	styles        []string
+	checkInterval time.Duration
+	reloadHandler func(ReloadEvent)
+}
+
+func (obj *config) applyDefault() error {
+	if obj.datadog == nil {
+		var err error
+		if obj.datadog, err = tracecontext.New(); err != nil {
+			return err
+		}
+	}
+	if len(obj.styles) == 0 {
+		obj.styles = []string{StyleDatadog}
+		if value := strings.TrimSpace(os.Getenv(envPropagationStyle)); value != "" {
+			obj.styles = strings.Split(value, ",")
+		}
+	}
+	if obj.checkInterval == 0 {
+		obj.checkInterval = DefaultCheckInterval
+	}
+	return nil
+}
+
+func (obj *config) parse() (err error) {
+	if _, ok := tracecontext.DescribeHeaderTags(obj.datadog); !ok {
+		return tracecontext.ErrUnsupportedPropagator
+	}
+	if obj.styles, err = normalizeStyles(obj.styles); err != nil {
+		return err
+	}
+	if obj.checkInterval < 0 {
+		return ErrInvalidCheckInterval
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package fileconfig
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func Test_newConfig(t *testing.T) {
+	setenv(t, envPropagationStyle, "")
+
+	// Defaults
+	conf, err := newConfig()
+	require.NoError(t, err)
+	assert.NotNil(t, conf.datadog)
+	assert.Nil(t, conf.sampler)
+	assert.Equal(t, []string{StyleDatadog}, conf.styles)
+	assert.Equal(t, DefaultCheckInterval, conf.checkInterval)
+
+	// Environment, options over environment
+	setenv(t, envPropagationStyle, "tracecontext,Datadog")
+	conf, err = newConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{StyleTraceContext, StyleDatadog}, conf.styles)
+
+	conf, err = newConfig(WithPropagationStyles(StyleBaggage), WithCheckInterval(time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, []string{StyleBaggage}, conf.styles)
+	assert.Equal(t, time.Second, conf.checkInterval)
+
+	// Invalid
+	setenv(t, envPropagationStyle, "b3")
+	_, err = newConfig()
+	assert.ErrorIs(t, err, ErrInvalidPropagationStyle)
+	_, err = newConfig(WithPropagationStyles(StyleDatadog), WithCheckInterval(-time.Second))
+	assert.ErrorIs(t, err, ErrInvalidCheckInterval)
+	_, err = newConfig(WithPropagationStyles(StyleDatadog), WithPropagator(propagation.TraceContext{}))
+	assert.ErrorIs(t, err, tracecontext.ErrUnsupportedPropagator)
+}
+
+func setenv(t *testing.T, key, value string) {
+	old, exists := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if exists {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
@@ -0,0 +1,144 @@
+package fileconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/samplers/ddsampler"
+	"gopkg.in/yaml.v3"
+)
+
+// Propagation styles, as DD_TRACE_PROPAGATION_STYLE values
+// Ref https://docs.datadoghq.com/tracing/trace_collection/trace_context_propagation/
+const (
+	// StyleDatadog propagates the Datadog headers (x-datadog-*).
+	StyleDatadog = "datadog"
+	// StyleTraceContext propagates the W3C Trace Context headers (traceparent).
+	StyleTraceContext = "tracecontext"
+	// StyleBaggage propagates the W3C Baggage header.
+	StyleBaggage = "baggage"
+	// StyleNone propagates nothing.
+	StyleNone = "none"
+)
+
+// Config is the content of a configuration file. Sections or values not set
+// in the file are the ones of the local configuration.
+type Config struct {
+	Propagation *PropagationConfig `json:"propagation,omitempty"`
+	Sampling    *SamplingConfig    `json:"sampling,omitempty"`
+}
+
+// PropagationConfig configures the propagator.
+type PropagationConfig struct {
+	// Styles are the propagation styles, extracted in order (ex: ["datadog", "tracecontext"]).
+	Styles []string `json:"styles,omitempty"`
+	// HeaderKeys are the keys of the Datadog headers, empty keys being the default ones.
+	HeaderKeys *HeaderKeys `json:"header_keys,omitempty"`
+	// HeaderTags are the tag mappings of request headers, by header.
+	HeaderTags map[string]string `json:"header_tags,omitempty"`
+}
+
+// HeaderKeys are the keys of the Datadog headers.
+type HeaderKeys struct {
+	TraceID          string `json:"trace_id,omitempty"`
+	ParentID         string `json:"parent_id,omitempty"`
+	SamplingPriority string `json:"sampling_priority,omitempty"`
+}
+
+// SamplingConfig configures the Datadog rules sampler.
+type SamplingConfig struct {
+	// SampleRate is the sample rate of traces matching no rule.
+	SampleRate *float64 `json:"sample_rate,omitempty"`
+	// Rules are the trace sampling rules, applied in order.
+	Rules []ddsampler.SamplingRule `json:"rules,omitempty"`
+}
+
+// parseConfig returns the configuration of a JSON file, or of a YAML file
+// (other extensions) decoded as JSON.
+func parseConfig(path string, data []byte) (*Config, error) {
+	if ext := strings.ToLower(filepath.Ext(path)); ext != ".json" {
+		var value interface{}
+		if err := yaml.Unmarshal(data, &value); err != nil {
+			return nil, err
+		}
+		var err error
+		if data, err = json.Marshal(value); err != nil {
+			return nil, err
+		}
+	}
+
+	var conf Config
+	var decoder = json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&conf); err != nil {
+		return nil, err
+	}
+	return &conf, nil
+}
+
+// headerKey returns the keys of the Datadog headers, the local ones if unset.
+func (obj *PropagationConfig) headerKey(local tracecontext.HeaderKey) tracecontext.HeaderKey {
+	if obj == nil || obj.HeaderKeys == nil {
+		return local
+	}
+	return tracecontext.HeaderKey{
+		TraceID:         obj.HeaderKeys.TraceID,
+		ParentID:        obj.HeaderKeys.ParentID,
+		SampledPriority: obj.HeaderKeys.SamplingPriority,
+	}
+}
+
+// headerTags returns the tag mappings of request headers, the local ones if
+// unset.
+func (obj *PropagationConfig) headerTags(local map[string]string) map[string]string {
+	if obj == nil || obj.HeaderTags == nil {
+		return local
+	}
+	return obj.HeaderTags
+}
+
+// styles returns the propagation styles, the local ones if unset.
+func (obj *PropagationConfig) styles(local []string) ([]string, error) {
+	if obj == nil || len(obj.Styles) == 0 {
+		return local, nil
+	}
+	return normalizeStyles(obj.Styles)
+}
+
+// samplingRules returns the sampling rules and the sample rate, nil values
+// being the local ones.
+func (obj *SamplingConfig) samplingRules() ([]ddsampler.SamplingRule, *float64) {
+	if obj == nil {
+		return nil, nil
+	}
+	return obj.Rules, obj.SampleRate
+}
+
+// normalizeStyles returns the lowercased styles, an error if a style is
+// unknown or repeated.
+func normalizeStyles(styles []string) ([]string, error) {
+	var normalized = make([]string, 0, len(styles))
+	for _, style := range styles {
+		style = strings.ToLower(strings.TrimSpace(style))
+		switch style {
+		case StyleDatadog, StyleTraceContext, StyleBaggage:
+		case StyleNone:
+			if len(styles) > 1 {
+				return nil, fmt.Errorf("%w: %q with other styles", ErrInvalidPropagationStyle, style)
+			}
+		default:
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPropagationStyle, style)
+		}
+		for _, v := range normalized {
+			if v == style {
+				return nil, fmt.Errorf("%w: %q repeated", ErrInvalidPropagationStyle, style)
+			}
+		}
+		normalized = append(normalized, style)
+	}
+	return normalized, nil
+}
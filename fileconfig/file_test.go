@@ -0,0 +1,90 @@
+package fileconfig
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/samplers/ddsampler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func test_parseConfig(t *testing.T, name string) *Config {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	conf, err := parseConfig(name, data)
+	require.NoError(t, err)
+	return conf
+}
+
+func Test_parseConfig(t *testing.T) {
+	// YAML
+	var conf = test_parseConfig(t, "config.yaml")
+	assert.Equal(t, []string{"datadog", "tracecontext"}, conf.Propagation.Styles)
+	assert.Equal(t, &HeaderKeys{TraceID: "x-trace-id", ParentID: "x-parent-id"}, conf.Propagation.HeaderKeys)
+	assert.Equal(t, map[string]string{"X-User-Id": "user.id", "X-Request-Id": ""}, conf.Propagation.HeaderTags)
+	assert.Equal(t, 0.5, *conf.Sampling.SampleRate)
+	assert.Equal(t, []ddsampler.SamplingRule{
+		{Service: "api", Resource: "GET /health", SampleRate: 0},
+		{Tags: map[string]string{"tenant": "vip-*"}, SampleRate: 1},
+	}, conf.Sampling.Rules)
+
+	// JSON
+	conf = test_parseConfig(t, "config.json")
+	assert.Equal(t, []string{"tracecontext", "baggage"}, conf.Propagation.Styles)
+	assert.Nil(t, conf.Propagation.HeaderKeys)
+	assert.Equal(t, map[string]string{}, conf.Propagation.HeaderTags)
+	assert.Nil(t, conf.Sampling.SampleRate)
+
+	// Invalid
+	for name, data := range map[string]string{
+		"unknown.yaml": "sampling:\n  rate: 1\n",
+		"syntax.yaml":  "propagation: [",
+		"syntax.json":  `{"propagation": `,
+		"type.json":    `{"sampling": {"rules": {}}}`,
+	} {
+		_, err := parseConfig(name, []byte(data))
+		assert.Error(t, err, name)
+	}
+}
+
+func Test_PropagationConfig_values(t *testing.T) {
+	var (
+		localKey  = tracecontext.HeaderKey{TraceID: "a", ParentID: "b", SampledPriority: "c"}
+		localTags = map[string]string{"X-Local": "local"}
+		local     = []string{StyleDatadog}
+		unset     *PropagationConfig
+	)
+	assert.Equal(t, localKey, unset.headerKey(localKey))
+	assert.Equal(t, localTags, unset.headerTags(localTags))
+	styles, err := unset.styles(local)
+	assert.NoError(t, err)
+	assert.Equal(t, local, styles)
+	rules, rate := (*SamplingConfig)(nil).samplingRules()
+	assert.Nil(t, rules)
+	assert.Nil(t, rate)
+
+	var conf = test_parseConfig(t, "config.yaml").Propagation
+	assert.Equal(t, tracecontext.HeaderKey{TraceID: "x-trace-id", ParentID: "x-parent-id"}, conf.headerKey(localKey))
+	assert.Equal(t, conf.HeaderTags, conf.headerTags(localTags))
+	styles, err = conf.styles(local)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{StyleDatadog, StyleTraceContext}, styles)
+}
+
+func Test_normalizeStyles(t *testing.T) {
+	styles, err := normalizeStyles([]string{" Datadog", "TRACECONTEXT ", "baggage"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{StyleDatadog, StyleTraceContext, StyleBaggage}, styles)
+
+	styles, err = normalizeStyles([]string{"none"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{StyleNone}, styles)
+
+	for _, invalid := range [][]string{{"b3"}, {"datadog", "Datadog"}, {"none", "datadog"}} {
+		_, err = normalizeStyles(invalid)
+		assert.ErrorIs(t, err, ErrInvalidPropagationStyle, invalid)
+	}
+}
@@ -0,0 +1,57 @@
+package fileconfig
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// dynamicPropagator delegates to the propagator of the propagation styles of
+// the configuration applied, replaced on reload.
+type dynamicPropagator struct {
+	mu   sync.RWMutex
+	prop propagation.TextMapPropagator
+}
+
+var _ propagation.TextMapPropagator = (*dynamicPropagator)(nil)
+
+// set replaces the propagator by the one of the styles.
+func (obj *dynamicPropagator) set(styles []string, datadog propagation.TextMapPropagator) {
+	var props []propagation.TextMapPropagator
+	for _, style := range styles {
+		switch style {
+		case StyleDatadog:
+			props = append(props, datadog)
+		case StyleTraceContext:
+			props = append(props, propagation.TraceContext{})
+		case StyleBaggage:
+			props = append(props, propagation.Baggage{})
+		}
+	}
+
+	obj.mu.Lock()
+	obj.prop = propagation.NewCompositeTextMapPropagator(props...)
+	obj.mu.Unlock()
+}
+
+func (obj *dynamicPropagator) get() propagation.TextMapPropagator {
+	obj.mu.RLock()
+	defer obj.mu.RUnlock()
+	return obj.prop
+}
+
+// Inject injects the context with the current propagation styles.
+func (obj *dynamicPropagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
+	obj.get().Inject(ctx, carrier)
+}
+
+// Extract extracts the context with the current propagation styles.
+func (obj *dynamicPropagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return obj.get().Extract(ctx, carrier)
+}
+
+// Fields returns the keys set by the current propagation styles.
+func (obj *dynamicPropagator) Fields() []string {
+	return obj.get().Fields()
+}
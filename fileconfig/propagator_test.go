@@ -0,0 +1,41 @@
+package fileconfig
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_dynamicPropagator(t *testing.T) {
+	var (
+		prop    dynamicPropagator
+		datadog = tracecontext.NewDefault()
+		spanCtx = trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    trace.TraceID{0x01},
+			SpanID:     trace.SpanID{0x02},
+			TraceFlags: trace.FlagsSampled,
+		})
+		ctx = trace.ContextWithSpanContext(context.Background(), spanCtx)
+	)
+
+	prop.set([]string{StyleDatadog, StyleTraceContext}, datadog)
+	assert.ElementsMatch(t, append(datadog.Fields(), "traceparent", "tracestate"), prop.Fields())
+	var carrier = propagation.MapCarrier{}
+	prop.Inject(ctx, carrier)
+	assert.NotEmpty(t, carrier.Get(tracecontext.DefaultTraceIDHeader))
+	assert.NotEmpty(t, carrier.Get("traceparent"))
+
+	// Styles replaced
+	prop.set([]string{StyleTraceContext}, datadog)
+	carrier = propagation.MapCarrier{}
+	prop.Inject(ctx, carrier)
+	assert.Empty(t, carrier.Get(tracecontext.DefaultTraceIDHeader))
+	assert.Equal(t, spanCtx.TraceID(), trace.SpanContextFromContext(prop.Extract(context.Background(), carrier)).TraceID())
+
+	prop.set([]string{StyleNone}, datadog)
+	assert.Empty(t, prop.Fields())
+}
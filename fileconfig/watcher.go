@@ -0,0 +1,201 @@
+// Package fileconfig applies a YAML or JSON configuration file to the Datadog
+// propagator and rules sampler, reloaded when the file changes.
+package fileconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"sync"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// ReloadEvent is the result of a load of the configuration file.
+type ReloadEvent struct {
+	// Path is the path of the file.
+	Path string
+	// Config is the configuration applied, nil if the file is invalid.
+	Config *Config
+	// Err is the error of an invalid file, the last good configuration being kept.
+	Err error
+}
+
+// Watcher checks the content of a configuration file periodically, applying
+// it when changed: propagation styles to the propagator returned by
+// Propagator, header keys and header tags to the Datadog propagator, sampling
+// rules and sample rate to the sampler.
+//
+// A configuration is applied atomically, once validated: an invalid file is
+// rejected, the last good configuration being kept. Settings missing from the
+// file are the ones of the local configuration (options and environment).
+type Watcher struct {
+	conf       *config
+	path       string
+	propagator dynamicPropagator
+	localKey   tracecontext.HeaderKey
+	localTags  map[string]string
+
+	mu       sync.Mutex
+	current  *Config
+	lastSeen string // hash of the content last loaded, or its read error
+
+	done chan struct{}
+	stop sync.Once
+	wg   sync.WaitGroup
+}
+
+// New returns a watcher of the configuration file at path, its configuration
+// being applied. An error is returned if the file is invalid.
+func New(path string, cfg ...configFn) (*Watcher, error) {
+	conf, err := newConfig(cfg...)
+	if err != nil {
+		return nil, err
+	}
+
+	var watcher = newWatcher(path, conf)
+	if err := watcher.Reload(); err != nil {
+		return nil, err
+	}
+	if conf.checkInterval > 0 {
+		watcher.wg.Add(1)
+		go watcher.run()
+	}
+	return watcher, nil
+}
+
+func newWatcher(path string, conf *config) *Watcher {
+	var (
+		localKey, _, _ = tracecontext.Describe(conf.datadog)
+		localTags, _   = tracecontext.DescribeHeaderTags(conf.datadog)
+	)
+	var watcher = &Watcher{
+		conf:      conf,
+		path:      path,
+		localKey:  localKey,
+		localTags: localTags,
+		done:      make(chan struct{}),
+	}
+	watcher.propagator.set(conf.styles, conf.datadog)
+	return watcher
+}
+
+// Propagator returns the propagator of the propagation styles of the
+// configuration applied.
+func (obj *Watcher) Propagator() propagation.TextMapPropagator {
+	return &obj.propagator
+}
+
+// Config returns the configuration applied, not to be modified.
+func (obj *Watcher) Config() *Config {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	return obj.current
+}
+
+// Stop stops checking the file. The configuration applied is kept.
+func (obj *Watcher) Stop() {
+	obj.stop.Do(func() { close(obj.done) })
+	obj.wg.Wait()
+}
+
+// Reload loads the file if its content changed since the last load, applying
+// its configuration if valid. The reload handler is called if loaded.
+func (obj *Watcher) Reload() error {
+	obj.mu.Lock()
+	var event, loaded = obj.reload()
+	obj.mu.Unlock()
+
+	if loaded && obj.conf.reloadHandler != nil {
+		obj.conf.reloadHandler(event)
+	}
+	return event.Err
+}
+
+// run checks the file periodically, until stopped.
+func (obj *Watcher) run() {
+	defer obj.wg.Done()
+	var ticker = time.NewTicker(obj.conf.checkInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-obj.done:
+			return
+		case <-ticker.C:
+			if err := obj.Reload(); err != nil && obj.conf.reloadHandler == nil {
+				otel.Handle(err)
+			}
+		}
+	}
+}
+
+// reload loads the file, loaded being false if unchanged, mu being locked.
+func (obj *Watcher) reload() (event ReloadEvent, loaded bool) {
+	event.Path = obj.path
+	data, err := ioutil.ReadFile(obj.path)
+	var seen string
+	if err != nil {
+		seen = err.Error()
+	} else {
+		var hash = sha256.Sum256(data)
+		seen = hex.EncodeToString(hash[:])
+	}
+	if seen == obj.lastSeen {
+		return event, false
+	}
+	obj.lastSeen = seen
+
+	var conf *Config
+	if err == nil {
+		if conf, err = parseConfig(obj.path, data); err == nil {
+			err = obj.apply(conf)
+		}
+	}
+	if err != nil {
+		event.Err = fmt.Errorf("%w: %s: %v", ErrInvalidConfig, obj.path, err)
+		return event, true
+	}
+	obj.current, event.Config = conf, conf
+	return event, true
+}
+
+// apply validates then applies the configuration, nothing being applied if
+// invalid: the propagator is rolled back when the sampling rules are rejected.
+func (obj *Watcher) apply(conf *Config) error {
+	styles, err := conf.Propagation.styles(obj.conf.styles)
+	if err != nil {
+		return err
+	}
+	var (
+		headerKey  = conf.Propagation.headerKey(obj.localKey)
+		headerTags = conf.Propagation.headerTags(obj.localTags)
+	)
+	if _, err := tracecontext.New(tracecontext.WithHeaderKey(headerKey), tracecontext.WithHeaderTags(headerTags)); err != nil {
+		return fmt.Errorf("propagation: %w", err)
+	}
+
+	var (
+		previousKey, _, _ = tracecontext.Describe(obj.conf.datadog)
+		previousTags, _   = tracecontext.DescribeHeaderTags(obj.conf.datadog)
+	)
+	if err := tracecontext.Update(obj.conf.datadog, tracecontext.WithHeaderKey(headerKey), tracecontext.WithHeaderTags(headerTags)); err != nil {
+		return fmt.Errorf("propagation: %w", err)
+	}
+
+	// Sampling rules checked when applied
+	if obj.conf.sampler != nil {
+		var rules, sampleRate = conf.Sampling.samplingRules()
+		if err := obj.conf.sampler.SetRules(rules, sampleRate); err != nil {
+			// Rollback of the propagator
+			_ = tracecontext.Update(obj.conf.datadog, tracecontext.WithHeaderKey(previousKey), tracecontext.WithHeaderTags(previousTags))
+			return fmt.Errorf("sampling: %w", err)
+		}
+	}
+
+	obj.propagator.set(styles, obj.conf.datadog)
+	return nil
+}
@@ -0,0 +1,154 @@
+package fileconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/samplers/ddsampler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// test_copyFile copies the file of testdata to path.
+func test_copyFile(t *testing.T, name, path string) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(path, data, 0o600))
+}
+
+func test_newSampler(t *testing.T) *ddsampler.RulesSampler {
+	sampler, err := ddsampler.NewRulesSampler(ddsampler.WithSamplingRules([]ddsampler.SamplingRule{{Service: "local", SampleRate: 1}}))
+	require.NoError(t, err)
+	return sampler
+}
+
+func Test_New(t *testing.T) {
+	var (
+		path    = filepath.Join(t.TempDir(), "config.yaml")
+		sampler = test_newSampler(t)
+	)
+
+	// Missing or invalid file
+	_, err := New(path, WithPropagationStyles(StyleDatadog))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	test_copyFile(t, "duplicated_header_keys.yaml", path)
+	_, err = New(path, WithPropagationStyles(StyleDatadog))
+	assert.ErrorIs(t, err, ErrInvalidConfig)
+	_, err = New(path, WithCheckInterval(-time.Second))
+	assert.ErrorIs(t, err, ErrInvalidCheckInterval)
+
+	// Applied then checked periodically
+	test_copyFile(t, "config.yaml", path)
+	var events = make(chan ReloadEvent, 10)
+	watcher, err := New(path,
+		WithSampler(sampler),
+		WithPropagationStyles(StyleDatadog),
+		WithCheckInterval(10*time.Millisecond),
+		WithReloadHandler(func(event ReloadEvent) { events <- event }),
+	)
+	require.NoError(t, err)
+	defer watcher.Stop()
+	assert.NoError(t, (<-events).Err)
+	assert.Equal(t, "api", sampler.Rules()[0].Service)
+
+	test_copyFile(t, "config.json", path)
+	select {
+	case event := <-events:
+		assert.NoError(t, event.Err)
+		assert.Equal(t, []string{StyleTraceContext, StyleBaggage}, event.Config.Propagation.Styles)
+	case <-time.After(5 * time.Second):
+		t.Fatal("file change not loaded")
+	}
+
+	// Configuration kept once stopped
+	watcher.Stop()
+	test_copyFile(t, "config.yaml", path)
+	assert.Equal(t, []string{StyleTraceContext, StyleBaggage}, watcher.Config().Propagation.Styles)
+}
+
+func Test_Watcher_Reload(t *testing.T) {
+	var (
+		path    = filepath.Join(t.TempDir(), "config.yaml")
+		sampler = test_newSampler(t)
+		events  []ReloadEvent
+	)
+	datadog, err := tracecontext.New(tracecontext.WithHeaderTags(map[string]string{"X-Local": "local"}))
+	require.NoError(t, err)
+	conf, err := newConfig(
+		WithSampler(sampler),
+		WithPropagator(datadog),
+		WithPropagationStyles(StyleDatadog),
+		WithReloadHandler(func(event ReloadEvent) { events = append(events, event) }),
+	)
+	require.NoError(t, err)
+	var watcher = newWatcher(path, conf)
+	assert.Nil(t, watcher.Config())
+
+	// Applied
+	test_copyFile(t, "config.yaml", path)
+	require.NoError(t, watcher.Reload())
+	require.Len(t, events, 1)
+	assert.Equal(t, path, events[0].Path)
+	assert.Same(t, events[0].Config, watcher.Config())
+	var headerKey, _, _ = tracecontext.Describe(datadog)
+	assert.Equal(t, "x-trace-id", headerKey.TraceID)
+	assert.Equal(t, tracecontext.DefaultPriorityHeader, headerKey.SampledPriority)
+	var headerTags, _ = tracecontext.DescribeHeaderTags(datadog)
+	assert.Equal(t, "user.id", headerTags["X-User-Id"])
+	assert.NotContains(t, headerTags, "X-Local")
+	if rules := sampler.Rules(); assert.Len(t, rules, 3) {
+		assert.Equal(t, "GET /health", rules[0].Resource)
+		assert.Equal(t, 0.5, rules[2].SampleRate)
+	}
+	assert.ElementsMatch(t, append(datadog.Fields(), "traceparent", "tracestate"), watcher.Propagator().Fields())
+
+	// Unchanged content not reloaded
+	require.NoError(t, watcher.Reload())
+	assert.Len(t, events, 1)
+
+	// Invalid content rejected, last good configuration kept
+	var good = watcher.Config()
+	test_copyFile(t, "duplicated_header_keys.yaml", path)
+	assert.ErrorIs(t, watcher.Reload(), ErrInvalidConfig)
+	require.Len(t, events, 2)
+	assert.ErrorIs(t, events[1].Err, ErrInvalidConfig)
+	assert.Nil(t, events[1].Config)
+	assert.Same(t, good, watcher.Config())
+	headerKey, _, _ = tracecontext.Describe(datadog)
+	assert.Equal(t, "x-trace-id", headerKey.TraceID)
+
+	// Propagation rolled back when sampling rules are invalid
+	require.NoError(t, ioutil.WriteFile(path, []byte("sampling:\n  rules: [{sample_rate: 2}]\n"), 0o600))
+	assert.ErrorIs(t, watcher.Reload(), ErrInvalidConfig)
+	assert.Len(t, sampler.Rules(), 3)
+	headerKey, _, _ = tracecontext.Describe(datadog)
+	assert.Equal(t, "x-trace-id", headerKey.TraceID)
+	headerTags, _ = tracecontext.DescribeHeaderTags(datadog)
+	assert.Equal(t, "user.id", headerTags["X-User-Id"])
+	assert.NotContains(t, headerTags, "X-Local")
+
+	require.NoError(t, ioutil.WriteFile(path, []byte("propagation:\n  styles: [b3]\n"), 0o600))
+	assert.ErrorIs(t, watcher.Reload(), ErrInvalidConfig)
+	assert.Len(t, sampler.Rules(), 3)
+
+	// Missing file rejected
+	require.NoError(t, os.Remove(path))
+	assert.ErrorIs(t, watcher.Reload(), ErrInvalidConfig)
+	assert.Same(t, good, watcher.Config())
+
+	// Unset settings being the local ones
+	require.NoError(t, ioutil.WriteFile(path, []byte("{}"), 0o600))
+	require.NoError(t, watcher.Reload())
+	headerKey, _, _ = tracecontext.Describe(datadog)
+	assert.Equal(t, tracecontext.DefaultTraceIDHeader, headerKey.TraceID)
+	headerTags, _ = tracecontext.DescribeHeaderTags(datadog)
+	assert.Contains(t, headerTags, "X-Local")
+	if rules := sampler.Rules(); assert.Len(t, rules, 1) {
+		assert.Equal(t, "local", rules[0].Service)
+	}
+	assert.ElementsMatch(t, datadog.Fields(), watcher.Propagator().Fields())
+}
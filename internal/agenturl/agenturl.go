@@ -0,0 +1,82 @@
+// Package agenturl resolves the URL of the Datadog agent and the HTTP client
+// reaching it, over TCP or a Unix domain socket.
+package agenturl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/internal/agent.go (AgentURLFromEnv)
+
+const (
+	EnvURL  = "DD_TRACE_AGENT_URL"
+	EnvHost = "DD_AGENT_HOST"
+	EnvPort = "DD_TRACE_AGENT_PORT"
+
+	// DefaultURL specifies the URL of the agent trace API.
+	DefaultURL = "http://" + defaultHost + ":" + defaultPort
+
+	defaultHost = "localhost"
+	defaultPort = "8126"
+)
+
+var ErrUnsupportedScheme = errors.New("unsupported scheme")
+
+// FromEnv returns the agent URL of DD_TRACE_AGENT_URL environment variable, or
+// DD_AGENT_HOST and DD_TRACE_AGENT_PORT, or DefaultURL.
+func FromEnv() string {
+	if value := os.Getenv(EnvURL); value != "" {
+		return value
+	}
+	var host, port = os.Getenv(EnvHost), os.Getenv(EnvPort)
+	if host == "" {
+		host = defaultHost
+	}
+	if port == "" {
+		port = defaultPort
+	}
+	return "http://" + net.JoinHostPort(host, port)
+}
+
+// Resolve returns the base URL of agent requests and the HTTP client to use:
+// client if not nil, or a client with the timeout, dialing the socket of
+// "unix" URLs (ex: "unix:///var/run/datadog/apm.socket").
+func Resolve(rawURL string, client *http.Client, timeout time.Duration) (string, *http.Client, error) {
+	agentURL, err := url.Parse(rawURL)
+	if err != nil {
+		return "", nil, err
+	}
+
+	switch agentURL.Scheme {
+	case "http", "https":
+		if client == nil {
+			client = &http.Client{Timeout: timeout}
+		}
+		return strings.TrimSuffix(agentURL.String(), "/"), client, nil
+	case "unix":
+		// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/option.go (udsClient)
+		var socketPath = agentURL.Path
+		if client == nil {
+			var dialer = &net.Dialer{Timeout: timeout}
+			client = &http.Client{
+				Timeout: timeout,
+				Transport: &http.Transport{
+					DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+						return dialer.DialContext(ctx, "unix", socketPath)
+					},
+				},
+			}
+		}
+		return "http://UDS_" + strings.NewReplacer(":", "_", "/", "_", `\`, "_").Replace(socketPath), client, nil
+	default:
+		return "", nil, fmt.Errorf("%w %q", ErrUnsupportedScheme, agentURL.Scheme)
+	}
+}
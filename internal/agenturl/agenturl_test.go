@@ -0,0 +1,88 @@
+package agenturl
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setenv(t *testing.T, key, value string) {
+	prev, exists := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if exists {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func Test_FromEnv(t *testing.T) {
+	setenv(t, EnvURL, "")
+	setenv(t, EnvHost, "")
+	setenv(t, EnvPort, "")
+	assert.Equal(t, DefaultURL, FromEnv())
+
+	setenv(t, EnvHost, "agent")
+	assert.Equal(t, "http://agent:8126", FromEnv())
+	setenv(t, EnvPort, "1234")
+	assert.Equal(t, "http://agent:1234", FromEnv())
+
+	// URL takes precedence over host and port
+	setenv(t, EnvURL, "unix:///var/run/datadog/apm.socket")
+	assert.Equal(t, "unix:///var/run/datadog/apm.socket", FromEnv())
+}
+
+func Test_Resolve(t *testing.T) {
+	baseURL, client, err := Resolve("https://agent:8443/", nil, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "https://agent:8443", baseURL)
+	assert.Equal(t, time.Second, client.Timeout)
+
+	// Client kept if set
+	var custom = &http.Client{}
+	_, client, err = Resolve("http://agent:8126", custom, time.Second)
+	require.NoError(t, err)
+	assert.Same(t, custom, client)
+
+	// Invalid URL
+	_, _, err = Resolve("ftp://agent", nil, time.Second)
+	assert.ErrorIs(t, err, ErrUnsupportedScheme)
+	_, _, err = Resolve(":", nil, time.Second)
+	assert.Error(t, err)
+}
+
+func Test_Resolve_UnixSocket(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "apm.socket")
+	listener, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	var server = &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	})}
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(func() { _ = server.Close() })
+
+	baseURL, _, err := Resolve("unix:///var/run/datadog/apm.socket", nil, time.Second)
+	require.NoError(t, err)
+	assert.Equal(t, "http://UDS__var_run_datadog_apm.socket", baseURL)
+
+	// Requests sent over the socket
+	baseURL, client, err := Resolve("unix://"+path, nil, time.Second)
+	require.NoError(t, err)
+
+	resp, err := client.Get(baseURL + "/info")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "/info", string(body))
+}
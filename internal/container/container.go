@@ -0,0 +1,141 @@
+// Package container detects the container running the process, sent to the
+// Datadog agent to tag traces with container metadata.
+package container
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/internal/container_linux.go
+
+const (
+	// cgroupPath is the path to the cgroup file of the process
+	cgroupPath = "/proc/self/cgroup"
+	// cgroupMountPath is the mount point of the cgroup file system
+	cgroupMountPath = "/sys/fs/cgroup"
+	// cgroupV1BaseController is the controller used to identify the cgroup node in cgroup v1
+	cgroupV1BaseController = "memory"
+
+	uuidSource      = "[0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12}"
+	containerSource = "[0-9a-f]{64}"
+	taskSource      = "[0-9a-f]{32}-\\d+"
+)
+
+var (
+	// expLine matches a line of the cgroup file, v1 ("<id>:<controllers>:<path>")
+	// or v2 ("0::<path>")
+	expLine = regexp.MustCompile(`^\d+:[^:]*:(.+)$`)
+
+	// expContainerID matches a container ID at the end of a cgroup path: docker,
+	// kubernetes (UUID), or ECS task
+	expContainerID = regexp.MustCompile(fmt.Sprintf(`(%s|%s|%s)(?:\.scope)?$`, uuidSource, containerSource, taskSource))
+)
+
+var (
+	detectOnce  sync.Once
+	containerID string
+	entityID    string
+)
+
+// ID returns the ID of the container running the process, empty if not found.
+func ID() string {
+	detectOnce.Do(detect)
+	return containerID
+}
+
+// EntityID returns the container ID prefixed by "ci-", or the cgroup node inode
+// prefixed by "in-" when the container ID is not found, empty if none.
+func EntityID() string {
+	detectOnce.Do(detect)
+	return entityID
+}
+
+func detect() {
+	containerID = readContainerID(cgroupPath)
+	entityID = readEntityID(containerID, cgroupMountPath, cgroupPath)
+}
+
+// readEntityID returns the entity ID from the container ID or the cgroup node inode.
+func readEntityID(containerID, mountPath, cgroupPath string) string {
+	if containerID != "" {
+		return "ci-" + containerID
+	}
+	if isHostCgroupNamespace() {
+		// Inode of the host cgroup is not specific to the container
+		return ""
+	}
+	return readCgroupInode(mountPath, cgroupPath)
+}
+
+// readContainerID returns the container ID of a cgroup file, empty if not found.
+func readContainerID(filepath string) string {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	return parseContainerID(f)
+}
+
+// parseContainerID returns the first container ID found in cgroup file content.
+func parseContainerID(r io.Reader) string {
+	var scanner = bufio.NewScanner(r)
+	for scanner.Scan() {
+		var line = expLine.FindStringSubmatch(scanner.Text())
+		if len(line) != 2 {
+			continue
+		}
+		if parts := expContainerID.FindStringSubmatch(line[1]); len(parts) == 2 {
+			return parts[1]
+		}
+	}
+	return ""
+}
+
+// readCgroupInode returns the inode of the cgroup node of the process, from the
+// memory controller (cgroup v1) or the unified hierarchy (cgroup v2).
+func readCgroupInode(mountPath, filepath string) string {
+	f, err := os.Open(filepath)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	var nodePaths = parseCgroupNodePaths(f)
+	for _, controller := range []string{cgroupV1BaseController, ""} {
+		nodePath, ok := nodePaths[controller]
+		if !ok {
+			continue
+		}
+		if inode := inodeForPath(path.Join(mountPath, controller, nodePath)); inode != 0 {
+			return fmt.Sprintf("in-%d", inode)
+		}
+	}
+	return ""
+}
+
+// parseCgroupNodePaths returns the cgroup node paths of the memory controller
+// (cgroup v1) and of the unified hierarchy (cgroup v2, empty controller).
+func parseCgroupNodePaths(r io.Reader) map[string]string {
+	var (
+		paths   = map[string]string{}
+		scanner = bufio.NewScanner(r)
+	)
+	for scanner.Scan() {
+		var tokens = strings.SplitN(scanner.Text(), ":", 3)
+		if len(tokens) != 3 {
+			continue
+		}
+		if tokens[1] == cgroupV1BaseController || tokens[1] == "" {
+			paths[tokens[1]] = tokens[2]
+		}
+	}
+	return paths
+}
@@ -0,0 +1,33 @@
+//go:build linux
+// +build linux
+
+package container
+
+import (
+	"os"
+	"syscall"
+)
+
+const (
+	// cgroupNamespacePath is the cgroup namespace of the process
+	cgroupNamespacePath = "/proc/self/ns/cgroup"
+	// hostCgroupNamespaceInode is the inode of the host cgroup namespace
+	hostCgroupNamespaceInode = 0xEFFFFFFB
+)
+
+// inodeForPath returns the inode of a file, 0 on error.
+func inodeForPath(path string) uint64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	if stat, ok := info.Sys().(*syscall.Stat_t); ok {
+		return uint64(stat.Ino)
+	}
+	return 0
+}
+
+// isHostCgroupNamespace returns true if the process runs in the host cgroup namespace.
+func isHostCgroupNamespace() bool {
+	return inodeForPath(cgroupNamespacePath) == hostCgroupNamespaceInode
+}
@@ -0,0 +1,40 @@
+//go:build linux
+// +build linux
+
+package container
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readCgroupInode(t *testing.T) {
+	var mountPath = t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(mountPath, "memory", "user.slice", "user-1000.slice"), 0o700))
+	require.NoError(t, os.MkdirAll(filepath.Join(mountPath, "system.slice", "app.service"), 0o700))
+
+	// cgroup v1: memory controller node
+	var expected = fmt.Sprintf("in-%d", inodeForPath(filepath.Join(mountPath, "memory", "user.slice", "user-1000.slice")))
+	assert.Equal(t, expected, readCgroupInode(mountPath, "testdata/cgroup_v1_no_id"))
+
+	// cgroup v2: unified hierarchy node
+	var cgroupFile = filepath.Join(t.TempDir(), "cgroup")
+	require.NoError(t, ioutil.WriteFile(cgroupFile, []byte("0::/system.slice/app.service\n"), 0o600))
+	expected = fmt.Sprintf("in-%d", inodeForPath(filepath.Join(mountPath, "system.slice", "app.service")))
+	assert.Equal(t, expected, readCgroupInode(mountPath, cgroupFile))
+
+	// Node not found
+	assert.Equal(t, "", readCgroupInode(mountPath, "testdata/cgroup_v1"))
+	assert.Equal(t, "", readCgroupInode(mountPath, "testdata/not_found"))
+}
+
+func Test_inodeForPath(t *testing.T) {
+	assert.NotZero(t, inodeForPath(t.TempDir()))
+	assert.Zero(t, inodeForPath("testdata/not_found"))
+}
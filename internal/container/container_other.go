@@ -0,0 +1,13 @@
+//go:build !linux
+// +build !linux
+
+package container
+
+// inodeForPath returns 0, cgroups being specific to Linux.
+func inodeForPath(path string) uint64 {
+	return 0
+}
+
+func isHostCgroupNamespace() bool {
+	return false
+}
@@ -0,0 +1,50 @@
+package container
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_readContainerID(t *testing.T) {
+	for file, expected := range map[string]string{
+		"testdata/cgroup_v1":         "3726184226f5d3147c25fdeab5b60097e378e8a720503a5e19ecfdf29f869860",
+		"testdata/cgroup_v2":         "cde7c2bab394630a42d73dc610b9c57415dced996106665d427f6d0566594411",
+		"testdata/cgroup_kubernetes": "3e74d3fd9db4c9dd921ae05c2502fb984d0cde1b36e581b13f79c639da4518a1",
+		"testdata/cgroup_v1_no_id":   "",
+		"testdata/cgroup_v2_no_id":   "",
+		"testdata/not_found":         "",
+	} {
+		assert.Equal(t, expected, readContainerID(file), file)
+	}
+}
+
+func Test_parseContainerID(t *testing.T) {
+	for content, expected := range map[string]string{
+		// Kubernetes pod with UUID container
+		"1:name=systemd:/kubepods/pod3d274242/7b8952da-f50f-4e63-b1b8-2a5f6b1b6b8a": "7b8952da-f50f-4e63-b1b8-2a5f6b1b6b8a",
+		// ECS task
+		"9:perf_event:/ecs/5a081c13b9a74e3d87d1d3ec39d04fbd/5a081c13b9a74e3d87d1d3ec39d04fbd-4247594226": "5a081c13b9a74e3d87d1d3ec39d04fbd-4247594226",
+		// Systemd scope with crio prefix
+		"0::/kubepods.slice/crio-3726184226f5d3147c25fdeab5b60097e378e8a720503a5e19ecfdf29f869860.scope": "3726184226f5d3147c25fdeab5b60097e378e8a720503a5e19ecfdf29f869860",
+		// Invalid lines
+		"invalid\n1:cpu:/docker/3726184226": "",
+		"":                                  "",
+	} {
+		assert.Equal(t, expected, parseContainerID(strings.NewReader(content)), content)
+	}
+}
+
+func Test_parseCgroupNodePaths(t *testing.T) {
+	var content = "12:hugetlb:/\n11:memory:/user.slice\n0::/system.slice/app.service\ninvalid"
+	assert.Equal(t, map[string]string{
+		"memory": "/user.slice",
+		"":       "/system.slice/app.service",
+	}, parseCgroupNodePaths(strings.NewReader(content)))
+}
+
+func Test_readEntityID(t *testing.T) {
+	assert.Equal(t, "ci-abc", readEntityID("abc", "testdata", "testdata/cgroup_v1"))
+	assert.Equal(t, "", readEntityID("", "testdata", "testdata/not_found"))
+}
@@ -0,0 +1,92 @@
+package ddsketch
+
+import "math"
+
+// _____________________ OpenTelemetry histograms _____________________
+
+// Histogram buckets are converted to the value representing the bucket, with
+// the bucket count. The relative error of a quantile is then bounded by the
+// relative half width of the buckets, plus the relative accuracy of the sketch.
+// Ref https://opentelemetry.io/docs/specs/otel/metrics/data-model/#exponentialhistogram
+// Ref https://github.com/DataDog/opentelemetry-mapping-go/blob/main/pkg/otlp/metrics/exponential_histograms_translator.go
+
+// ExponentialHistogram is an OpenTelemetry exponential histogram data point.
+type ExponentialHistogram struct {
+	// Scale sets the bucket base: 2^(2^-Scale).
+	Scale int32
+	// ZeroCount is the count of values equal to zero.
+	ZeroCount uint64
+	// Positive are the buckets of positive values.
+	Positive ExponentialBuckets
+	// Negative are the buckets of negative values, by absolute value.
+	Negative ExponentialBuckets
+}
+
+// ExponentialBuckets are the buckets of an exponential histogram. The bucket of
+// index i holds the values in (base^i, base^(i+1)].
+type ExponentialBuckets struct {
+	// Offset is the index of the first bucket.
+	Offset int32
+	// Counts are the counts of the buckets from Offset.
+	Counts []uint64
+}
+
+// ForEachExponentialBucket calls fn for each non empty bucket of the histogram,
+// by increasing value, with the geometric middle of the bucket: its relative
+// distance to values of the bucket is at most sqrt(base)-1 (0.55% at scale 6).
+func ForEachExponentialBucket(h ExponentialHistogram, fn func(value float64, count uint64)) {
+	var base = math.Exp2(-float64(h.Scale)) // log2 of the bucket base
+	var value = func(index int) float64 {
+		return math.Exp2((float64(index) + 0.5) * base)
+	}
+
+	for i := len(h.Negative.Counts) - 1; i >= 0; i-- {
+		if count := h.Negative.Counts[i]; count > 0 {
+			fn(-value(int(h.Negative.Offset)+i), count)
+		}
+	}
+	if h.ZeroCount > 0 {
+		fn(0, h.ZeroCount)
+	}
+	for i, count := range h.Positive.Counts {
+		if count > 0 {
+			fn(value(int(h.Positive.Offset)+i), count)
+		}
+	}
+}
+
+// ForEachExplicitBucket calls fn for each non empty bucket of an explicit
+// bucket histogram, by increasing value. The bucket i holds the values in
+// (boundaries[i-1], boundaries[i]], the first and last ones being unbounded.
+// The value of a bucket is its geometric middle (arithmetic middle if it
+// contains zero), or its boundary for unbounded buckets, or the mean without
+// boundaries.
+func ForEachExplicitBucket(boundaries []float64, counts []uint64, sum float64, fn func(value float64, count uint64)) {
+	for i, count := range counts {
+		if count == 0 {
+			continue
+		}
+		switch {
+		case len(boundaries) == 0:
+			fn(sum/float64(count), count)
+		case i == 0:
+			fn(boundaries[0], count)
+		case i >= len(boundaries):
+			fn(boundaries[len(boundaries)-1], count)
+		default:
+			fn(middle(boundaries[i-1], boundaries[i]), count)
+		}
+	}
+}
+
+// middle returns the geometric middle of lower and upper with the same sign,
+// the arithmetic middle otherwise.
+func middle(lower, upper float64) float64 {
+	switch {
+	case lower > 0:
+		return math.Sqrt(lower * upper)
+	case upper < 0:
+		return -math.Sqrt(lower * upper)
+	}
+	return (lower + upper) / 2
+}
@@ -0,0 +1,149 @@
+package ddsketch
+
+import (
+	"math"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// test_bucket is a value and count passed to a bucket callback
+type test_bucket struct {
+	value float64
+	count uint64
+}
+
+func test_collect(buckets *[]test_bucket) func(value float64, count uint64) {
+	return func(value float64, count uint64) {
+		*buckets = append(*buckets, test_bucket{value, count})
+	}
+}
+
+func Test_ForEachExponentialBucket(t *testing.T) {
+	var buckets []test_bucket
+	ForEachExponentialBucket(ExponentialHistogram{
+		Scale:     0,
+		ZeroCount: 2,
+		Positive:  ExponentialBuckets{Offset: 1, Counts: []uint64{3, 0, 4}},
+		Negative:  ExponentialBuckets{Offset: -1, Counts: []uint64{5, 6}},
+	}, test_collect(&buckets))
+
+	// Base 2: bucket i holds (2^i, 2^(i+1)], with middle 2^(i+0.5)
+	var expected = []test_bucket{
+		{-math.Sqrt2, 6},
+		{-math.Sqrt2 / 2, 5},
+		{0, 2},
+		{2 * math.Sqrt2, 3},
+		{8 * math.Sqrt2, 4},
+	}
+	require.Len(t, buckets, len(expected))
+	for i, b := range expected {
+		assert.InDelta(t, b.value, buckets[i].value, 1e-12)
+		assert.Equal(t, b.count, buckets[i].count)
+	}
+
+	buckets = nil
+	ForEachExponentialBucket(ExponentialHistogram{}, test_collect(&buckets))
+	assert.Empty(t, buckets)
+}
+
+func Test_ForEachExponentialBucket_Scales(t *testing.T) {
+	for _, scale := range []int32{-3, -1, 0, 1, 3, 6, 10} {
+		var (
+			base    = math.Exp2(math.Exp2(-float64(scale)))
+			buckets []test_bucket
+		)
+		ForEachExponentialBucket(ExponentialHistogram{
+			Scale:    scale,
+			Positive: ExponentialBuckets{Offset: -7, Counts: []uint64{1, 1, 1}},
+		}, test_collect(&buckets))
+
+		require.Len(t, buckets, 3, "scale %d", scale)
+		for i, b := range buckets {
+			var (
+				lower = math.Pow(base, float64(-7+i))
+				upper = lower * base
+			)
+			assert.InEpsilon(t, math.Sqrt(lower*upper), b.value, 1e-9, "scale %d", scale)
+			// Relative distance to values of the bucket is at most sqrt(base)-1
+			assert.LessOrEqual(t, b.value/lower-1, math.Sqrt(base)-1+1e-9)
+			assert.LessOrEqual(t, upper/b.value-1, math.Sqrt(base)-1+1e-9)
+		}
+	}
+}
+
+func Test_ForEachExplicitBucket(t *testing.T) {
+	var buckets []test_bucket
+	ForEachExplicitBucket([]float64{-10, -1, 1, 10, 100}, []uint64{1, 2, 3, 0, 4, 5}, 0, test_collect(&buckets))
+	assert.Equal(t, []test_bucket{
+		{-10, 1},
+		{-math.Sqrt(10), 2},
+		{0, 3},
+		{math.Sqrt(1000), 4},
+		{100, 5},
+	}, buckets)
+
+	// Without boundaries, the mean
+	buckets = nil
+	ForEachExplicitBucket(nil, []uint64{4}, 10, test_collect(&buckets))
+	assert.Equal(t, []test_bucket{{2.5, 4}}, buckets)
+
+	buckets = nil
+	ForEachExplicitBucket([]float64{1}, []uint64{0, 0}, 0, test_collect(&buckets))
+	assert.Empty(t, buckets)
+}
+
+func Test_ForEachExponentialBucket_Accuracy(t *testing.T) {
+	var (
+		sketch = NewDefault()
+		values []float64
+		h      = ExponentialHistogram{
+			Scale:     6,
+			ZeroCount: 10,
+			Positive:  ExponentialBuckets{Offset: 300, Counts: make([]uint64, 400)},
+			Negative:  ExponentialBuckets{Offset: 200, Counts: make([]uint64, 100)},
+		}
+		base = math.Exp2(math.Exp2(-6))
+	)
+	for i := range h.Positive.Counts {
+		h.Positive.Counts[i] = uint64(i%7 + 1)
+	}
+	for i := range h.Negative.Counts {
+		h.Negative.Counts[i] = uint64(i%3 + 1)
+	}
+	// Exact values at the lower bound of their bucket, the worst case
+	for i, n := range h.Positive.Counts {
+		for j := uint64(0); j < n; j++ {
+			values = append(values, math.Pow(base, float64(300+i))*(1+1e-12))
+		}
+	}
+	for i, n := range h.Negative.Counts {
+		for j := uint64(0); j < n; j++ {
+			values = append(values, -math.Pow(base, float64(200+i))*(1+1e-12))
+		}
+	}
+	for i := 0; i < 10; i++ {
+		values = append(values, 0)
+	}
+	sort.Float64s(values)
+
+	ForEachExponentialBucket(h, func(value float64, count uint64) {
+		require.NoError(t, sketch.AddWithCount(value, float64(count)))
+	})
+	require.Equal(t, float64(len(values)), sketch.Count())
+
+	// Bucket half width plus sketch accuracy
+	var bound = (math.Sqrt(base) - 1) + DefaultRelativeAccuracy + 1e-6
+	for _, q := range []float64{0, 0.01, 0.1, 0.25, 0.5, 0.75, 0.9, 0.99, 1} {
+		expected := values[int(q*float64(len(values)-1))]
+		actual, err := sketch.Quantile(q)
+		require.NoError(t, err)
+		if expected == 0 {
+			assert.Equal(t, 0.0, actual, "q%v", q)
+			continue
+		}
+		assert.InEpsilon(t, expected, actual, bound, "q%v", q)
+	}
+}
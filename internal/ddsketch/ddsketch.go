@@ -0,0 +1,225 @@
+// Package ddsketch implements DDSketch, a quantile sketch with relative-error
+// guarantees, compatible with the Datadog sketches protobuf format.
+// Ref https://arxiv.org/abs/1908.10693
+// Ref https://github.com/DataDog/sketches-go
+package ddsketch
+
+import (
+	"errors"
+	"math"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/protowire"
+)
+
+const (
+	// DefaultRelativeAccuracy is the relative accuracy used by Datadog.
+	DefaultRelativeAccuracy = 0.01
+
+	// DefaultMaxNumBins is the maximum number of bins used by Datadog, enough
+	// to track values from 1ns to more than 100 years with 1% relative accuracy.
+	DefaultMaxNumBins = 2048
+)
+
+var (
+	ErrUntrackableValue         = errors.New("ddsketch: value is out of the range of trackable values")
+	ErrEmptySketch              = errors.New("ddsketch: no such element exists")
+	ErrInvalidQuantile          = errors.New("ddsketch: quantile must be between 0 and 1")
+	ErrIncompatibleMapping      = errors.New("ddsketch: cannot merge sketches with different mappings")
+	ErrUnsupportedInterpolation = errors.New("ddsketch: only NONE interpolation is supported")
+)
+
+// DDSketch is a quantile sketch with relative-error guarantees.
+type DDSketch struct {
+	mapping        *logarithmicMapping
+	positiveValues *denseStore
+	negativeValues *denseStore
+	zeroCount      float64
+}
+
+// New returns a sketch guaranteeing the relative accuracy of quantiles, with
+// an unbounded memory size.
+func New(relativeAccuracy float64) (*DDSketch, error) {
+	mapping, err := newLogarithmicMapping(relativeAccuracy)
+	if err != nil {
+		return nil, err
+	}
+	return &DDSketch{
+		mapping:        mapping,
+		positiveValues: newDenseStore(),
+		negativeValues: newDenseStore(),
+	}, nil
+}
+
+// NewCollapsingLowest returns a sketch guaranteeing the relative accuracy of
+// quantiles, using at most maxNumBins bins for positive values and as many for
+// negative values. Lowest bins are collapsed when the limit is reached, so that
+// the accuracy of the highest quantiles is kept.
+func NewCollapsingLowest(relativeAccuracy float64, maxNumBins int) (*DDSketch, error) {
+	mapping, err := newLogarithmicMapping(relativeAccuracy)
+	if err != nil {
+		return nil, err
+	}
+	return &DDSketch{
+		mapping:        mapping,
+		positiveValues: newCollapsingLowestDenseStore(maxNumBins),
+		negativeValues: newCollapsingLowestDenseStore(maxNumBins),
+	}, nil
+}
+
+// NewDefault returns a sketch with Datadog relative accuracy and bins limit.
+func NewDefault() *DDSketch {
+	// No error can happen since relative accuracy is valid
+	sketch, _ := NewCollapsingLowest(DefaultRelativeAccuracy, DefaultMaxNumBins)
+	return sketch
+}
+
+// RelativeAccuracy returns the relative accuracy guaranteed on quantiles.
+func (obj *DDSketch) RelativeAccuracy() float64 {
+	return obj.mapping.relativeAccuracy
+}
+
+// Add adds a value to the sketch.
+func (obj *DDSketch) Add(value float64) error {
+	return obj.AddWithCount(value, 1)
+}
+
+// AddWithCount adds a value to the sketch with a count (weight).
+func (obj *DDSketch) AddWithCount(value, count float64) error {
+	if value < -obj.mapping.maxIndexableValue || value > obj.mapping.maxIndexableValue {
+		return ErrUntrackableValue
+	}
+
+	switch {
+	case value > obj.mapping.minIndexableValue:
+		obj.positiveValues.add(obj.mapping.index(value), count)
+	case value < -obj.mapping.minIndexableValue:
+		obj.negativeValues.add(obj.mapping.index(-value), count)
+	default:
+		obj.zeroCount += count
+	}
+	return nil
+}
+
+// IsEmpty returns true if no value has been added.
+func (obj *DDSketch) IsEmpty() bool {
+	return obj.zeroCount == 0 && obj.positiveValues.isEmpty() && obj.negativeValues.isEmpty()
+}
+
+// Count returns the number of values added.
+func (obj *DDSketch) Count() float64 {
+	return obj.zeroCount + obj.positiveValues.totalCount() + obj.negativeValues.totalCount()
+}
+
+// Quantile returns the value at quantile q (between 0 and 1), within the
+// relative accuracy of the exact value.
+func (obj *DDSketch) Quantile(q float64) (float64, error) {
+	if q < 0 || q > 1 {
+		return 0, ErrInvalidQuantile
+	}
+	var count = obj.Count()
+	if count == 0 {
+		return 0, ErrEmptySketch
+	}
+
+	// Values are sorted: negative values, zeros, positive values
+	var (
+		rank          = q * (count - 1)
+		negativeCount = obj.negativeValues.totalCount()
+	)
+	switch {
+	case rank < negativeCount:
+		return -obj.mapping.value(obj.negativeValues.keyAtRank(negativeCount - 1 - rank)), nil
+	case rank < negativeCount+obj.zeroCount:
+		return 0, nil
+	}
+	return obj.mapping.value(obj.positiveValues.keyAtRank(rank - negativeCount - obj.zeroCount)), nil
+}
+
+// MergeWith adds the values of other sketch. Both sketches must use the same mapping.
+func (obj *DDSketch) MergeWith(other *DDSketch) error {
+	if !obj.mapping.equals(other.mapping) {
+		return ErrIncompatibleMapping
+	}
+	obj.positiveValues.mergeWith(other.positiveValues)
+	obj.negativeValues.mergeWith(other.negativeValues)
+	obj.zeroCount += other.zeroCount
+	return nil
+}
+
+// _____________________ Protobuf _____________________
+
+// Ref https://github.com/DataDog/sketches-go/blob/master/ddsketch/pb/ddsketch.proto (DDSketch)
+const (
+	fieldSketchMapping        = 1
+	fieldSketchPositiveValues = 2
+	fieldSketchNegativeValues = 3
+	fieldSketchZeroCount      = 4
+)
+
+// MarshalProto returns the protobuf serialization of the sketch.
+func (obj *DDSketch) MarshalProto() []byte {
+	var b []byte
+	b = appendMessage(b, fieldSketchMapping, obj.mapping.appendProto(nil))
+	b = appendMessage(b, fieldSketchPositiveValues, obj.positiveValues.appendProto(nil))
+	b = appendMessage(b, fieldSketchNegativeValues, obj.negativeValues.appendProto(nil))
+	if obj.zeroCount != 0 {
+		b = protowire.AppendDouble(b, fieldSketchZeroCount, obj.zeroCount)
+	}
+	return b
+}
+
+func appendMessage(b []byte, num int, message []byte) []byte {
+	return protowire.AppendBytes(protowire.AppendTag(b, num, protowire.BytesType), message)
+}
+
+// UnmarshalProto returns the sketch of a protobuf serialization. Stores are
+// unbounded to keep all serialized bins.
+func UnmarshalProto(b []byte) (*DDSketch, error) {
+	var (
+		sketch = &DDSketch{positiveValues: newDenseStore(), negativeValues: newDenseStore()}
+		err    error
+	)
+	err = consumeFields(b, func(num int, typ protowire.Type, value uint64, data []byte) error {
+		switch num {
+		case fieldSketchMapping:
+			sketch.mapping, err = unmarshalMappingProto(data)
+			return err
+		case fieldSketchPositiveValues:
+			return sketch.positiveValues.unmarshalProto(data)
+		case fieldSketchNegativeValues:
+			return sketch.negativeValues.unmarshalProto(data)
+		case fieldSketchZeroCount:
+			sketch.zeroCount = math.Float64frombits(value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if sketch.mapping == nil {
+		return nil, ErrInvalidGamma
+	}
+	return sketch, nil
+}
+
+// consumeFields calls fn for each field of a protobuf message.
+func consumeFields(b []byte, fn func(num int, typ protowire.Type, value uint64, data []byte) error) error {
+	for len(b) > 0 {
+		num, typ, n, err := protowire.ConsumeTag(b)
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+
+		value, data, n, err := protowire.ConsumeFieldValue(b, typ)
+		if err != nil {
+			return err
+		}
+		b = b[n:]
+
+		if err = fn(num, typ, value, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,207 @@
+package ddsketch
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/protowire"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_DDSketch_New(t *testing.T) {
+	_, err := New(0)
+	assert.ErrorIs(t, err, ErrInvalidRelativeAccuracy)
+
+	sketch := NewDefault()
+	assert.Equal(t, DefaultRelativeAccuracy, sketch.mapping.relativeAccuracy)
+	assert.True(t, sketch.IsEmpty())
+}
+
+func Test_DDSketch_Add(t *testing.T) {
+	var sketch = NewDefault()
+
+	assert.NoError(t, sketch.Add(0))
+	assert.NoError(t, sketch.Add(-1))
+	assert.NoError(t, sketch.AddWithCount(100, 2))
+	assert.False(t, sketch.IsEmpty())
+	assert.Equal(t, 4.0, sketch.Count())
+	assert.Equal(t, 1.0, sketch.zeroCount)
+	assert.Equal(t, 1.0, sketch.negativeValues.totalCount())
+	assert.Equal(t, 2.0, sketch.positiveValues.totalCount())
+
+	assert.ErrorIs(t, sketch.Add(math.Inf(1)), ErrUntrackableValue)
+	assert.ErrorIs(t, sketch.Add(-math.MaxFloat64), ErrUntrackableValue)
+}
+
+// test_decodeFields returns the fields of a protobuf message by number
+func test_decodeFields(t *testing.T, b []byte) map[int][]interface{} {
+	var fields = map[int][]interface{}{}
+	for len(b) > 0 {
+		num, typ, n, err := protowire.ConsumeTag(b)
+		require.NoError(t, err)
+		b = b[n:]
+		value, data, n, err := protowire.ConsumeFieldValue(b, typ)
+		require.NoError(t, err)
+		b = b[n:]
+		if typ == protowire.BytesType {
+			fields[num] = append(fields[num], data)
+		} else {
+			fields[num] = append(fields[num], value)
+		}
+	}
+	return fields
+}
+
+func Test_DDSketch_MarshalProto(t *testing.T) {
+	var sketch = NewDefault()
+	assert.NoError(t, sketch.Add(0))
+	assert.NoError(t, sketch.AddWithCount(1, 2))
+	assert.NoError(t, sketch.Add(sketch.mapping.gamma))
+
+	var fields = test_decodeFields(t, sketch.MarshalProto())
+
+	// Check mapping
+	mapping := test_decodeFields(t, fields[fieldSketchMapping][0].([]byte))
+	assert.Equal(t, sketch.mapping.gamma, math.Float64frombits(mapping[fieldMappingGamma][0].(uint64)))
+	assert.NotContains(t, mapping, fieldMappingIndexOffset)
+	assert.NotContains(t, mapping, fieldMappingInterpolation)
+
+	// Check positive values: contiguous bins 0 and 1
+	positive := test_decodeFields(t, fields[fieldSketchPositiveValues][0].([]byte))
+	assert.Equal(t, uint64(0), positive[fieldStoreContiguousBinIndexOffset][0])
+	bins := positive[fieldStoreContiguousBinCounts][0].([]byte)
+	if assert.Len(t, bins, 16) {
+		first, _, _ := protowire.ConsumeFixed64(bins)
+		second, _, _ := protowire.ConsumeFixed64(bins[8:])
+		assert.Equal(t, 2.0, math.Float64frombits(first))
+		assert.Equal(t, 1.0, math.Float64frombits(second))
+	}
+
+	// Check empty negative values and zero count
+	assert.Equal(t, []byte{}, fields[fieldSketchNegativeValues][0])
+	assert.Equal(t, 1.0, math.Float64frombits(fields[fieldSketchZeroCount][0].(uint64)))
+}
+
+func Test_DDSketch_Quantile(t *testing.T) {
+	var sketch = NewDefault()
+
+	_, err := sketch.Quantile(0.5)
+	assert.ErrorIs(t, err, ErrEmptySketch)
+
+	for _, v := range []float64{-10, 0, 0, 10, 20} {
+		assert.NoError(t, sketch.Add(v))
+	}
+	_, err = sketch.Quantile(1.1)
+	assert.ErrorIs(t, err, ErrInvalidQuantile)
+	_, err = sketch.Quantile(-0.1)
+	assert.ErrorIs(t, err, ErrInvalidQuantile)
+
+	for q, expected := range map[float64]float64{0: -10, 0.25: 0, 0.5: 0, 0.75: 10, 1: 20} {
+		value, err := sketch.Quantile(q)
+		if assert.NoError(t, err) {
+			assert.InEpsilon(t, expected+1e-300, value+1e-300, DefaultRelativeAccuracy+1e-9, q)
+		}
+	}
+}
+
+func Test_DDSketch_MergeWith(t *testing.T) {
+	var sketch, other = NewDefault(), NewDefault()
+	assert.NoError(t, sketch.Add(1))
+	assert.NoError(t, other.Add(-1))
+	assert.NoError(t, other.Add(0))
+	assert.NoError(t, other.Add(100))
+
+	assert.NoError(t, sketch.MergeWith(other))
+	assert.Equal(t, 4.0, sketch.Count())
+	maxValue, _ := sketch.Quantile(1)
+	assert.InEpsilon(t, 100, maxValue, DefaultRelativeAccuracy+1e-9)
+	minValue, _ := sketch.Quantile(0)
+	assert.InEpsilon(t, -1, minValue, DefaultRelativeAccuracy+1e-9)
+
+	incompatible, _ := New(0.05)
+	assert.ErrorIs(t, sketch.MergeWith(incompatible), ErrIncompatibleMapping)
+}
+
+func Test_DDSketch_UnmarshalProto(t *testing.T) {
+	var sketch = NewDefault()
+	for _, v := range []float64{-5, 0, 0.001, 1, 1e9} {
+		assert.NoError(t, sketch.Add(v))
+	}
+
+	decoded, err := UnmarshalProto(sketch.MarshalProto())
+	require.NoError(t, err)
+	assert.True(t, sketch.mapping.equals(decoded.mapping))
+	assert.Equal(t, sketch.Count(), decoded.Count())
+	for _, q := range []float64{0, 0.25, 0.5, 0.75, 1} {
+		expected, _ := sketch.Quantile(q)
+		value, _ := decoded.Quantile(q)
+		assert.Equal(t, expected, value, q)
+	}
+
+	// Check invalid payloads
+	_, err = UnmarshalProto(nil)
+	assert.ErrorIs(t, err, ErrInvalidGamma)
+	_, err = UnmarshalProto([]byte{0x0a, 0x05})
+	assert.ErrorIs(t, err, protowire.ErrTruncated)
+}
+
+// Test_DDSketch_Accuracy validates quantiles against exact values of several
+// distributions, as done by sketches-go.
+func Test_DDSketch_Accuracy(t *testing.T) {
+	var (
+		random        = rand.New(rand.NewSource(42))
+		quantiles     = []float64{0, 0.01, 0.1, 0.25, 0.5, 0.75, 0.9, 0.95, 0.99, 0.999, 1}
+		distributions = map[string]func() float64{
+			"uniform":     func() float64 { return random.Float64() * 1000 },
+			"exponential": func() float64 { return random.ExpFloat64() * 1e6 },
+			"lognormal":   func() float64 { return math.Exp(random.NormFloat64() * 3) },
+			"normal":      func() float64 { return random.NormFloat64() * 100 },
+			"integers":    func() float64 { return float64(random.Intn(10)) },
+		}
+	)
+
+	for name, generate := range distributions {
+		for _, relativeAccuracy := range []float64{0.001, 0.01, 0.05} {
+			sketch, err := New(relativeAccuracy)
+			require.NoError(t, err)
+
+			// Values count so that ranks of quantiles are integers
+			var values = make([]float64, 10001)
+			for i := range values {
+				values[i] = generate()
+				require.NoError(t, sketch.Add(values[i]))
+			}
+			sort.Float64s(values)
+
+			for _, q := range quantiles {
+				var expected = values[int(math.Round(q*float64(len(values)-1)))]
+				value, err := sketch.Quantile(q)
+				require.NoError(t, err)
+				assert.LessOrEqual(t, math.Abs(value-expected), relativeAccuracy*math.Abs(expected)+1e-12,
+					"%s accuracy=%v q=%v", name, relativeAccuracy, q)
+			}
+		}
+	}
+}
+
+func Test_DDSketch_Accuracy_Collapsing(t *testing.T) {
+	sketch, err := NewCollapsingLowest(DefaultRelativeAccuracy, 100)
+	require.NoError(t, err)
+
+	// Values over a range larger than 100 bins: lowest quantiles are collapsed
+	for v := 1.0; v < 1e6; v *= 1.01 {
+		require.NoError(t, sketch.Add(v))
+	}
+	assert.LessOrEqual(t, sketch.positiveValues.maxIndex-sketch.positiveValues.minIndex+1, 100)
+
+	// Check highest quantiles still accurate
+	maxValue, err := sketch.Quantile(1)
+	assert.NoError(t, err)
+	assert.InEpsilon(t, 1e6, maxValue, 2*DefaultRelativeAccuracy)
+	minValue, err := sketch.Quantile(0)
+	assert.NoError(t, err)
+	assert.Greater(t, minValue, 1.0)
+}
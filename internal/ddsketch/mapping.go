@@ -0,0 +1,139 @@
+package ddsketch
+
+import (
+	"errors"
+	"math"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/protowire"
+)
+
+const (
+	// minNormalFloat64 is the smallest positive normal float64 value
+	minNormalFloat64 = 2.2250738585072014e-308
+	// expOverflow is the highest value accepted by math.Exp
+	expOverflow = 7.094361393031e+02
+)
+
+var (
+	ErrInvalidRelativeAccuracy = errors.New("ddsketch: relative accuracy must be between 0 and 1")
+	ErrInvalidGamma            = errors.New("ddsketch: gamma must be greater than 1")
+)
+
+// logarithmicMapping maps values to bin indexes so that values of a bin are
+// within the relative accuracy of the bin representative value.
+// Ref https://github.com/DataDog/sketches-go/blob/master/ddsketch/mapping/logarithmic_mapping.go
+type logarithmicMapping struct {
+	relativeAccuracy  float64
+	gamma             float64
+	multiplier        float64
+	indexOffset       float64
+	minIndexableValue float64
+	maxIndexableValue float64
+}
+
+func newLogarithmicMapping(relativeAccuracy float64) (*logarithmicMapping, error) {
+	if relativeAccuracy <= 0 || relativeAccuracy >= 1 {
+		return nil, ErrInvalidRelativeAccuracy
+	}
+
+	mapping, err := newLogarithmicMappingWithGamma((1+relativeAccuracy)/(1-relativeAccuracy), 0)
+	if err != nil {
+		return nil, err
+	}
+	// Avoid rounding error of the value computed from gamma
+	mapping.relativeAccuracy = relativeAccuracy
+	return mapping, nil
+}
+
+// newLogarithmicMappingWithGamma returns the mapping of a serialized sketch.
+func newLogarithmicMappingWithGamma(gamma, indexOffset float64) (*logarithmicMapping, error) {
+	if !(gamma > 1) || math.IsInf(gamma, 1) {
+		return nil, ErrInvalidGamma
+	}
+
+	var relativeAccuracy = (gamma - 1) / (gamma + 1)
+	var mapping = &logarithmicMapping{
+		relativeAccuracy: relativeAccuracy,
+		gamma:            gamma,
+		multiplier:       1 / math.Log(gamma),
+		indexOffset:      indexOffset,
+	}
+	mapping.minIndexableValue = math.Max(
+		math.Exp((math.MinInt32-mapping.indexOffset)/mapping.multiplier+1),
+		minNormalFloat64*gamma,
+	)
+	mapping.maxIndexableValue = math.Min(
+		math.Exp((math.MaxInt32-mapping.indexOffset)/mapping.multiplier-1),
+		// So that math.Exp does not overflow
+		math.Exp(expOverflow)/(1+relativeAccuracy),
+	)
+	return mapping, nil
+}
+
+// index returns the bin index of a positive value.
+func (obj *logarithmicMapping) index(value float64) int {
+	var index = math.Log(value)*obj.multiplier + obj.indexOffset
+	if index >= 0 {
+		return int(index)
+	}
+	return int(index) - 1 // faster than math.Floor
+}
+
+// value returns the representative value of a bin, which is within the relative
+// accuracy of any value of the bin.
+func (obj *logarithmicMapping) value(index int) float64 {
+	return obj.lowerBound(index) * (1 + obj.relativeAccuracy)
+}
+
+// lowerBound returns the lowest value of a bin.
+func (obj *logarithmicMapping) lowerBound(index int) float64 {
+	return math.Exp((float64(index) - obj.indexOffset) / obj.multiplier)
+}
+
+// _____________________ Protobuf _____________________
+
+// Ref https://github.com/DataDog/sketches-go/blob/master/ddsketch/pb/ddsketch.proto (IndexMapping)
+const (
+	fieldMappingGamma         = 1
+	fieldMappingIndexOffset   = 2
+	fieldMappingInterpolation = 3
+)
+
+// equals returns true if both mappings give same indexes.
+func (obj *logarithmicMapping) equals(other *logarithmicMapping) bool {
+	const epsilon = 1e-12
+	return math.Abs(obj.gamma-other.gamma) <= epsilon*obj.gamma && math.Abs(obj.indexOffset-other.indexOffset) <= epsilon
+}
+
+func (obj *logarithmicMapping) appendProto(b []byte) []byte {
+	b = protowire.AppendDouble(b, fieldMappingGamma, obj.gamma)
+	if obj.indexOffset != 0 {
+		b = protowire.AppendDouble(b, fieldMappingIndexOffset, obj.indexOffset)
+	}
+	// Interpolation NONE (0) is the default value, not encoded
+	return b
+}
+
+// Ref https://github.com/DataDog/sketches-go/blob/master/ddsketch/pb/ddsketch.proto (IndexMapping.Interpolation)
+const interpolationNone = 0
+
+func unmarshalMappingProto(b []byte) (*logarithmicMapping, error) {
+	var gamma, indexOffset float64
+	err := consumeFields(b, func(num int, typ protowire.Type, value uint64, data []byte) error {
+		switch num {
+		case fieldMappingGamma:
+			gamma = math.Float64frombits(value)
+		case fieldMappingIndexOffset:
+			indexOffset = math.Float64frombits(value)
+		case fieldMappingInterpolation:
+			if value != interpolationNone {
+				return ErrUnsupportedInterpolation
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newLogarithmicMappingWithGamma(gamma, indexOffset)
+}
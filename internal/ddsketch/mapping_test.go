@@ -0,0 +1,78 @@
+package ddsketch
+
+import (
+	"math"
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/protowire"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_newLogarithmicMapping(t *testing.T) {
+	for _, v := range []float64{0, -0.1, 1, 2} {
+		_, err := newLogarithmicMapping(v)
+		assert.ErrorIs(t, err, ErrInvalidRelativeAccuracy, v)
+	}
+
+	mapping, err := newLogarithmicMapping(0.01)
+	if assert.NoError(t, err) {
+		assert.InDelta(t, 1.0202020202, mapping.gamma, 1e-9)
+		assert.Less(t, mapping.minIndexableValue, 1e-300)
+		assert.Greater(t, mapping.maxIndexableValue, 1e300)
+	}
+}
+
+func Test_logarithmicMapping_Accuracy(t *testing.T) {
+	for _, relativeAccuracy := range []float64{0.001, 0.01, 0.05} {
+		mapping, err := newLogarithmicMapping(relativeAccuracy)
+		if !assert.NoError(t, err) {
+			continue
+		}
+
+		for value := mapping.minIndexableValue; value < mapping.maxIndexableValue; value *= 1.7 {
+			var (
+				index          = mapping.index(value)
+				representative = mapping.value(index)
+			)
+			assert.LessOrEqual(t, math.Abs(representative-value)/value, relativeAccuracy+1e-12, value)
+			assert.LessOrEqual(t, mapping.lowerBound(index), value*(1+1e-12), value)
+			assert.Greater(t, mapping.lowerBound(index+1), value*(1-1e-12), value)
+		}
+	}
+}
+
+func Test_newLogarithmicMappingWithGamma(t *testing.T) {
+	for _, v := range []float64{0, 1, math.NaN(), math.Inf(1)} {
+		_, err := newLogarithmicMappingWithGamma(v, 0)
+		assert.ErrorIs(t, err, ErrInvalidGamma, v)
+	}
+
+	mapping, err := newLogarithmicMappingWithGamma(1.0202020202020203, 2)
+	if assert.NoError(t, err) {
+		assert.InDelta(t, 0.01, mapping.relativeAccuracy, 1e-12)
+		assert.Equal(t, 2.0, mapping.indexOffset)
+
+		reference, _ := newLogarithmicMapping(0.01)
+		assert.False(t, mapping.equals(reference))
+		assert.Equal(t, reference.index(100)+2, mapping.index(100))
+	}
+}
+
+func Test_logarithmicMapping_Proto(t *testing.T) {
+	mapping, err := newLogarithmicMappingWithGamma(1.02, 3)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	decoded, err := unmarshalMappingProto(mapping.appendProto(nil))
+	if assert.NoError(t, err) {
+		assert.True(t, mapping.equals(decoded))
+	}
+
+	// Check unsupported interpolation
+	var b = protowire.AppendDouble(nil, fieldMappingGamma, 1.02)
+	b = protowire.AppendTag(b, fieldMappingInterpolation, protowire.VarintType)
+	b = protowire.AppendVarint(b, 1)
+	_, err = unmarshalMappingProto(b)
+	assert.ErrorIs(t, err, ErrUnsupportedInterpolation)
+}
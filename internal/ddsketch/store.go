@@ -0,0 +1,232 @@
+package ddsketch
+
+import (
+	"math"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/protowire"
+)
+
+// growthIncrement is the granularity of bins allocation
+const growthIncrement = 64
+
+// denseStore stores bin counts in a contiguous array, growing as needed.
+// When maxNumBins is set, lowest bins are collapsed to keep at most maxNumBins
+// bins: the accuracy of the highest quantiles is preserved.
+// Ref https://github.com/DataDog/sketches-go/blob/master/ddsketch/store/dense_store.go
+// Ref https://github.com/DataDog/sketches-go/blob/master/ddsketch/store/collapsing_lowest_dense_store.go
+type denseStore struct {
+	bins       []float64
+	offset     int // index of bins[0]
+	minIndex   int
+	maxIndex   int
+	count      float64
+	maxNumBins int // 0 means unbounded
+}
+
+func newDenseStore() *denseStore {
+	return &denseStore{}
+}
+
+func newCollapsingLowestDenseStore(maxNumBins int) *denseStore {
+	return &denseStore{maxNumBins: maxNumBins}
+}
+
+func (obj *denseStore) isEmpty() bool {
+	return obj.count == 0
+}
+
+func (obj *denseStore) totalCount() float64 {
+	return obj.count
+}
+
+// add adds count to the bin of index.
+func (obj *denseStore) add(index int, count float64) {
+	if count == 0 {
+		return
+	}
+	if obj.maxNumBins > 0 && !obj.isEmpty() {
+		index = obj.collapse(index)
+	}
+	obj.extendRange(index, index)
+	obj.bins[index-obj.offset] += count
+	obj.count += count
+}
+
+// collapse merges lowest bins so that index fits in maxNumBins bins, and returns
+// the index where the count must be added.
+func (obj *denseStore) collapse(index int) int {
+	var maxIndex = obj.maxIndex
+	if index > maxIndex {
+		maxIndex = index
+	}
+	var lowest = maxIndex - obj.maxNumBins + 1
+	if index < lowest {
+		return lowest
+	}
+	if obj.minIndex >= lowest {
+		return index
+	}
+
+	// Move counts of bins lower than lowest in lowest bin
+	var collapsed float64
+	for i := obj.minIndex; i <= obj.maxIndex && i < lowest; i++ {
+		collapsed += obj.bins[i-obj.offset]
+		obj.bins[i-obj.offset] = 0
+	}
+	if lowest > obj.maxIndex {
+		// All bins collapsed
+		obj.bins, obj.count = nil, 0
+	} else {
+		obj.minIndex = lowest
+		obj.count -= collapsed
+	}
+	obj.extendRange(lowest, lowest)
+	obj.bins[lowest-obj.offset] += collapsed
+	obj.count += collapsed
+	return index
+}
+
+// extendRange makes sure bins between minIndex and maxIndex are allocated.
+func (obj *denseStore) extendRange(minIndex, maxIndex int) {
+	if len(obj.bins) == 0 {
+		obj.bins = make([]float64, roundUpLength(maxIndex-minIndex+1))
+		obj.offset, obj.minIndex, obj.maxIndex = minIndex, minIndex, maxIndex
+		return
+	}
+
+	if minIndex > obj.minIndex {
+		minIndex = obj.minIndex
+	}
+	if maxIndex < obj.maxIndex {
+		maxIndex = obj.maxIndex
+	}
+	if minIndex < obj.offset || maxIndex >= obj.offset+len(obj.bins) {
+		var bins = make([]float64, roundUpLength(maxIndex-minIndex+1))
+		copy(bins[obj.minIndex-minIndex:], obj.bins[obj.minIndex-obj.offset:obj.maxIndex-obj.offset+1])
+		obj.bins, obj.offset = bins, minIndex
+	}
+	obj.minIndex, obj.maxIndex = minIndex, maxIndex
+}
+
+func roundUpLength(length int) int {
+	return int(math.Ceil(float64(length)/growthIncrement)) * growthIncrement
+}
+
+// forEach calls fn for each non empty bin, by increasing index.
+func (obj *denseStore) forEach(fn func(index int, count float64)) {
+	if obj.isEmpty() {
+		return
+	}
+	for i := obj.minIndex; i <= obj.maxIndex; i++ {
+		if count := obj.bins[i-obj.offset]; count != 0 {
+			fn(i, count)
+		}
+	}
+}
+
+// keyAtRank returns the index of the bin containing the value of rank (0 based).
+func (obj *denseStore) keyAtRank(rank float64) int {
+	if rank < 0 {
+		rank = 0
+	}
+	var n float64
+	for i := obj.minIndex; i <= obj.maxIndex; i++ {
+		n += obj.bins[i-obj.offset]
+		if n > rank {
+			return i
+		}
+	}
+	return obj.maxIndex
+}
+
+// mergeWith adds the counts of other store.
+func (obj *denseStore) mergeWith(other *denseStore) {
+	other.forEach(obj.add)
+}
+
+// _____________________ Protobuf _____________________
+
+// Ref https://github.com/DataDog/sketches-go/blob/master/ddsketch/pb/ddsketch.proto (Store)
+const (
+	fieldStoreBinCounts                = 1
+	fieldStoreContiguousBinCounts      = 2
+	fieldStoreContiguousBinIndexOffset = 3
+
+	// Map entry of binCounts
+	fieldMapEntryKey   = 1
+	fieldMapEntryValue = 2
+)
+
+func (obj *denseStore) appendProto(b []byte) []byte {
+	if obj.isEmpty() {
+		return b
+	}
+
+	// Packed repeated double
+	var size = obj.maxIndex - obj.minIndex + 1
+	b = protowire.AppendTag(b, fieldStoreContiguousBinCounts, protowire.BytesType)
+	b = protowire.AppendVarint(b, uint64(size*8))
+	for _, v := range obj.bins[obj.minIndex-obj.offset : obj.maxIndex-obj.offset+1] {
+		b = protowire.AppendFixed64(b, math.Float64bits(v))
+	}
+
+	// sint32
+	b = protowire.AppendTag(b, fieldStoreContiguousBinIndexOffset, protowire.VarintType)
+	return protowire.AppendVarint(b, protowire.EncodeZigZag(int64(obj.minIndex)))
+}
+
+// unmarshalProto adds the counts of a serialized store, in sparse or contiguous format.
+func (obj *denseStore) unmarshalProto(b []byte) error {
+	var (
+		contiguous []float64
+		offset     int
+	)
+	err := consumeFields(b, func(num int, typ protowire.Type, value uint64, data []byte) error {
+		switch num {
+		case fieldStoreBinCounts:
+			index, count, err := unmarshalMapEntryProto(data)
+			if err != nil {
+				return err
+			}
+			obj.add(index, count)
+		case fieldStoreContiguousBinCounts:
+			if typ == protowire.Fixed64Type {
+				// Not packed
+				contiguous = append(contiguous, math.Float64frombits(value))
+				return nil
+			}
+			for len(data) > 0 {
+				v, n, err := protowire.ConsumeFixed64(data)
+				if err != nil {
+					return err
+				}
+				contiguous = append(contiguous, math.Float64frombits(v))
+				data = data[n:]
+			}
+		case fieldStoreContiguousBinIndexOffset:
+			offset = int(protowire.DecodeZigZag(value))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, count := range contiguous {
+		obj.add(offset+i, count)
+	}
+	return nil
+}
+
+func unmarshalMapEntryProto(b []byte) (index int, count float64, err error) {
+	err = consumeFields(b, func(num int, typ protowire.Type, value uint64, data []byte) error {
+		switch num {
+		case fieldMapEntryKey:
+			index = int(protowire.DecodeZigZag(value))
+		case fieldMapEntryValue:
+			count = math.Float64frombits(value)
+		}
+		return nil
+	})
+	return
+}
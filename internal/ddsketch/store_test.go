@@ -0,0 +1,147 @@
+package ddsketch
+
+import (
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/protowire"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_denseStore_add(t *testing.T) {
+	var store = newDenseStore()
+	assert.True(t, store.isEmpty())
+
+	// Check zero count ignored
+	store.add(10, 0)
+	assert.True(t, store.isEmpty())
+
+	store.add(10, 1)
+	store.add(10, 2)
+	assert.Equal(t, 3.0, store.totalCount())
+	assert.Equal(t, 10, store.minIndex)
+	assert.Equal(t, 10, store.maxIndex)
+	assert.Len(t, store.bins, growthIncrement)
+
+	// Check growth on lower and higher indexes
+	store.add(-100, 1)
+	store.add(200, 1)
+	assert.Equal(t, 5.0, store.totalCount())
+	assert.Equal(t, -100, store.minIndex)
+	assert.Equal(t, 200, store.maxIndex)
+	assert.Equal(t, 3.0, store.bins[10-store.offset])
+	assert.Equal(t, 1.0, store.bins[-100-store.offset])
+	assert.Equal(t, 1.0, store.bins[200-store.offset])
+	assert.Len(t, store.bins, 320)
+}
+
+func Test_roundUpLength(t *testing.T) {
+	assert.Equal(t, 64, roundUpLength(1))
+	assert.Equal(t, 64, roundUpLength(64))
+	assert.Equal(t, 128, roundUpLength(65))
+}
+
+func Test_denseStore_Collapsing(t *testing.T) {
+	var store = newCollapsingLowestDenseStore(10)
+
+	for i := 0; i < 10; i++ {
+		store.add(i, 1)
+	}
+	assert.Equal(t, 0, store.minIndex)
+	assert.Equal(t, 9, store.maxIndex)
+
+	// Check lowest bins collapsed when a higher index is added
+	store.add(12, 1)
+	assert.Equal(t, 3, store.minIndex)
+	assert.Equal(t, 12, store.maxIndex)
+	assert.Equal(t, 4.0, store.bins[3-store.offset])
+	assert.Equal(t, 11.0, store.totalCount())
+
+	// Check lower index added in lowest bin
+	store.add(-5, 2)
+	assert.Equal(t, 3, store.minIndex)
+	assert.Equal(t, 6.0, store.bins[3-store.offset])
+	assert.Equal(t, 13.0, store.totalCount())
+
+	// Check all bins collapsed
+	store.add(1000, 1)
+	assert.Equal(t, 991, store.minIndex)
+	assert.Equal(t, 1000, store.maxIndex)
+	assert.Equal(t, 13.0, store.bins[991-store.offset])
+	assert.Equal(t, 14.0, store.totalCount())
+	assert.LessOrEqual(t, len(store.bins), 10+growthIncrement)
+}
+
+func Test_denseStore_forEach(t *testing.T) {
+	var (
+		store   = newDenseStore()
+		indexes []int
+		counts  []float64
+	)
+	store.forEach(func(index int, count float64) { t.Fail() })
+
+	store.add(5, 1)
+	store.add(-2, 3)
+	store.forEach(func(index int, count float64) {
+		indexes = append(indexes, index)
+		counts = append(counts, count)
+	})
+	assert.Equal(t, []int{-2, 5}, indexes)
+	assert.Equal(t, []float64{3, 1}, counts)
+}
+
+func Test_denseStore_keyAtRank(t *testing.T) {
+	var store = newDenseStore()
+	store.add(1, 2)
+	store.add(3, 1)
+
+	assert.Equal(t, 1, store.keyAtRank(-1))
+	assert.Equal(t, 1, store.keyAtRank(0))
+	assert.Equal(t, 1, store.keyAtRank(1.5))
+	assert.Equal(t, 3, store.keyAtRank(2))
+	assert.Equal(t, 3, store.keyAtRank(10))
+}
+
+func Test_denseStore_mergeWith(t *testing.T) {
+	var store, other = newDenseStore(), newDenseStore()
+	store.add(1, 1)
+	other.add(1, 2)
+	other.add(100, 1)
+
+	store.mergeWith(other)
+	assert.Equal(t, 4.0, store.totalCount())
+	assert.Equal(t, 3.0, store.bins[1-store.offset])
+	assert.Equal(t, 1.0, store.bins[100-store.offset])
+}
+
+func Test_denseStore_Proto(t *testing.T) {
+	var store = newDenseStore()
+	store.add(-3, 1)
+	store.add(2, 4)
+
+	var decoded = newDenseStore()
+	assert.NoError(t, decoded.unmarshalProto(store.appendProto(nil)))
+	assert.Equal(t, store.totalCount(), decoded.totalCount())
+	assert.Equal(t, -3, decoded.minIndex)
+	assert.Equal(t, 2, decoded.maxIndex)
+	assert.Equal(t, 1.0, decoded.bins[-3-decoded.offset])
+	assert.Equal(t, 4.0, decoded.bins[2-decoded.offset])
+
+	// Check sparse and not packed formats
+	var b []byte
+	entry := protowire.AppendTag(nil, fieldMapEntryKey, protowire.VarintType)
+	entry = protowire.AppendVarint(entry, protowire.EncodeZigZag(-7))
+	entry = protowire.AppendDouble(entry, fieldMapEntryValue, 2)
+	b = protowire.AppendBytes(protowire.AppendTag(b, fieldStoreBinCounts, protowire.BytesType), entry)
+	b = protowire.AppendDouble(b, fieldStoreContiguousBinCounts, 3)
+	b = protowire.AppendTag(b, fieldStoreContiguousBinIndexOffset, protowire.VarintType)
+	b = protowire.AppendVarint(b, protowire.EncodeZigZag(10))
+
+	decoded = newDenseStore()
+	assert.NoError(t, decoded.unmarshalProto(b))
+	assert.Equal(t, 5.0, decoded.totalCount())
+	assert.Equal(t, 2.0, decoded.bins[-7-decoded.offset])
+	assert.Equal(t, 3.0, decoded.bins[10-decoded.offset])
+
+	// Check invalid data
+	assert.Error(t, newDenseStore().unmarshalProto([]byte{0x12, 0x05, 0x01}))
+}
@@ -0,0 +1,145 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrShortBuffer       = errors.New("msgpack: short buffer")
+	ErrUnsupportedType   = errors.New("msgpack: unsupported type")
+	ErrUnsupportedMapKey = errors.New("msgpack: map key is not a string")
+)
+
+// Decode reads the first value of b and returns it with the remaining bytes.
+// Values are decoded as nil, bool, int64, uint64, float64, string, []byte,
+// []interface{} or map[string]interface{}.
+func Decode(b []byte) (interface{}, []byte, error) {
+	if len(b) == 0 {
+		return nil, b, ErrShortBuffer
+	}
+
+	var marker = b[0]
+	b = b[1:]
+	switch {
+	case marker <= math.MaxInt8:
+		return uint64(marker), b, nil
+	case marker >= 0xe0:
+		return int64(int8(marker)), b, nil
+	case marker&0xf0 == fixMapPrefix:
+		return decodeMap(b, int(marker&0x0f))
+	case marker&0xf0 == fixArrayPrefix:
+		return decodeArray(b, int(marker&0x0f))
+	case marker&0xe0 == fixStrPrefix:
+		return decodeString(b, int(marker&0x1f))
+	}
+
+	switch marker {
+	case markerNil:
+		return nil, b, nil
+	case markerFalse:
+		return false, b, nil
+	case markerTrue:
+		return true, b, nil
+	case markerFloat64:
+		v, b, err := readUint(b, 8)
+		return math.Float64frombits(v), b, err
+	case markerUint8, markerUint16, markerUint32, markerUint64:
+		return readUint(b, 1<<(marker-markerUint8))
+	case markerInt8, markerInt16, markerInt32, markerInt64:
+		var size = 1 << (marker - markerInt8)
+		v, b, err := readUint(b, size)
+		// Sign extension
+		var shift = 64 - 8*uint(size)
+		return int64(v<<shift) >> shift, b, err
+	case markerStr8, markerStr16, markerStr32:
+		size, b, err := readUint(b, 1<<(marker-markerStr8))
+		if err != nil {
+			return nil, b, err
+		}
+		return decodeString(b, int(size))
+	case markerBin8, markerBin16, markerBin32:
+		size, b, err := readUint(b, 1<<(marker-markerBin8))
+		if err != nil {
+			return nil, b, err
+		}
+		if len(b) < int(size) {
+			return nil, b, ErrShortBuffer
+		}
+		return append([]byte{}, b[:size]...), b[size:], nil
+	case markerArray16, markerArray32:
+		size, b, err := readUint(b, 2<<(marker-markerArray16))
+		if err != nil {
+			return nil, b, err
+		}
+		return decodeArray(b, int(size))
+	case markerMap16, markerMap32:
+		size, b, err := readUint(b, 2<<(marker-markerMap16))
+		if err != nil {
+			return nil, b, err
+		}
+		return decodeMap(b, int(size))
+	}
+	return nil, b, fmt.Errorf("%w: 0x%x", ErrUnsupportedType, marker)
+}
+
+func decodeString(b []byte, size int) (interface{}, []byte, error) {
+	if len(b) < size {
+		return nil, b, ErrShortBuffer
+	}
+	return string(b[:size]), b[size:], nil
+}
+
+func decodeArray(b []byte, size int) (interface{}, []byte, error) {
+	var (
+		values = make([]interface{}, 0, size)
+		value  interface{}
+		err    error
+	)
+	for i := 0; i < size; i++ {
+		if value, b, err = Decode(b); err != nil {
+			return nil, b, err
+		}
+		values = append(values, value)
+	}
+	return values, b, nil
+}
+
+func decodeMap(b []byte, size int) (interface{}, []byte, error) {
+	var (
+		values     = make(map[string]interface{}, size)
+		key, value interface{}
+		err        error
+	)
+	for i := 0; i < size; i++ {
+		if key, b, err = Decode(b); err != nil {
+			return nil, b, err
+		}
+		keyString, ok := key.(string)
+		if !ok {
+			return nil, b, ErrUnsupportedMapKey
+		}
+		if value, b, err = Decode(b); err != nil {
+			return nil, b, err
+		}
+		values[keyString] = value
+	}
+	return values, b, nil
+}
+
+func readUint(b []byte, size int) (uint64, []byte, error) {
+	if len(b) < size {
+		return 0, b, ErrShortBuffer
+	}
+	switch size {
+	case 1:
+		return uint64(b[0]), b[1:], nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), b[2:], nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), b[4:], nil
+	}
+	return binary.BigEndian.Uint64(b), b[8:], nil
+}
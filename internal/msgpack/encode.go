@@ -0,0 +1,163 @@
+// Package msgpack implements the subset of the MessagePack format used by the
+// Datadog agent API.
+// Ref https://github.com/msgpack/msgpack/blob/master/spec.md
+package msgpack
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// _____________________ Format markers _____________________
+
+const (
+	markerNil     = 0xc0
+	markerFalse   = 0xc2
+	markerTrue    = 0xc3
+	markerBin8    = 0xc4
+	markerBin16   = 0xc5
+	markerBin32   = 0xc6
+	markerFloat64 = 0xcb
+	markerUint8   = 0xcc
+	markerUint16  = 0xcd
+	markerUint32  = 0xce
+	markerUint64  = 0xcf
+	markerInt8    = 0xd0
+	markerInt16   = 0xd1
+	markerInt32   = 0xd2
+	markerInt64   = 0xd3
+	markerStr8    = 0xd9
+	markerStr16   = 0xda
+	markerStr32   = 0xdb
+	markerArray16 = 0xdc
+	markerArray32 = 0xdd
+	markerMap16   = 0xde
+	markerMap32   = 0xdf
+
+	fixMapPrefix   = 0x80
+	fixArrayPrefix = 0x90
+	fixStrPrefix   = 0xa0
+	negFixIntMin   = -32
+)
+
+// _____________________ Append functions _____________________
+
+// AppendNil appends a nil value to b.
+func AppendNil(b []byte) []byte { return append(b, markerNil) }
+
+// AppendBool appends a boolean to b.
+func AppendBool(b []byte, value bool) []byte {
+	if value {
+		return append(b, markerTrue)
+	}
+	return append(b, markerFalse)
+}
+
+// AppendInt64 appends a signed integer to b using the smallest representation.
+func AppendInt64(b []byte, value int64) []byte {
+	switch {
+	case value >= 0:
+		return AppendUint64(b, uint64(value))
+	case value >= negFixIntMin:
+		return append(b, byte(value))
+	case value >= math.MinInt8:
+		return append(b, markerInt8, byte(value))
+	case value >= math.MinInt16:
+		return appendUint16(append(b, markerInt16), uint16(value))
+	case value >= math.MinInt32:
+		return appendUint32(append(b, markerInt32), uint32(value))
+	}
+	return appendUint64(append(b, markerInt64), uint64(value))
+}
+
+// AppendUint64 appends an unsigned integer to b using the smallest representation.
+func AppendUint64(b []byte, value uint64) []byte {
+	switch {
+	case value <= math.MaxInt8:
+		return append(b, byte(value))
+	case value <= math.MaxUint8:
+		return append(b, markerUint8, byte(value))
+	case value <= math.MaxUint16:
+		return appendUint16(append(b, markerUint16), uint16(value))
+	case value <= math.MaxUint32:
+		return appendUint32(append(b, markerUint32), uint32(value))
+	}
+	return appendUint64(append(b, markerUint64), value)
+}
+
+// AppendFloat64 appends a 64-bits float to b.
+func AppendFloat64(b []byte, value float64) []byte {
+	return appendUint64(append(b, markerFloat64), math.Float64bits(value))
+}
+
+// AppendString appends a string to b.
+func AppendString(b []byte, value string) []byte {
+	var size = len(value)
+	switch {
+	case size < 32:
+		b = append(b, fixStrPrefix|byte(size))
+	case size <= math.MaxUint8:
+		b = append(b, markerStr8, byte(size))
+	case size <= math.MaxUint16:
+		b = appendUint16(append(b, markerStr16), uint16(size))
+	default:
+		b = appendUint32(append(b, markerStr32), uint32(size))
+	}
+	return append(b, value...)
+}
+
+// AppendBytes appends a binary value to b.
+func AppendBytes(b []byte, value []byte) []byte {
+	var size = len(value)
+	switch {
+	case size <= math.MaxUint8:
+		b = append(b, markerBin8, byte(size))
+	case size <= math.MaxUint16:
+		b = appendUint16(append(b, markerBin16), uint16(size))
+	default:
+		b = appendUint32(append(b, markerBin32), uint32(size))
+	}
+	return append(b, value...)
+}
+
+// AppendArrayHeader appends the header of an array of size elements to b.
+func AppendArrayHeader(b []byte, size int) []byte {
+	switch {
+	case size < 16:
+		return append(b, fixArrayPrefix|byte(size))
+	case size <= math.MaxUint16:
+		return appendUint16(append(b, markerArray16), uint16(size))
+	}
+	return appendUint32(append(b, markerArray32), uint32(size))
+}
+
+// AppendMapHeader appends the header of a map of size key/value pairs to b.
+func AppendMapHeader(b []byte, size int) []byte {
+	switch {
+	case size < 16:
+		return append(b, fixMapPrefix|byte(size))
+	case size <= math.MaxUint16:
+		return appendUint16(append(b, markerMap16), uint16(size))
+	}
+	return appendUint32(append(b, markerMap32), uint32(size))
+}
+
+// _____________________ Big endian helpers _____________________
+
+func appendUint16(b []byte, value uint16) []byte {
+	var buf [2]byte
+	binary.BigEndian.PutUint16(buf[:], value)
+	return append(b, buf[:]...)
+}
+
+func appendUint32(b []byte, value uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], value)
+	return append(b, buf[:]...)
+}
+
+func appendUint64(b []byte, value uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], value)
+	return append(b, buf[:]...)
+}
@@ -0,0 +1,87 @@
+package msgpack
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func test_roundTrip(t *testing.T, b []byte) interface{} {
+	value, remaining, err := Decode(b)
+	assert.NoError(t, err)
+	assert.Empty(t, remaining)
+	return value
+}
+
+func Test_Integer(t *testing.T) {
+	for _, v := range []uint64{0, 127, 128, math.MaxUint8, math.MaxUint16, math.MaxUint16 + 1, math.MaxUint32, math.MaxUint32 + 1, math.MaxUint64} {
+		assert.Equal(t, v, test_roundTrip(t, AppendUint64(nil, v)), v)
+	}
+	for _, v := range []int64{-1, -32, -33, math.MinInt8, math.MinInt8 - 1, math.MinInt16, math.MinInt16 - 1, math.MinInt32, math.MinInt32 - 1, math.MinInt64} {
+		assert.Equal(t, v, test_roundTrip(t, AppendInt64(nil, v)), v)
+	}
+	assert.Equal(t, uint64(12), test_roundTrip(t, AppendInt64(nil, 12)))
+
+	// Check smallest representation used
+	assert.Equal(t, []byte{0x7f}, AppendUint64(nil, 127))
+	assert.Equal(t, []byte{0xcc, 0x80}, AppendUint64(nil, 128))
+	assert.Equal(t, []byte{0xff}, AppendInt64(nil, -1))
+	assert.Equal(t, []byte{0xd0, 0xdf}, AppendInt64(nil, -33))
+}
+
+func Test_Scalar(t *testing.T) {
+	assert.Nil(t, test_roundTrip(t, AppendNil(nil)))
+	assert.Equal(t, true, test_roundTrip(t, AppendBool(nil, true)))
+	assert.Equal(t, false, test_roundTrip(t, AppendBool(nil, false)))
+	assert.Equal(t, 1.5, test_roundTrip(t, AppendFloat64(nil, 1.5)))
+
+	for _, size := range []int{0, 31, 32, math.MaxUint8 + 1, math.MaxUint16 + 1} {
+		var value = strings.Repeat("a", size)
+		assert.Equal(t, value, test_roundTrip(t, AppendString(nil, value)), size)
+		assert.Equal(t, []byte(value), test_roundTrip(t, AppendBytes(nil, []byte(value))), size)
+	}
+}
+
+func Test_Container(t *testing.T) {
+	for _, size := range []int{0, 15, 16, math.MaxUint16 + 1} {
+		var (
+			b        = AppendArrayHeader(nil, size)
+			expected = make([]interface{}, 0, size)
+		)
+		for i := 0; i < size; i++ {
+			b = AppendNil(b)
+			expected = append(expected, nil)
+		}
+		assert.Equal(t, expected, test_roundTrip(t, b), size)
+	}
+
+	for _, size := range []int{0, 15, 16, math.MaxUint16 + 1} {
+		var (
+			b        = AppendMapHeader(nil, size)
+			expected = make(map[string]interface{}, size)
+		)
+		for i := 0; i < size; i++ {
+			var key = strconv.Itoa(i)
+			b = AppendBool(AppendString(b, key), true)
+			expected[key] = true
+		}
+		assert.Equal(t, expected, test_roundTrip(t, b), size)
+	}
+}
+
+func Test_Decode_Error(t *testing.T) {
+	_, _, err := Decode(nil)
+	assert.ErrorIs(t, err, ErrShortBuffer)
+
+	_, _, err = Decode([]byte{0xc1})
+	assert.ErrorIs(t, err, ErrUnsupportedType)
+
+	_, _, err = Decode(AppendString(nil, "abc")[:2])
+	assert.ErrorIs(t, err, ErrShortBuffer)
+
+	_, _, err = Decode(AppendUint64(AppendMapHeader(nil, 1), 1))
+	assert.ErrorIs(t, err, ErrUnsupportedMapKey)
+}
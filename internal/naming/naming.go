@@ -0,0 +1,259 @@
+// Package naming computes the Datadog service, operation name, resource and type
+// of OpenTelemetry spans, following the Datadog OTLP ingest rules. It is shared
+// by the exporter and the samplers, so that sampling rules match exported names.
+package naming
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Naming follows the Datadog OTLP ingest rules.
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/trace/traceutil/otel_util.go
+// Ref https://docs.datadoghq.com/opentelemetry/schema_semantics/semantic_mapping/
+
+const (
+	// DefaultServiceName is the service of spans without service.name resource attribute.
+	DefaultServiceName = "otlpresourcenoservicename"
+
+	// maxResourceLen is the maximum length of a resource name accepted by the agent
+	maxResourceLen = 5000
+)
+
+// Attributes overriding Datadog span fields
+const (
+	// AttributeOperationName overrides the operation name of the span.
+	AttributeOperationName = attribute.Key("operation.name")
+	// AttributeResourceName overrides the resource name of the span.
+	AttributeResourceName = attribute.Key("resource.name")
+	// AttributeSpanType overrides the type of the span.
+	AttributeSpanType = attribute.Key("span.type")
+)
+
+// Semantic conventions more recent than the ones of the OpenTelemetry SDK
+const (
+	httpRequestMethodKey        = attribute.Key("http.request.method")
+	dbQueryTextKey              = attribute.Key("db.query.text")
+	messagingDestinationNameKey = attribute.Key("messaging.destination.name")
+	networkProtocolNameKey      = attribute.Key("network.protocol.name")
+	graphqlOperationTypeKey     = attribute.Key("graphql.operation.type")
+	graphqlOperationNameKey     = attribute.Key("graphql.operation.name")
+)
+
+// Attributes gives access to span attributes by key.
+type Attributes map[attribute.Key]attribute.Value
+
+// NewAttributes indexes attributes by key.
+func NewAttributes(kvs []attribute.KeyValue) Attributes {
+	var attrs = make(Attributes, len(kvs))
+	for _, kv := range kvs {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+// Get returns the first non empty value of keys.
+func (obj Attributes) Get(keys ...attribute.Key) string {
+	for _, key := range keys {
+		if v, ok := obj[key]; ok {
+			if value := v.Emit(); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
+
+// ServiceName returns the service.name resource attribute, DefaultServiceName if not set.
+func ServiceName(resAttr Attributes) string {
+	if service := resAttr.Get(semconv.ServiceNameKey); service != "" {
+		return service
+	}
+	return DefaultServiceName
+}
+
+// OperationName returns the name from span kind and protocol attributes
+// (ex: "http.server.request", "postgresql.query").
+func OperationName(kind trace.SpanKind, attrs Attributes) string {
+	if name := attrs.Get(AttributeOperationName); name != "" {
+		return name
+	}
+
+	var (
+		isClient = kind == trace.SpanKindClient
+		isServer = kind == trace.SpanKindServer
+	)
+
+	// HTTP
+	if attrs.Get(httpRequestMethodKey, semconv.HTTPMethodKey) != "" {
+		switch {
+		case isServer:
+			return "http.server.request"
+		case isClient:
+			return "http.client.request"
+		}
+	}
+
+	// Database
+	if system := attrs.Get(semconv.DBSystemKey); system != "" && isClient {
+		return system + ".query"
+	}
+
+	// Messaging
+	if system, operation := attrs.Get(semconv.MessagingSystemKey), attrs.Get(semconv.MessagingOperationKey); system != "" && operation != "" {
+		switch kind {
+		case trace.SpanKindClient, trace.SpanKindServer, trace.SpanKindConsumer, trace.SpanKindProducer:
+			return system + "." + operation
+		}
+	}
+
+	// RPC and AWS
+	if system := attrs.Get(semconv.RPCSystemKey); system != "" {
+		switch {
+		case system == "aws-api" && isClient:
+			if service := attrs.Get(semconv.RPCServiceKey); service != "" {
+				return "aws." + service + ".request"
+			}
+			return "aws.client.request"
+		case isClient:
+			return system + ".client.request"
+		case isServer:
+			return system + ".server.request"
+		}
+	}
+
+	// FaaS
+	if provider, name := attrs.Get(semconv.FaaSInvokedProviderKey), attrs.Get(semconv.FaaSInvokedNameKey); provider != "" && name != "" && isClient {
+		return provider + "." + name + ".invoke"
+	}
+	if trigger := attrs.Get(semconv.FaaSTriggerKey); trigger != "" && isServer {
+		return trigger + ".invoke"
+	}
+
+	// GraphQL
+	if attrs.Get(graphqlOperationTypeKey) != "" {
+		return "graphql.server.request"
+	}
+
+	// Generic server and client
+	var protocol = attrs.Get(networkProtocolNameKey)
+	switch {
+	case isServer && protocol != "":
+		return protocol + ".server.request"
+	case isServer:
+		return "server.request"
+	case isClient && protocol != "":
+		return protocol + ".client.request"
+	case isClient:
+		return "client.request"
+	}
+	return strings.ToLower(trace.ValidateSpanKind(kind).String())
+}
+
+// ResourceName returns the resource from protocol attributes (ex: "GET /users/{id}"),
+// the span name otherwise.
+func ResourceName(name string, kind trace.SpanKind, attrs Attributes) string {
+	return Truncate(resourceNameFull(name, kind, attrs), maxResourceLen)
+}
+
+func resourceNameFull(name string, kind trace.SpanKind, attrs Attributes) string {
+	if resource := attrs.Get(AttributeResourceName); resource != "" {
+		return resource
+	}
+
+	if method := attrs.Get(httpRequestMethodKey, semconv.HTTPMethodKey); method != "" {
+		if method == "_OTHER" {
+			method = "HTTP"
+		}
+		if route := attrs.Get(semconv.HTTPRouteKey); route != "" && kind == trace.SpanKindServer {
+			return method + " " + route
+		}
+		return method
+	}
+
+	if operation := attrs.Get(semconv.MessagingOperationKey); operation != "" {
+		return joinNotEmpty(operation, attrs.Get(semconv.MessagingDestinationKey, messagingDestinationNameKey))
+	}
+
+	if method := attrs.Get(semconv.RPCMethodKey); method != "" {
+		return joinNotEmpty(method, attrs.Get(semconv.RPCServiceKey))
+	}
+
+	if operationType := attrs.Get(graphqlOperationTypeKey); operationType != "" {
+		return joinNotEmpty(operationType, attrs.Get(graphqlOperationNameKey))
+	}
+
+	if attrs.Get(semconv.DBSystemKey) != "" {
+		if statement := attrs.Get(semconv.DBStatementKey, dbQueryTextKey); statement != "" {
+			return statement
+		}
+	}
+
+	return name
+}
+
+func joinNotEmpty(value, suffix string) string {
+	if suffix == "" {
+		return value
+	}
+	return value + " " + suffix
+}
+
+// SpanType returns the Datadog type from span kind and database system.
+func SpanType(kind trace.SpanKind, attrs, resAttr Attributes) string {
+	if typ := attrs.Get(AttributeSpanType); typ != "" {
+		return typ
+	}
+	if typ := resAttr.Get(AttributeSpanType); typ != "" {
+		return typ
+	}
+
+	switch kind {
+	case trace.SpanKindServer:
+		return "web"
+	case trace.SpanKindClient:
+		if system := attrs.Get(semconv.DBSystemKey); system != "" {
+			return dbType(system)
+		}
+		return "http"
+	}
+	return "custom"
+}
+
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/trace/traceutil/otel_util.go (checkDBType)
+var sqlDBSystems = map[string]bool{
+	"other_sql": true, "mssql": true, "mysql": true, "oracle": true, "db2": true,
+	"postgresql": true, "redshift": true, "cloudscape": true, "hsqldb": true,
+	"maxdb": true, "ingres": true, "firstsql": true, "edb": true, "cache": true,
+	"firebird": true, "derby": true, "informix": true, "mariadb": true,
+	"sqlite": true, "sybase": true, "teradata": true, "vertica": true,
+	"h2": true, "coldfusion": true, "cockroachdb": true, "progress": true,
+	"hanadb": true, "adabas": true, "filemaker": true, "instantdb": true,
+	"interbase": true, "netezza": true, "pervasive": true, "pointbase": true,
+	"clickhouse": true,
+}
+
+func dbType(system string) string {
+	switch {
+	case system == semconv.DBSystemRedis.Value.AsString(), system == semconv.DBSystemMemcached.Value.AsString():
+		return "cache"
+	case sqlDBSystems[system]:
+		return "sql"
+	}
+	return "db"
+}
+
+// Truncate cuts value to at most size bytes without breaking UTF-8 characters.
+func Truncate(value string, size int) string {
+	if len(value) <= size {
+		return value
+	}
+	for size > 0 && !utf8.RuneStart(value[size]) {
+		size--
+	}
+	return value[:size]
+}
@@ -0,0 +1,107 @@
+package naming
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_Attributes_Get(t *testing.T) {
+	var attrs = NewAttributes([]attribute.KeyValue{
+		attribute.String("empty", ""),
+		attribute.Int("int", 1),
+	})
+	assert.Equal(t, "1", attrs.Get("missing", "empty", "int"))
+	assert.Equal(t, "", attrs.Get("missing", "empty"))
+}
+
+func Test_OperationName(t *testing.T) {
+	for expected, tt := range map[string]struct {
+		kind  trace.SpanKind
+		attrs []attribute.KeyValue
+	}{
+		"http.server.request":        {trace.SpanKindServer, []attribute.KeyValue{semconv.HTTPMethodKey.String("GET")}},
+		"http.client.request":        {trace.SpanKindClient, []attribute.KeyValue{httpRequestMethodKey.String("GET")}},
+		"postgresql.query":           {trace.SpanKindClient, []attribute.KeyValue{semconv.DBSystemKey.String("postgresql")}},
+		"kafka.publish":              {trace.SpanKindProducer, []attribute.KeyValue{semconv.MessagingSystemKey.String("kafka"), semconv.MessagingOperationKey.String("publish")}},
+		"aws.s3.request":             {trace.SpanKindClient, []attribute.KeyValue{semconv.RPCSystemKey.String("aws-api"), semconv.RPCServiceKey.String("s3")}},
+		"aws.client.request":         {trace.SpanKindClient, []attribute.KeyValue{semconv.RPCSystemKey.String("aws-api")}},
+		"grpc.client.request":        {trace.SpanKindClient, []attribute.KeyValue{semconv.RPCSystemKey.String("grpc")}},
+		"grpc.server.request":        {trace.SpanKindServer, []attribute.KeyValue{semconv.RPCSystemKey.String("grpc")}},
+		"aws.my-lambda.invoke":       {trace.SpanKindClient, []attribute.KeyValue{semconv.FaaSInvokedProviderKey.String("aws"), semconv.FaaSInvokedNameKey.String("my-lambda")}},
+		"pubsub.invoke":              {trace.SpanKindServer, []attribute.KeyValue{semconv.FaaSTriggerKey.String("pubsub")}},
+		"graphql.server.request":     {trace.SpanKindInternal, []attribute.KeyValue{graphqlOperationTypeKey.String("query")}},
+		"amqp.server.request":        {trace.SpanKindServer, []attribute.KeyValue{networkProtocolNameKey.String("amqp")}},
+		"amqp.client.request":        {trace.SpanKindClient, []attribute.KeyValue{networkProtocolNameKey.String("amqp")}},
+		"server.request":             {trace.SpanKindServer, nil},
+		"client.request":             {trace.SpanKindClient, nil},
+		"producer":                   {trace.SpanKindProducer, nil},
+		"internal":                   {trace.SpanKindUnspecified, nil},
+		"overridden.operation":       {trace.SpanKindServer, []attribute.KeyValue{AttributeOperationName.String("overridden.operation"), semconv.HTTPMethodKey.String("GET")}},
+		"redis.query":                {trace.SpanKindClient, []attribute.KeyValue{semconv.DBSystemKey.String("redis")}},
+		"internal.db":                {trace.SpanKindInternal, []attribute.KeyValue{AttributeOperationName.String("internal.db"), semconv.DBSystemKey.String("redis")}},
+		"unmatched.internal.request": {trace.SpanKindInternal, []attribute.KeyValue{AttributeOperationName.String("unmatched.internal.request")}},
+	} {
+		assert.Equal(t, expected, OperationName(tt.kind, NewAttributes(tt.attrs)), expected)
+	}
+}
+
+func Test_ResourceName(t *testing.T) {
+	for expected, tt := range map[string]struct {
+		kind  trace.SpanKind
+		attrs []attribute.KeyValue
+	}{
+		"GET /users/{id}":       {trace.SpanKindServer, []attribute.KeyValue{semconv.HTTPMethodKey.String("GET"), semconv.HTTPRouteKey.String("/users/{id}")}},
+		"POST":                  {trace.SpanKindClient, []attribute.KeyValue{httpRequestMethodKey.String("POST"), semconv.HTTPRouteKey.String("/users/{id}")}},
+		"HTTP":                  {trace.SpanKindClient, []attribute.KeyValue{httpRequestMethodKey.String("_OTHER")}},
+		"publish orders":        {trace.SpanKindProducer, []attribute.KeyValue{semconv.MessagingOperationKey.String("publish"), messagingDestinationNameKey.String("orders")}},
+		"receive":               {trace.SpanKindConsumer, []attribute.KeyValue{semconv.MessagingOperationKey.String("receive")}},
+		"GetUser users.Service": {trace.SpanKindClient, []attribute.KeyValue{semconv.RPCMethodKey.String("GetUser"), semconv.RPCServiceKey.String("users.Service")}},
+		"query GetUser":         {trace.SpanKindServer, []attribute.KeyValue{graphqlOperationTypeKey.String("query"), graphqlOperationNameKey.String("GetUser")}},
+		"SELECT 1":              {trace.SpanKindClient, []attribute.KeyValue{semconv.DBSystemKey.String("mysql"), semconv.DBStatementKey.String("SELECT 1")}},
+		"SELECT 2":              {trace.SpanKindClient, []attribute.KeyValue{semconv.DBSystemKey.String("mysql"), dbQueryTextKey.String("SELECT 2")}},
+		"span":                  {trace.SpanKindClient, []attribute.KeyValue{semconv.DBSystemKey.String("mysql")}},
+		"overridden":            {trace.SpanKindServer, []attribute.KeyValue{AttributeResourceName.String("overridden"), semconv.HTTPMethodKey.String("GET")}},
+	} {
+		assert.Equal(t, expected, ResourceName("span", tt.kind, NewAttributes(tt.attrs)), expected)
+	}
+
+	// Check truncation
+	assert.Len(t, ResourceName(strings.Repeat("a", maxResourceLen+1), trace.SpanKindInternal, nil), maxResourceLen)
+}
+
+func Test_SpanType(t *testing.T) {
+	var db = func(system string) Attributes {
+		return NewAttributes([]attribute.KeyValue{semconv.DBSystemKey.String(system)})
+	}
+	assert.Equal(t, "web", SpanType(trace.SpanKindServer, nil, nil))
+	assert.Equal(t, "http", SpanType(trace.SpanKindClient, nil, nil))
+	assert.Equal(t, "custom", SpanType(trace.SpanKindInternal, nil, nil))
+	assert.Equal(t, "custom", SpanType(trace.SpanKindProducer, nil, nil))
+	assert.Equal(t, "cache", SpanType(trace.SpanKindClient, db("redis"), nil))
+	assert.Equal(t, "cache", SpanType(trace.SpanKindClient, db("memcached"), nil))
+	assert.Equal(t, "sql", SpanType(trace.SpanKindClient, db("postgresql"), nil))
+	assert.Equal(t, "db", SpanType(trace.SpanKindClient, db("mongodb"), nil))
+
+	// Check overrides, span first
+	var resAttr = NewAttributes([]attribute.KeyValue{AttributeSpanType.String("resource")})
+	assert.Equal(t, "resource", SpanType(trace.SpanKindServer, nil, resAttr))
+	assert.Equal(t, "span", SpanType(trace.SpanKindServer, NewAttributes([]attribute.KeyValue{AttributeSpanType.String("span")}), resAttr))
+}
+
+func Test_ServiceName(t *testing.T) {
+	assert.Equal(t, "api", ServiceName(NewAttributes([]attribute.KeyValue{semconv.ServiceNameKey.String("api")})))
+	assert.Equal(t, DefaultServiceName, ServiceName(nil))
+}
+
+func Test_Truncate(t *testing.T) {
+	assert.Equal(t, "abc", Truncate("abc", 5))
+	assert.Equal(t, "ab", Truncate("abc", 2))
+	// "é" is encoded on 2 bytes, must not be cut
+	assert.Equal(t, "a", Truncate("aéb", 2))
+	assert.Equal(t, "aé", Truncate("aéb", 3))
+}
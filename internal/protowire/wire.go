@@ -0,0 +1,138 @@
+// Package protowire implements the subset of the Protocol Buffers wire format
+// used by Datadog payloads, without code generation.
+// Ref https://developers.google.com/protocol-buffers/docs/encoding
+package protowire
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Type is a protobuf wire type.
+type Type int8
+
+const (
+	VarintType  Type = 0
+	Fixed64Type Type = 1
+	BytesType   Type = 2
+	Fixed32Type Type = 5
+)
+
+var (
+	ErrTruncated   = errors.New("protowire: truncated data")
+	ErrInvalidType = errors.New("protowire: invalid wire type")
+)
+
+// _____________________ Append functions _____________________
+
+// AppendTag appends the key of a field to b.
+func AppendTag(b []byte, num int, typ Type) []byte {
+	return AppendVarint(b, uint64(num)<<3|uint64(typ))
+}
+
+// AppendVarint appends a base 128 varint to b.
+func AppendVarint(b []byte, value uint64) []byte {
+	for value >= 0x80 {
+		b = append(b, byte(value)|0x80)
+		value >>= 7
+	}
+	return append(b, byte(value))
+}
+
+// AppendFixed64 appends a little endian 64-bits value to b.
+func AppendFixed64(b []byte, value uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], value)
+	return append(b, buf[:]...)
+}
+
+// AppendBytes appends a length-delimited value to b.
+func AppendBytes(b []byte, value []byte) []byte {
+	return append(AppendVarint(b, uint64(len(value))), value...)
+}
+
+// AppendString appends a length-delimited string to b.
+func AppendString(b []byte, value string) []byte {
+	return append(AppendVarint(b, uint64(len(value))), value...)
+}
+
+// AppendDouble appends a double field (number and value) to b.
+func AppendDouble(b []byte, num int, value float64) []byte {
+	return AppendFixed64(AppendTag(b, num, Fixed64Type), math.Float64bits(value))
+}
+
+// EncodeZigZag encodes a signed integer for sint32 and sint64 fields.
+func EncodeZigZag(value int64) uint64 {
+	return uint64(value<<1) ^ uint64(value>>63)
+}
+
+// DecodeZigZag decodes a sint32 or sint64 field value.
+func DecodeZigZag(value uint64) int64 {
+	return int64(value>>1) ^ -int64(value&1)
+}
+
+// _____________________ Consume functions _____________________
+
+// ConsumeTag parses the key of a field and returns the number of bytes read.
+func ConsumeTag(b []byte) (num int, typ Type, n int, err error) {
+	value, n, err := ConsumeVarint(b)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return int(value >> 3), Type(value & 7), n, nil
+}
+
+// ConsumeVarint parses a base 128 varint and returns the number of bytes read.
+func ConsumeVarint(b []byte) (uint64, int, error) {
+	var value uint64
+	for i := 0; i < len(b) && i < binary.MaxVarintLen64; i++ {
+		value |= uint64(b[i]&0x7f) << (7 * uint(i))
+		if b[i] < 0x80 {
+			return value, i + 1, nil
+		}
+	}
+	return 0, 0, ErrTruncated
+}
+
+// ConsumeFixed64 parses a little endian 64-bits value and returns the number of bytes read.
+func ConsumeFixed64(b []byte) (uint64, int, error) {
+	if len(b) < 8 {
+		return 0, 0, ErrTruncated
+	}
+	return binary.LittleEndian.Uint64(b), 8, nil
+}
+
+// ConsumeBytes parses a length-delimited value and returns the number of bytes read.
+func ConsumeBytes(b []byte) ([]byte, int, error) {
+	size, n, err := ConsumeVarint(b)
+	if err != nil {
+		return nil, 0, err
+	}
+	if uint64(len(b)-n) < size {
+		return nil, 0, ErrTruncated
+	}
+	return b[n : n+int(size)], n + int(size), nil
+}
+
+// ConsumeFieldValue parses the value of a field of type typ and returns the
+// number of bytes read. Varint and fixed values are returned in value, length
+// delimited ones in data.
+func ConsumeFieldValue(b []byte, typ Type) (value uint64, data []byte, n int, err error) {
+	switch typ {
+	case VarintType:
+		value, n, err = ConsumeVarint(b)
+	case Fixed64Type:
+		value, n, err = ConsumeFixed64(b)
+	case BytesType:
+		data, n, err = ConsumeBytes(b)
+	case Fixed32Type:
+		if len(b) < 4 {
+			return 0, nil, 0, ErrTruncated
+		}
+		value, n = uint64(binary.LittleEndian.Uint32(b)), 4
+	default:
+		err = ErrInvalidType
+	}
+	return
+}
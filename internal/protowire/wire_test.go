@@ -0,0 +1,79 @@
+package protowire
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Varint(t *testing.T) {
+	for _, v := range []uint64{0, 1, 127, 128, 300, math.MaxUint32, math.MaxUint64} {
+		b := AppendVarint(nil, v)
+		value, n, err := ConsumeVarint(b)
+		assert.NoError(t, err)
+		assert.Equal(t, len(b), n)
+		assert.Equal(t, v, value)
+	}
+	// Ref https://developers.google.com/protocol-buffers/docs/encoding#varints
+	assert.Equal(t, []byte{0xac, 0x02}, AppendVarint(nil, 300))
+
+	_, _, err := ConsumeVarint([]byte{0x80})
+	assert.ErrorIs(t, err, ErrTruncated)
+}
+
+func Test_Tag(t *testing.T) {
+	b := AppendTag(nil, 150, BytesType)
+	num, typ, n, err := ConsumeTag(b)
+	assert.NoError(t, err)
+	assert.Equal(t, 150, num)
+	assert.Equal(t, BytesType, typ)
+	assert.Equal(t, len(b), n)
+}
+
+func Test_ZigZag(t *testing.T) {
+	// Ref https://developers.google.com/protocol-buffers/docs/encoding#signed-ints
+	assert.Equal(t, uint64(0), EncodeZigZag(0))
+	assert.Equal(t, uint64(1), EncodeZigZag(-1))
+	assert.Equal(t, uint64(2), EncodeZigZag(1))
+	assert.Equal(t, uint64(4294967295), EncodeZigZag(-2147483648))
+	for _, v := range []int64{0, -1, 1, math.MinInt32, math.MaxInt32, math.MinInt64, math.MaxInt64} {
+		assert.Equal(t, v, DecodeZigZag(EncodeZigZag(v)))
+	}
+}
+
+func Test_FieldValue(t *testing.T) {
+	// Check fixed 64
+	b := AppendDouble(nil, 1, 1.5)
+	num, typ, n, err := ConsumeTag(b)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, num)
+	value, _, _, err := ConsumeFieldValue(b[n:], typ)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.5, math.Float64frombits(value))
+
+	// Check bytes
+	_, data, n, err := ConsumeFieldValue(AppendString(nil, "abc"), BytesType)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, n)
+	assert.Equal(t, []byte("abc"), data)
+	_, data, _, err = ConsumeFieldValue(AppendBytes(nil, []byte{1}), BytesType)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{1}, data)
+
+	// Check fixed 32
+	value, _, n, err = ConsumeFieldValue([]byte{1, 0, 0, 0}, Fixed32Type)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), value)
+	assert.Equal(t, 4, n)
+
+	// Check errors
+	_, _, _, err = ConsumeFieldValue([]byte{1}, Fixed64Type)
+	assert.ErrorIs(t, err, ErrTruncated)
+	_, _, _, err = ConsumeFieldValue([]byte{1}, Fixed32Type)
+	assert.ErrorIs(t, err, ErrTruncated)
+	_, _, _, err = ConsumeFieldValue([]byte{5, 1}, BytesType)
+	assert.ErrorIs(t, err, ErrTruncated)
+	_, _, _, err = ConsumeFieldValue(nil, 3)
+	assert.ErrorIs(t, err, ErrInvalidType)
+}
@@ -0,0 +1,25 @@
+// Package runtimeid identifies this process in the payloads sent to Datadog.
+package runtimeid
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/internal/globalconfig/globalconfig.go (RuntimeID)
+
+var runtimeID = NewUUID()
+
+// ID returns the runtime ID of this process, a random UUID.
+func ID() string {
+	return runtimeID
+}
+
+// NewUUID returns a random UUID (version 4).
+func NewUUID() string {
+	var uuid [16]byte
+	_, _ = rand.Read(uuid[:])
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:])
+}
@@ -0,0 +1,18 @@
+package runtimeid
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NewUUID(t *testing.T) {
+	var id = NewUUID()
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), id)
+	assert.NotEqual(t, id, NewUUID())
+}
+
+func Test_ID(t *testing.T) {
+	assert.Equal(t, ID(), ID())
+}
@@ -0,0 +1,64 @@
+package sampling
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/rules_sampler.go
+
+// Glob is a case insensitive glob pattern: "*" matches any sequence of
+// characters and "?" any single character. An empty pattern matches all values.
+type Glob struct {
+	wildcard bool // pattern made of "*" only
+	exp      *regexp.Regexp
+}
+
+// NewGlob compiles a glob pattern.
+func NewGlob(pattern string) Glob {
+	var glob = Glob{wildcard: strings.Trim(pattern, "*") == ""}
+	if pattern == "" {
+		return glob
+	}
+
+	var builder strings.Builder
+	builder.WriteString("(?is)^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			builder.WriteString(".*")
+		case '?':
+			builder.WriteString(".")
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	builder.WriteString("$")
+	glob.exp = regexp.MustCompile(builder.String())
+	return glob
+}
+
+// Match returns true if the value matches the pattern.
+func (obj Glob) Match(value string) bool {
+	return obj.exp == nil || obj.exp.MatchString(value)
+}
+
+// MatchValue returns true if an attribute value matches the pattern. Numbers
+// with a fractional part only match patterns made of "*".
+func (obj Glob) MatchValue(value attribute.Value) bool {
+	switch value.Type() {
+	case attribute.INT64:
+		return obj.Match(strconv.FormatInt(value.AsInt64(), 10))
+	case attribute.FLOAT64:
+		if v := value.AsFloat64(); v == math.Trunc(v) && math.Abs(v) < math.MaxInt64 {
+			return obj.Match(strconv.FormatInt(int64(v), 10))
+		}
+		return obj.wildcard
+	default:
+		return obj.Match(value.Emit())
+	}
+}
@@ -0,0 +1,40 @@
+package sampling
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func Test_Glob_Match(t *testing.T) {
+	for _, tc := range []struct {
+		pattern, value string
+		expected       bool
+	}{
+		{"", "any", true},
+		{"api", "api", true},
+		{"API", "api", true},
+		{"api", "api2", false},
+		{"http.*", "http.server.request", true},
+		{"http.?erver.*", "http.server.request", true},
+		{"http", "http.server.request", false},
+		{"GET /users/(id)", "GET /users/1", false},
+		{"GET /users/(id)", "GET /users/(id)", true},
+		{"*", "", true},
+		{"a\nb", "a\nb", true},
+	} {
+		assert.Equal(t, tc.expected, NewGlob(tc.pattern).Match(tc.value), tc.pattern+" "+tc.value)
+	}
+}
+
+func Test_Glob_MatchValue(t *testing.T) {
+	assert.True(t, NewGlob("acme").MatchValue(attribute.StringValue("Acme")))
+	assert.True(t, NewGlob("true").MatchValue(attribute.BoolValue(true)))
+	assert.True(t, NewGlob("1?").MatchValue(attribute.Int64Value(12)))
+	assert.True(t, NewGlob("3").MatchValue(attribute.Float64Value(3)))
+
+	// Fractional numbers only match wildcards
+	assert.False(t, NewGlob("0.5").MatchValue(attribute.Float64Value(0.5)))
+	assert.True(t, NewGlob("**").MatchValue(attribute.Float64Value(0.5)))
+}
@@ -0,0 +1,67 @@
+package sampling
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/rules_sampler.go
+
+// RateLimiter is a token bucket limiting the number of traces or spans per
+// second, also computing the effective rate of allowed ones.
+type RateLimiter struct {
+	limit float64
+	burst float64
+
+	mu       sync.Mutex
+	tokens   float64
+	last     time.Time // last refill of tokens
+	prevTime time.Time // start of the current window
+	prevRate float64   // allowed ratio of the previous window
+	allowed  int
+	seen     int
+}
+
+// NewRateLimiter returns a limiter of limit per second, with a burst of limit.
+func NewRateLimiter(limit float64) *RateLimiter {
+	var burst = math.Ceil(limit)
+	return &RateLimiter{
+		limit:  limit,
+		burst:  burst,
+		tokens: burst,
+	}
+}
+
+// AllowOne returns true if one more is allowed at time now, and the ratio of
+// allowed ones over the previous and current second.
+func (obj *RateLimiter) AllowOne(now time.Time) (bool, float64) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	// Reset window counters every second
+	if d := now.Sub(obj.prevTime); d >= time.Second {
+		if d < 2*time.Second && obj.seen > 0 {
+			obj.prevRate = float64(obj.allowed) / float64(obj.seen)
+		} else {
+			obj.prevRate = 0
+		}
+		obj.prevTime = now
+		obj.allowed = 0
+		obj.seen = 0
+	}
+
+	// Refill tokens since last call
+	if !obj.last.IsZero() {
+		obj.tokens = math.Min(obj.burst, obj.tokens+now.Sub(obj.last).Seconds()*obj.limit)
+	}
+	obj.last = now
+
+	obj.seen++
+	var allowed = obj.tokens >= 1
+	if allowed {
+		obj.tokens--
+		obj.allowed++
+	}
+	return allowed, (obj.prevRate + float64(obj.allowed)/float64(obj.seen)) / 2
+}
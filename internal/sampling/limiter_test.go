@@ -0,0 +1,49 @@
+package sampling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RateLimiter_AllowOne(t *testing.T) {
+	var (
+		limiter = NewRateLimiter(2)
+		now     = time.Unix(1000, 0)
+	)
+
+	// Burst of limit traces allowed
+	allowed, rate := limiter.AllowOne(now)
+	assert.True(t, allowed)
+	assert.Equal(t, 0.5, rate)
+	allowed, rate = limiter.AllowOne(now)
+	assert.True(t, allowed)
+	assert.Equal(t, 0.5, rate)
+	allowed, rate = limiter.AllowOne(now)
+	assert.False(t, allowed)
+	assert.InDelta(t, 1.0/3, rate, 1e-9)
+
+	// Tokens refilled over time
+	allowed, _ = limiter.AllowOne(now.Add(500 * time.Millisecond))
+	assert.True(t, allowed)
+	allowed, _ = limiter.AllowOne(now.Add(500 * time.Millisecond))
+	assert.False(t, allowed)
+
+	// Rate of the previous second averaged
+	allowed, rate = limiter.AllowOne(now.Add(1500 * time.Millisecond))
+	assert.True(t, allowed)
+	assert.Equal(t, (0.6+1)/2, rate)
+
+	// Previous rate reset after more than a second
+	allowed, rate = limiter.AllowOne(now.Add(5 * time.Second))
+	assert.True(t, allowed)
+	assert.Equal(t, 0.5, rate)
+}
+
+func Test_RateLimiter_zero(t *testing.T) {
+	var limiter = NewRateLimiter(0)
+	allowed, rate := limiter.AllowOne(time.Unix(1000, 0))
+	assert.False(t, allowed)
+	assert.Zero(t, rate)
+}
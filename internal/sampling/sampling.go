@@ -0,0 +1,21 @@
+// Package sampling provides the building blocks of Datadog sampling decisions
+// shared by samplers and processors: rate hashing, glob patterns and rate
+// limiting.
+package sampling
+
+import "math"
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/sampler.go
+
+// knuthFactor is the multiplicative hash factor used by Datadog tracers, so that
+// all services keep or drop the same traces for a given rate.
+const knuthFactor = uint64(1111111111111111111)
+
+// SampledByRate returns true if an ID (lower 64 bits of the trace ID or span ID)
+// is kept for a sample rate.
+func SampledByRate(id uint64, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	return id*knuthFactor < uint64(rate*math.MaxUint64)
+}
@@ -0,0 +1,18 @@
+package sampling
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SampledByRate(t *testing.T) {
+	// 1 * knuthFactor = 0.06 * MaxUint64, 9 * knuthFactor = 0.54 * MaxUint64
+	assert.True(t, SampledByRate(1, 0.5))
+	assert.False(t, SampledByRate(9, 0.5))
+	assert.True(t, SampledByRate(9, 0.55))
+
+	// Bounds
+	assert.True(t, SampledByRate(9, 1))
+	assert.False(t, SampledByRate(1, 0))
+}
@@ -0,0 +1,6 @@
+// Package version defines the version of this module, sent to Datadog as
+// tracer version.
+package version
+
+// Tag specifies the current release tag.
+const Tag = "v0.2.0"
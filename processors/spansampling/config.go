@@ -0,0 +1,106 @@
+package spansampling
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// _____________________ With option functions _____________________
+
+// WithRules sets the span sampling rules, applied in order.
+// It defaults to the rules of WithRulesFile, DD_SPAN_SAMPLING_RULES or
+// DD_SPAN_SAMPLING_RULES_FILE environment variables.
+func WithRules(rules []Rule) configFn {
+	return func(conf *config) {
+		conf.rules = rules
+	}
+}
+
+// WithRulesFile sets the path of a JSON file holding the span sampling rules.
+func WithRulesFile(path string) configFn {
+	return func(conf *config) {
+		conf.rulesFile = path
+	}
+}
+
+// _____________________ Definition _____________________
+
+type configFn func(*config)
+
+var ErrInvalidRules = errors.New("invalid span sampling rules")
+
+// Ref https://docs.datadoghq.com/tracing/trace_pipeline/ingestion_mechanisms/?tab=go#single-spans
+
+const (
+	envRules     = "DD_SPAN_SAMPLING_RULES"
+	envRulesFile = "DD_SPAN_SAMPLING_RULES_FILE"
+)
+
+// _____________________ Configuration _____________________
+
+func newConfig(cfg ...configFn) (*config, error) {
+	var conf = &config{}
+
+	// Apply configurations
+	for _, v := range cfg {
+		if v != nil {
+			v(conf)
+		}
+	}
+
+	// Apply default value on empty
+	if err := conf.applyDefault(); err != nil {
+		return nil, err
+	}
+
+	// Check configuration is valid
+	if err := conf.parse(); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+type config struct {
+	rules     []Rule
+	rulesFile string
+}
+
+func (obj *config) applyDefault() error {
+	if obj.rules != nil {
+		return nil
+	}
+
+	// Rules of the environment take precedence over the rules file of the environment
+	var data = strings.TrimSpace(os.Getenv(envRules))
+	if obj.rulesFile == "" && data == "" {
+		obj.rulesFile = strings.TrimSpace(os.Getenv(envRulesFile))
+	}
+	if obj.rulesFile != "" {
+		content, err := os.ReadFile(obj.rulesFile)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidRules, err)
+		}
+		data = string(content)
+	}
+	if data == "" {
+		return nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &obj.rules); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidRules, err)
+	}
+	return nil
+}
+
+func (obj *config) parse() error {
+	for i := range obj.rules {
+		if err := obj.rules[i].compile(); err != nil {
+			return fmt.Errorf("%w: rule %d: %v", ErrInvalidRules, i, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package spansampling
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setenv(t *testing.T, key, value string) {
+	prev, exists := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if exists {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+// test_rulesFile writes rules in a temporary file, returning its path.
+func test_rulesFile(t *testing.T, content string) string {
+	var path = filepath.Join(t.TempDir(), "rules.json")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func Test_Config_NewConfig(t *testing.T) {
+	setenv(t, envRules, "")
+	setenv(t, envRulesFile, "")
+
+	// Check default values applied
+	if conf, err := newConfig(); assert.NoError(t, err) {
+		assert.Empty(t, conf.rules)
+	}
+
+	// Check options applied
+	if conf, err := newConfig(WithRules([]Rule{{Service: "api", SampleRate: 0.5}})); assert.NoError(t, err) {
+		assert.Len(t, conf.rules, 1)
+	}
+	var path = test_rulesFile(t, `[{"name": "kafka.*", "max_per_second": 10}]`)
+	if conf, err := newConfig(WithRulesFile(path)); assert.NoError(t, err) && assert.Len(t, conf.rules, 1) {
+		assert.Equal(t, "kafka.*", conf.rules[0].Name)
+		assert.Equal(t, 1.0, conf.rules[0].SampleRate)
+		assert.Equal(t, 10.0, conf.rules[0].MaxPerSecond)
+	}
+
+	// Check invalid configuration
+	_, err := newConfig(WithRules([]Rule{{SampleRate: 2}}))
+	assert.ErrorIs(t, err, ErrInvalidRules)
+	_, err = newConfig(WithRulesFile(filepath.Join(t.TempDir(), "missing.json")))
+	assert.ErrorIs(t, err, ErrInvalidRules)
+	_, err = newConfig(WithRulesFile(test_rulesFile(t, `{`)))
+	assert.ErrorIs(t, err, ErrInvalidRules)
+}
+
+func Test_Config_Env(t *testing.T) {
+	setenv(t, envRules, "")
+	setenv(t, envRulesFile, test_rulesFile(t, `[{"service": "file"}]`))
+
+	// Rules file of the environment
+	if conf, err := newConfig(); assert.NoError(t, err) && assert.Len(t, conf.rules, 1) {
+		assert.Equal(t, "file", conf.rules[0].Service)
+	}
+
+	// Rules of the environment take precedence over the rules file
+	setenv(t, envRules, `[{"service": "env"}, {"name": "db.*", "sample_rate": 0.5}]`)
+	if conf, err := newConfig(); assert.NoError(t, err) && assert.Len(t, conf.rules, 2) {
+		assert.Equal(t, "env", conf.rules[0].Service)
+		assert.Equal(t, 0.5, conf.rules[1].SampleRate)
+	}
+
+	// Options take precedence
+	if conf, err := newConfig(WithRulesFile(test_rulesFile(t, `[{"service": "option"}]`))); assert.NoError(t, err) && assert.Len(t, conf.rules, 1) {
+		assert.Equal(t, "option", conf.rules[0].Service)
+	}
+	if conf, err := newConfig(WithRules([]Rule{})); assert.NoError(t, err) {
+		assert.Empty(t, conf.rules)
+	}
+
+	// Check invalid rules
+	setenv(t, envRules, `[{"max_per_second": -1}]`)
+	_, err := newConfig()
+	assert.ErrorIs(t, err, ErrInvalidRules)
+}
@@ -0,0 +1,174 @@
+// Package spansampling keeps single spans of dropped traces matching span
+// sampling rules, as the Datadog tracing library does.
+package spansampling
+
+import (
+	"context"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/naming"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/sampling"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/span.go (keySpanSampling*)
+
+// Attributes set on spans kept by span sampling rules
+const (
+	// AttributeMechanism stores the span sampling mechanism (8).
+	AttributeMechanism = attribute.Key("_dd.span_sampling.mechanism")
+	// AttributeRuleRate stores the sample rate of the matching rule.
+	AttributeRuleRate = attribute.Key("_dd.span_sampling.rule_rate")
+	// AttributeMaxPerSecond stores the span limit of the matching rule, if set.
+	AttributeMaxPerSecond = attribute.Key("_dd.span_sampling.max_per_second")
+
+	attributeSamplingPriority = attribute.Key("_sampling_priority_v1")
+)
+
+// mechanismSingleSpan is the sampling mechanism of span sampling rules.
+const mechanismSingleSpan = 8
+
+// Processor is a span processor forwarding the spans of sampled traces to the
+// next processor, with the spans of dropped traces kept by the span sampling
+// rules. Spans of dropped traces are only recorded with the sampler returned by
+// NewSampler.
+type Processor struct {
+	next  sdktrace.SpanProcessor
+	rules []Rule
+	now   func() time.Time
+}
+
+var _ sdktrace.SpanProcessor = (*Processor)(nil)
+
+// New returns a processor applying span sampling rules, forwarding kept spans
+// to next (ex: a batch span processor).
+// To use the defaults, call with next only.
+func New(next sdktrace.SpanProcessor, cfg ...configFn) (*Processor, error) {
+	conf, err := newConfig(cfg...)
+	if err != nil {
+		return nil, err
+	}
+	return &Processor{
+		next:  next,
+		rules: conf.rules,
+		now:   time.Now,
+	}, nil
+}
+
+// OnStart forwards spans of sampled traces.
+func (obj *Processor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	if s.SpanContext().IsSampled() {
+		obj.next.OnStart(parent, s)
+	}
+}
+
+// OnEnd forwards spans of sampled traces, and spans of dropped traces kept by
+// the first matching rule, marked as sampled.
+func (obj *Processor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanContext().IsSampled() {
+		obj.next.OnEnd(s)
+		return
+	}
+	if kept, ok := obj.sample(s); ok {
+		obj.next.OnEnd(kept)
+	}
+}
+
+// ForceFlush flushes the next processor.
+func (obj *Processor) ForceFlush(ctx context.Context) error {
+	return obj.next.ForceFlush(ctx)
+}
+
+// Shutdown shuts down the next processor.
+func (obj *Processor) Shutdown(ctx context.Context) error {
+	return obj.next.Shutdown(ctx)
+}
+
+// Rules returns the span sampling rules applied in order.
+func (obj *Processor) Rules() []Rule {
+	return append([]Rule(nil), obj.rules...)
+}
+
+// sample returns the span marked as kept if sampled by the first matching rule.
+func (obj *Processor) sample(s sdktrace.ReadOnlySpan) (sdktrace.ReadOnlySpan, bool) {
+	if len(obj.rules) == 0 {
+		return nil, false
+	}
+
+	var resAttr naming.Attributes
+	if res := s.Resource(); res != nil {
+		resAttr = naming.NewAttributes(res.Attributes())
+	}
+	var (
+		service = naming.ServiceName(resAttr)
+		name    = naming.OperationName(s.SpanKind(), naming.NewAttributes(s.Attributes()))
+	)
+	for i := range obj.rules {
+		var rule = &obj.rules[i]
+		if !rule.match(service, name) {
+			continue
+		}
+		if !sampling.SampledByRate(tracecontext.SpanIDToUint64(s.SpanContext().SpanID()), rule.SampleRate) {
+			return nil, false
+		}
+		if rule.limiter != nil {
+			if allowed, _ := rule.limiter.AllowOne(obj.now()); !allowed {
+				return nil, false
+			}
+		}
+		return newKeptSpan(s, rule), true
+	}
+	return nil, false
+}
+
+// _____ Kept span _____
+
+// keptSpan is a span of a dropped trace kept by a rule, marked as sampled so
+// that processors export it, with the span sampling attributes.
+type keptSpan struct {
+	sdktrace.ReadOnlySpan
+	spanCtx trace.SpanContext
+	attrs   []attribute.KeyValue
+}
+
+func newKeptSpan(s sdktrace.ReadOnlySpan, rule *Rule) *keptSpan {
+	var (
+		src   = s.Attributes()
+		attrs = make([]attribute.KeyValue, 0, len(src)+4)
+	)
+	attrs = append(attrs, src...)
+
+	// Priority of the dropped trace kept
+	var hasPriority bool
+	for _, kv := range src {
+		hasPriority = hasPriority || kv.Key == attributeSamplingPriority
+	}
+	if !hasPriority {
+		attrs = append(attrs, attributeSamplingPriority.Int64(0))
+	}
+
+	attrs = append(attrs,
+		AttributeMechanism.Int64(mechanismSingleSpan),
+		AttributeRuleRate.Float64(rule.SampleRate),
+	)
+	if rule.MaxPerSecond > 0 {
+		attrs = append(attrs, AttributeMaxPerSecond.Float64(rule.MaxPerSecond))
+	}
+
+	return &keptSpan{
+		ReadOnlySpan: s,
+		spanCtx:      s.SpanContext().WithTraceFlags(s.SpanContext().TraceFlags().WithSampled(true)),
+		attrs:        attrs,
+	}
+}
+
+func (obj *keptSpan) SpanContext() trace.SpanContext {
+	return obj.spanCtx
+}
+
+func (obj *keptSpan) Attributes() []attribute.KeyValue {
+	return obj.attrs
+}
@@ -0,0 +1,122 @@
+package spansampling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func test_newProvider(t *testing.T, root sdktrace.Sampler, cfg ...configFn) (trace.Tracer, *Processor, *tracetest.SpanRecorder) {
+	var recorder = tracetest.NewSpanRecorder()
+	processor, err := New(recorder, cfg...)
+	require.NoError(t, err)
+	var now = time.Unix(1000, 0)
+	processor.now = func() time.Time { return now }
+
+	var provider = sdktrace.NewTracerProvider(
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceNameKey.String("api"))),
+		sdktrace.WithSampler(NewSampler(root)),
+		sdktrace.WithSpanProcessor(processor),
+	)
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return provider.Tracer("test"), processor, recorder
+}
+
+var kafkaProduce = trace.WithAttributes(
+	semconv.MessagingSystemKey.String("kafka"),
+	semconv.MessagingOperationKey.String("produce"),
+)
+
+func Test_New(t *testing.T) {
+	_, err := New(tracetest.NewSpanRecorder(), WithRules([]Rule{{SampleRate: -1}}))
+	assert.ErrorIs(t, err, ErrInvalidRules)
+
+	processor, err := New(tracetest.NewSpanRecorder(), WithRules([]Rule{{Name: "kafka.*", SampleRate: 1}}))
+	require.NoError(t, err)
+	if rules := processor.Rules(); assert.Len(t, rules, 1) {
+		assert.Equal(t, "kafka.*", rules[0].Name)
+	}
+}
+
+func Test_Processor_SampledTrace(t *testing.T) {
+	var tracer, _, recorder = test_newProvider(t, sdktrace.AlwaysSample(), WithRules([]Rule{{SampleRate: 1}}))
+
+	_, s := tracer.Start(context.Background(), "root")
+	s.End()
+
+	assert.Len(t, recorder.Started(), 1)
+	if ended := recorder.Ended(); assert.Len(t, ended, 1) {
+		assert.Empty(t, ended[0].Attributes())
+	}
+}
+
+func Test_Processor_DroppedTrace(t *testing.T) {
+	var tracer, _, recorder = test_newProvider(t, sdktrace.NeverSample(), WithRules([]Rule{
+		{Service: "api", Name: "kafka.produce", SampleRate: 1, MaxPerSecond: 1},
+		{Name: "db.*", SampleRate: 0},
+	}))
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, produce := tracer.Start(ctx, "publish", trace.WithSpanKind(trace.SpanKindProducer), kafkaProduce)
+	produce.End()
+	_, other := tracer.Start(ctx, "other")
+	other.End()
+	root.End()
+
+	// Only the span matching a rule kept, marked as sampled
+	assert.Empty(t, recorder.Started())
+	var ended = recorder.Ended()
+	require.Len(t, ended, 1)
+	assert.Equal(t, "publish", ended[0].Name())
+	assert.True(t, ended[0].SpanContext().IsSampled())
+	assert.Equal(t, produce.SpanContext().SpanID(), ended[0].SpanContext().SpanID())
+	assert.Subset(t, ended[0].Attributes(), []attribute.KeyValue{
+		attributeSamplingPriority.Int64(0),
+		AttributeMechanism.Int64(8),
+		AttributeRuleRate.Float64(1),
+		AttributeMaxPerSecond.Float64(1),
+	})
+
+	// Spans over the limit dropped
+	_, produce = tracer.Start(ctx, "publish", trace.WithSpanKind(trace.SpanKindProducer), kafkaProduce)
+	produce.End()
+	assert.Len(t, recorder.Ended(), 1)
+}
+
+func Test_Processor_sample(t *testing.T) {
+	var tracer, processor, _ = test_newProvider(t, sdktrace.NeverSample(), WithRules([]Rule{
+		{Name: "postgresql.*", SampleRate: 0},
+		{SampleRate: 1},
+	}))
+
+	// First matching rule applied
+	_, s := tracer.Start(context.Background(), "query", trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(semconv.DBSystemKey.String("postgresql")))
+	s.End()
+	_, ok := processor.sample(s.(sdktrace.ReadOnlySpan))
+	assert.False(t, ok)
+
+	// Priority of the trace kept, max per second not set when unlimited
+	_, s = tracer.Start(context.Background(), "other", trace.WithAttributes(attributeSamplingPriority.Int64(-1)))
+	s.End()
+	kept, ok := processor.sample(s.(sdktrace.ReadOnlySpan))
+	require.True(t, ok)
+	assert.Equal(t, []attribute.KeyValue{
+		attributeSamplingPriority.Int64(-1),
+		AttributeMechanism.Int64(8),
+		AttributeRuleRate.Float64(1),
+	}, kept.Attributes())
+
+	// No rule
+	processor.rules = nil
+	_, ok = processor.sample(s.(sdktrace.ReadOnlySpan))
+	assert.False(t, ok)
+}
@@ -0,0 +1,78 @@
+package spansampling
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/sampling"
+)
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/rules_sampler.go (SpanSamplingRules)
+
+// Rule keeps the spans of dropped traces matching its service and operation
+// name patterns with its sample rate, up to a maximum number of spans per
+// second. Patterns are globs, case insensitive: "*" matches any sequence of
+// characters and "?" any single character. An empty pattern matches all values.
+type Rule struct {
+	// Service is the pattern of the service.
+	Service string `json:"service,omitempty"`
+	// Name is the pattern of the Datadog operation name of the span.
+	Name string `json:"name,omitempty"`
+	// SampleRate is the rate of kept spans, in [0, 1].
+	SampleRate float64 `json:"sample_rate"`
+	// MaxPerSecond is the maximum number of kept spans per second, unlimited if 0.
+	MaxPerSecond float64 `json:"max_per_second,omitempty"`
+
+	service sampling.Glob
+	name    sampling.Glob
+	limiter *sampling.RateLimiter
+}
+
+var (
+	errRuleSampleRate   = errors.New("sample rate out of [0, 1] range")
+	errRuleMaxPerSecond = errors.New("negative max per second")
+)
+
+// UnmarshalJSON decodes a rule of DD_SPAN_SAMPLING_RULES, the sample rate
+// defaulting to 1 when missing.
+func (obj *Rule) UnmarshalJSON(data []byte) error {
+	type jsonRule Rule
+	var rule = jsonRule{SampleRate: 1}
+	if err := json.Unmarshal(data, &rule); err != nil {
+		return err
+	}
+	*obj = Rule(rule)
+	return nil
+}
+
+// ParseRules returns the checked span sampling rules of the options, or of the
+// environment variables, as applied by New (ex: to know if rules are set).
+func ParseRules(cfg ...configFn) ([]Rule, error) {
+	conf, err := newConfig(cfg...)
+	if err != nil {
+		return nil, err
+	}
+	return conf.rules, nil
+}
+
+// compile checks the rule, compiles its patterns and creates its rate limiter.
+func (obj *Rule) compile() error {
+	if obj.SampleRate < 0 || obj.SampleRate > 1 || math.IsNaN(obj.SampleRate) {
+		return errRuleSampleRate
+	}
+	if obj.MaxPerSecond < 0 {
+		return errRuleMaxPerSecond
+	}
+	obj.service = sampling.NewGlob(obj.Service)
+	obj.name = sampling.NewGlob(obj.Name)
+	if obj.MaxPerSecond > 0 {
+		obj.limiter = sampling.NewRateLimiter(obj.MaxPerSecond)
+	}
+	return nil
+}
+
+// match returns true if the span matches the patterns of the rule.
+func (obj *Rule) match(service, name string) bool {
+	return obj.service.Match(service) && obj.name.Match(name)
+}
@@ -0,0 +1,66 @@
+package spansampling
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_Rule_UnmarshalJSON(t *testing.T) {
+	var rules []Rule
+	require.NoError(t, json.Unmarshal([]byte(`[{"service": "api", "name": "kafka.produce", "sample_rate": 0.5, "max_per_second": 10}, {"name": "db.*"}]`), &rules))
+	assert.Equal(t, []Rule{
+		{Service: "api", Name: "kafka.produce", SampleRate: 0.5, MaxPerSecond: 10},
+		{Name: "db.*", SampleRate: 1},
+	}, rules)
+
+	assert.Error(t, json.Unmarshal([]byte(`[{"max_per_second": "high"}]`), &rules))
+}
+
+func Test_ParseRules(t *testing.T) {
+	setenv(t, envRules, `[{"service": "api", "sample_rate": 0.5}]`)
+	setenv(t, envRulesFile, "")
+
+	if rules, err := ParseRules(); assert.NoError(t, err) && assert.Len(t, rules, 1) {
+		assert.Equal(t, "api", rules[0].Service)
+		assert.Equal(t, 0.5, rules[0].SampleRate)
+	}
+	if rules, err := ParseRules(WithRules([]Rule{})); assert.NoError(t, err) {
+		assert.Empty(t, rules)
+	}
+
+	setenv(t, envRules, `[{"sample_rate": 2}]`)
+	_, err := ParseRules()
+	assert.ErrorIs(t, err, ErrInvalidRules)
+}
+
+func Test_Rule_compile(t *testing.T) {
+	for _, rule := range []Rule{{SampleRate: -0.1}, {SampleRate: 1.1}} {
+		assert.ErrorIs(t, rule.compile(), errRuleSampleRate)
+	}
+	var rule = Rule{SampleRate: 1, MaxPerSecond: -1}
+	assert.ErrorIs(t, rule.compile(), errRuleMaxPerSecond)
+
+	// Limiter created only when limited
+	rule = Rule{SampleRate: 1}
+	require.NoError(t, rule.compile())
+	assert.Nil(t, rule.limiter)
+	rule = Rule{SampleRate: 1, MaxPerSecond: 5}
+	require.NoError(t, rule.compile())
+	assert.NotNil(t, rule.limiter)
+}
+
+func Test_Rule_match(t *testing.T) {
+	var rule = Rule{Service: "api*", Name: "kafka.produce", SampleRate: 1}
+	require.NoError(t, rule.compile())
+	assert.True(t, rule.match("api", "kafka.produce"))
+	assert.True(t, rule.match("API-v2", "Kafka.Produce"))
+	assert.False(t, rule.match("worker", "kafka.produce"))
+	assert.False(t, rule.match("api", "kafka.consume"))
+
+	rule = Rule{SampleRate: 1}
+	require.NoError(t, rule.compile())
+	assert.True(t, rule.match("any", "any"))
+}
@@ -0,0 +1,32 @@
+package spansampling
+
+import (
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// sampler records the spans of dropped traces, for the span sampling rules of
+// the processor.
+type sampler struct {
+	root sdktrace.Sampler
+}
+
+// NewSampler returns a sampler taking the decisions of root, the spans of
+// dropped traces being recorded but not sampled, so that the processor applies
+// the span sampling rules on them.
+func NewSampler(root sdktrace.Sampler) sdktrace.Sampler {
+	return sampler{root: root}
+}
+
+// ShouldSample returns the decision of the root sampler, RecordOnly instead of Drop.
+func (obj sampler) ShouldSample(params sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	var result = obj.root.ShouldSample(params)
+	if result.Decision == sdktrace.Drop {
+		result.Decision = sdktrace.RecordOnly
+	}
+	return result
+}
+
+// Description returns the name of the sampler.
+func (obj sampler) Description() string {
+	return "SpanSampling{" + obj.root.Description() + "}"
+}
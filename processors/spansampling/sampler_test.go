@@ -0,0 +1,17 @@
+package spansampling
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func Test_sampler_ShouldSample(t *testing.T) {
+	var sampler = NewSampler(sdktrace.NeverSample())
+	assert.Equal(t, "SpanSampling{AlwaysOffSampler}", sampler.Description())
+	assert.Equal(t, sdktrace.RecordOnly, sampler.ShouldSample(sdktrace.SamplingParameters{}).Decision)
+
+	sampler = NewSampler(sdktrace.AlwaysSample())
+	assert.Equal(t, sdktrace.RecordAndSample, sampler.ShouldSample(sdktrace.SamplingParameters{}).Decision)
+}
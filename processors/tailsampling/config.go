@@ -0,0 +1,156 @@
+package tailsampling
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// _____________________ With option functions _____________________
+
+// WithErrors keeps the traces having a span with an error status.
+// It defaults to true.
+func WithErrors(enabled bool) configFn {
+	return func(conf *config) {
+		conf.errors = &enabled
+	}
+}
+
+// WithLatencyThreshold keeps the traces having a span lasting at least value.
+// It defaults to 0, latency being ignored.
+func WithLatencyThreshold(value time.Duration) configFn {
+	return func(conf *config) {
+		conf.latencyThreshold = value
+	}
+}
+
+// WithPredicate keeps the traces having a span matching the predicate
+// (ex: an attribute value). It can be set several times, any predicate keeping
+// the trace.
+func WithPredicate(predicate func(sdktrace.ReadOnlySpan) bool) configFn {
+	return func(conf *config) {
+		if predicate != nil {
+			conf.predicates = append(conf.predicates, predicate)
+		}
+	}
+}
+
+// WithSampleRate keeps the traces matching no keep rule at a sample rate, in
+// [0, 1], from the hash of their trace ID as the Datadog samplers do.
+// It defaults to 0, only traces matching a rule being upgraded.
+func WithSampleRate(rate float64) configFn {
+	return func(conf *config) {
+		conf.sampleRate = rate
+	}
+}
+
+// WithDecisionWait sets the maximum duration a trace is buffered, waiting for
+// its local spans to end. The decision is taken with the spans ended so far
+// once elapsed.
+// It defaults to DefaultDecisionWait.
+func WithDecisionWait(value time.Duration) configFn {
+	return func(conf *config) {
+		conf.decisionWait = value
+	}
+}
+
+// WithMaxBufferedSpans sets the maximum number of buffered spans, the oldest
+// traces being evicted (decided with the spans ended so far) when reached.
+// It defaults to DefaultMaxBufferedSpans.
+func WithMaxBufferedSpans(value int) configFn {
+	return func(conf *config) {
+		conf.maxBufferedSpans = value
+	}
+}
+
+// WithMaxBufferedBytes sets the maximum estimated size in bytes of buffered
+// spans, the oldest traces being evicted (decided with the spans ended so far)
+// when reached.
+// It defaults to DefaultMaxBufferedBytes.
+func WithMaxBufferedBytes(value int) configFn {
+	return func(conf *config) {
+		conf.maxBufferedBytes = value
+	}
+}
+
+// _____________________ Definition _____________________
+
+type configFn func(*config)
+
+var (
+	ErrInvalidSampleRate       = errors.New("sample rate out of [0, 1] range")
+	ErrInvalidDecisionWait     = errors.New("invalid decision wait")
+	ErrInvalidMaxBufferedSpans = errors.New("invalid maximum buffered spans")
+	ErrInvalidMaxBufferedBytes = errors.New("invalid maximum buffered bytes")
+)
+
+const (
+	// DefaultDecisionWait specifies the maximum duration a trace is buffered.
+	DefaultDecisionWait = 30 * time.Second
+
+	// DefaultMaxBufferedSpans specifies the maximum number of buffered spans.
+	DefaultMaxBufferedSpans = 100000
+
+	// DefaultMaxBufferedBytes specifies the maximum estimated size of buffered spans.
+	DefaultMaxBufferedBytes = 64 << 20
+)
+
+// _____________________ Configuration _____________________
+
+func newConfig(cfg ...configFn) (*config, error) {
+	var conf = &config{}
+
+	// Apply configurations
+	for _, v := range cfg {
+		if v != nil {
+			v(conf)
+		}
+	}
+
+	// Apply default value on empty
+	conf.applyDefault()
+
+	// Check configuration is valid
+	if conf.sampleRate < 0 || conf.sampleRate > 1 || math.IsNaN(conf.sampleRate) {
+		return nil, ErrInvalidSampleRate
+	}
+	if conf.decisionWait < 0 {
+		return nil, ErrInvalidDecisionWait
+	}
+	if conf.maxBufferedSpans < 0 {
+		return nil, ErrInvalidMaxBufferedSpans
+	}
+	if conf.maxBufferedBytes < 0 {
+		return nil, ErrInvalidMaxBufferedBytes
+	}
+
+	return conf, nil
+}
+
+type config struct {
+	errors           *bool
+	latencyThreshold time.Duration
+	predicates       []func(sdktrace.ReadOnlySpan) bool
+	sampleRate       float64
+	decisionWait     time.Duration
+	maxBufferedSpans int
+	maxBufferedBytes int
+}
+
+func (obj *config) applyDefault() {
+	if obj.errors == nil {
+		var enabled = true
+		obj.errors = &enabled
+	}
+	if obj.decisionWait == 0 {
+		obj.decisionWait = DefaultDecisionWait
+	}
+	if obj.maxBufferedSpans == 0 {
+		obj.maxBufferedSpans = DefaultMaxBufferedSpans
+	}
+	if obj.maxBufferedBytes == 0 {
+		obj.maxBufferedBytes = DefaultMaxBufferedBytes
+	}
+}
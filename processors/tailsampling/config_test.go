@@ -0,0 +1,50 @@
+package tailsampling
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func Test_Config_NewConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	// Check default values applied
+	if conf, err := newConfig(); assert.NoError(err) {
+		assert.True(*conf.errors)
+		assert.Zero(conf.latencyThreshold)
+		assert.Empty(conf.predicates)
+		assert.Zero(conf.sampleRate)
+		assert.Equal(DefaultDecisionWait, conf.decisionWait)
+		assert.Equal(DefaultMaxBufferedSpans, conf.maxBufferedSpans)
+		assert.Equal(DefaultMaxBufferedBytes, conf.maxBufferedBytes)
+	}
+
+	// Check options applied
+	var predicate = func(sdktrace.ReadOnlySpan) bool { return true }
+	if conf, err := newConfig(WithErrors(false), WithLatencyThreshold(time.Second), WithPredicate(predicate), WithPredicate(nil),
+		WithSampleRate(0.1), WithDecisionWait(time.Minute), WithMaxBufferedSpans(10), WithMaxBufferedBytes(1000)); assert.NoError(err) {
+		assert.False(*conf.errors)
+		assert.Equal(time.Second, conf.latencyThreshold)
+		assert.Len(conf.predicates, 1)
+		assert.Equal(0.1, conf.sampleRate)
+		assert.Equal(time.Minute, conf.decisionWait)
+		assert.Equal(10, conf.maxBufferedSpans)
+		assert.Equal(1000, conf.maxBufferedBytes)
+	}
+
+	// Check invalid configuration
+	for _, rate := range []float64{-0.1, 1.1, math.NaN()} {
+		_, err := newConfig(WithSampleRate(rate))
+		assert.ErrorIs(err, ErrInvalidSampleRate)
+	}
+	_, err := newConfig(WithDecisionWait(-1))
+	assert.ErrorIs(err, ErrInvalidDecisionWait)
+	_, err = newConfig(WithMaxBufferedSpans(-1))
+	assert.ErrorIs(err, ErrInvalidMaxBufferedSpans)
+	_, err = newConfig(WithMaxBufferedBytes(-1))
+	assert.ErrorIs(err, ErrInvalidMaxBufferedBytes)
+}
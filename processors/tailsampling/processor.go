@@ -0,0 +1,439 @@
+// Package tailsampling keeps whole local traces having an error, a slow span or
+// a span matching a predicate, decided once their spans ended.
+package tailsampling
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/sampling"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Attributes set on the spans of kept traces, mapped by the Datadog exporter
+const (
+	attributeSamplingPriority = attribute.Key("_sampling_priority_v1")
+	attributeDecisionMaker    = attribute.Key("_dd.p.dm")
+)
+
+const (
+	// priorityUserKeep is the priority of kept traces.
+	priorityUserKeep = 2
+	// decisionMakerManual is the decision maker of kept traces, kept by user configuration.
+	decisionMakerManual = "-4"
+)
+
+// expireInterval is the maximum interval between checks of expired traces.
+const expireInterval = time.Second
+
+var errBufferFull = errors.New("tail sampling buffer full, oldest traces decided before their end")
+
+// Processor is a span processor buffering the spans of local traces until they
+// all end. A trace having a span with an error status, lasting at least the
+// latency threshold or matching a predicate, or kept by the sample rate, is
+// upgraded to USER_KEEP, its spans being marked as sampled. Spans of other traces are forwarded unchanged, with
+// the decision of the head sampler. Spans are forwarded to the next processor
+// (ex: a batch span processor) when the trace is decided.
+//
+// The head sampler must record the spans of all traces, for them to be
+// buffered (ex: spansampling.NewSampler or sdktrace.AlwaysSample). Buffered
+// spans are limited by number, estimated size and duration: oldest traces are
+// evicted, or expired, being decided with the spans ended so far. Evictions are
+// reported with otel.Handle, at most once per second.
+type Processor struct {
+	conf *config
+	next sdktrace.SpanProcessor
+	now  func() time.Time
+
+	mu      sync.Mutex
+	traces  map[trace.TraceID]*bufferedTrace
+	pending *list.List // undecided traces, oldest first
+	spans   int
+	bytes   int
+	stats   Stats
+	stopped bool
+	// Evictions already reported by otel.Handle
+	reportedTraces uint64
+	reportedSpans  uint64
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// Stats are the counters of the processor.
+type Stats struct {
+	// BufferedTraces is the number of traces waiting for a decision.
+	BufferedTraces int
+	// BufferedSpans is the number of buffered spans.
+	BufferedSpans int
+	// BufferedBytes is the estimated size in bytes of buffered spans.
+	BufferedBytes int
+	// KeptTraces is the number of traces upgraded to USER_KEEP.
+	KeptTraces uint64
+	// EvictedTraces is the number of traces decided before their end, the
+	// maximum number or size of buffered spans being reached.
+	EvictedTraces uint64
+	// EvictedSpans is the number of spans of evicted traces.
+	EvictedSpans uint64
+	// ExpiredTraces is the number of traces decided before their end, the
+	// decision wait being elapsed.
+	ExpiredTraces uint64
+}
+
+// bufferedTrace holds the ended spans of a trace having unfinished local spans,
+// or the decision of a trace decided before its end.
+type bufferedTrace struct {
+	start   time.Time
+	open    int
+	spans   []sdktrace.ReadOnlySpan
+	bytes   int
+	elem    *list.Element // element in pending, nil once decided
+	kept    bool
+	decided time.Time
+}
+
+// New returns a processor keeping traces with errors by default, forwarding
+// spans to next.
+// To use the defaults, call with next only.
+func New(next sdktrace.SpanProcessor, cfg ...configFn) (*Processor, error) {
+	conf, err := newConfig(cfg...)
+	if err != nil {
+		return nil, err
+	}
+
+	var processor = newProcessor(next, conf)
+	var interval = expireInterval
+	if conf.decisionWait < interval {
+		interval = conf.decisionWait
+	}
+	processor.wg.Add(1)
+	go processor.run(interval)
+	return processor, nil
+}
+
+func newProcessor(next sdktrace.SpanProcessor, conf *config) *Processor {
+	return &Processor{
+		conf:    conf,
+		next:    next,
+		now:     time.Now,
+		traces:  map[trace.TraceID]*bufferedTrace{},
+		pending: list.New(),
+		done:    make(chan struct{}),
+	}
+}
+
+// OnStart tracks the span as an unfinished span of its trace.
+func (obj *Processor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	if obj.stopped {
+		return
+	}
+
+	var traceID = s.SpanContext().TraceID()
+	t, ok := obj.traces[traceID]
+	if !ok {
+		t = &bufferedTrace{start: obj.now()}
+		t.elem = obj.pending.PushBack(traceID)
+		obj.traces[traceID] = t
+	}
+	t.open++
+}
+
+// OnEnd buffers the span until the end of the local spans of its trace, then
+// forwards the spans with the decision.
+func (obj *Processor) OnEnd(s sdktrace.ReadOnlySpan) {
+	obj.mu.Lock()
+	var forward = obj.end(s)
+	obj.mu.Unlock()
+
+	obj.forward(forward)
+}
+
+// ForceFlush forwards the spans of buffered traces, decided with the spans
+// ended so far, and flushes the next processor.
+func (obj *Processor) ForceFlush(ctx context.Context) error {
+	obj.mu.Lock()
+	var forward = obj.decideAll()
+	obj.mu.Unlock()
+
+	obj.forward(forward)
+	return obj.next.ForceFlush(ctx)
+}
+
+// Shutdown forwards the spans of buffered traces, then shuts down the next
+// processor. Spans ended after shutdown are ignored.
+func (obj *Processor) Shutdown(ctx context.Context) error {
+	obj.mu.Lock()
+	if obj.stopped {
+		obj.mu.Unlock()
+		return nil
+	}
+	var forward = obj.decideAll()
+	obj.stopped = true
+	obj.traces = nil
+	close(obj.done)
+	obj.mu.Unlock()
+
+	obj.wg.Wait()
+	obj.forward(forward)
+	return obj.next.Shutdown(ctx)
+}
+
+// Stats returns the counters of the processor.
+func (obj *Processor) Stats() Stats {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	var stats = obj.stats
+	stats.BufferedTraces = obj.pending.Len()
+	stats.BufferedSpans = obj.spans
+	stats.BufferedBytes = obj.bytes
+	return stats
+}
+
+// run decides expired traces periodically, until shutdown.
+func (obj *Processor) run(interval time.Duration) {
+	defer obj.wg.Done()
+	var ticker = time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-obj.done:
+			return
+		case <-ticker.C:
+			obj.mu.Lock()
+			var (
+				forward = obj.expire(obj.now())
+				err     = obj.evictionError()
+			)
+			obj.mu.Unlock()
+			obj.forward(forward)
+			if err != nil {
+				otel.Handle(err)
+			}
+		}
+	}
+}
+
+// evictionError returns the evictions since the last call, nil if none, mu
+// being locked.
+func (obj *Processor) evictionError() error {
+	var traces, spans = obj.stats.EvictedTraces - obj.reportedTraces, obj.stats.EvictedSpans - obj.reportedSpans
+	if traces == 0 {
+		return nil
+	}
+	obj.reportedTraces, obj.reportedSpans = obj.stats.EvictedTraces, obj.stats.EvictedSpans
+	return fmt.Errorf("%w: %d traces (%d spans)", errBufferFull, traces, spans)
+}
+
+// end buffers the ended span, returning the spans to forward, mu being locked.
+func (obj *Processor) end(s sdktrace.ReadOnlySpan) []sdktrace.ReadOnlySpan {
+	if obj.stopped {
+		return nil
+	}
+
+	var traceID = s.SpanContext().TraceID()
+	t, ok := obj.traces[traceID]
+	if !ok {
+		// Started before the processor was registered
+		return []sdktrace.ReadOnlySpan{s}
+	}
+
+	t.open--
+	if t.elem == nil {
+		// Span ended after the decision of its trace
+		if t.open <= 0 {
+			delete(obj.traces, traceID)
+		}
+		return markSpans([]sdktrace.ReadOnlySpan{s}, t.kept)
+	}
+
+	var size = spanSize(s)
+	t.spans = append(t.spans, s)
+	t.bytes += size
+	obj.spans++
+	obj.bytes += size
+	var forward []sdktrace.ReadOnlySpan
+	if t.open <= 0 {
+		forward = obj.decide(traceID, t)
+	}
+
+	// Oldest traces evicted over the buffer limit
+	for (obj.spans > obj.conf.maxBufferedSpans || obj.bytes > obj.conf.maxBufferedBytes) && obj.pending.Len() > 0 {
+		var (
+			oldestID = obj.pending.Front().Value.(trace.TraceID)
+			oldest   = obj.traces[oldestID]
+		)
+		obj.stats.EvictedTraces++
+		obj.stats.EvictedSpans += uint64(len(oldest.spans))
+		forward = append(forward, obj.decide(oldestID, oldest)...)
+	}
+	return forward
+}
+
+// expire decides the traces buffered for longer than the decision wait, and
+// forgets the decisions of traces decided for longer than the decision wait,
+// mu being locked.
+func (obj *Processor) expire(now time.Time) []sdktrace.ReadOnlySpan {
+	var forward []sdktrace.ReadOnlySpan
+	for traceID, t := range obj.traces {
+		switch {
+		case t.elem != nil && now.Sub(t.start) >= obj.conf.decisionWait:
+			obj.stats.ExpiredTraces++
+			forward = append(forward, obj.decide(traceID, t)...)
+		case t.elem == nil && now.Sub(t.decided) >= obj.conf.decisionWait:
+			delete(obj.traces, traceID)
+		}
+	}
+	return forward
+}
+
+// decideAll decides all buffered traces, mu being locked.
+func (obj *Processor) decideAll() []sdktrace.ReadOnlySpan {
+	var forward []sdktrace.ReadOnlySpan
+	for traceID, t := range obj.traces {
+		if t.elem != nil {
+			forward = append(forward, obj.decide(traceID, t)...)
+		}
+	}
+	return forward
+}
+
+// decide takes the decision of a buffered trace, returning its spans to
+// forward. The decision is kept for spans ending later, mu being locked.
+func (obj *Processor) decide(traceID trace.TraceID, t *bufferedTrace) []sdktrace.ReadOnlySpan {
+	var spans = t.spans
+	t.kept = obj.keep(traceID, spans)
+	if t.kept {
+		obj.stats.KeptTraces++
+	}
+
+	obj.pending.Remove(t.elem)
+	obj.spans -= len(spans)
+	obj.bytes -= t.bytes
+	t.elem, t.spans, t.bytes, t.decided = nil, nil, 0, obj.now()
+	if t.open <= 0 {
+		delete(obj.traces, traceID)
+	}
+	return markSpans(spans, t.kept)
+}
+
+// keep returns true if a span has an error, lasts at least the latency
+// threshold or matches a predicate, or if the trace is kept by the sample rate.
+func (obj *Processor) keep(traceID trace.TraceID, spans []sdktrace.ReadOnlySpan) bool {
+	for _, s := range spans {
+		if *obj.conf.errors && s.Status().Code == codes.Error {
+			return true
+		}
+		if obj.conf.latencyThreshold > 0 && s.EndTime().Sub(s.StartTime()) >= obj.conf.latencyThreshold {
+			return true
+		}
+		for _, predicate := range obj.conf.predicates {
+			if predicate(s) {
+				return true
+			}
+		}
+	}
+	var low, _ = tracecontext.TraceIDToUint64(traceID)
+	return obj.conf.sampleRate > 0 && sampling.SampledByRate(low, obj.conf.sampleRate)
+}
+
+// forward sends spans to the next processor, mu being unlocked.
+func (obj *Processor) forward(spans []sdktrace.ReadOnlySpan) {
+	for _, s := range spans {
+		obj.next.OnEnd(s)
+	}
+}
+
+// spanOverhead is the estimated size of a span without its variable fields.
+const spanOverhead = 256
+
+// spanSize returns the estimated size in bytes of a span.
+func spanSize(s sdktrace.ReadOnlySpan) int {
+	var size = spanOverhead + len(s.Name()) + len(s.Status().Description) + attributesSize(s.Attributes())
+	for _, e := range s.Events() {
+		size += 32 + len(e.Name) + attributesSize(e.Attributes)
+	}
+	for _, l := range s.Links() {
+		size += 32 + attributesSize(l.Attributes)
+	}
+	return size
+}
+
+// attributesSize returns the estimated size in bytes of attributes.
+func attributesSize(attrs []attribute.KeyValue) int {
+	var size int
+	for _, kv := range attrs {
+		size += 16 + len(kv.Key)
+		switch kv.Value.Type() {
+		case attribute.STRING:
+			size += len(kv.Value.AsString())
+		case attribute.BOOL, attribute.INT64, attribute.FLOAT64:
+			size += 8
+		default:
+			size += len(kv.Value.Emit())
+		}
+	}
+	return size
+}
+
+// markSpans returns the spans marked as USER_KEEP if kept, unchanged otherwise.
+func markSpans(spans []sdktrace.ReadOnlySpan, kept bool) []sdktrace.ReadOnlySpan {
+	if !kept {
+		return spans
+	}
+	var marked = make([]sdktrace.ReadOnlySpan, 0, len(spans))
+	for _, s := range spans {
+		marked = append(marked, newKeptSpan(s))
+	}
+	return marked
+}
+
+// _____ Kept span _____
+
+// keptSpan is a span of a kept trace, marked as sampled with USER_KEEP
+// priority, replacing the decision of the head sampler.
+type keptSpan struct {
+	sdktrace.ReadOnlySpan
+	spanCtx trace.SpanContext
+	attrs   []attribute.KeyValue
+}
+
+func newKeptSpan(s sdktrace.ReadOnlySpan) *keptSpan {
+	var (
+		src   = s.Attributes()
+		attrs = make([]attribute.KeyValue, 0, len(src)+2)
+	)
+	for _, kv := range src {
+		if kv.Key != attributeSamplingPriority && kv.Key != attributeDecisionMaker {
+			attrs = append(attrs, kv)
+		}
+	}
+	attrs = append(attrs,
+		attributeSamplingPriority.Int64(priorityUserKeep),
+		attributeDecisionMaker.String(decisionMakerManual),
+	)
+
+	return &keptSpan{
+		ReadOnlySpan: s,
+		spanCtx:      s.SpanContext().WithTraceFlags(s.SpanContext().TraceFlags().WithSampled(true)),
+		attrs:        attrs,
+	}
+}
+
+func (obj *keptSpan) SpanContext() trace.SpanContext {
+	return obj.spanCtx
+}
+
+func (obj *keptSpan) Attributes() []attribute.KeyValue {
+	return obj.attrs
+}
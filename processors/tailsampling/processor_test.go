@@ -0,0 +1,256 @@
+package tailsampling
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// test_newProvider returns a tracer recording all spans, sampled by sampler,
+// with a processor without expiration goroutine.
+func test_newProvider(t *testing.T, sampler sdktrace.Sampler, cfg ...configFn) (trace.Tracer, *Processor, *tracetest.SpanRecorder) {
+	var recorder = tracetest.NewSpanRecorder()
+	conf, err := newConfig(cfg...)
+	require.NoError(t, err)
+	var processor = newProcessor(recorder, conf)
+
+	var provider = sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler), sdktrace.WithSpanProcessor(processor))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return provider.Tracer("test"), processor, recorder
+}
+
+// recordOnly records spans without sampling them.
+type recordOnly struct{}
+
+func (recordOnly) ShouldSample(sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return sdktrace.SamplingResult{Decision: sdktrace.RecordOnly}
+}
+
+func (recordOnly) Description() string { return "RecordOnly" }
+
+func names(spans []sdktrace.ReadOnlySpan) []string {
+	var names []string
+	for _, s := range spans {
+		names = append(names, s.Name())
+	}
+	return names
+}
+
+func Test_New(t *testing.T) {
+	_, err := New(tracetest.NewSpanRecorder(), WithMaxBufferedSpans(-1))
+	assert.ErrorIs(t, err, ErrInvalidMaxBufferedSpans)
+	_, err = New(tracetest.NewSpanRecorder(), WithMaxBufferedBytes(-1))
+	assert.ErrorIs(t, err, ErrInvalidMaxBufferedBytes)
+
+	processor, err := New(tracetest.NewSpanRecorder(), WithDecisionWait(time.Millisecond))
+	require.NoError(t, err)
+	assert.NoError(t, processor.Shutdown(context.Background()))
+	assert.NoError(t, processor.Shutdown(context.Background()))
+}
+
+func Test_Processor_KeepError(t *testing.T) {
+	var tracer, processor, recorder = test_newProvider(t, recordOnly{})
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.SetStatus(codes.Error, "failure")
+	child.End()
+
+	// Trace buffered until the end of its local spans
+	assert.Empty(t, recorder.Ended())
+	assert.Equal(t, Stats{BufferedTraces: 1, BufferedSpans: 1, BufferedBytes: spanOverhead + len("child") + len("failure")}, processor.Stats())
+
+	root.End()
+	var ended = recorder.Ended()
+	assert.Equal(t, []string{"child", "root"}, names(ended))
+	for _, s := range ended {
+		assert.True(t, s.SpanContext().IsSampled())
+		assert.Subset(t, s.Attributes(), []attribute.KeyValue{
+			attributeSamplingPriority.Int64(2),
+			attributeDecisionMaker.String("-4"),
+		})
+	}
+	assert.Equal(t, Stats{KeptTraces: 1}, processor.Stats())
+}
+
+func Test_Processor_NotKept(t *testing.T) {
+	var tracer, processor, recorder = test_newProvider(t, recordOnly{}, WithErrors(false))
+
+	_, root := tracer.Start(context.Background(), "root", trace.WithAttributes(attributeSamplingPriority.Int64(0)))
+	root.SetStatus(codes.Error, "failure")
+	root.End()
+
+	// Forwarded unchanged with the head decision
+	var ended = recorder.Ended()
+	require.Len(t, ended, 1)
+	assert.False(t, ended[0].SpanContext().IsSampled())
+	assert.Equal(t, []attribute.KeyValue{attributeSamplingPriority.Int64(0)}, ended[0].Attributes())
+	assert.Zero(t, processor.Stats().KeptTraces)
+}
+
+func Test_Processor_keep(t *testing.T) {
+	var (
+		start = time.Unix(1000, 0)
+		tail  = tracetest.SpanStub{Name: "slow", StartTime: start, EndTime: start.Add(2 * time.Second)}
+		fast  = tracetest.SpanStub{Name: "fast", StartTime: start, EndTime: start.Add(time.Millisecond), Attributes: []attribute.KeyValue{attribute.String("tenant", "vip")}}
+	)
+
+	conf, err := newConfig(WithLatencyThreshold(time.Second))
+	require.NoError(t, err)
+	var processor = newProcessor(tracetest.NewSpanRecorder(), conf)
+	assert.True(t, processor.keep(trace.TraceID{}, []sdktrace.ReadOnlySpan{fast.Snapshot(), tail.Snapshot()}))
+	assert.False(t, processor.keep(trace.TraceID{}, []sdktrace.ReadOnlySpan{fast.Snapshot()}))
+
+	conf, err = newConfig(WithPredicate(func(s sdktrace.ReadOnlySpan) bool {
+		for _, kv := range s.Attributes() {
+			if kv.Key == "tenant" && kv.Value.AsString() == "vip" {
+				return true
+			}
+		}
+		return false
+	}))
+	require.NoError(t, err)
+	processor = newProcessor(tracetest.NewSpanRecorder(), conf)
+	assert.True(t, processor.keep(trace.TraceID{}, []sdktrace.ReadOnlySpan{fast.Snapshot()}))
+	assert.False(t, processor.keep(trace.TraceID{}, []sdktrace.ReadOnlySpan{tail.Snapshot()}))
+
+	// Traces matching no rule kept by the hash of the trace ID
+	conf, err = newConfig(WithErrors(false), WithSampleRate(0.5))
+	require.NoError(t, err)
+	processor = newProcessor(tracetest.NewSpanRecorder(), conf)
+	assert.True(t, processor.keep(trace.TraceID{15: 1}, []sdktrace.ReadOnlySpan{fast.Snapshot()}))
+	assert.False(t, processor.keep(trace.TraceID{15: 9}, []sdktrace.ReadOnlySpan{fast.Snapshot()}))
+}
+
+func Test_Processor_SampleRate(t *testing.T) {
+	var tracer, processor, recorder = test_newProvider(t, recordOnly{}, WithSampleRate(1))
+
+	_, span := tracer.Start(context.Background(), "root")
+	span.End()
+
+	if ended := recorder.Ended(); assert.Len(t, ended, 1) {
+		assert.True(t, ended[0].SpanContext().IsSampled())
+		assert.Contains(t, ended[0].Attributes(), attributeSamplingPriority.Int64(priorityUserKeep))
+	}
+	assert.Equal(t, uint64(1), processor.Stats().KeptTraces)
+}
+
+func Test_Processor_Evict(t *testing.T) {
+	var tracer, processor, recorder = test_newProvider(t, sdktrace.AlwaysSample(), WithMaxBufferedSpans(2))
+
+	ctx1, root1 := tracer.Start(context.Background(), "root1")
+	_, child1 := tracer.Start(ctx1, "child1")
+	child1.SetStatus(codes.Error, "failure")
+	child1.End()
+	ctx2, root2 := tracer.Start(context.Background(), "root2")
+	_, child2 := tracer.Start(ctx2, "child2")
+	child2.End()
+	assert.Empty(t, recorder.Ended())
+
+	// Oldest trace evicted when the limit is exceeded, decided with its ended spans
+	_, child3 := tracer.Start(ctx2, "child3")
+	child3.End()
+	assert.Equal(t, []string{"child1"}, names(recorder.Ended()))
+	assert.Equal(t, Stats{BufferedTraces: 1, BufferedSpans: 2, BufferedBytes: 2 * (spanOverhead + len("child2")), KeptTraces: 1, EvictedTraces: 1, EvictedSpans: 1}, processor.Stats())
+
+	// Decision kept for spans ending later
+	root1.End()
+	if ended := recorder.Ended(); assert.Len(t, ended, 2) {
+		assert.Equal(t, "root1", ended[1].Name())
+		assert.Contains(t, ended[1].Attributes(), attributeSamplingPriority.Int64(2))
+	}
+	root2.End()
+	assert.Len(t, recorder.Ended(), 5)
+	assert.Empty(t, processor.traces)
+}
+
+func Test_Processor_EvictBytes(t *testing.T) {
+	var (
+		large                       = attribute.String("payload", string(make([]byte, 1000)))
+		tracer, processor, recorder = test_newProvider(t, sdktrace.AlwaysSample(), WithMaxBufferedBytes(2000))
+	)
+
+	ctx1, root1 := tracer.Start(context.Background(), "root1")
+	_, child1 := tracer.Start(ctx1, "child1", trace.WithAttributes(large))
+	child1.End()
+	ctx2, root2 := tracer.Start(context.Background(), "root2")
+	_, child2 := tracer.Start(ctx2, "child2", trace.WithAttributes(large))
+	child2.End()
+
+	// Oldest trace evicted when the size limit is exceeded
+	assert.Equal(t, []string{"child1"}, names(recorder.Ended()))
+	var stats = processor.Stats()
+	assert.Equal(t, 1, stats.BufferedSpans)
+	assert.Equal(t, spanSize(recorder.Ended()[0]), stats.BufferedBytes)
+	assert.Equal(t, uint64(1), stats.EvictedTraces)
+
+	// Evictions reported once
+	processor.mu.Lock()
+	assert.EqualError(t, processor.evictionError(), errBufferFull.Error()+": 1 traces (1 spans)")
+	assert.NoError(t, processor.evictionError())
+	processor.mu.Unlock()
+
+	root1.End()
+	root2.End()
+	assert.Zero(t, processor.Stats().BufferedBytes)
+}
+
+func Test_spanSize(t *testing.T) {
+	var span = tracetest.SpanStub{
+		Name:       "name",
+		Attributes: []attribute.KeyValue{attribute.String("key", "value"), attribute.Int("count", 1)},
+		Events:     []sdktrace.Event{{Name: "event"}},
+	}
+	assert.Equal(t, spanOverhead+4+(16+3+5)+(16+5+8)+(32+5), spanSize(span.Snapshot()))
+}
+
+func Test_Processor_expire(t *testing.T) {
+	var tracer, processor, recorder = test_newProvider(t, sdktrace.AlwaysSample(), WithDecisionWait(time.Minute))
+	var now = time.Now()
+	processor.now = func() time.Time { return now }
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+
+	// Not expired before the decision wait
+	assert.Empty(t, processor.expire(now.Add(time.Second)))
+
+	// Decided with the spans ended so far
+	processor.forward(processor.expire(now.Add(time.Minute)))
+	assert.Equal(t, []string{"child"}, names(recorder.Ended()))
+	assert.Equal(t, uint64(1), processor.Stats().ExpiredTraces)
+	assert.Len(t, processor.traces, 1)
+
+	// Decision forgotten after the decision wait
+	now = now.Add(time.Minute)
+	assert.Empty(t, processor.expire(now.Add(time.Minute)))
+	assert.Empty(t, processor.traces)
+	root.End()
+	assert.Equal(t, []string{"child", "root"}, names(recorder.Ended()))
+}
+
+func Test_Processor_Shutdown(t *testing.T) {
+	var tracer, processor, recorder = test_newProvider(t, sdktrace.AlwaysSample())
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+
+	// Buffered spans forwarded on flush
+	require.NoError(t, processor.ForceFlush(context.Background()))
+	assert.Equal(t, []string{"child"}, names(recorder.Ended()))
+
+	// Spans ended after shutdown ignored
+	require.NoError(t, processor.Shutdown(context.Background()))
+	root.End()
+	assert.Equal(t, []string{"child"}, names(recorder.Ended()))
+}
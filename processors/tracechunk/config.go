@@ -0,0 +1,132 @@
+package tracechunk
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+)
+
+// _____________________ With option functions _____________________
+
+// WithPartialFlush enables the export of the finished spans of an open trace as
+// soon as they reach the minimum number of spans set by WithPartialFlushMinSpans.
+// It defaults to DD_TRACE_PARTIAL_FLUSH_ENABLED environment variable or false.
+func WithPartialFlush(enabled bool) configFn {
+	return func(conf *config) {
+		conf.partialFlush = &enabled
+	}
+}
+
+// WithPartialFlushMinSpans sets the number of finished spans of an open trace
+// triggering a partial flush.
+// It defaults to DD_TRACE_PARTIAL_FLUSH_MIN_SPANS environment variable or DefaultPartialFlushMinSpans.
+func WithPartialFlushMinSpans(value int) configFn {
+	return func(conf *config) {
+		conf.partialFlushMinSpans = value
+	}
+}
+
+// WithExportUnsampled enables the export of the spans of unsampled traces,
+// recorded by a RecordOnly sampler decision.
+// It defaults to false, unsampled spans being dropped like the batch span processor does.
+func WithExportUnsampled(enabled bool) configFn {
+	return func(conf *config) {
+		conf.exportUnsampled = enabled
+	}
+}
+
+// WithMaxQueueSize sets the maximum number of chunks waiting to be exported,
+// chunks being dropped when the queue is full.
+// It defaults to DefaultMaxQueueSize.
+func WithMaxQueueSize(value int) configFn {
+	return func(conf *config) {
+		conf.maxQueueSize = value
+	}
+}
+
+// WithExportTimeout sets the maximum duration of the export of a chunk.
+// It defaults to DefaultExportTimeout.
+func WithExportTimeout(value time.Duration) configFn {
+	return func(conf *config) {
+		conf.exportTimeout = value
+	}
+}
+
+// _____________________ Definition _____________________
+
+type configFn func(*config)
+
+var ErrInvalidPartialFlushMinSpans = errors.New("invalid partial flush minimum spans")
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/option.go (partialFlushMinSpans)
+
+const (
+	envPartialFlushEnabled  = "DD_TRACE_PARTIAL_FLUSH_ENABLED"
+	envPartialFlushMinSpans = "DD_TRACE_PARTIAL_FLUSH_MIN_SPANS"
+)
+
+const (
+	// DefaultPartialFlushMinSpans specifies the number of finished spans of an
+	// open trace triggering a partial flush.
+	DefaultPartialFlushMinSpans = 1000
+
+	// DefaultMaxQueueSize specifies the maximum number of chunks waiting to be exported.
+	DefaultMaxQueueSize = 1000
+
+	// DefaultExportTimeout specifies the maximum duration of the export of a chunk.
+	DefaultExportTimeout = 30 * time.Second
+)
+
+// _____________________ Configuration _____________________
+
+func newConfig(cfg ...configFn) (*config, error) {
+	var conf = &config{}
+
+	// Apply configurations
+	for _, v := range cfg {
+		if v != nil {
+			v(conf)
+		}
+	}
+
+	// Apply default value on empty
+	conf.applyDefault()
+
+	// Check configuration is valid
+	if conf.partialFlushMinSpans < 0 {
+		return nil, ErrInvalidPartialFlushMinSpans
+	}
+
+	return conf, nil
+}
+
+type config struct {
+	partialFlush         *bool
+	partialFlushMinSpans int
+	exportUnsampled      bool
+	maxQueueSize         int
+	exportTimeout        time.Duration
+}
+
+func (obj *config) applyDefault() {
+	// Set default partial flush
+	if obj.partialFlush == nil {
+		var enabled, _ = strconv.ParseBool(os.Getenv(envPartialFlushEnabled))
+		obj.partialFlush = &enabled
+	}
+	if obj.partialFlushMinSpans == 0 {
+		obj.partialFlushMinSpans, _ = strconv.Atoi(os.Getenv(envPartialFlushMinSpans))
+	}
+	if obj.partialFlushMinSpans == 0 {
+		obj.partialFlushMinSpans = DefaultPartialFlushMinSpans
+	}
+
+	// Set default export queue
+	if obj.maxQueueSize <= 0 {
+		obj.maxQueueSize = DefaultMaxQueueSize
+	}
+	if obj.exportTimeout <= 0 {
+		obj.exportTimeout = DefaultExportTimeout
+	}
+}
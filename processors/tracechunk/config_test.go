@@ -0,0 +1,63 @@
+package tracechunk
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Config_NewConfig(t *testing.T) {
+	assert := assert.New(t)
+
+	// Check default values applied
+	if conf, err := newConfig(); assert.NoError(err) {
+		assert.False(*conf.partialFlush)
+		assert.False(conf.exportUnsampled)
+		assert.Equal(DefaultPartialFlushMinSpans, conf.partialFlushMinSpans)
+		assert.Equal(DefaultMaxQueueSize, conf.maxQueueSize)
+		assert.Equal(DefaultExportTimeout, conf.exportTimeout)
+	}
+
+	// Check options applied
+	if conf, err := newConfig(WithPartialFlush(true), WithPartialFlushMinSpans(10), WithExportUnsampled(true), WithMaxQueueSize(5), WithExportTimeout(time.Second)); assert.NoError(err) {
+		assert.True(*conf.partialFlush)
+		assert.True(conf.exportUnsampled)
+		assert.Equal(10, conf.partialFlushMinSpans)
+		assert.Equal(5, conf.maxQueueSize)
+		assert.Equal(time.Second, conf.exportTimeout)
+	}
+
+	// Check invalid configuration
+	_, err := newConfig(WithPartialFlushMinSpans(-1))
+	assert.ErrorIs(err, ErrInvalidPartialFlushMinSpans)
+}
+
+func Test_Config_Env(t *testing.T) {
+	setenv(t, envPartialFlushEnabled, "true")
+	setenv(t, envPartialFlushMinSpans, "50")
+
+	if conf, err := newConfig(); assert.NoError(t, err) {
+		assert.True(t, *conf.partialFlush)
+		assert.Equal(t, 50, conf.partialFlushMinSpans)
+	}
+
+	// Options take precedence
+	if conf, err := newConfig(WithPartialFlush(false), WithPartialFlushMinSpans(3)); assert.NoError(t, err) {
+		assert.False(t, *conf.partialFlush)
+		assert.Equal(t, 3, conf.partialFlushMinSpans)
+	}
+}
+
+func setenv(t *testing.T, key, value string) {
+	prev, exists := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if exists {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
@@ -0,0 +1,320 @@
+package tracechunk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace level tags of the local root copied on partial chunks.
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/spancontext.go (finishedOneLocked)
+const (
+	keySamplingPriority = "_sampling_priority_v1"
+	keyOrigin           = "_dd.origin"
+	keyPropagatedPrefix = "_dd.p."
+)
+
+var errQueueFull = errors.New("trace chunk queue is full, chunk dropped")
+
+// Processor is a span processor exporting the spans of a trace together, in one
+// chunk once all its local spans are finished. With partial flush enabled, the
+// finished spans of a long running trace are exported in several chunks, memory
+// being released before the end of the trace.
+// Chunks are exported in the background, by a single goroutine.
+type Processor struct {
+	conf     *config
+	exporter sdktrace.SpanExporter
+
+	mu      sync.Mutex
+	traces  map[trace.TraceID]*openTrace
+	stopped bool
+	sending sync.WaitGroup // ForceFlush sending to the queue, closed on Shutdown
+
+	queue        chan queueItem
+	done         chan struct{}
+	droppedSpans uint64
+}
+
+// openTrace holds the spans of a trace having unfinished local spans.
+type openTrace struct {
+	root     sdktrace.ReadOnlySpan // local root, nil if not started by this processor
+	open     int
+	finished []sdktrace.ReadOnlySpan
+}
+
+// queueItem is a chunk to export, or a flush request when flushed is set.
+type queueItem struct {
+	chunk   []sdktrace.ReadOnlySpan
+	flushed chan struct{}
+}
+
+// New returns a processor exporting trace chunks with exporter.
+// To use the defaults, call with exporter only.
+func New(exporter sdktrace.SpanExporter, cfg ...configFn) (*Processor, error) {
+	conf, err := newConfig(cfg...)
+	if err != nil {
+		return nil, err
+	}
+
+	var processor = &Processor{
+		conf:     conf,
+		exporter: exporter,
+		traces:   map[trace.TraceID]*openTrace{},
+		queue:    make(chan queueItem, conf.maxQueueSize),
+		done:     make(chan struct{}),
+	}
+	go processor.run()
+	return processor, nil
+}
+
+// OnStart tracks the span as an unfinished span of its trace.
+func (obj *Processor) OnStart(parent context.Context, s sdktrace.ReadWriteSpan) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	if obj.stopped {
+		return
+	}
+
+	var t = obj.openTrace(s.SpanContext().TraceID())
+	t.open++
+	if !s.Parent().IsValid() || s.Parent().IsRemote() {
+		t.root = s
+	}
+}
+
+// OnEnd exports the trace when its last local span ends, or its finished spans
+// when partial flush is enabled and the minimum number of spans is reached.
+// Unsampled spans are dropped unless WithExportUnsampled is set.
+func (obj *Processor) OnEnd(s sdktrace.ReadOnlySpan) {
+	var exported = s.SpanContext().IsSampled() || obj.conf.exportUnsampled
+
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	if obj.stopped {
+		return
+	}
+
+	var traceID = s.SpanContext().TraceID()
+	t, ok := obj.traces[traceID]
+	if !ok {
+		// Started before the processor was registered
+		if exported {
+			obj.enqueue([]sdktrace.ReadOnlySpan{s})
+		}
+		return
+	}
+
+	t.open--
+	if exported {
+		t.finished = append(t.finished, s)
+	}
+	switch {
+	case t.open <= 0:
+		delete(obj.traces, traceID)
+		if len(t.finished) != 0 {
+			obj.enqueue(t.chunk())
+		}
+	case *obj.conf.partialFlush && len(t.finished) >= obj.conf.partialFlushMinSpans:
+		obj.enqueue(t.chunk())
+	}
+}
+
+// ForceFlush exports the finished spans of open traces and waits for the export
+// of all queued chunks.
+func (obj *Processor) ForceFlush(ctx context.Context) error {
+	obj.mu.Lock()
+	if obj.stopped {
+		obj.mu.Unlock()
+		return nil
+	}
+	var items = append(obj.openChunks(), queueItem{flushed: make(chan struct{})})
+	obj.sending.Add(1)
+	obj.mu.Unlock()
+
+	// Sent without lock, spans ending meanwhile
+	var err = obj.send(ctx, items)
+	obj.sending.Done()
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-items[len(items)-1].flushed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports the finished spans of open traces and the queued chunks,
+// then shuts down the exporter, even if the export failed. Spans ended after
+// shutdown are ignored.
+func (obj *Processor) Shutdown(ctx context.Context) error {
+	obj.mu.Lock()
+	if obj.stopped {
+		obj.mu.Unlock()
+		return nil
+	}
+	var items = obj.openChunks()
+	obj.stopped = true
+	obj.traces = nil
+	obj.mu.Unlock()
+
+	var err = obj.send(ctx, items)
+	obj.sending.Wait()
+	close(obj.queue)
+	if err == nil {
+		select {
+		case <-obj.done:
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+	}
+
+	if shutdownErr := obj.exporter.Shutdown(ctx); shutdownErr != nil {
+		if err == nil {
+			return shutdownErr
+		}
+		return fmt.Errorf("%w; exporter shutdown: %v", err, shutdownErr)
+	}
+	return err
+}
+
+// DroppedSpans returns the number of spans dropped because the queue was full.
+func (obj *Processor) DroppedSpans() uint64 {
+	return atomic.LoadUint64(&obj.droppedSpans)
+}
+
+func (obj *Processor) openTrace(traceID trace.TraceID) *openTrace {
+	t, ok := obj.traces[traceID]
+	if !ok {
+		t = &openTrace{}
+		obj.traces[traceID] = t
+	}
+	return t
+}
+
+// openChunks returns the finished spans of open traces, mu being locked.
+func (obj *Processor) openChunks() []queueItem {
+	var items []queueItem
+	for _, t := range obj.traces {
+		if len(t.finished) != 0 {
+			items = append(items, queueItem{chunk: t.chunk()})
+		}
+	}
+	return items
+}
+
+// enqueue queues a chunk without blocking, mu being locked.
+func (obj *Processor) enqueue(chunk []sdktrace.ReadOnlySpan) {
+	select {
+	case obj.queue <- queueItem{chunk: chunk}:
+	default:
+		atomic.AddUint64(&obj.droppedSpans, uint64(len(chunk)))
+		otel.Handle(errQueueFull)
+	}
+}
+
+// send queues items, waiting for space in the queue.
+func (obj *Processor) send(ctx context.Context, items []queueItem) error {
+	for _, item := range items {
+		select {
+		case obj.queue <- item:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// run exports queued chunks until the queue is closed.
+func (obj *Processor) run() {
+	defer close(obj.done)
+	for item := range obj.queue {
+		if item.flushed != nil {
+			close(item.flushed)
+			continue
+		}
+
+		var ctx, cancel = context.WithTimeout(context.Background(), obj.conf.exportTimeout)
+		if err := obj.exporter.ExportSpans(ctx, item.chunk); err != nil {
+			otel.Handle(err)
+		}
+		cancel()
+	}
+}
+
+// chunk returns the finished spans, removed from the trace. The trace level tags
+// of the local root are set on the first span when the root is not part of the chunk.
+func (obj *openTrace) chunk() []sdktrace.ReadOnlySpan {
+	var chunk = obj.finished
+	obj.finished = nil
+
+	if obj.root == nil || containsSpan(chunk, obj.root) {
+		return chunk
+	}
+	if tags := traceTags(obj.root.Attributes()); len(tags) != 0 {
+		chunk[0] = withAttributes(chunk[0], tags)
+	}
+	return chunk
+}
+
+func containsSpan(spans []sdktrace.ReadOnlySpan, s sdktrace.ReadOnlySpan) bool {
+	var spanID = s.SpanContext().SpanID()
+	for _, v := range spans {
+		if v.SpanContext().SpanID() == spanID {
+			return true
+		}
+	}
+	return false
+}
+
+// traceTags returns the sampling priority, origin and propagated tags.
+func traceTags(attrs []attribute.KeyValue) []attribute.KeyValue {
+	var tags []attribute.KeyValue
+	for _, kv := range attrs {
+		var key = string(kv.Key)
+		if key == keySamplingPriority || key == keyOrigin || strings.HasPrefix(key, keyPropagatedPrefix) {
+			tags = append(tags, kv)
+		}
+	}
+	return tags
+}
+
+// _____________________ Chunk span _____________________
+
+// chunkSpan is a finished span with additional attributes, span attributes
+// taking precedence.
+type chunkSpan struct {
+	sdktrace.ReadOnlySpan
+	attrs []attribute.KeyValue
+}
+
+func withAttributes(s sdktrace.ReadOnlySpan, tags []attribute.KeyValue) sdktrace.ReadOnlySpan {
+	var (
+		attrs = s.Attributes()
+		keys  = make(map[attribute.Key]bool, len(attrs))
+	)
+	for _, kv := range attrs {
+		keys[kv.Key] = true
+	}
+	var merged = append([]attribute.KeyValue{}, attrs...)
+	for _, kv := range tags {
+		if !keys[kv.Key] {
+			merged = append(merged, kv)
+		}
+	}
+	return chunkSpan{ReadOnlySpan: s, attrs: merged}
+}
+
+func (obj chunkSpan) Attributes() []attribute.KeyValue {
+	return obj.attrs
+}
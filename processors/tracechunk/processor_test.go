@@ -0,0 +1,249 @@
+package tracechunk
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// testExporter records exported chunks.
+type testExporter struct {
+	mu       sync.Mutex
+	chunks   [][]sdktrace.ReadOnlySpan
+	shutdown bool
+}
+
+func (obj *testExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.chunks = append(obj.chunks, spans)
+	return nil
+}
+
+func (obj *testExporter) Shutdown(ctx context.Context) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.shutdown = true
+	return nil
+}
+
+// names returns the span names of each exported chunk.
+func (obj *testExporter) names() [][]string {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	var names [][]string
+	for _, chunk := range obj.chunks {
+		var chunkNames []string
+		for _, s := range chunk {
+			chunkNames = append(chunkNames, s.Name())
+		}
+		names = append(names, chunkNames)
+	}
+	return names
+}
+
+func test_newProvider(t *testing.T, cfg ...configFn) (*sdktrace.TracerProvider, *Processor, *testExporter) {
+	var exporter = &testExporter{}
+	processor, err := New(exporter, cfg...)
+	require.NoError(t, err)
+	var provider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(processor))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return provider, processor, exporter
+}
+
+func Test_New(t *testing.T) {
+	_, err := New(&testExporter{}, WithPartialFlushMinSpans(-1))
+	assert.ErrorIs(t, err, ErrInvalidPartialFlushMinSpans)
+}
+
+func Test_Processor_CompleteTrace(t *testing.T) {
+	var provider, processor, exporter = test_newProvider(t)
+	var tracer = provider.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child1 := tracer.Start(ctx, "child1")
+	_, child2 := tracer.Start(ctx, "child2")
+	child1.End()
+	child2.End()
+
+	// Trace kept until local root ends
+	require.NoError(t, processor.ForceFlush(context.Background()))
+	assert.Equal(t, [][]string{{"child1", "child2"}}, exporter.names())
+
+	root.End()
+	require.NoError(t, processor.ForceFlush(context.Background()))
+	assert.Equal(t, [][]string{{"child1", "child2"}, {"root"}}, exporter.names())
+}
+
+func Test_Processor_OneChunk(t *testing.T) {
+	var provider, processor, exporter = test_newProvider(t)
+	var tracer = provider.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	for _, name := range []string{"child1", "child2", "child3"} {
+		_, child := tracer.Start(ctx, name)
+		child.End()
+	}
+	root.End()
+
+	require.NoError(t, processor.ForceFlush(context.Background()))
+	assert.Equal(t, [][]string{{"child1", "child2", "child3", "root"}}, exporter.names())
+}
+
+func Test_Processor_PartialFlush(t *testing.T) {
+	var provider, processor, exporter = test_newProvider(t, WithPartialFlush(true), WithPartialFlushMinSpans(2))
+	var tracer = provider.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root", trace.WithAttributes(
+		attribute.Int64(keySamplingPriority, 2),
+		attribute.String("_dd.p.dm", "-4"),
+		attribute.String("other", "value"),
+	))
+	for _, name := range []string{"child1", "child2", "child3"} {
+		_, child := tracer.Start(ctx, name, trace.WithAttributes(attribute.String("_dd.p.dm", "kept")))
+		child.End()
+	}
+	processor.mu.Lock()
+	var waiting = len(processor.traces[root.SpanContext().TraceID()].finished)
+	processor.mu.Unlock()
+	assert.Equal(t, 1, waiting)
+
+	root.End()
+	require.NoError(t, processor.ForceFlush(context.Background()))
+	assert.Equal(t, [][]string{{"child1", "child2"}, {"child3", "root"}}, exporter.names())
+
+	// Trace tags of the root set on the first span of the partial chunk
+	var first = exporter.chunks[0][0]
+	assert.ElementsMatch(t, []attribute.KeyValue{
+		attribute.String("_dd.p.dm", "kept"),
+		attribute.Int64(keySamplingPriority, 2),
+	}, first.Attributes())
+	assert.Equal(t, root.SpanContext().TraceID(), first.SpanContext().TraceID())
+	assert.Len(t, exporter.chunks[0][1].Attributes(), 1)
+
+	// Chunk with root unchanged
+	assert.Len(t, exporter.chunks[1][0].Attributes(), 1)
+}
+
+func Test_Processor_Shutdown(t *testing.T) {
+	var provider, processor, exporter = test_newProvider(t)
+	var tracer = provider.Tracer("test")
+
+	ctx, root := tracer.Start(context.Background(), "root")
+	_, child := tracer.Start(ctx, "child")
+	child.End()
+
+	// Finished spans of open traces exported
+	require.NoError(t, processor.Shutdown(context.Background()))
+	assert.Equal(t, [][]string{{"child"}}, exporter.names())
+	assert.True(t, exporter.shutdown)
+
+	// Spans ended after shutdown ignored
+	root.End()
+	assert.NoError(t, processor.ForceFlush(context.Background()))
+	assert.NoError(t, processor.Shutdown(context.Background()))
+	assert.Equal(t, [][]string{{"child"}}, exporter.names())
+}
+
+func Test_Processor_Unsampled(t *testing.T) {
+	for _, exportUnsampled := range []bool{false, true} {
+		var exporter = &testExporter{}
+		processor, err := New(exporter, WithExportUnsampled(exportUnsampled))
+		require.NoError(t, err)
+		var provider = sdktrace.NewTracerProvider(sdktrace.WithSampler(test_recordOnly{}), sdktrace.WithSpanProcessor(processor))
+
+		ctx, root := provider.Tracer("test").Start(context.Background(), "root")
+		_, child := provider.Tracer("test").Start(ctx, "child")
+		child.End()
+		root.End()
+		require.NoError(t, processor.ForceFlush(context.Background()))
+
+		// Unsampled spans exported only if enabled, trace closed anyway
+		if exportUnsampled {
+			assert.Equal(t, [][]string{{"child", "root"}}, exporter.names())
+		} else {
+			assert.Empty(t, exporter.names())
+		}
+		assert.Empty(t, processor.traces)
+		require.NoError(t, provider.Shutdown(context.Background()))
+	}
+}
+
+func Test_Processor_ForceFlush_Unlocked(t *testing.T) {
+	// Processor without export goroutine, queue blocking
+	var processor = test_blockedProcessor(t, &testExporter{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	var flushed = make(chan error)
+	go func() { flushed <- processor.ForceFlush(ctx) }()
+
+	// Lock available while waiting for space in the queue
+	time.Sleep(10 * time.Millisecond)
+	processor.mu.Lock()
+	processor.mu.Unlock()
+	select {
+	case <-flushed:
+		t.Fatal("lock held until the end of ForceFlush")
+	default:
+	}
+	assert.ErrorIs(t, <-flushed, context.DeadlineExceeded)
+}
+
+func Test_Processor_Shutdown_Error(t *testing.T) {
+	var (
+		exporter  = &testExporter{}
+		processor = test_blockedProcessor(t, exporter)
+	)
+
+	// Exporter shut down even if open traces could not be queued
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, processor.Shutdown(ctx), context.Canceled)
+	assert.True(t, exporter.shutdown)
+}
+
+// test_blockedProcessor returns a processor with an open trace and a queue
+// without export goroutine.
+func test_blockedProcessor(t *testing.T, exporter *testExporter) *Processor {
+	conf, err := newConfig()
+	require.NoError(t, err)
+	return &Processor{
+		conf:     conf,
+		exporter: exporter,
+		traces:   map[trace.TraceID]*openTrace{{15: 1}: {open: 1, finished: []sdktrace.ReadOnlySpan{tracetest.SpanStub{Name: "child"}.Snapshot()}}},
+		queue:    make(chan queueItem),
+		done:     make(chan struct{}),
+	}
+}
+
+// test_recordOnly records all spans without sampling them.
+type test_recordOnly struct{}
+
+func (test_recordOnly) ShouldSample(sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return sdktrace.SamplingResult{Decision: sdktrace.RecordOnly}
+}
+
+func (test_recordOnly) Description() string { return "RecordOnly" }
+
+func Test_Processor_enqueue(t *testing.T) {
+	// Processor without export goroutine
+	var processor = &Processor{queue: make(chan queueItem, 1)}
+	var chunk = []sdktrace.ReadOnlySpan{nil, nil}
+
+	processor.enqueue(chunk)
+	assert.Zero(t, processor.DroppedSpans())
+
+	// Chunk dropped when queue is full
+	processor.enqueue(chunk)
+	assert.Equal(t, uint64(2), processor.DroppedSpans())
+	assert.Len(t, processor.queue, 1)
+}
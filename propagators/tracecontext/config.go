@@ -2,6 +2,8 @@ package tracecontext
 
 import (
 	"errors"
+	"fmt"
+	"os"
 
 	"go.opentelemetry.io/otel/trace"
 )
@@ -22,10 +24,24 @@ func WithHeaderValueConverter(headerConv HeaderValueConverterPort) configFn {
 	}
 }
 
+// WithHeaderTags sets the request headers extracted as span tags, mapped to
+// their tag names (ex: "X-User-Id": "user.id"). An empty tag name defaults to
+// "http.request.headers.<header>". Extracted tags are returned by HeaderTags.
+// It defaults to DD_TRACE_HEADER_TAGS environment variable ("header:tag,...").
+func WithHeaderTags(value map[string]string) configFn {
+	return func(conf *config) {
+		conf.headerTags = value
+	}
+}
+
 // _____________________ Definition _____________________
 
 type configFn func(*config)
 
+// Ref https://docs.datadoghq.com/tracing/trace_collection/library_config/go/#traces
+
+const envHeaderTags = "DD_TRACE_HEADER_TAGS"
+
 type HeaderValueConverterPort interface {
 	// Trace
 	traceToDatadog(value trace.TraceID) string
@@ -33,6 +49,8 @@ type HeaderValueConverterPort interface {
 	// Span
 	spanToDatadog(value trace.SpanID) string
 	spanFromDatadog(value string) (trace.SpanID, error)
+	// Description
+	name() string
 }
 
 // _____________________ HeaderKey _____________________
@@ -92,7 +110,12 @@ func (obj *HeaderKey) Validate() error {
 // _____________________ Configuration _____________________
 
 func newConfig(cfg ...configFn) (*config, error) {
-	var conf = &config{}
+	return (&config{}).with(cfg...)
+}
+
+// with returns a copy of the configuration with the options applied.
+func (obj config) with(cfg ...configFn) (*config, error) {
+	var conf = &obj
 
 	// Apply configurations
 	for _, v := range cfg {
@@ -108,6 +131,9 @@ func newConfig(cfg ...configFn) (*config, error) {
 	if err := conf.headerKey.Validate(); err != nil {
 		return nil, err
 	}
+	if err := conf.parseHeaderTags(); err != nil {
+		return nil, err
+	}
 
 	return conf, nil
 }
@@ -115,6 +141,8 @@ func newConfig(cfg ...configFn) (*config, error) {
 type config struct {
 	headerKey       HeaderKey
 	headerValueConv HeaderValueConverterPort
+	headerTags      map[string]string
+	envHeaderTags   string // DD_TRACE_HEADER_TAGS, parsed on check
 }
 
 func (obj *config) applyDefault() {
@@ -125,4 +153,32 @@ func (obj *config) applyDefault() {
 	if obj.headerValueConv == nil {
 		obj.headerValueConv = NewHeaderConvBinary()
 	}
+
+	// Set default header tags from environment
+	if obj.headerTags == nil {
+		obj.envHeaderTags = os.Getenv(envHeaderTags)
+	}
+}
+
+func (obj *config) parseHeaderTags() error {
+	var tags = obj.headerTags
+	if tags == nil {
+		var err error
+		if tags, err = ParseHeaderTags(obj.envHeaderTags); err != nil {
+			return err
+		}
+	}
+
+	// Copied, tag names defaulted
+	obj.headerTags = make(map[string]string, len(tags))
+	for header, tag := range tags {
+		if !validHeaderName(header) {
+			return fmt.Errorf("%w: %q", ErrInvalidHeaderTags, header)
+		}
+		if tag == "" {
+			tag = defaultHeaderTag(header)
+		}
+		obj.headerTags[header] = tag
+	}
+	return nil
 }
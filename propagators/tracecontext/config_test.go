@@ -1,6 +1,7 @@
 package tracecontext
 
 import (
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -76,6 +77,27 @@ func Test_Config_NewConfig(t *testing.T) {
 	}
 }
 
+func Test_Config_HeaderTags(t *testing.T) {
+	setenv(t, envHeaderTags, "X-User-Id:user.id,X-Request-Id")
+
+	// Default from environment, tag names defaulted
+	if conf, err := newConfig(); assert.NoError(t, err) {
+		assert.Equal(t, map[string]string{"X-User-Id": "user.id", "X-Request-Id": "http.request.headers.x-request-id"}, conf.headerTags)
+	}
+
+	// Option over environment
+	if conf, err := newConfig(WithHeaderTags(map[string]string{})); assert.NoError(t, err) {
+		assert.Empty(t, conf.headerTags)
+	}
+
+	// Invalid header tags
+	_, err := newConfig(WithHeaderTags(map[string]string{"X User": ""}))
+	assert.ErrorIs(t, err, ErrInvalidHeaderTags)
+	setenv(t, envHeaderTags, ":tag")
+	_, err = newConfig()
+	assert.ErrorIs(t, err, ErrInvalidHeaderTags)
+}
+
 func Test_Config_ApplyDefault(t *testing.T) {
 	// Check each value is empty
 	var conf config
@@ -95,3 +117,17 @@ func test_Config_CheckDefaultValue(t *testing.T, conf *config) {
 	assert.Equal(t, DefaultPriorityHeader, conf.headerKey.SampledPriority)
 	assert.Equal(t, NewHeaderConvBinary(), conf.headerValueConv)
 }
+
+func setenv(t *testing.T, key, value string) {
+	old, exists := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if exists {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
@@ -27,6 +27,8 @@ type headerConvBinary struct {
 	endian binary.ByteOrder
 }
 
+func (obj *headerConvBinary) name() string { return "binary" }
+
 func (obj *headerConvBinary) traceToDatadog(value trace.TraceID) string {
 	// Convert OpenTelemetry 128-bits trace ID to Datadog 64-bits trace IDs
 	// Datadog only uses last 64-bits data like for his propagator B3 extractTextMap function:
@@ -75,6 +77,8 @@ func NewHeaderConvString() HeaderValueConverterPort { return &headerConvString{}
 
 type headerConvString struct{}
 
+func (obj headerConvString) name() string { return "string" }
+
 func (obj headerConvString) traceToDatadog(value trace.TraceID) string {
 	// Convert OpenTelemetry 128-bits trace ID to Datadog 64-bits trace IDs
 	valueStringHex := value.String()[16:]
@@ -106,6 +110,21 @@ func (obj headerConvString) spanFromDatadog(value string) (trace.SpanID, error)
 	return trace.SpanIDFromHex(fmt.Sprintf("%016x", id64b))
 }
 
+// ____________________ Numeric converter ____________________
+
+// TraceIDToUint64 splits an OpenTelemetry 128-bits trace ID in two 64-bits values.
+// The lower part is the Datadog trace ID, the higher part is propagated by Datadog
+// in the _dd.p.tid tag.
+func TraceIDToUint64(value trace.TraceID) (low, high uint64) {
+	// Trace ID byte array is a big endian representation (same as hexadecimal string)
+	return binary.BigEndian.Uint64(value[8:]), binary.BigEndian.Uint64(value[:8])
+}
+
+// SpanIDToUint64 converts an OpenTelemetry 64-bits span ID to a Datadog 64-bits span ID.
+func SpanIDToUint64(value trace.SpanID) uint64 {
+	return binary.BigEndian.Uint64(value[:])
+}
+
 // ___________________________ Convert from header ___________________________
 
 // parseUint64 parses a uint64 from either an unsigned 64 bit base-10 string
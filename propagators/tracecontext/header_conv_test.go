@@ -129,6 +129,21 @@ func Test_headerConvString_spanFromDatadog(t *testing.T) {
 	assert.ErrorContains(t, err, `strconv.ParseUint: parsing "": invalid syntax`)
 }
 
+func Test_TraceIDToUint64(t *testing.T) {
+	low, high := TraceIDToUint64(trace.TraceID{0xb8, 0x10, 0xdb, 0xa2, 0x98, 0x03, 0xee, 0x61, 0xe7, 0xc7, 0x1f, 0xf0, 0xc2, 0xc9, 0x5a, 0x9d})
+	assert.Equal(t, uint64(16701352862047361693), low)
+	assert.Equal(t, uint64(0xb810dba29803ee61), high)
+
+	low, high = TraceIDToUint64(trace.TraceID{})
+	assert.Zero(t, low)
+	assert.Zero(t, high)
+}
+
+func Test_SpanIDToUint64(t *testing.T) {
+	assert.Equal(t, uint64(16701352862047361693), SpanIDToUint64(trace.SpanID{0xe7, 0xc7, 0x1f, 0xf0, 0xc2, 0xc9, 0x5a, 0x9d}))
+	assert.Zero(t, SpanIDToUint64(trace.SpanID{}))
+}
+
 // https://github.com/DataDog/dd-trace-go/blob/v1.38.1/ddtrace/tracer/util_test.go#L55-L72
 func TestParseUint64(t *testing.T) {
 	t.Run("negative", func(t *testing.T) {
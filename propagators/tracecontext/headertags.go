@@ -0,0 +1,91 @@
+package tracecontext
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// Request headers are extracted as span tags, as the Datadog tracing library
+// does for the requests of its HTTP integrations.
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/internal/normalizer/normalizer.go
+
+// headerTagPrefix is the prefix of the default tag name of a header.
+const headerTagPrefix = "http.request.headers."
+
+var ErrInvalidHeaderTags = errors.New("invalid header tags")
+
+type headerTagsKey struct{}
+
+// HeaderTags returns the span tags extracted from the request headers by a
+// propagator returned by New, sorted by key, nil if none.
+func HeaderTags(ctx context.Context) []attribute.KeyValue {
+	attrs, _ := ctx.Value(headerTagsKey{}).([]attribute.KeyValue)
+	return attrs
+}
+
+// ParseHeaderTags returns the header tags of a list of "header:tag" pairs
+// separated by commas (ex: "X-User-Id:user.id,X-Request-Id"), the tag name
+// being optional.
+func ParseHeaderTags(value string) (map[string]string, error) {
+	var tags = map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		var header, tag = pair, ""
+		if i := strings.LastIndexByte(pair, ':'); i >= 0 {
+			header, tag = strings.TrimSpace(pair[:i]), strings.TrimSpace(pair[i+1:])
+		}
+		if !validHeaderName(header) {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidHeaderTags, pair)
+		}
+		tags[header] = tag
+	}
+	return tags, nil
+}
+
+// validHeaderName returns true if the header name is not empty, without spaces
+// or colons.
+func validHeaderName(header string) bool {
+	return header != "" && !strings.ContainsAny(header, " \t\r\n:")
+}
+
+// defaultHeaderTag returns the tag name of a header, lowercased, characters
+// other than letters, digits and "-" being replaced by "_".
+func defaultHeaderTag(header string) string {
+	return headerTagPrefix + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		}
+		return '_'
+	}, header)
+}
+
+// extractHeaderTags returns the context with the tags of the headers present in
+// the carrier, unchanged if none.
+func extractHeaderTags(ctx context.Context, carrier propagation.TextMapCarrier, headerTags map[string]string) context.Context {
+	if len(headerTags) == 0 {
+		return ctx
+	}
+
+	var attrs []attribute.KeyValue
+	for header, tag := range headerTags {
+		if value := carrier.Get(header); value != "" {
+			attrs = append(attrs, attribute.String(tag, value))
+		}
+	}
+	if len(attrs) == 0 {
+		return ctx
+	}
+	sort.Slice(attrs, func(i, j int) bool { return attrs[i].Key < attrs[j].Key })
+	return context.WithValue(ctx, headerTagsKey{}, attrs)
+}
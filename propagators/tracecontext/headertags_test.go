@@ -0,0 +1,51 @@
+package tracecontext
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func Test_ParseHeaderTags(t *testing.T) {
+	tags, err := ParseHeaderTags(" X-User-Id:user.id, X-Request-Id ,,X-Tenant: ")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"X-User-Id": "user.id", "X-Request-Id": "", "X-Tenant": ""}, tags)
+
+	tags, err = ParseHeaderTags("")
+	assert.NoError(t, err)
+	assert.Empty(t, tags)
+
+	_, err = ParseHeaderTags(":user.id")
+	assert.ErrorIs(t, err, ErrInvalidHeaderTags)
+	_, err = ParseHeaderTags("X User:user")
+	assert.ErrorIs(t, err, ErrInvalidHeaderTags)
+}
+
+func Test_defaultHeaderTag(t *testing.T) {
+	assert.Equal(t, "http.request.headers.x-user-id", defaultHeaderTag("X-User-Id"))
+	assert.Equal(t, "http.request.headers.x_b3_id", defaultHeaderTag("x.b3.id"))
+}
+
+func Test_extractHeaderTags(t *testing.T) {
+	var (
+		ctx        = context.Background()
+		headerTags = map[string]string{"X-User-Id": "user.id", "X-Tenant": "tenant", "X-Missing": "missing"}
+		carrier    = propagation.HeaderCarrier(http.Header{})
+	)
+
+	// No header tags or no header present
+	assert.Equal(t, ctx, extractHeaderTags(ctx, carrier, nil))
+	assert.Equal(t, ctx, extractHeaderTags(ctx, carrier, headerTags))
+	assert.Nil(t, HeaderTags(ctx))
+
+	carrier.Set("x-user-id", "42")
+	carrier.Set("x-tenant", "acme")
+	assert.Equal(t,
+		[]attribute.KeyValue{attribute.String("tenant", "acme"), attribute.String("user.id", "42")},
+		HeaderTags(extractHeaderTags(ctx, carrier, headerTags)),
+	)
+}
@@ -3,11 +3,15 @@ package tracecontext
 import (
 	"context"
 	"errors"
+	"strconv"
+	"sync"
 
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
 
+var ErrUnsupportedPropagator = errors.New("propagator not returned by tracecontext.New")
+
 var (
 	errMalformedTraceID = errors.New("cannot parse Datadog trace ID as 64bit unsigned int from header")
 	errMalformedSpanID  = errors.New("cannot parse Datadog span ID as 64bit unsigned int from header")
@@ -35,11 +39,65 @@ func New(cfg ...configFn) (propagation.TextMapPropagator, error) {
 	return &propagator{conf: propagatorConf}, nil
 }
 
+// Describe returns the header keys and the name of the header value converter
+// ("binary" or "string") of a propagator returned by New, ok being false for
+// other propagators.
+func Describe(prop propagation.TextMapPropagator) (headerKey HeaderKey, converter string, ok bool) {
+	p, ok := prop.(*propagator)
+	if !ok {
+		return HeaderKey{}, "", false
+	}
+	var conf = p.config()
+	return conf.headerKey, conf.headerValueConv.name(), true
+}
+
+// DescribeHeaderTags returns the header tags of a propagator returned by New,
+// ok being false for other propagators.
+func DescribeHeaderTags(prop propagation.TextMapPropagator) (headerTags map[string]string, ok bool) {
+	p, ok := prop.(*propagator)
+	if !ok {
+		return nil, false
+	}
+	var conf = p.config()
+	headerTags = make(map[string]string, len(conf.headerTags))
+	for k, v := range conf.headerTags {
+		headerTags[k] = v
+	}
+	return headerTags, true
+}
+
+// Update applies the options atomically to the configuration of a propagator
+// returned by New, at runtime (ex: by remote configuration). Options not given
+// are kept. The configuration is unchanged if invalid.
+func Update(prop propagation.TextMapPropagator, cfg ...configFn) error {
+	p, ok := prop.(*propagator)
+	if !ok {
+		return ErrUnsupportedPropagator
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	conf, err := p.conf.with(cfg...)
+	if err != nil {
+		return err
+	}
+	p.conf = conf
+	return nil
+}
+
 // propagator serializes Span Context to/from Datadog headers.
 type propagator struct {
+	mu   sync.RWMutex
 	conf *config
 }
 
+// config returns the current configuration, replaced as a whole by Update.
+func (obj *propagator) config() *config {
+	obj.mu.RLock()
+	defer obj.mu.RUnlock()
+	return obj.conf
+}
+
 // Inject injects a context to the carrier following Datadog format.
 func (obj *propagator) Inject(ctx context.Context, carrier propagation.TextMapCarrier) {
 	// If no Span Context or invalid, do not inject it
@@ -47,15 +105,25 @@ func (obj *propagator) Inject(ctx context.Context, carrier propagation.TextMapCa
 	if !spanCtx.IsValid() {
 		return
 	}
+	var conf = obj.config()
 
 	// Inject Trace ID, Span ID, Sampled in carrier
-	carrier.Set(obj.conf.headerKey.TraceID, obj.conf.headerValueConv.traceToDatadog(spanCtx.TraceID()))
-	carrier.Set(obj.conf.headerKey.ParentID, obj.conf.headerValueConv.spanToDatadog(spanCtx.SpanID()))
-	carrier.Set(obj.conf.headerKey.SampledPriority, otelToSampledDatadogHeader(spanCtx.TraceFlags()))
+	carrier.Set(conf.headerKey.TraceID, conf.headerValueConv.traceToDatadog(spanCtx.TraceID()))
+	carrier.Set(conf.headerKey.ParentID, conf.headerValueConv.spanToDatadog(spanCtx.SpanID()))
+	carrier.Set(conf.headerKey.SampledPriority, samplingPriorityHeader(spanCtx))
+
+	// Inject propagated tags extracted from upstream
+	if tags := formatPropagatedTags(PropagatedTags(spanCtx)); tags != "" {
+		carrier.Set(DefaultTagsHeader, tags)
+	}
 }
 
-// Extract gets a context from the carrier if it contains Datadog headers.
+// Extract gets a context from the carrier if it contains Datadog headers, with
+// the header tags returned by HeaderTags.
 func (obj *propagator) Extract(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	var conf = obj.config()
+	ctx = extractHeaderTags(ctx, carrier, conf.headerTags)
+
 	// If an Span Context already defined, do not override it
 	var spanCtx = trace.SpanContextFromContext(ctx)
 	if spanCtx.IsValid() {
@@ -63,11 +131,12 @@ func (obj *propagator) Extract(ctx context.Context, carrier propagation.TextMapC
 	}
 
 	var (
-		traceID = carrier.Get(obj.conf.headerKey.TraceID)
-		spanID  = carrier.Get(obj.conf.headerKey.ParentID)
-		sampled = carrier.Get(obj.conf.headerKey.SampledPriority)
+		traceID  = carrier.Get(conf.headerKey.TraceID)
+		spanID   = carrier.Get(conf.headerKey.ParentID)
+		priority = carrier.Get(conf.headerKey.SampledPriority)
+		tags     = carrier.Get(DefaultTagsHeader)
 	)
-	sc, err := obj.extract(traceID, spanID, sampled)
+	sc, err := extract(conf, traceID, spanID, priority, tags)
 	if err != nil || !sc.IsValid() {
 		return ctx
 	}
@@ -75,31 +144,37 @@ func (obj *propagator) Extract(ctx context.Context, carrier propagation.TextMapC
 	return trace.ContextWithRemoteSpanContext(ctx, sc)
 }
 
-func (obj *propagator) extract(traceID, spanID, sampled string) (trace.SpanContext, error) {
+func extract(conf *config, traceID, spanID, priority, tags string) (trace.SpanContext, error) {
 	var (
 		scc trace.SpanContextConfig
 		err error
 	)
 
-	if scc.TraceID, err = obj.conf.headerValueConv.traceFromDatadog(traceID); err != nil {
+	if scc.TraceID, err = conf.headerValueConv.traceFromDatadog(traceID); err != nil {
 		return trace.SpanContext{}, errMalformedTraceID
 	}
 
-	if scc.SpanID, err = obj.conf.headerValueConv.spanFromDatadog(spanID); err != nil {
+	if scc.SpanID, err = conf.headerValueConv.spanFromDatadog(spanID); err != nil {
 		return trace.SpanContext{}, errMalformedSpanID
 	}
 
-	scc.TraceFlags = scc.TraceFlags.WithSampled(sampled == datadogHeaderSampled)
+	// Sampled for AUTO_KEEP and USER_KEEP priorities, kept with propagated tags in trace state
+	if value, err := strconv.Atoi(priority); err == nil {
+		scc.TraceFlags = scc.TraceFlags.WithSampled(value > 0)
+		scc.TraceState = withDatadogTraceState(trace.TraceState{}, value, parsePropagatedTags(tags))
+	}
 
 	return trace.NewSpanContext(scc), nil
 }
 
 // Fields returns the keys whose values are set with Inject.
 func (obj *propagator) Fields() []string {
+	var conf = obj.config()
 	return []string{
-		obj.conf.headerKey.TraceID,
-		obj.conf.headerKey.ParentID,
-		obj.conf.headerKey.SampledPriority,
+		conf.headerKey.TraceID,
+		conf.headerKey.ParentID,
+		conf.headerKey.SampledPriority,
+		DefaultTagsHeader,
 	}
 }
 
@@ -116,3 +191,12 @@ func otelToSampledDatadogHeader(value trace.TraceFlags) string {
 	}
 	return datadogHeaderNotSampled
 }
+
+// samplingPriorityHeader returns the priority extracted from upstream if it
+// agrees with the sampled flag, "0" or "1" from the sampled flag otherwise.
+func samplingPriorityHeader(spanCtx trace.SpanContext) string {
+	if priority, ok := SamplingPriority(spanCtx); ok && (priority > 0) == spanCtx.IsSampled() {
+		return strconv.Itoa(priority)
+	}
+	return otelToSampledDatadogHeader(spanCtx.TraceFlags())
+}
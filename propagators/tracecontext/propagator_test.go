@@ -2,10 +2,12 @@ package tracecontext
 
 import (
 	"context"
+	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 )
@@ -86,15 +88,119 @@ func Test_propagator_Extract(t *testing.T) {
 	assert.True(t, extractedSc.TraceFlags().IsSampled())
 }
 
+func Test_propagator_Extract_Priority(t *testing.T) {
+	var prop = NewDefault()
+	var extract = func(priority, tags string) trace.SpanContext {
+		var carrier = propagation.MapCarrier{DefaultTraceIDHeader: "1", DefaultParentIDHeader: "2", DefaultPriorityHeader: priority}
+		if tags != "" {
+			carrier.Set(DefaultTagsHeader, tags)
+		}
+		return trace.SpanContextFromContext(prop.Extract(context.Background(), carrier))
+	}
+
+	// USER_KEEP sampled, priority and propagated tags kept in trace state
+	var sc = extract("2", "_dd.p.dm=-4,_dd.p.tid=640cfd8d00000000,other=value")
+	assert.True(t, sc.IsSampled())
+	priority, ok := SamplingPriority(sc)
+	assert.True(t, ok)
+	assert.Equal(t, 2, priority)
+	assert.Equal(t, map[string]string{"_dd.p.dm": "-4", "_dd.p.tid": "640cfd8d00000000"}, PropagatedTags(sc))
+	assert.Equal(t, "s:2;t.dm:-4;t.tid:640cfd8d00000000", sc.TraceState().Get("dd"))
+
+	// Rejected priorities not sampled
+	for _, value := range []string{"-1", "0"} {
+		sc = extract(value, "")
+		assert.False(t, sc.IsSampled(), value)
+		priority, ok = SamplingPriority(sc)
+		assert.True(t, ok, value)
+		assert.Equal(t, value, strconv.Itoa(priority))
+	}
+
+	// Missing or invalid priority
+	sc = extract("", "_dd.p.dm=-4")
+	assert.True(t, sc.IsValid())
+	assert.False(t, sc.IsSampled())
+	_, ok = SamplingPriority(sc)
+	assert.False(t, ok)
+	assert.Empty(t, PropagatedTags(sc))
+}
+
+func Test_propagator_Inject_Priority(t *testing.T) {
+	var prop = NewDefault()
+	var inject = func(priority, tags string, sampled bool) propagation.MapCarrier {
+		var carrier = propagation.MapCarrier{DefaultTraceIDHeader: "1", DefaultParentIDHeader: "2", DefaultPriorityHeader: priority, DefaultTagsHeader: tags}
+		var sc = trace.SpanContextFromContext(prop.Extract(context.Background(), carrier))
+		sc = sc.WithTraceFlags(sc.TraceFlags().WithSampled(sampled))
+		var injected = propagation.MapCarrier{}
+		prop.Inject(trace.ContextWithSpanContext(context.Background(), sc), injected)
+		return injected
+	}
+
+	// Upstream priority and tags propagated
+	var carrier = inject("2", "_dd.p.dm=-4", true)
+	assert.Equal(t, "2", carrier.Get(DefaultPriorityHeader))
+	assert.Equal(t, "_dd.p.dm=-4", carrier.Get(DefaultTagsHeader))
+	carrier = inject("-1", "", false)
+	assert.Equal(t, "-1", carrier.Get(DefaultPriorityHeader))
+	assert.NotContains(t, carrier, DefaultTagsHeader)
+
+	// Sampled flag taking precedence over upstream priority
+	carrier = inject("2", "", false)
+	assert.Equal(t, datadogHeaderNotSampled, carrier.Get(DefaultPriorityHeader))
+}
+
 func Test_propagator_Fields(t *testing.T) {
 	if prop, err := New(); assert.NoError(t, err) {
 		assert.ElementsMatch(t,
 			prop.Fields(),
-			[]string{DefaultParentIDHeader, DefaultPriorityHeader, DefaultTraceIDHeader},
+			[]string{DefaultParentIDHeader, DefaultPriorityHeader, DefaultTraceIDHeader, DefaultTagsHeader},
 		)
 	}
 }
 
+func Test_Describe(t *testing.T) {
+	headerKey, converter, ok := Describe(NewDefault())
+	assert.True(t, ok)
+	assert.Equal(t, HeaderKey{DefaultTraceIDHeader, DefaultParentIDHeader, DefaultPriorityHeader}, headerKey)
+	assert.Equal(t, "binary", converter)
+
+	if prop, err := New(WithHeaderKey(HeaderKey{"a", "b", "c"}), WithHeaderValueConverter(NewHeaderConvString())); assert.NoError(t, err) {
+		headerKey, converter, ok = Describe(prop)
+		assert.True(t, ok)
+		assert.Equal(t, HeaderKey{"a", "b", "c"}, headerKey)
+		assert.Equal(t, "string", converter)
+	}
+
+	_, _, ok = Describe(propagation.TraceContext{})
+	assert.False(t, ok)
+}
+
+func Test_Update(t *testing.T) {
+	prop, err := New(WithHeaderKey(HeaderKey{"a", "b", "c"}), WithHeaderTags(map[string]string{"X-User-Id": "user.id"}))
+	require.NoError(t, err)
+	var carrier = propagation.MapCarrier{"a": "1", "b": "2", "c": "1", "X-User-Id": "42", "X-Tenant": "acme"}
+	assert.Equal(t, []attribute.KeyValue{attribute.String("user.id", "42")}, HeaderTags(prop.Extract(context.Background(), carrier)))
+
+	// Options applied, others kept
+	require.NoError(t, Update(prop, WithHeaderTags(map[string]string{"X-Tenant": "tenant"})))
+	var ctx = prop.Extract(context.Background(), carrier)
+	assert.Equal(t, []attribute.KeyValue{attribute.String("tenant", "acme")}, HeaderTags(ctx))
+	assert.True(t, trace.SpanContextFromContext(ctx).IsValid())
+	headerTags, ok := DescribeHeaderTags(prop)
+	assert.True(t, ok)
+	assert.Equal(t, map[string]string{"X-Tenant": "tenant"}, headerTags)
+
+	// Invalid configuration rejected
+	assert.ErrorIs(t, Update(prop, WithHeaderKey(HeaderKey{"a", "a", "c"})), ErrDuplicatedHeaderKey)
+	headerKey, _, _ := Describe(prop)
+	assert.Equal(t, HeaderKey{"a", "b", "c"}, headerKey)
+
+	// Other propagators
+	assert.ErrorIs(t, Update(propagation.TraceContext{}), ErrUnsupportedPropagator)
+	_, ok = DescribeHeaderTags(propagation.TraceContext{})
+	assert.False(t, ok)
+}
+
 func Test_otelToSampledDatadogHeader(t *testing.T) {
 	var value trace.TraceFlags
 	assert.Equal(t, datadogHeaderNotSampled, otelToSampledDatadogHeader(value))
@@ -0,0 +1,139 @@
+package tracecontext
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// The Datadog sampling priority and propagated tags (_dd.p.*) extracted from
+// headers are kept in the "dd" member of the trace state, as Datadog tracers do
+// with W3C headers (ex: "s:2;t.dm:-4"), so that samplers read them and children
+// spans carry them.
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/textmap.go (composeTracestate)
+
+const (
+	// DefaultTagsHeader specifies the key that will be used in HTTP headers or
+	// text maps to store the propagated tags (ex: "_dd.p.dm=-4").
+	DefaultTagsHeader = "x-datadog-tags"
+
+	// PropagatedTagPrefix is the prefix of the keys of propagated tags.
+	PropagatedTagPrefix = "_dd.p."
+
+	decisionMakerTag      = PropagatedTagPrefix + "dm"
+	traceStateKey         = "dd"
+	traceStatePriority    = "s"
+	traceStateTagPrefix   = "t."
+	maxPropagatedTagsSize = 512
+)
+
+// SamplingPriority returns the Datadog sampling priority extracted from headers
+// and kept in the trace state, ok being false if none.
+func SamplingPriority(spanCtx trace.SpanContext) (priority int, ok bool) {
+	value, ok := traceStateValues(spanCtx.TraceState())[traceStatePriority]
+	if !ok {
+		return 0, false
+	}
+	priority, err := strconv.Atoi(value)
+	return priority, err == nil
+}
+
+// PropagatedTags returns the Datadog propagated tags (ex: "_dd.p.dm") extracted
+// from headers and kept in the trace state.
+func PropagatedTags(spanCtx trace.SpanContext) map[string]string {
+	return traceStateTags(spanCtx.TraceState())
+}
+
+// traceStateTags returns the propagated tags of the trace state.
+func traceStateTags(state trace.TraceState) map[string]string {
+	var tags = map[string]string{}
+	for k, v := range traceStateValues(state) {
+		if strings.HasPrefix(k, traceStateTagPrefix) {
+			tags[PropagatedTagPrefix+strings.TrimPrefix(k, traceStateTagPrefix)] = strings.ReplaceAll(v, "~", "=")
+		}
+	}
+	return tags
+}
+
+// WithSamplingDecision returns the trace state with the Datadog member holding
+// the sampling priority and decision maker (ex: "-3") of a sampling decision,
+// so that Inject propagates it. Other propagated tags are kept, the decision
+// maker being removed for reject priorities.
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/spancontext.go (setSamplingPriorityLocked)
+func WithSamplingDecision(state trace.TraceState, priority int, decisionMaker string) trace.TraceState {
+	var tags = traceStateTags(state)
+	if priority > 0 {
+		tags[decisionMakerTag] = decisionMaker
+	} else {
+		delete(tags, decisionMakerTag)
+	}
+	return withDatadogTraceState(state, priority, tags)
+}
+
+// traceStateValues returns the values of the Datadog member of the trace state.
+func traceStateValues(state trace.TraceState) map[string]string {
+	var values = map[string]string{}
+	for _, field := range strings.Split(state.Get(traceStateKey), ";") {
+		if i := strings.IndexByte(field, ':'); i > 0 {
+			values[field[:i]] = field[i+1:]
+		}
+	}
+	return values
+}
+
+// withDatadogTraceState returns the trace state with the Datadog member holding
+// the priority and the propagated tags, unchanged if invalid.
+func withDatadogTraceState(state trace.TraceState, priority int, tags map[string]string) trace.TraceState {
+	var fields = []string{traceStatePriority + ":" + strconv.Itoa(priority)}
+	for _, k := range sortedKeys(tags) {
+		var value = strings.NewReplacer("=", "~", ",", "_", ";", "_").Replace(tags[k])
+		fields = append(fields, traceStateTagPrefix+strings.TrimPrefix(k, PropagatedTagPrefix)+":"+value)
+	}
+	if updated, err := state.Insert(traceStateKey, strings.Join(fields, ";")); err == nil {
+		return updated
+	}
+	return state
+}
+
+// parsePropagatedTags returns the propagated tags of the tags header
+// ("_dd.p.k1=v1,_dd.p.k2=v2"), ignoring other keys and headers over 512 bytes.
+func parsePropagatedTags(header string) map[string]string {
+	var tags = map[string]string{}
+	if header == "" || len(header) > maxPropagatedTagsSize {
+		return tags
+	}
+	for _, pair := range strings.Split(header, ",") {
+		var i = strings.IndexByte(pair, '=')
+		if i <= 0 {
+			continue
+		}
+		if key := strings.TrimSpace(pair[:i]); strings.HasPrefix(key, PropagatedTagPrefix) {
+			tags[key] = strings.TrimSpace(pair[i+1:])
+		}
+	}
+	return tags
+}
+
+// formatPropagatedTags returns the tags header of propagated tags, empty if
+// none or over 512 bytes.
+func formatPropagatedTags(tags map[string]string) string {
+	var pairs = make([]string, 0, len(tags))
+	for _, k := range sortedKeys(tags) {
+		pairs = append(pairs, k+"="+tags[k])
+	}
+	if header := strings.Join(pairs, ","); len(header) <= maxPropagatedTagsSize {
+		return header
+	}
+	return ""
+}
+
+func sortedKeys(values map[string]string) []string {
+	var keys = make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,62 @@
+package tracecontext
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func Test_withDatadogTraceState(t *testing.T) {
+	other, _ := trace.ParseTraceState("vendor=value")
+
+	var state = withDatadogTraceState(other, 1, map[string]string{"_dd.p.dm": "-1", "_dd.p.usr": "a=b,c;d"})
+	assert.Equal(t, "s:1;t.dm:-1;t.usr:a~b_c_d", state.Get("dd"))
+	assert.Equal(t, "value", state.Get("vendor"))
+
+	var sc = trace.NewSpanContext(trace.SpanContextConfig{TraceState: state})
+	priority, ok := SamplingPriority(sc)
+	assert.True(t, ok)
+	assert.Equal(t, 1, priority)
+	assert.Equal(t, map[string]string{"_dd.p.dm": "-1", "_dd.p.usr": "a=b_c_d"}, PropagatedTags(sc))
+
+	// Invalid value ignored
+	assert.Equal(t, other, withDatadogTraceState(other, 1, map[string]string{"_dd.p.k": "é"}))
+}
+
+func Test_WithSamplingDecision(t *testing.T) {
+	upstream, _ := trace.ParseTraceState("dd=s:1;t.dm:-1;t.tid:66,vendor=value")
+
+	var state = WithSamplingDecision(upstream, 2, "-3")
+	assert.Equal(t, "s:2;t.dm:-3;t.tid:66", state.Get("dd"))
+	assert.Equal(t, "value", state.Get("vendor"))
+
+	// Decision maker removed on reject
+	state = WithSamplingDecision(upstream, -1, "-3")
+	assert.Equal(t, "s:-1;t.tid:66", state.Get("dd"))
+
+	state = WithSamplingDecision(trace.TraceState{}, 1, "-0")
+	assert.Equal(t, "s:1;t.dm:-0", state.Get("dd"))
+}
+
+func Test_SamplingPriority(t *testing.T) {
+	_, ok := SamplingPriority(trace.SpanContext{})
+	assert.False(t, ok)
+
+	state, _ := trace.ParseTraceState("dd=s:abc")
+	_, ok = SamplingPriority(trace.NewSpanContext(trace.SpanContextConfig{TraceState: state}))
+	assert.False(t, ok)
+}
+
+func Test_parsePropagatedTags(t *testing.T) {
+	assert.Equal(t, map[string]string{"_dd.p.dm": "-4", "_dd.p.tid": "1"}, parsePropagatedTags(" _dd.p.dm=-4 ,_dd.p.tid=1,key=value,invalid,=x"))
+	assert.Empty(t, parsePropagatedTags(""))
+	assert.Empty(t, parsePropagatedTags("_dd.p.dm="+strings.Repeat("x", maxPropagatedTagsSize)))
+}
+
+func Test_formatPropagatedTags(t *testing.T) {
+	assert.Equal(t, "_dd.p.dm=-4,_dd.p.tid=1", formatPropagatedTags(map[string]string{"_dd.p.tid": "1", "_dd.p.dm": "-4"}))
+	assert.Empty(t, formatPropagatedTags(nil))
+	assert.Empty(t, formatPropagatedTags(map[string]string{"_dd.p.dm": strings.Repeat("x", maxPropagatedTagsSize)}))
+}
@@ -0,0 +1,212 @@
+package remoteconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/samplers/ddsampler"
+)
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/remote_config.go
+
+// TracingSettings are the settings of the APM_TRACING configuration applied,
+// nil values being unset: the settings of the local configuration apply.
+type TracingSettings struct {
+	// SampleRate is the global sample rate of traces.
+	SampleRate *float64
+	// SamplingRules are the trace sampling rules.
+	SamplingRules []ddsampler.SamplingRule
+	// HeaderTags are the request headers extracted as span tags, by header.
+	HeaderTags map[string]string
+	// LogInjection enables the injection of trace IDs in logs.
+	LogInjection *bool
+}
+
+// apmTracingConfig is the content of an APM_TRACING configuration file.
+type apmTracingConfig struct {
+	LibConfig struct {
+		SampleRate    *float64                  `json:"tracing_sampling_rate"`
+		SamplingRules *[]apmTracingSamplingRule `json:"tracing_sampling_rules"`
+		HeaderTags    *[]apmTracingHeaderTag    `json:"tracing_header_tags"`
+		LogInjection  *bool                     `json:"log_injection_enabled"`
+	} `json:"lib_config"`
+	ServiceTarget *struct {
+		Service string `json:"service"`
+		Env     string `json:"env"`
+	} `json:"service_target"`
+}
+
+type apmTracingSamplingRule struct {
+	Service  string `json:"service"`
+	Name     string `json:"name"`
+	Resource string `json:"resource"`
+	Tags     []struct {
+		Key       string `json:"key"`
+		ValueGlob string `json:"value_glob"`
+	} `json:"tags"`
+	SampleRate float64 `json:"sample_rate"`
+}
+
+type apmTracingHeaderTag struct {
+	Header  string `json:"header"`
+	TagName string `json:"tag_name"`
+}
+
+// settings returns the tracing settings of the configuration.
+func (obj *apmTracingConfig) settings() TracingSettings {
+	var settings = TracingSettings{
+		SampleRate:   obj.LibConfig.SampleRate,
+		LogInjection: obj.LibConfig.LogInjection,
+	}
+	if rules := obj.LibConfig.SamplingRules; rules != nil {
+		settings.SamplingRules = make([]ddsampler.SamplingRule, 0, len(*rules))
+		for _, r := range *rules {
+			var rule = ddsampler.SamplingRule{Service: r.Service, Name: r.Name, Resource: r.Resource, SampleRate: r.SampleRate}
+			if len(r.Tags) > 0 {
+				rule.Tags = make(map[string]string, len(r.Tags))
+				for _, tag := range r.Tags {
+					rule.Tags[tag.Key] = tag.ValueGlob
+				}
+			}
+			settings.SamplingRules = append(settings.SamplingRules, rule)
+		}
+	}
+	if tags := obj.LibConfig.HeaderTags; tags != nil {
+		settings.HeaderTags = make(map[string]string, len(*tags))
+		for _, tag := range *tags {
+			settings.HeaderTags[tag.Header] = tag.TagName
+		}
+	}
+	return settings
+}
+
+// specificity returns how specifically the configuration targets the service
+// and environment, -1 if it targets others. Configurations without target or
+// with "*" apply to all.
+func (obj *apmTracingConfig) specificity(service, env string) int {
+	if obj.ServiceTarget == nil {
+		return 0
+	}
+	var score int
+	switch obj.ServiceTarget.Service {
+	case "", "*":
+	case service:
+		score += 2
+	default:
+		return -1
+	}
+	switch obj.ServiceTarget.Env {
+	case "", "*":
+	case env:
+		score++
+	default:
+		return -1
+	}
+	return score
+}
+
+// tracingTarget applies tracing settings to the sampler and the propagator.
+type tracingTarget struct {
+	sampler    *ddsampler.RulesSampler
+	propagator propagationUpdater
+	handler    func(TracingSettings)
+}
+
+// propagationUpdater updates the header tags of a tracecontext propagator.
+type propagationUpdater struct {
+	localHeaderTags map[string]string // nil if no propagator
+	current         map[string]string // header tags applied
+	update          func(headerTags map[string]string) error
+}
+
+// apply applies atomically the tracing settings: all or none of them are
+// applied. Unset settings restore the local configuration.
+func (obj *tracingTarget) apply(settings TracingSettings) error {
+	if obj.propagator.update != nil {
+		var headerTags = settings.HeaderTags
+		if headerTags == nil {
+			headerTags = obj.propagator.localHeaderTags
+		}
+		if err := obj.propagator.update(headerTags); err != nil {
+			return fmt.Errorf("header tags: %w", err)
+		}
+	}
+	if obj.sampler != nil {
+		if err := obj.sampler.SetRules(settings.SamplingRules, settings.SampleRate); err != nil {
+			// Rollback of the propagator
+			if obj.propagator.update != nil {
+				_ = obj.propagator.update(obj.propagator.current)
+			}
+			return fmt.Errorf("sampling: %w", err)
+		}
+	}
+	if obj.propagator.update != nil {
+		obj.propagator.current = settings.HeaderTags
+		if obj.propagator.current == nil {
+			obj.propagator.current = obj.propagator.localHeaderTags
+		}
+	}
+	if obj.handler != nil {
+		obj.handler(settings)
+	}
+	return nil
+}
+
+// applyTracing applies the APM_TRACING configuration targeting most
+// specifically the service and environment, returning the states of the
+// configurations by path. Configurations for other services are acknowledged,
+// not applied.
+func (obj *Client) applyTracing(files map[string]*cachedFile) map[string]*configState {
+	var (
+		states   = map[string]*configState{}
+		selected string
+		best     = -1
+		settings TracingSettings
+	)
+	for _, path := range sortedPaths(files) {
+		var file = files[path]
+		var state = &configState{ID: file.id, Version: file.meta.Custom.Version, Product: ProductAPMTracing, ApplyState: ApplyStateAcknowledged}
+		states[path] = state
+
+		var conf apmTracingConfig
+		if err := json.Unmarshal(file.raw, &conf); err != nil {
+			state.ApplyState, state.ApplyError = ApplyStateError, err.Error()
+			continue
+		}
+		if score := conf.specificity(obj.conf.service, obj.conf.env); score > best {
+			selected, best, settings = path, score, conf.settings()
+		}
+	}
+
+	if err := obj.tracing.apply(settings); err != nil && selected != "" {
+		states[selected].ApplyState, states[selected].ApplyError = ApplyStateError, err.Error()
+	}
+	return states
+}
+
+func sortedPaths(files map[string]*cachedFile) []string {
+	var paths = make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// newPropagationUpdater returns the updater of the header tags of a tracecontext
+// propagator, empty if nil.
+func newPropagationUpdater(conf *config) propagationUpdater {
+	if conf.propagator == nil {
+		return propagationUpdater{}
+	}
+	var localHeaderTags, _ = tracecontext.DescribeHeaderTags(conf.propagator)
+	return propagationUpdater{
+		localHeaderTags: localHeaderTags,
+		current:         localHeaderTags,
+		update: func(headerTags map[string]string) error {
+			return tracecontext.Update(conf.propagator, tracecontext.WithHeaderTags(headerTags))
+		},
+	}
+}
@@ -0,0 +1,81 @@
+package remoteconfig
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/samplers/ddsampler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func test_apmTracingConfig(t *testing.T, name string) *apmTracingConfig {
+	raw, err := ioutil.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+	var conf apmTracingConfig
+	require.NoError(t, json.Unmarshal(raw, &conf))
+	return &conf
+}
+
+func Test_apmTracingConfig_settings(t *testing.T) {
+	var settings = test_apmTracingConfig(t, "apm_tracing_service.json").settings()
+	assert.Equal(t, 0.25, *settings.SampleRate)
+	assert.Equal(t, []ddsampler.SamplingRule{
+		{Service: "api", Resource: "GET /health", Tags: map[string]string{"tenant": "internal-*"}, SampleRate: 0},
+	}, settings.SamplingRules)
+	assert.Equal(t, map[string]string{"X-User-Id": "user.id", "X-Request-Id": ""}, settings.HeaderTags)
+	assert.True(t, *settings.LogInjection)
+
+	// Unset settings
+	settings = test_apmTracingConfig(t, "apm_tracing_org.json").settings()
+	assert.Equal(t, 0.5, *settings.SampleRate)
+	assert.Nil(t, settings.SamplingRules)
+	assert.Nil(t, settings.HeaderTags)
+	assert.Nil(t, settings.LogInjection)
+}
+
+func Test_apmTracingConfig_specificity(t *testing.T) {
+	assert.Equal(t, 3, test_apmTracingConfig(t, "apm_tracing_service.json").specificity("api", "prod"))
+	assert.Equal(t, -1, test_apmTracingConfig(t, "apm_tracing_service.json").specificity("api", "staging"))
+	assert.Equal(t, 0, test_apmTracingConfig(t, "apm_tracing_org.json").specificity("api", "prod"))
+	assert.Equal(t, -1, test_apmTracingConfig(t, "apm_tracing_other_service.json").specificity("api", "prod"))
+	assert.Equal(t, 0, (&apmTracingConfig{}).specificity("api", "prod"))
+}
+
+func Test_tracingTarget_apply(t *testing.T) {
+	var (
+		applied []map[string]string
+		fail    bool
+		target  = &tracingTarget{propagator: propagationUpdater{
+			localHeaderTags: map[string]string{"X-Local": "local"},
+			current:         map[string]string{"X-Local": "local"},
+			update: func(headerTags map[string]string) error {
+				if fail {
+					return errors.New("invalid")
+				}
+				applied = append(applied, headerTags)
+				return nil
+			},
+		}}
+	)
+	var err error
+	target.sampler, err = ddsampler.NewRulesSampler()
+	require.NoError(t, err)
+
+	// Applied, unset header tags being the local ones
+	require.NoError(t, target.apply(TracingSettings{HeaderTags: map[string]string{"X-User-Id": "user.id"}}))
+	require.NoError(t, target.apply(TracingSettings{}))
+	assert.Equal(t, []map[string]string{{"X-User-Id": "user.id"}, {"X-Local": "local"}}, applied)
+
+	// Propagator rolled back when the sampler fails
+	var rate = -1.0
+	require.NoError(t, target.apply(TracingSettings{HeaderTags: map[string]string{"X-Tenant": "tenant"}}))
+	assert.ErrorIs(t, target.apply(TracingSettings{HeaderTags: map[string]string{"X-Other": ""}, SampleRate: &rate}), ddsampler.ErrInvalidSampleRate)
+	assert.Equal(t, map[string]string{"X-Tenant": "tenant"}, applied[len(applied)-1])
+
+	// Propagator failing
+	fail = true
+	assert.Error(t, target.apply(TracingSettings{}))
+}
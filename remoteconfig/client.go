@@ -0,0 +1,336 @@
+// Package remoteconfig polls the Remote Configuration endpoint of the Datadog
+// agent, applying the tracing settings (APM_TRACING product) at runtime to a
+// Datadog rules sampler and a tracecontext propagator.
+package remoteconfig
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/runtimeid"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/version"
+	"go.opentelemetry.io/otel"
+)
+
+// ErrNotEnabled is returned by Poll when Remote Configuration is not enabled
+// on the agent.
+var ErrNotEnabled = errors.New("remote configuration not enabled on the agent")
+
+// Client polls the agent for the configurations of the APM_TRACING product,
+// applying them when changed, and reports their apply state on the next poll.
+//
+// Configurations are verified against the TUF targets metadata served by the
+// agent: length and SHA-256 hash of each file, and signatures of the metadata
+// by the trusted root unless WithInsecureSkipVerify is enabled. Configurations
+// failing verification are not applied, the error being reported to the agent.
+type Client struct {
+	conf         *config
+	id           string
+	capabilities []byte
+	tracing      *tracingTarget
+
+	mu             sync.Mutex // serializes polls
+	root           *rootMetadata
+	rootVersion    int64
+	targetsVersion int64
+	backendState   []byte
+	lastError      error
+	files          map[string]*cachedFile  // applied configurations by path
+	states         map[string]*configState // states of the applied configurations by path
+
+	done chan struct{}
+	stop sync.Once
+	wg   sync.WaitGroup
+}
+
+// cachedFile is a configuration file received from the agent.
+type cachedFile struct {
+	id   string
+	raw  []byte
+	meta targetMeta
+}
+
+// New returns a client polling the agent every poll interval, until Stop.
+// At least one of the sampler, the propagator or the tracing handler options
+// is required.
+func New(cfg ...configFn) (*Client, error) {
+	conf, err := newConfig(cfg...)
+	if err != nil {
+		return nil, err
+	}
+
+	var client = newClient(conf)
+	client.wg.Add(1)
+	go client.run()
+	return client, nil
+}
+
+func newClient(conf *config) *Client {
+	var capabilities []uint
+	if conf.sampler != nil {
+		capabilities = append(capabilities, capabilityAPMTracingSampleRate, capabilityAPMTracingSampleRules)
+	}
+	if conf.propagator != nil {
+		capabilities = append(capabilities, capabilityAPMTracingHeaderTags)
+	}
+	if conf.tracingHandler != nil {
+		capabilities = append(capabilities, capabilityAPMTracingLogsInjection)
+	}
+
+	var rootVersion int64 = 1
+	if conf.root != nil {
+		rootVersion = conf.root.Version
+	}
+	return &Client{
+		conf:         conf,
+		id:           runtimeid.NewUUID(),
+		capabilities: capabilitiesBytes(capabilities...),
+		tracing: &tracingTarget{
+			sampler:    conf.sampler,
+			propagator: newPropagationUpdater(conf),
+			handler:    conf.tracingHandler,
+		},
+		root:        conf.root,
+		rootVersion: rootVersion,
+		files:       map[string]*cachedFile{},
+		states:      map[string]*configState{},
+		done:        make(chan struct{}),
+	}
+}
+
+// Stop stops polling the agent. Applied settings are kept.
+func (obj *Client) Stop() {
+	obj.stop.Do(func() { close(obj.done) })
+	obj.wg.Wait()
+}
+
+// run polls the agent periodically, until stopped.
+func (obj *Client) run() {
+	defer obj.wg.Done()
+	var ticker = time.NewTicker(obj.conf.pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-obj.done:
+			return
+		case <-ticker.C:
+			if err := obj.Poll(context.Background()); err != nil && !errors.Is(err, ErrNotEnabled) {
+				otel.Handle(err)
+			}
+		}
+	}
+}
+
+// States returns the states of the configurations received, sorted by path.
+func (obj *Client) States() []ConfigState {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	var states = make([]ConfigState, 0, len(obj.states))
+	for _, path := range sortedPaths(obj.files) {
+		var s = obj.states[path]
+		states = append(states, ConfigState{Path: path, ID: s.ID, Version: s.Version, Product: s.Product, ApplyState: s.ApplyState, ApplyError: s.ApplyError})
+	}
+	return states
+}
+
+// ConfigState is the state of a configuration received from the agent.
+type ConfigState struct {
+	Path       string
+	ID         string
+	Version    uint64
+	Product    string
+	ApplyState ApplyState
+	ApplyError string
+}
+
+// Poll sends the client state to the agent, and applies the configurations
+// received if changed.
+func (obj *Client) Poll(ctx context.Context) error {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	resp, err := obj.getConfigs(ctx)
+	if err != nil {
+		return err
+	}
+	obj.lastError = obj.update(resp)
+	return obj.lastError
+}
+
+// getConfigs sends the client state to the agent, returning its response.
+func (obj *Client) getConfigs(ctx context.Context) (*getConfigsResponse, error) {
+	body, err := json.Marshal(obj.newRequest())
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, obj.conf.agentBaseURL+agentPathConfig, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := obj.conf.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		return nil, ErrNotEnabled
+	case resp.StatusCode >= 300:
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("remote configuration: agent response %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+
+	var result getConfigsResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("remote configuration: %w", err)
+	}
+	return &result, nil
+}
+
+// newRequest returns the request of the client state, mu being locked.
+func (obj *Client) newRequest() *getConfigsRequest {
+	var state = &clientState{
+		RootVersion:        obj.rootVersion,
+		TargetsVersion:     obj.targetsVersion,
+		BackendClientState: obj.backendState,
+	}
+	if obj.lastError != nil {
+		state.HasError, state.Error = true, obj.lastError.Error()
+	}
+
+	var cached []*targetFileMeta
+	for _, path := range sortedPaths(obj.files) {
+		state.ConfigStates = append(state.ConfigStates, obj.states[path])
+
+		var file = obj.files[path]
+		var meta = &targetFileMeta{Path: path, Length: file.meta.Length}
+		for _, algorithm := range sortedKeys(file.meta.Hashes) {
+			meta.Hashes = append(meta.Hashes, &targetFileHash{Algorithm: algorithm, Hash: file.meta.Hashes[algorithm]})
+		}
+		cached = append(cached, meta)
+	}
+
+	return &getConfigsRequest{
+		Client: &clientData{
+			State:    state,
+			ID:       obj.id,
+			Products: []string{ProductAPMTracing},
+			IsTracer: true,
+			ClientTracer: &clientTracer{
+				RuntimeID:     runtimeid.ID(),
+				Language:      "go",
+				TracerVersion: version.Tag,
+				Service:       obj.conf.service,
+				Env:           obj.conf.env,
+				AppVersion:    obj.conf.version,
+				Tags:          []string{"runtime.version:" + runtime.Version()},
+			},
+			Capabilities: obj.capabilities,
+		},
+		CachedTargetFiles: cached,
+	}
+}
+
+// update verifies the configurations of the response and applies them if
+// changed, mu being locked. Nothing is applied if the verification fails.
+func (obj *Client) update(resp *getConfigsResponse) error {
+	// Unchanged since the last poll
+	if len(resp.Roots) == 0 && len(resp.Targets) == 0 {
+		return nil
+	}
+
+	var root, rootVersion = obj.root, obj.rootVersion
+	for _, data := range resp.Roots {
+		var err error
+		if root, rootVersion, err = updateRoot(root, rootVersion, data); err != nil {
+			return fmt.Errorf("remote configuration: root: %w", err)
+		}
+	}
+	targets, err := parseTargets(root, resp.Targets)
+	if err != nil {
+		return fmt.Errorf("remote configuration: targets: %w", err)
+	}
+	files, err := obj.collectFiles(targets, resp)
+	if err != nil {
+		return fmt.Errorf("remote configuration: %w", err)
+	}
+
+	obj.root, obj.rootVersion = root, rootVersion
+	obj.targetsVersion, obj.backendState = targets.Version, targets.Custom.OpaqueBackendState
+	if !sameFiles(obj.files, files) {
+		obj.states = obj.applyTracing(files)
+	}
+	obj.files = files
+	return nil
+}
+
+// collectFiles returns the verified configurations of the response by path,
+// unchanged ones being taken from the cache.
+func (obj *Client) collectFiles(targets *targetsMetadata, resp *getConfigsResponse) (map[string]*cachedFile, error) {
+	var received = make(map[string][]byte, len(resp.TargetFiles))
+	for _, file := range resp.TargetFiles {
+		received[file.Path] = file.Raw
+	}
+
+	var files = map[string]*cachedFile{}
+	for _, path := range resp.ClientConfigs {
+		var product, id, ok = parseConfigPath(path)
+		if !ok {
+			return nil, fmt.Errorf("invalid configuration path %q", path)
+		}
+		if product != ProductAPMTracing {
+			continue
+		}
+		meta, ok := targets.Targets[path]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", errTargetMissing, path)
+		}
+
+		raw, ok := received[path]
+		if cached := obj.files[path]; !ok && cached != nil {
+			raw = cached.raw
+		}
+		if err := verifyTarget(meta, raw); err != nil {
+			return nil, fmt.Errorf("%w: %s", err, path)
+		}
+		files[path] = &cachedFile{id: id, raw: raw, meta: meta}
+	}
+	return files, nil
+}
+
+// sameFiles returns true if both sets hold the same versions of the files.
+func sameFiles(a, b map[string]*cachedFile) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, file := range a {
+		var other, ok = b[path]
+		if !ok || file.meta.Hashes[hashSHA256] != other.meta.Hashes[hashSHA256] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedKeys(values map[string]string) []string {
+	var keys = make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
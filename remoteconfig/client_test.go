@@ -0,0 +1,426 @@
+package remoteconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/samplers/ddsampler"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// _____ Stand-in agent _____
+
+const (
+	test_pathService      = "datadog/2/APM_TRACING/6b1c0b3f8d9c/config"
+	test_pathOrg          = "datadog/2/APM_TRACING/0e5e8a7d2c41/config"
+	test_pathOtherService = "datadog/2/APM_TRACING/a9f04c7e1b22/config"
+	test_pathInvalidRate  = "employee/APM_TRACING/c3d2e1f0a9b8/config"
+)
+
+// test_agent is a stand-in Datadog agent serving the configurations of
+// testdata, signed as TUF targets.
+type test_agent struct {
+	*httptest.Server
+	t *testing.T
+
+	mu             sync.Mutex
+	rootKeys       []ed25519.PrivateKey // by root version - 1
+	roots          [][]byte             // signed roots by version - 1
+	targetsKey     ed25519.PrivateKey
+	targetsVersion int64
+	configs        map[string][]byte // by path
+	corrupt        bool              // files served not matching their hash
+	requests       []getConfigsRequest
+}
+
+func test_newAgent(t *testing.T) *test_agent {
+	var agent = &test_agent{
+		t:          t,
+		targetsKey: test_key(0x20),
+		configs:    map[string][]byte{},
+	}
+	agent.rotateRoot()
+	agent.Server = httptest.NewServer(agent)
+	t.Cleanup(agent.Close)
+	return agent
+}
+
+func test_key(seed byte) ed25519.PrivateKey {
+	return ed25519.NewKeyFromSeed(bytes.Repeat([]byte{seed}, ed25519.SeedSize))
+}
+
+func test_keyID(key ed25519.PrivateKey) string {
+	var hash = sha256.Sum256(key.Public().(ed25519.PublicKey))
+	return hex.EncodeToString(hash[:])
+}
+
+// test_sign returns the TUF envelope of the metadata signed by the keys, the
+// signed metadata being indented to check the canonical form is verified.
+func test_sign(t *testing.T, signed interface{}, keys ...ed25519.PrivateKey) []byte {
+	indented, err := json.MarshalIndent(signed, "", "  ")
+	require.NoError(t, err)
+	canonical, err := canonicalJSON(indented)
+	require.NoError(t, err)
+
+	var signatures = []map[string]string{}
+	for _, key := range keys {
+		signatures = append(signatures, map[string]string{
+			"keyid": test_keyID(key),
+			"sig":   hex.EncodeToString(ed25519.Sign(key, canonical)),
+		})
+	}
+	envelope, err := json.Marshal(map[string]interface{}{"signed": json.RawMessage(indented), "signatures": signatures})
+	require.NoError(t, err)
+	return envelope
+}
+
+// rotateRoot adds a root version with a new root key, signed by the previous
+// and the new root keys.
+func (obj *test_agent) rotateRoot() {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	var (
+		version = len(obj.roots) + 1
+		rootKey = test_key(byte(0x10 + version))
+		keys    = map[string]interface{}{}
+	)
+	for _, key := range []ed25519.PrivateKey{rootKey, obj.targetsKey} {
+		keys[test_keyID(key)] = map[string]interface{}{
+			"keytype": "ed25519",
+			"scheme":  "ed25519",
+			"keyval":  map[string]string{"public": hex.EncodeToString(key.Public().(ed25519.PublicKey))},
+		}
+	}
+	var signed = map[string]interface{}{
+		"_type":        "root",
+		"spec_version": "1.0",
+		"version":      version,
+		"expires":      "2030-01-01T00:00:00Z",
+		"keys":         keys,
+		"roles": map[string]interface{}{
+			"root":    map[string]interface{}{"keyids": []string{test_keyID(rootKey)}, "threshold": 1},
+			"targets": map[string]interface{}{"keyids": []string{test_keyID(obj.targetsKey)}, "threshold": 1},
+		},
+	}
+
+	var signers = []ed25519.PrivateKey{rootKey}
+	if version > 1 {
+		signers = append(signers, obj.rootKeys[version-2])
+	}
+	obj.rootKeys = append(obj.rootKeys, rootKey)
+	obj.roots = append(obj.roots, test_sign(obj.t, signed, signers...))
+}
+
+// setConfigs serves the testdata files by path as a new targets version.
+func (obj *test_agent) setConfigs(files map[string]string) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+
+	obj.configs = map[string][]byte{}
+	for path, name := range files {
+		raw, err := ioutil.ReadFile(filepath.Join("testdata", name))
+		require.NoError(obj.t, err)
+		obj.configs[path] = raw
+	}
+	obj.targetsVersion++
+}
+
+func (obj *test_agent) setCorrupt(corrupt bool) {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.corrupt = corrupt
+	obj.targetsVersion++
+}
+
+func (obj *test_agent) lastRequest() getConfigsRequest {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	require.NotEmpty(obj.t, obj.requests)
+	return obj.requests[len(obj.requests)-1]
+}
+
+func (obj *test_agent) requestCount() int {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	return len(obj.requests)
+}
+
+func (obj *test_agent) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != agentPathConfig || r.Method != http.MethodPost {
+		http.NotFound(w, r)
+		return
+	}
+	var req getConfigsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.requests = append(obj.requests, req)
+
+	var resp getConfigsResponse
+	for v := req.Client.State.RootVersion + 1; v <= int64(len(obj.roots)); v++ {
+		resp.Roots = append(resp.Roots, obj.roots[v-1])
+	}
+	if req.Client.State.TargetsVersion != obj.targetsVersion {
+		var cached = map[string]string{}
+		for _, meta := range req.CachedTargetFiles {
+			for _, hash := range meta.Hashes {
+				cached[meta.Path] = hash.Hash
+			}
+		}
+
+		var targets = map[string]interface{}{}
+		for path, raw := range obj.configs {
+			var hash = sha256.Sum256(raw)
+			targets[path] = map[string]interface{}{
+				"length": len(raw),
+				"hashes": map[string]string{"sha256": hex.EncodeToString(hash[:])},
+				"custom": map[string]interface{}{"v": obj.targetsVersion},
+			}
+			resp.ClientConfigs = append(resp.ClientConfigs, path)
+			if cached[path] == hex.EncodeToString(hash[:]) {
+				continue
+			}
+			if obj.corrupt {
+				raw = bytes.Replace(raw, []byte("0."), []byte("1."), 1)
+			}
+			resp.TargetFiles = append(resp.TargetFiles, &targetFile{Path: path, Raw: raw})
+		}
+		resp.Targets = test_sign(obj.t, map[string]interface{}{
+			"_type":        "targets",
+			"spec_version": "1.0",
+			"version":      obj.targetsVersion,
+			"expires":      "2030-01-01T00:00:00Z",
+			"targets":      targets,
+			"custom":       map[string]interface{}{"opaque_backend_state": []byte("state-" + strconv.FormatInt(obj.targetsVersion, 10))},
+		}, obj.targetsKey)
+	}
+	_ = json.NewEncoder(w).Encode(resp)
+}
+
+// _____ Client _____
+
+type test_targets struct {
+	sampler    *ddsampler.RulesSampler
+	propagator propagation.TextMapPropagator
+	settings   []TracingSettings
+}
+
+func test_newClient(t *testing.T, agent *test_agent, cfg ...configFn) (*Client, *test_targets) {
+	var targets = &test_targets{}
+	var err error
+	targets.sampler, err = ddsampler.NewRulesSampler(
+		ddsampler.WithService("api"),
+		ddsampler.WithSamplingRules([]ddsampler.SamplingRule{{Service: "api", Name: "local", SampleRate: 1}}),
+	)
+	require.NoError(t, err)
+	targets.propagator, err = tracecontext.New(tracecontext.WithHeaderTags(map[string]string{"X-Local": "local"}))
+	require.NoError(t, err)
+
+	conf, err := newConfig(append([]configFn{
+		WithAgentURL(agent.URL),
+		WithService("api"),
+		WithEnv("prod"),
+		WithVersion("1.2.3"),
+		WithTUFRoot(agent.roots[0]),
+		WithSampler(targets.sampler),
+		WithPropagator(targets.propagator),
+		WithTracingHandler(func(settings TracingSettings) { targets.settings = append(targets.settings, settings) }),
+	}, cfg...)...)
+	require.NoError(t, err)
+	return newClient(conf), targets
+}
+
+func test_headerTags(t *testing.T, prop propagation.TextMapPropagator) map[string]string {
+	headerTags, ok := tracecontext.DescribeHeaderTags(prop)
+	require.True(t, ok)
+	return headerTags
+}
+
+func Test_Client_Poll(t *testing.T) {
+	var agent = test_newAgent(t)
+	var client, targets = test_newClient(t, agent)
+
+	// Nothing configured
+	require.NoError(t, client.Poll(context.Background()))
+	var req = agent.lastRequest()
+	assert.Equal(t, []string{ProductAPMTracing}, req.Client.Products)
+	assert.True(t, req.Client.IsTracer)
+	assert.Equal(t, "go", req.Client.ClientTracer.Language)
+	assert.Equal(t, []string{"api", "prod", "1.2.3"}, []string{req.Client.ClientTracer.Service, req.Client.ClientTracer.Env, req.Client.ClientTracer.AppVersion})
+	assert.Equal(t, capabilitiesBytes(12, 13, 14, 29), req.Client.Capabilities)
+	assert.Equal(t, int64(1), req.Client.State.RootVersion)
+	assert.Empty(t, targets.settings)
+
+	// Most specific configuration applied to the sampler and the propagator
+	agent.setConfigs(map[string]string{
+		test_pathService:      "apm_tracing_service.json",
+		test_pathOrg:          "apm_tracing_org.json",
+		test_pathOtherService: "apm_tracing_other_service.json",
+	})
+	require.NoError(t, client.Poll(context.Background()))
+	if rules := targets.sampler.Rules(); assert.Len(t, rules, 2) {
+		assert.Equal(t, ddsampler.SamplingRule{Service: "api", Resource: "GET /health", Tags: map[string]string{"tenant": "internal-*"}}, ddsampler.SamplingRule{
+			Service: rules[0].Service, Resource: rules[0].Resource, Tags: rules[0].Tags, SampleRate: rules[0].SampleRate,
+		})
+		assert.Equal(t, 0.25, rules[1].SampleRate)
+	}
+	assert.Equal(t, map[string]string{"X-User-Id": "user.id", "X-Request-Id": "http.request.headers.x-request-id"}, test_headerTags(t, targets.propagator))
+	if assert.Len(t, targets.settings, 1) {
+		assert.Equal(t, true, *targets.settings[0].LogInjection)
+	}
+	var states = client.States()
+	if assert.Len(t, states, 3) {
+		for _, state := range states {
+			assert.Equal(t, ApplyStateAcknowledged, state.ApplyState, state.Path)
+			assert.Equal(t, ProductAPMTracing, state.Product)
+			assert.Equal(t, uint64(1), state.Version)
+		}
+		assert.Equal(t, "6b1c0b3f8d9c", states[1].ID)
+	}
+
+	// States reported, unchanged configurations not applied again
+	require.NoError(t, client.Poll(context.Background()))
+	req = agent.lastRequest()
+	assert.Equal(t, int64(1), req.Client.State.TargetsVersion)
+	assert.Equal(t, []byte("state-1"), req.Client.State.BackendClientState)
+	assert.False(t, req.Client.State.HasError)
+	if assert.Len(t, req.Client.State.ConfigStates, 3) {
+		assert.Equal(t, ApplyStateAcknowledged, req.Client.State.ConfigStates[0].ApplyState)
+	}
+	assert.Len(t, req.CachedTargetFiles, 3)
+	assert.Len(t, targets.settings, 1)
+
+	// Configuration removed, next most specific one applied
+	agent.setConfigs(map[string]string{test_pathOrg: "apm_tracing_org.json"})
+	require.NoError(t, client.Poll(context.Background()))
+	if rules := targets.sampler.Rules(); assert.Len(t, rules, 2) {
+		assert.Equal(t, "local", rules[0].Name)
+		assert.Equal(t, 0.5, rules[1].SampleRate)
+	}
+	assert.Equal(t, map[string]string{"X-Local": "local"}, test_headerTags(t, targets.propagator))
+
+	// All configurations removed, local configuration restored
+	agent.setConfigs(nil)
+	require.NoError(t, client.Poll(context.Background()))
+	assert.Len(t, targets.sampler.Rules(), 1)
+	assert.Empty(t, client.States())
+	if assert.Len(t, targets.settings, 3) {
+		assert.Equal(t, TracingSettings{}, targets.settings[2])
+	}
+}
+
+func Test_Client_Poll_ApplyError(t *testing.T) {
+	var agent = test_newAgent(t)
+	var client, targets = test_newClient(t, agent)
+
+	// Invalid sample rate, header tags not applied either
+	agent.setConfigs(map[string]string{test_pathInvalidRate: "apm_tracing_invalid_rate.json"})
+	require.NoError(t, client.Poll(context.Background()))
+	if states := client.States(); assert.Len(t, states, 1) {
+		assert.Equal(t, ApplyStateError, states[0].ApplyState)
+		assert.Contains(t, states[0].ApplyError, "sample rate")
+	}
+	assert.Len(t, targets.sampler.Rules(), 1)
+	assert.Equal(t, map[string]string{"X-Local": "local"}, test_headerTags(t, targets.propagator))
+	assert.Empty(t, targets.settings)
+
+	require.NoError(t, client.Poll(context.Background()))
+	if req := agent.lastRequest(); assert.Len(t, req.Client.State.ConfigStates, 1) {
+		assert.Equal(t, ApplyStateError, req.Client.State.ConfigStates[0].ApplyState)
+		assert.Equal(t, "c3d2e1f0a9b8", req.Client.State.ConfigStates[0].ID)
+	}
+}
+
+func Test_Client_Poll_Verification(t *testing.T) {
+	var agent = test_newAgent(t)
+	var client, targets = test_newClient(t, agent)
+
+	// Files not matching their hash rejected, error reported
+	agent.setConfigs(map[string]string{test_pathService: "apm_tracing_service.json"})
+	agent.setCorrupt(true)
+	assert.ErrorIs(t, client.Poll(context.Background()), errTargetHash)
+	assert.Len(t, targets.sampler.Rules(), 1)
+	assert.Empty(t, client.States())
+
+	agent.setCorrupt(false)
+	require.NoError(t, client.Poll(context.Background()))
+	var req = agent.lastRequest()
+	assert.True(t, req.Client.State.HasError)
+	assert.Contains(t, req.Client.State.Error, "hash")
+	assert.Len(t, targets.sampler.Rules(), 2)
+
+	// Root rotation verified
+	agent.rotateRoot()
+	agent.setConfigs(map[string]string{test_pathOrg: "apm_tracing_org.json"})
+	require.NoError(t, client.Poll(context.Background()))
+	require.NoError(t, client.Poll(context.Background()))
+	assert.Equal(t, int64(2), agent.lastRequest().Client.State.RootVersion)
+	assert.Empty(t, agent.lastRequest().Client.State.Error)
+
+	// Targets not signed by the trusted root rejected
+	var other = test_newAgent(t)
+	other.targetsKey = test_key(0x30)
+	other.setConfigs(map[string]string{test_pathOrg: "apm_tracing_org.json"})
+	client, _ = test_newClient(t, other, WithTUFRoot(agent.roots[0]))
+	assert.ErrorIs(t, client.Poll(context.Background()), errSignatureThreshold)
+
+	// Only hashes verified if verification explicitly skipped
+	setenv(t, envTUFRoot, "")
+	client, targets = test_newClient(t, other, WithTUFRoot(nil), WithInsecureSkipVerify(true))
+	require.NoError(t, client.Poll(context.Background()))
+	assert.Equal(t, 0.5, targets.sampler.Rules()[1].SampleRate)
+}
+
+func Test_Client_Poll_NotEnabled(t *testing.T) {
+	var agent = httptest.NewServer(http.NotFoundHandler())
+	defer agent.Close()
+	conf, err := newConfig(WithAgentURL(agent.URL), WithTracingHandler(func(TracingSettings) {}), WithInsecureSkipVerify(true))
+	require.NoError(t, err)
+	assert.ErrorIs(t, newClient(conf).Poll(context.Background()), ErrNotEnabled)
+
+	agent.Close()
+	assert.Error(t, newClient(conf).Poll(context.Background()))
+}
+
+func Test_New(t *testing.T) {
+	var agent = test_newAgent(t)
+	agent.setConfigs(map[string]string{test_pathOrg: "apm_tracing_org.json"})
+	sampler, err := ddsampler.NewRulesSampler()
+	require.NoError(t, err)
+
+	_, err = New(WithAgentURL(agent.URL))
+	assert.ErrorIs(t, err, ErrNothingToConfigure)
+	setenv(t, envTUFRoot, "")
+	_, err = New(WithAgentURL(agent.URL), WithSampler(sampler))
+	assert.ErrorIs(t, err, ErrMissingTUFRoot)
+
+	// Agent polled until stopped
+	client, err := New(WithAgentURL(agent.URL), WithSampler(sampler), WithTUFRoot(agent.roots[0]), WithPollInterval(5*time.Millisecond))
+	require.NoError(t, err)
+	assert.Eventually(t, func() bool { return len(sampler.Rules()) == 1 }, time.Second, 5*time.Millisecond)
+	client.Stop()
+	client.Stop()
+	var count = agent.requestCount()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, count, agent.requestCount())
+}
@@ -0,0 +1,263 @@
+package remoteconfig
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/detectors/unifiedtagging"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/agenturl"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/naming"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/samplers/ddsampler"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+// _____________________ With option functions _____________________
+
+// WithAgentURL sets the URL of the Datadog agent (ex: "http://localhost:8126"
+// or "unix:///var/run/datadog/apm.socket").
+// It defaults to DD_TRACE_AGENT_URL environment variable, or DD_AGENT_HOST and
+// DD_TRACE_AGENT_PORT, or DefaultAgentURL.
+func WithAgentURL(value string) configFn {
+	return func(conf *config) {
+		conf.agentURL = value
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to poll the agent.
+func WithHTTPClient(value *http.Client) configFn {
+	return func(conf *config) {
+		conf.httpClient = value
+	}
+}
+
+// WithPollInterval sets the interval between polls of the agent.
+// It defaults to DD_REMOTE_CONFIG_POLL_INTERVAL_SECONDS environment variable or DefaultPollInterval.
+func WithPollInterval(value time.Duration) configFn {
+	return func(conf *config) {
+		conf.pollInterval = value
+	}
+}
+
+// WithService sets the service of this process, used to select configurations.
+// It defaults to the service of the unified service tagging detector.
+func WithService(value string) configFn {
+	return func(conf *config) {
+		conf.service = value
+	}
+}
+
+// WithEnv sets the environment of this process, used to select configurations.
+// It defaults to the environment of the unified service tagging detector.
+func WithEnv(value string) configFn {
+	return func(conf *config) {
+		conf.env = value
+	}
+}
+
+// WithVersion sets the version of this process, reported to the agent.
+// It defaults to the version of the unified service tagging detector.
+func WithVersion(value string) configFn {
+	return func(conf *config) {
+		conf.version = value
+	}
+}
+
+// WithSampler sets the sampler receiving the sampling rate and rules of the
+// APM_TRACING configurations.
+func WithSampler(value *ddsampler.RulesSampler) configFn {
+	return func(conf *config) {
+		conf.sampler = value
+	}
+}
+
+// WithPropagator sets the propagator receiving the header tags of the
+// APM_TRACING configurations. It must be returned by tracecontext.New.
+func WithPropagator(value propagation.TextMapPropagator) configFn {
+	return func(conf *config) {
+		conf.propagator = value
+	}
+}
+
+// WithTracingHandler sets the function called with the settings of the
+// APM_TRACING configurations once applied (ex: to enable log injection).
+func WithTracingHandler(fn func(TracingSettings)) configFn {
+	return func(conf *config) {
+		conf.tracingHandler = fn
+	}
+}
+
+// WithTUFRoot sets the trusted TUF root metadata (JSON), verifying the
+// signatures of the configurations served by the agent.
+// It defaults to DD_RC_TUF_ROOT environment variable, and is required unless
+// WithInsecureSkipVerify is enabled.
+func WithTUFRoot(value []byte) configFn {
+	return func(conf *config) {
+		conf.tufRoot = value
+	}
+}
+
+// WithInsecureSkipVerify allows configurations to be applied without trusted
+// TUF root, only their hashes being verified: anyone able to answer in place
+// of the agent can then change the sampling and propagation settings.
+// It is ignored when a TUF root is set.
+func WithInsecureSkipVerify(value bool) configFn {
+	return func(conf *config) {
+		conf.insecureSkipVerify = value
+	}
+}
+
+// _____________________ Definition _____________________
+
+type configFn func(*config)
+
+var (
+	ErrInvalidAgentURL     = errors.New("invalid agent URL")
+	ErrInvalidPollInterval = errors.New("invalid poll interval")
+	ErrInvalidTUFRoot      = errors.New("invalid TUF root")
+	ErrMissingTUFRoot      = errors.New("missing TUF root, required unless verification is explicitly skipped")
+	ErrNothingToConfigure  = errors.New("neither sampler, propagator nor tracing handler to configure")
+)
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/internal/remoteconfig/config.go
+
+const (
+	envPollInterval = "DD_REMOTE_CONFIG_POLL_INTERVAL_SECONDS"
+	envTUFRoot      = "DD_RC_TUF_ROOT"
+)
+
+const (
+	// DefaultAgentURL specifies the URL of the Datadog agent.
+	DefaultAgentURL = agenturl.DefaultURL
+
+	// DefaultPollInterval specifies the interval between polls of the agent.
+	DefaultPollInterval = 5 * time.Second
+
+	// defaultHTTPTimeout is the timeout of requests sent to the agent
+	defaultHTTPTimeout = 10 * time.Second
+)
+
+// _____________________ Configuration _____________________
+
+func newConfig(cfg ...configFn) (*config, error) {
+	var conf = &config{}
+
+	// Apply configurations
+	for _, v := range cfg {
+		if v != nil {
+			v(conf)
+		}
+	}
+
+	// Apply default value on empty
+	if err := conf.applyDefault(); err != nil {
+		return nil, err
+	}
+
+	// Check configuration is valid
+	if err := conf.parse(); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+type config struct {
+	agentURL           string
+	httpClient         *http.Client
+	pollInterval       time.Duration
+	service            string
+	env                string
+	version            string
+	sampler            *ddsampler.RulesSampler
+	propagator         propagation.TextMapPropagator
+	tracingHandler     func(TracingSettings)
+	tufRoot            []byte
+	insecureSkipVerify bool
+
+	// Parsed values
+	agentBaseURL string
+	root         *rootMetadata // nil if signatures are not verified
+}
+
+func (obj *config) applyDefault() error {
+	// Set default agent URL
+	if obj.agentURL == "" {
+		obj.agentURL = agenturl.FromEnv()
+	}
+
+	// Set default poll interval
+	if obj.pollInterval == 0 {
+		obj.pollInterval = DefaultPollInterval
+		if value := strings.TrimSpace(os.Getenv(envPollInterval)); value != "" {
+			seconds, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return ErrInvalidPollInterval
+			}
+			obj.pollInterval = time.Duration(seconds * float64(time.Second))
+		}
+	}
+
+	// Set default service, env and version from environment
+	if obj.service == "" || obj.env == "" || obj.version == "" {
+		res, _ := unifiedtagging.New().Detect(context.Background())
+		for _, kv := range res.Attributes() {
+			switch {
+			case kv.Key == semconv.ServiceNameKey && obj.service == "":
+				obj.service = kv.Value.Emit()
+			case kv.Key == semconv.DeploymentEnvironmentKey && obj.env == "":
+				obj.env = kv.Value.Emit()
+			case kv.Key == semconv.ServiceVersionKey && obj.version == "":
+				obj.version = kv.Value.Emit()
+			}
+		}
+	}
+
+	if obj.service == "" {
+		obj.service = naming.DefaultServiceName
+	}
+
+	// Set default TUF root from environment
+	if obj.tufRoot == nil {
+		if value := strings.TrimSpace(os.Getenv(envTUFRoot)); value != "" {
+			obj.tufRoot = []byte(value)
+		}
+	}
+	return nil
+}
+
+func (obj *config) parse() (err error) {
+	obj.agentBaseURL, obj.httpClient, err = agenturl.Resolve(obj.agentURL, obj.httpClient, defaultHTTPTimeout)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidAgentURL, err)
+	}
+
+	if obj.pollInterval <= 0 {
+		return ErrInvalidPollInterval
+	}
+
+	if obj.sampler == nil && obj.propagator == nil && obj.tracingHandler == nil {
+		return ErrNothingToConfigure
+	}
+	if obj.propagator != nil {
+		if _, ok := tracecontext.DescribeHeaderTags(obj.propagator); !ok {
+			return tracecontext.ErrUnsupportedPropagator
+		}
+	}
+
+	if obj.tufRoot != nil {
+		if obj.root, err = parseRoot(obj.tufRoot); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidTUFRoot, err)
+		}
+	} else if !obj.insecureSkipVerify {
+		return ErrMissingTUFRoot
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package remoteconfig
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/agenturl"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+func Test_newConfig(t *testing.T) {
+	for _, key := range []string{agenturl.EnvURL, agenturl.EnvHost, agenturl.EnvPort, envPollInterval, envTUFRoot, "DD_SERVICE", "DD_ENV", "DD_VERSION", "OTEL_SERVICE_NAME", "OTEL_RESOURCE_ATTRIBUTES"} {
+		setenv(t, key, "")
+	}
+	var (
+		handler  = WithTracingHandler(func(TracingSettings) {})
+		insecure = WithInsecureSkipVerify(true)
+	)
+
+	// Defaults
+	conf, err := newConfig(handler, insecure)
+	require.NoError(t, err)
+	assert.Equal(t, DefaultAgentURL, conf.agentBaseURL)
+	assert.Equal(t, DefaultPollInterval, conf.pollInterval)
+	assert.NotNil(t, conf.httpClient)
+	assert.NotEmpty(t, conf.service)
+	assert.Nil(t, conf.root)
+
+	// Environment
+	setenv(t, agenturl.EnvHost, "agent")
+	setenv(t, envPollInterval, "0.5")
+	setenv(t, "DD_SERVICE", "api")
+	setenv(t, "DD_ENV", "prod")
+	setenv(t, "DD_VERSION", "1.2.3")
+	conf, err = newConfig(handler, insecure)
+	require.NoError(t, err)
+	assert.Equal(t, "http://agent:8126", conf.agentBaseURL)
+	assert.Equal(t, 500*time.Millisecond, conf.pollInterval)
+	assert.Equal(t, []string{"api", "prod", "1.2.3"}, []string{conf.service, conf.env, conf.version})
+
+	setenv(t, agenturl.EnvURL, "http://127.0.0.1:9126/")
+	conf, err = newConfig(handler, insecure)
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:9126", conf.agentBaseURL)
+
+	// Unix domain socket of the agent, as the exporter
+	setenv(t, agenturl.EnvURL, "unix:///var/run/datadog/apm.socket")
+	conf, err = newConfig(handler, insecure)
+	require.NoError(t, err)
+	assert.Equal(t, "http://UDS__var_run_datadog_apm.socket", conf.agentBaseURL)
+	assert.NotNil(t, conf.httpClient.Transport)
+
+	// Options over environment
+	conf, err = newConfig(handler, insecure, WithAgentURL("https://agent:8443"), WithPollInterval(time.Minute), WithService("other"))
+	require.NoError(t, err)
+	assert.Equal(t, "https://agent:8443", conf.agentBaseURL)
+	assert.Equal(t, time.Minute, conf.pollInterval)
+	assert.Equal(t, "other", conf.service)
+}
+
+func Test_newConfig_Invalid(t *testing.T) {
+	setenv(t, envPollInterval, "")
+	setenv(t, envTUFRoot, "")
+	var handler = WithTracingHandler(func(TracingSettings) {})
+
+	_, err := newConfig()
+	assert.ErrorIs(t, err, ErrNothingToConfigure)
+	_, err = newConfig(handler, WithAgentURL("ftp://agent"))
+	assert.ErrorIs(t, err, ErrInvalidAgentURL)
+	_, err = newConfig(handler, WithAgentURL("http://[::1"))
+	assert.ErrorIs(t, err, ErrInvalidAgentURL)
+	_, err = newConfig(handler, WithPollInterval(-time.Second))
+	assert.ErrorIs(t, err, ErrInvalidPollInterval)
+	_, err = newConfig(WithPropagator(propagation.TraceContext{}))
+	assert.ErrorIs(t, err, tracecontext.ErrUnsupportedPropagator)
+	_, err = newConfig(handler, WithTUFRoot([]byte(`{}`)), WithInsecureSkipVerify(true))
+	assert.ErrorIs(t, err, ErrInvalidTUFRoot)
+
+	// Trusted root required unless verification explicitly skipped
+	_, err = newConfig(handler)
+	assert.ErrorIs(t, err, ErrMissingTUFRoot)
+	_, err = newConfig(handler, WithInsecureSkipVerify(false))
+	assert.ErrorIs(t, err, ErrMissingTUFRoot)
+
+	setenv(t, envPollInterval, "often")
+	_, err = newConfig(handler, WithInsecureSkipVerify(true))
+	assert.ErrorIs(t, err, ErrInvalidPollInterval)
+	setenv(t, envPollInterval, "0")
+	_, err = newConfig(handler, WithInsecureSkipVerify(true))
+	assert.ErrorIs(t, err, ErrInvalidPollInterval)
+	setenv(t, envPollInterval, "")
+
+	// Trusted root from environment
+	var agent = test_newAgent(t)
+	setenv(t, envTUFRoot, string(agent.roots[0]))
+	if conf, err := newConfig(handler); assert.NoError(t, err) && assert.NotNil(t, conf.root) {
+		assert.Equal(t, int64(1), conf.root.Version)
+	}
+}
+
+func setenv(t *testing.T, key, value string) {
+	old, exists := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if exists {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
@@ -0,0 +1,125 @@
+package remoteconfig
+
+import (
+	"math/big"
+	"strings"
+)
+
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/proto/datadog/remoteconfig/remoteconfig.proto
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/internal/remoteconfig/types.go
+
+const (
+	agentPathConfig = "/v0.7/config"
+
+	// ProductAPMTracing is the product of the tracing library settings.
+	ProductAPMTracing = "APM_TRACING"
+)
+
+// ApplyState is the state of a configuration reported to the agent.
+type ApplyState uint64
+
+const (
+	// ApplyStateUnknown is the state of a configuration not handled.
+	ApplyStateUnknown ApplyState = iota
+	// ApplyStateUnacknowledged is the state of a configuration received, not applied yet.
+	ApplyStateUnacknowledged
+	// ApplyStateAcknowledged is the state of a configuration applied.
+	ApplyStateAcknowledged
+	// ApplyStateError is the state of a configuration failing to be applied.
+	ApplyStateError
+)
+
+// Capabilities of the client, bit indexes of the capabilities sent to the agent.
+const (
+	capabilityAPMTracingSampleRate    = 12
+	capabilityAPMTracingLogsInjection = 13
+	capabilityAPMTracingHeaderTags    = 14
+	capabilityAPMTracingSampleRules   = 29
+)
+
+type getConfigsRequest struct {
+	Client            *clientData       `json:"client"`
+	CachedTargetFiles []*targetFileMeta `json:"cached_target_files,omitempty"`
+}
+
+type clientData struct {
+	State        *clientState  `json:"state"`
+	ID           string        `json:"id"`
+	Products     []string      `json:"products"`
+	IsTracer     bool          `json:"is_tracer"`
+	ClientTracer *clientTracer `json:"client_tracer"`
+	Capabilities []byte        `json:"capabilities"`
+}
+
+type clientTracer struct {
+	RuntimeID     string   `json:"runtime_id"`
+	Language      string   `json:"language"`
+	TracerVersion string   `json:"tracer_version"`
+	Service       string   `json:"service"`
+	Env           string   `json:"env,omitempty"`
+	AppVersion    string   `json:"app_version,omitempty"`
+	Tags          []string `json:"tags,omitempty"`
+}
+
+type clientState struct {
+	RootVersion        int64          `json:"root_version"`
+	TargetsVersion     int64          `json:"targets_version"`
+	ConfigStates       []*configState `json:"config_states,omitempty"`
+	HasError           bool           `json:"has_error,omitempty"`
+	Error              string         `json:"error,omitempty"`
+	BackendClientState []byte         `json:"backend_client_state,omitempty"`
+}
+
+type configState struct {
+	ID         string     `json:"id"`
+	Version    uint64     `json:"version"`
+	Product    string     `json:"product"`
+	ApplyState ApplyState `json:"apply_state"`
+	ApplyError string     `json:"apply_error,omitempty"`
+}
+
+type targetFileMeta struct {
+	Path   string            `json:"path"`
+	Length int64             `json:"length"`
+	Hashes []*targetFileHash `json:"hashes"`
+}
+
+type targetFileHash struct {
+	Algorithm string `json:"algorithm"`
+	Hash      string `json:"hash"`
+}
+
+type getConfigsResponse struct {
+	Roots         [][]byte      `json:"roots"`
+	Targets       []byte        `json:"targets"`
+	TargetFiles   []*targetFile `json:"target_files"`
+	ClientConfigs []string      `json:"client_configs"`
+}
+
+type targetFile struct {
+	Path string `json:"path"`
+	Raw  []byte `json:"raw"`
+}
+
+// parseConfigPath returns the product and the ID of a configuration path
+// ("datadog/<org>/<product>/<id>/<name>" or "employee/<product>/<id>/<name>"),
+// ok being false if invalid.
+func parseConfigPath(path string) (product, id string, ok bool) {
+	var parts = strings.Split(path, "/")
+	switch {
+	case len(parts) == 5 && parts[0] == "datadog":
+		return parts[2], parts[3], parts[2] != "" && parts[3] != ""
+	case len(parts) == 4 && parts[0] == "employee":
+		return parts[1], parts[2], parts[1] != "" && parts[2] != ""
+	}
+	return "", "", false
+}
+
+// capabilitiesBytes returns the capabilities as a big-endian bit set.
+func capabilitiesBytes(capabilities ...uint) []byte {
+	var value big.Int
+	for _, c := range capabilities {
+		value.SetBit(&value, int(c), 1)
+	}
+	return value.Bytes()
+}
@@ -0,0 +1,28 @@
+package remoteconfig
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_parseConfigPath(t *testing.T) {
+	product, id, ok := parseConfigPath("datadog/2/APM_TRACING/6b1c0b3f8d9c/config")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"APM_TRACING", "6b1c0b3f8d9c"}, []string{product, id})
+
+	product, id, ok = parseConfigPath("employee/ASM_DD/1.2.3/config")
+	assert.True(t, ok)
+	assert.Equal(t, []string{"ASM_DD", "1.2.3"}, []string{product, id})
+
+	for _, path := range []string{"", "datadog/2/APM_TRACING/config", "employee//id/config", "other/2/APM_TRACING/id/config"} {
+		_, _, ok = parseConfigPath(path)
+		assert.False(t, ok, path)
+	}
+}
+
+func Test_capabilitiesBytes(t *testing.T) {
+	assert.Empty(t, capabilitiesBytes())
+	assert.Equal(t, []byte{0x10, 0x00}, capabilitiesBytes(12))
+	assert.Equal(t, []byte{0x20, 0x00, 0x70, 0x00}, capabilitiesBytes(12, 13, 14, 29))
+}
@@ -0,0 +1,291 @@
+package remoteconfig
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Configurations are served by the agent as TUF (The Update Framework) targets:
+// the targets metadata, signed by the keys of the root metadata, holds the
+// length and hashes of each configuration file.
+// Ref https://theupdateframework.github.io/specification/latest/
+// Ref https://github.com/DataDog/datadog-agent/blob/main/pkg/remoteconfig/state/repository.go
+
+const (
+	roleRoot    = "root"
+	roleTargets = "targets"
+
+	keyTypeEd25519 = "ed25519"
+	hashSHA256     = "sha256"
+)
+
+var (
+	errSignatureThreshold = errors.New("signature threshold not reached")
+	errRootVersion        = errors.New("unexpected root version")
+	errTargetMissing      = errors.New("missing target")
+	errTargetLength       = errors.New("target length mismatch")
+	errTargetHash         = errors.New("target hash mismatch")
+	errCanonicalNumber    = errors.New("canonical JSON number not an integer")
+)
+
+// signedMetadata is the envelope of TUF metadata.
+type signedMetadata struct {
+	Signed     json.RawMessage `json:"signed"`
+	Signatures []struct {
+		KeyID     string `json:"keyid"`
+		Signature string `json:"sig"`
+	} `json:"signatures"`
+}
+
+// rootMetadata holds the keys allowed to sign the metadata of each role.
+type rootMetadata struct {
+	Type    string `json:"_type"`
+	Version int64  `json:"version"`
+	Keys    map[string]struct {
+		KeyType string `json:"keytype"`
+		KeyVal  struct {
+			Public string `json:"public"`
+		} `json:"keyval"`
+	} `json:"keys"`
+	Roles map[string]struct {
+		KeyIDs    []string `json:"keyids"`
+		Threshold int      `json:"threshold"`
+	} `json:"roles"`
+}
+
+// targetsMetadata holds the metadata of the configuration files.
+type targetsMetadata struct {
+	Type    string                `json:"_type"`
+	Version int64                 `json:"version"`
+	Targets map[string]targetMeta `json:"targets"`
+	Custom  struct {
+		OpaqueBackendState []byte `json:"opaque_backend_state"`
+	} `json:"custom"`
+}
+
+type targetMeta struct {
+	Length int64             `json:"length"`
+	Hashes map[string]string `json:"hashes"`
+	Custom struct {
+		Version uint64 `json:"v"`
+	} `json:"custom"`
+}
+
+// parseRoot returns the root metadata of a trusted root, signed by its own keys.
+func parseRoot(data []byte) (*rootMetadata, error) {
+	var envelope signedMetadata
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	var root rootMetadata
+	if err := json.Unmarshal(envelope.Signed, &root); err != nil {
+		return nil, err
+	}
+	if root.Type != roleRoot {
+		return nil, fmt.Errorf("unexpected metadata type %q", root.Type)
+	}
+	if err := root.verify(roleRoot, &envelope); err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+// updateRoot returns the next version of the root, signed by the keys of both
+// the current and the next root. The next root is not verified without trusted
+// root (nil), only its version being returned.
+func updateRoot(current *rootMetadata, version int64, data []byte) (*rootMetadata, int64, error) {
+	var envelope signedMetadata
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, 0, err
+	}
+	var next rootMetadata
+	if err := json.Unmarshal(envelope.Signed, &next); err != nil {
+		return nil, 0, err
+	}
+	if next.Version != version+1 {
+		return nil, 0, fmt.Errorf("%w: %d after %d", errRootVersion, next.Version, version)
+	}
+	if current == nil {
+		return nil, next.Version, nil
+	}
+
+	if err := current.verify(roleRoot, &envelope); err != nil {
+		return nil, 0, err
+	}
+	if err := next.verify(roleRoot, &envelope); err != nil {
+		return nil, 0, err
+	}
+	return &next, next.Version, nil
+}
+
+// parseTargets returns the targets metadata, its signatures being verified by
+// the root if not nil.
+func parseTargets(root *rootMetadata, data []byte) (*targetsMetadata, error) {
+	var envelope signedMetadata
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, err
+	}
+	if root != nil {
+		if err := root.verify(roleTargets, &envelope); err != nil {
+			return nil, err
+		}
+	}
+	var targets targetsMetadata
+	if err := json.Unmarshal(envelope.Signed, &targets); err != nil {
+		return nil, err
+	}
+	if targets.Type != roleTargets {
+		return nil, fmt.Errorf("unexpected metadata type %q", targets.Type)
+	}
+	return &targets, nil
+}
+
+// verify checks the metadata is signed by the threshold of keys of the role,
+// the signatures being computed on its canonical JSON.
+func (obj *rootMetadata) verify(role string, envelope *signedMetadata) error {
+	var r, ok = obj.Roles[role]
+	if !ok || r.Threshold < 1 {
+		return fmt.Errorf("%w: role %q", errSignatureThreshold, role)
+	}
+	msg, err := canonicalJSON(envelope.Signed)
+	if err != nil {
+		return err
+	}
+
+	var valid = map[string]bool{}
+	for _, s := range envelope.Signatures {
+		if valid[s.KeyID] || !contains(r.KeyIDs, s.KeyID) {
+			continue
+		}
+		var key, ok = obj.Keys[s.KeyID]
+		if !ok || key.KeyType != keyTypeEd25519 {
+			continue
+		}
+		public, err := hex.DecodeString(key.KeyVal.Public)
+		if err != nil || len(public) != ed25519.PublicKeySize {
+			continue
+		}
+		sig, err := hex.DecodeString(s.Signature)
+		if err != nil {
+			continue
+		}
+		valid[s.KeyID] = ed25519.Verify(public, msg, sig)
+	}
+
+	var count int
+	for _, v := range valid {
+		if v {
+			count++
+		}
+	}
+	if count < r.Threshold {
+		return fmt.Errorf("%w: role %q, %d of %d", errSignatureThreshold, role, count, r.Threshold)
+	}
+	return nil
+}
+
+// verifyTarget checks the length and the SHA-256 hash of a target file.
+func verifyTarget(meta targetMeta, raw []byte) error {
+	if int64(len(raw)) != meta.Length {
+		return errTargetLength
+	}
+	var hash = sha256.Sum256(raw)
+	if expected, ok := meta.Hashes[hashSHA256]; !ok || expected != hex.EncodeToString(hash[:]) {
+		return errTargetHash
+	}
+	return nil
+}
+
+// canonicalJSON returns the OLPC canonical JSON of a JSON value, as signed by
+// TUF: object keys sorted, no whitespace, integers only and strings escaping
+// only the backslash and the double quote.
+// Ref http://wiki.laptop.org/go/Canonical_JSON
+// Ref https://github.com/secure-systems-lab/go-securesystemslib/blob/main/cjson/canonicaljson.go
+func canonicalJSON(data []byte) ([]byte, error) {
+	var decoder = json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := writeCanonical(&buf, value); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canonicalEscaper escapes the characters of a canonical JSON string.
+var canonicalEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+
+// writeCanonical writes the canonical JSON of a value decoded with json.Number.
+func writeCanonical(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		buf.WriteString(strconv.FormatBool(v))
+	case json.Number:
+		n, err := v.Int64()
+		if err != nil {
+			return fmt.Errorf("%w: %s", errCanonicalNumber, v)
+		}
+		buf.WriteString(strconv.FormatInt(n, 10))
+	case string:
+		buf.WriteByte('"')
+		_, _ = canonicalEscaper.WriteString(buf, v)
+		buf.WriteByte('"')
+	case []interface{}:
+		buf.WriteByte('[')
+		for i, item := range v {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case map[string]interface{}:
+		var keys = make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		buf.WriteByte('{')
+		for i, key := range keys {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeCanonical(buf, key); err != nil {
+				return err
+			}
+			buf.WriteByte(':')
+			if err := writeCanonical(buf, v[key]); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return fmt.Errorf("unexpected JSON value %T", value)
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
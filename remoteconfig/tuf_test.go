@@ -0,0 +1,129 @@
+package remoteconfig
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_canonicalJSON(t *testing.T) {
+	actual, err := canonicalJSON([]byte(`{ "b": [1, -250, "<&>\n\u00e9"], "a": {"y": null, "x": true}, "c": "\"\\" }`))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":{\"x\":true,\"y\":null},\"b\":[1,-250,\"<&>\né\"],\"c\":\"\\\"\\\\\"}", string(actual))
+
+	// Integers only
+	_, err = canonicalJSON([]byte(`[2.50]`))
+	assert.ErrorIs(t, err, errCanonicalNumber)
+	_, err = canonicalJSON([]byte(`{`))
+	assert.Error(t, err)
+}
+
+// Test_canonicalJSON_signed checks signatures of metadata served by Datadog:
+// the director root embedded in the agent (meta/prod.1.director.json of
+// pkg/config/remote), and targets signed on the canonical JSON encoded by
+// go-securesystemslib, holding characters escaped by encoding/json.
+func Test_canonicalJSON_signed(t *testing.T) {
+	raw, err := ioutil.ReadFile("testdata/root_prod_director.json")
+	require.NoError(t, err)
+	root, err := parseRoot(raw)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), root.Version)
+
+	raw, err = ioutil.ReadFile("testdata/targets_canonical.json")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"_type": "root", "version": 1,
+		"keys": {"test": {"keytype": "ed25519", "keyval": {"public": "ccbc3da2c4267cc8553be0c7b5790cc75a121297528462c3d2cc679d16eb8684"}}},
+		"roles": {"targets": {"keyids": ["test"], "threshold": 1}}
+	}`), root))
+	targets, err := parseTargets(root, raw)
+	require.NoError(t, err)
+	assert.Equal(t, int64(7), targets.Version)
+}
+
+func Test_parseRoot(t *testing.T) {
+	var agent = test_newAgent(t)
+	root, err := parseRoot(agent.roots[0])
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), root.Version)
+
+	// Not self signed
+	agent.rotateRoot()
+	var unsigned = test_sign(t, map[string]interface{}{"_type": "root", "version": 1}, test_key(0x01))
+	_, err = parseRoot(unsigned)
+	assert.ErrorIs(t, err, errSignatureThreshold)
+
+	// Not a root
+	_, err = parseRoot(test_sign(t, map[string]interface{}{"_type": "targets"}))
+	assert.Error(t, err)
+	_, err = parseRoot([]byte(`[]`))
+	assert.Error(t, err)
+}
+
+func Test_updateRoot(t *testing.T) {
+	var agent = test_newAgent(t)
+	agent.rotateRoot()
+	agent.rotateRoot()
+	root, err := parseRoot(agent.roots[0])
+	require.NoError(t, err)
+
+	// Versions in order only
+	_, _, err = updateRoot(root, 1, agent.roots[2])
+	assert.ErrorIs(t, err, errRootVersion)
+
+	next, version, err := updateRoot(root, 1, agent.roots[1])
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), version)
+	assert.Equal(t, int64(2), next.Version)
+
+	// Not signed by the current root
+	_, _, err = updateRoot(root, 2, agent.roots[2])
+	assert.ErrorIs(t, err, errSignatureThreshold)
+
+	// Version only without trusted root
+	next, version, err = updateRoot(nil, 2, agent.roots[2])
+	require.NoError(t, err)
+	assert.Nil(t, next)
+	assert.Equal(t, int64(3), version)
+}
+
+func Test_parseTargets(t *testing.T) {
+	var agent = test_newAgent(t)
+	root, err := parseRoot(agent.roots[0])
+	require.NoError(t, err)
+
+	var signed = map[string]interface{}{"_type": "targets", "version": 3, "targets": map[string]interface{}{}}
+	targets, err := parseTargets(root, test_sign(t, signed, agent.targetsKey))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), targets.Version)
+
+	// Signed by a key of another role
+	_, err = parseTargets(root, test_sign(t, signed, agent.rootKeys[0]))
+	assert.ErrorIs(t, err, errSignatureThreshold)
+
+	// Unsigned
+	_, err = parseTargets(root, test_sign(t, signed))
+	assert.ErrorIs(t, err, errSignatureThreshold)
+
+	// Not verified without trusted root, type checked
+	_, err = parseTargets(nil, test_sign(t, signed))
+	assert.NoError(t, err)
+	_, err = parseTargets(nil, test_sign(t, map[string]interface{}{"_type": "root"}))
+	assert.Error(t, err)
+}
+
+func Test_verifyTarget(t *testing.T) {
+	var raw = []byte(`{"lib_config":{}}`)
+	var hash = sha256.Sum256(raw)
+	var meta = targetMeta{Length: int64(len(raw)), Hashes: map[string]string{hashSHA256: hex.EncodeToString(hash[:])}}
+
+	assert.NoError(t, verifyTarget(meta, raw))
+	assert.ErrorIs(t, verifyTarget(meta, raw[1:]), errTargetLength)
+	assert.ErrorIs(t, verifyTarget(meta, []byte(`{"lib_config":[]}`)), errTargetHash)
+	assert.ErrorIs(t, verifyTarget(targetMeta{Length: meta.Length}, raw), errTargetHash)
+}
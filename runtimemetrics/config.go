@@ -0,0 +1,89 @@
+package runtimemetrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/detectors/unifiedtagging"
+	"go.opentelemetry.io/otel/sdk/resource"
+)
+
+// _____________________ With option functions _____________________
+
+// WithReportInterval sets the interval between reports to DogStatsD.
+// It defaults to DefaultReportInterval.
+func WithReportInterval(value time.Duration) configFn {
+	return func(conf *config) {
+		conf.reportInterval = value
+	}
+}
+
+// WithResource sets the resource of the unified service tags (service, env
+// and version) of the metrics sent to DogStatsD.
+// It defaults to the resource of the unified service tagging detector.
+func WithResource(value *resource.Resource) configFn {
+	return func(conf *config) {
+		conf.resource = value
+	}
+}
+
+// _____________________ Definition _____________________
+
+type configFn func(*config)
+
+var ErrInvalidReportInterval = errors.New("invalid report interval")
+
+const (
+	// DefaultReportInterval specifies the interval between reports, the one
+	// of the Datadog tracer.
+	DefaultReportInterval = 10 * time.Second
+)
+
+// _____________________ Configuration _____________________
+
+func newConfig(cfg ...configFn) (*config, error) {
+	var conf = &config{}
+
+	// Apply configurations
+	for _, v := range cfg {
+		if v != nil {
+			v(conf)
+		}
+	}
+
+	// Apply default value on empty
+	if err := conf.applyDefault(); err != nil {
+		return nil, err
+	}
+
+	// Check configuration is valid
+	if err := conf.parse(); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+type config struct {
+	reportInterval time.Duration
+	resource       *resource.Resource
+}
+
+func (obj *config) applyDefault() error {
+	if obj.reportInterval == 0 {
+		obj.reportInterval = DefaultReportInterval
+	}
+	if obj.resource == nil {
+		// A partial resource is kept on invalid OTEL_RESOURCE_ATTRIBUTES
+		obj.resource, _ = unifiedtagging.New().Detect(context.Background())
+	}
+	return nil
+}
+
+func (obj *config) parse() error {
+	if obj.reportInterval < 0 {
+		return ErrInvalidReportInterval
+	}
+	return nil
+}
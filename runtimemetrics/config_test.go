@@ -0,0 +1,48 @@
+package runtimemetrics
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+func Test_newConfig(t *testing.T) {
+	setenv(t, "DD_SERVICE", "api")
+
+	// Defaults
+	conf, err := newConfig()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultReportInterval, conf.reportInterval)
+	value, _ := conf.resource.Set().Value(semconv.ServiceNameKey)
+	assert.Equal(t, "api", value.AsString())
+
+	// Options
+	var res = resource.NewSchemaless(semconv.ServiceNameKey.String("worker"))
+	conf, err = newConfig(WithReportInterval(time.Second), WithResource(res))
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, conf.reportInterval)
+	assert.Equal(t, res, conf.resource)
+
+	// Invalid
+	_, err = newConfig(WithReportInterval(-time.Second))
+	assert.ErrorIs(t, err, ErrInvalidReportInterval)
+}
+
+func setenv(t *testing.T, key, value string) {
+	old, exists := os.LookupEnv(key)
+	if err := os.Setenv(key, value); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if exists {
+			os.Setenv(key, old)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
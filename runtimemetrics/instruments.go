@@ -0,0 +1,32 @@
+package runtimemetrics
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/metric/instrument"
+	"go.opentelemetry.io/otel/metric/instrument/asyncfloat64"
+)
+
+// Register registers the runtime metrics as gauge observers of the meter, the
+// runtime being read once per collection. Unified service tags are the ones of
+// the resource of the meter provider.
+func Register(meter metric.Meter) error {
+	var (
+		gauges = make([]asyncfloat64.Gauge, len(gaugeNames))
+		insts  = make([]instrument.Asynchronous, len(gaugeNames))
+	)
+	for i, name := range gaugeNames {
+		gauge, err := meter.AsyncFloat64().Gauge(name)
+		if err != nil {
+			return err
+		}
+		gauges[i], insts[i] = gauge, gauge
+	}
+
+	return meter.RegisterCallback(insts, func(ctx context.Context) {
+		for i, value := range readSnapshot() {
+			gauges[i].Observe(ctx, value, runtimeTags...)
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package runtimemetrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	controller "go.opentelemetry.io/otel/sdk/metric/controller/basic"
+	"go.opentelemetry.io/otel/sdk/metric/export"
+	"go.opentelemetry.io/otel/sdk/metric/export/aggregation"
+	processor "go.opentelemetry.io/otel/sdk/metric/processor/basic"
+	"go.opentelemetry.io/otel/sdk/metric/selector/simple"
+)
+
+func Test_Register(t *testing.T) {
+	var ctrl = controller.New(
+		processor.NewFactory(simple.NewWithInexpensiveDistribution(), aggregation.CumulativeTemporalitySelector()),
+		controller.WithCollectPeriod(0),
+	)
+	require.NoError(t, Register(ctrl.Meter("runtime")))
+	require.NoError(t, ctrl.Collect(context.Background()))
+
+	var (
+		values = map[string]float64{}
+		attrs  *attribute.Set
+	)
+	require.NoError(t, ctrl.ForEach(func(_ instrumentation.Library, r export.Reader) error {
+		return r.ForEach(aggregation.CumulativeTemporalitySelector(), func(record export.Record) error {
+			last, _, err := record.Aggregation().(aggregation.LastValue).LastValue()
+			require.NoError(t, err)
+			values[record.Descriptor().Name()] = last.AsFloat64()
+			attrs = record.Attributes()
+			return nil
+		})
+	}))
+
+	assert.Len(t, values, len(gaugeNames))
+	assert.Positive(t, values["runtime.go.num_goroutine"])
+	assert.Positive(t, values["runtime.go.mem_stats.heap_alloc"])
+	if assert.NotNil(t, attrs) {
+		value, _ := attrs.Value("lang")
+		assert.Equal(t, "go", value.AsString())
+		value, _ = attrs.Value("runtime-id")
+		assert.NotEmpty(t, value.AsString())
+	}
+}
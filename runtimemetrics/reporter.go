@@ -0,0 +1,78 @@
+package runtimemetrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/exporters/dogstatsd"
+	"go.opentelemetry.io/otel"
+)
+
+// Reporter sends the runtime metrics periodically to DogStatsD, out of the
+// OpenTelemetry pipeline, as the Datadog tracer does.
+type Reporter struct {
+	conf     *config
+	exporter *dogstatsd.Exporter
+	tags     []string
+
+	done chan struct{}
+	stop sync.Once
+	wg   sync.WaitGroup
+}
+
+// NewReporter returns a reporter of the runtime metrics to the DogStatsD
+// exporter, sending them at each report interval until stopped.
+func NewReporter(exporter *dogstatsd.Exporter, cfg ...configFn) (*Reporter, error) {
+	conf, err := newConfig(cfg...)
+	if err != nil {
+		return nil, err
+	}
+
+	var reporter = newReporter(exporter, conf)
+	reporter.wg.Add(1)
+	go reporter.run()
+	return reporter, nil
+}
+
+func newReporter(exporter *dogstatsd.Exporter, conf *config) *Reporter {
+	var tags = make([]string, len(runtimeTags))
+	for i, kv := range runtimeTags {
+		tags[i] = string(kv.Key) + ":" + kv.Value.Emit()
+	}
+	return &Reporter{conf: conf, exporter: exporter, tags: tags, done: make(chan struct{})}
+}
+
+// Report reads the runtime metrics and sends them.
+func (obj *Reporter) Report() error {
+	var (
+		values = readSnapshot()
+		gauges = make([]dogstatsd.Gauge, len(gaugeNames))
+	)
+	for i, name := range gaugeNames {
+		gauges[i] = dogstatsd.Gauge{Name: name, Value: values[i]}
+	}
+	return obj.exporter.SendGauges(obj.conf.resource, obj.tags, gauges)
+}
+
+// Stop stops the periodic reports. The exporter is not closed.
+func (obj *Reporter) Stop() {
+	obj.stop.Do(func() { close(obj.done) })
+	obj.wg.Wait()
+}
+
+// run reports periodically, until stopped.
+func (obj *Reporter) run() {
+	defer obj.wg.Done()
+	var ticker = time.NewTicker(obj.conf.reportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-obj.done:
+			return
+		case <-ticker.C:
+			if err := obj.Report(); err != nil {
+				otel.Handle(err)
+			}
+		}
+	}
+}
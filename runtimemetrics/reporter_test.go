@@ -0,0 +1,74 @@
+package runtimemetrics
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/exporters/dogstatsd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+// test_listen returns a DogStatsD server and an exporter sending to it.
+func test_listen(t *testing.T) (net.PacketConn, *dogstatsd.Exporter) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	exporter, err := dogstatsd.New(dogstatsd.WithAddress(conn.LocalAddr().String()), dogstatsd.WithEntityID("-"))
+	require.NoError(t, err)
+	t.Cleanup(func() { exporter.Close() })
+	return conn, exporter
+}
+
+// test_readLines returns the datagrams received within timeout.
+func test_readLines(t *testing.T, conn net.PacketConn, timeout time.Duration) []string {
+	var (
+		lines    []string
+		buffer   = make([]byte, 65536)
+		deadline = time.Now().Add(timeout)
+	)
+	require.NoError(t, conn.SetReadDeadline(deadline))
+	for {
+		n, _, err := conn.ReadFrom(buffer)
+		if err != nil {
+			return lines
+		}
+		lines = append(lines, strings.Split(strings.TrimSuffix(string(buffer[:n]), "\n"), "\n")...)
+	}
+}
+
+func Test_Reporter_Report(t *testing.T) {
+	var (
+		server, exporter = test_listen(t)
+		res              = resource.NewSchemaless(semconv.ServiceNameKey.String("api"), semconv.DeploymentEnvironmentKey.String("prod"))
+	)
+	reporter, err := NewReporter(exporter, WithResource(res), WithReportInterval(time.Hour))
+	require.NoError(t, err)
+	defer reporter.Stop()
+
+	require.NoError(t, reporter.Report())
+	var lines = test_readLines(t, server, 200*time.Millisecond)
+	require.Len(t, lines, len(gaugeNames))
+	for i, line := range lines {
+		assert.True(t, strings.HasPrefix(line, gaugeNames[i]+":"), line)
+		assert.Contains(t, line, "|g|#service:api,env:prod,dd.internal.entity_id:-,lang:go,lang_version:")
+		assert.Contains(t, line, ",runtime-id:")
+	}
+}
+
+func Test_Reporter_run(t *testing.T) {
+	var server, exporter = test_listen(t)
+	reporter, err := NewReporter(exporter, WithResource(resource.Empty()), WithReportInterval(10*time.Millisecond))
+	require.NoError(t, err)
+
+	assert.NotEmpty(t, test_readLines(t, server, 100*time.Millisecond))
+	reporter.Stop()
+	reporter.Stop()
+	test_readLines(t, server, 20*time.Millisecond) // report in flight
+	assert.Empty(t, test_readLines(t, server, 50*time.Millisecond))
+}
@@ -0,0 +1,124 @@
+// Package runtimemetrics reports the Go runtime metrics with the names of the
+// Datadog tracer (runtime.go.*), used by the Datadog Go runtime dashboard.
+package runtimemetrics
+
+import (
+	"runtime"
+	"runtime/debug"
+	"runtime/metrics"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/runtimeid"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Ref https://docs.datadoghq.com/tracing/metrics/runtime_metrics/go/
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/metrics.go
+
+// goroutinesMetric is the runtime/metrics sample of the number of goroutines.
+const goroutinesMetric = "/sched/goroutines:goroutines"
+
+// gaugeNames are the names of the runtime metrics, all gauges.
+var gaugeNames = [...]string{
+	"runtime.go.num_cpu",
+	"runtime.go.num_goroutine",
+	"runtime.go.mem_stats.alloc",
+	"runtime.go.mem_stats.total_alloc",
+	"runtime.go.mem_stats.sys",
+	"runtime.go.mem_stats.lookups",
+	"runtime.go.mem_stats.mallocs",
+	"runtime.go.mem_stats.frees",
+	"runtime.go.mem_stats.heap_alloc",
+	"runtime.go.mem_stats.heap_sys",
+	"runtime.go.mem_stats.heap_idle",
+	"runtime.go.mem_stats.heap_inuse",
+	"runtime.go.mem_stats.heap_released",
+	"runtime.go.mem_stats.heap_objects",
+	"runtime.go.mem_stats.stack_inuse",
+	"runtime.go.mem_stats.stack_sys",
+	"runtime.go.mem_stats.m_span_inuse",
+	"runtime.go.mem_stats.m_span_sys",
+	"runtime.go.mem_stats.m_cache_inuse",
+	"runtime.go.mem_stats.m_cache_sys",
+	"runtime.go.mem_stats.buck_hash_sys",
+	"runtime.go.mem_stats.gc_sys",
+	"runtime.go.mem_stats.other_sys",
+	"runtime.go.mem_stats.next_gc",
+	"runtime.go.mem_stats.last_gc",
+	"runtime.go.mem_stats.pause_total_ns",
+	"runtime.go.mem_stats.num_gc",
+	"runtime.go.mem_stats.num_forced_gc",
+	"runtime.go.mem_stats.gc_cpu_fraction",
+	"runtime.go.gc_stats.pause_quantiles.min",
+	"runtime.go.gc_stats.pause_quantiles.25p",
+	"runtime.go.gc_stats.pause_quantiles.50p",
+	"runtime.go.gc_stats.pause_quantiles.75p",
+	"runtime.go.gc_stats.pause_quantiles.max",
+}
+
+// runtimeTags are the tags of the runtime metrics, added to the unified service
+// tags. The runtime ID links the metrics to the traces of this process.
+var runtimeTags = []attribute.KeyValue{
+	attribute.String("lang", "go"),
+	attribute.String("lang_version", runtime.Version()),
+	attribute.String("runtime-id", runtimeid.ID()),
+}
+
+// snapshot is a read of the runtime metrics, in the order of gaugeNames.
+type snapshot [len(gaugeNames)]float64
+
+// readSnapshot reads the runtime metrics: the number of goroutines from
+// runtime/metrics, memory statistics from MemStats and GC pause quantiles
+// (nanoseconds) from GCStats.
+func readSnapshot() *snapshot {
+	var (
+		samples = []metrics.Sample{{Name: goroutinesMetric}}
+		mem     runtime.MemStats
+		gc      = debug.GCStats{PauseQuantiles: make([]time.Duration, 5)}
+	)
+	metrics.Read(samples)
+	runtime.ReadMemStats(&mem)
+	debug.ReadGCStats(&gc)
+
+	var goroutines = float64(runtime.NumGoroutine())
+	if samples[0].Value.Kind() == metrics.KindUint64 {
+		goroutines = float64(samples[0].Value.Uint64())
+	}
+
+	return &snapshot{
+		float64(runtime.NumCPU()),
+		goroutines,
+		float64(mem.Alloc),
+		float64(mem.TotalAlloc),
+		float64(mem.Sys),
+		float64(mem.Lookups),
+		float64(mem.Mallocs),
+		float64(mem.Frees),
+		float64(mem.HeapAlloc),
+		float64(mem.HeapSys),
+		float64(mem.HeapIdle),
+		float64(mem.HeapInuse),
+		float64(mem.HeapReleased),
+		float64(mem.HeapObjects),
+		float64(mem.StackInuse),
+		float64(mem.StackSys),
+		float64(mem.MSpanInuse),
+		float64(mem.MSpanSys),
+		float64(mem.MCacheInuse),
+		float64(mem.MCacheSys),
+		float64(mem.BuckHashSys),
+		float64(mem.GCSys),
+		float64(mem.OtherSys),
+		float64(mem.NextGC),
+		float64(mem.LastGC),
+		float64(mem.PauseTotalNs),
+		float64(mem.NumGC),
+		float64(mem.NumForcedGC),
+		mem.GCCPUFraction,
+		float64(gc.PauseQuantiles[0]),
+		float64(gc.PauseQuantiles[1]),
+		float64(gc.PauseQuantiles[2]),
+		float64(gc.PauseQuantiles[3]),
+		float64(gc.PauseQuantiles[4]),
+	}
+}
@@ -0,0 +1,49 @@
+package runtimemetrics
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_gaugeNames(t *testing.T) {
+	assert.Len(t, gaugeNames, len(snapshot{}))
+	var seen = map[string]bool{}
+	for _, name := range gaugeNames {
+		assert.True(t, strings.HasPrefix(name, "runtime.go."), name)
+		assert.False(t, seen[name], name)
+		seen[name] = true
+	}
+}
+
+// test_value returns the value of the named metric in the snapshot
+func test_value(t *testing.T, values *snapshot, name string) float64 {
+	for i, v := range gaugeNames {
+		if v == name {
+			return values[i]
+		}
+	}
+	t.Fatalf("unknown metric %s", name)
+	return 0
+}
+
+func Test_readSnapshot(t *testing.T) {
+	runtime.GC()
+	var values = readSnapshot()
+
+	assert.Equal(t, float64(runtime.NumCPU()), test_value(t, values, "runtime.go.num_cpu"))
+	assert.Positive(t, test_value(t, values, "runtime.go.num_goroutine"))
+	assert.Positive(t, test_value(t, values, "runtime.go.mem_stats.heap_alloc"))
+	assert.GreaterOrEqual(t, test_value(t, values, "runtime.go.mem_stats.num_gc"), 1.0)
+	assert.GreaterOrEqual(t, test_value(t, values, "runtime.go.mem_stats.num_forced_gc"), 1.0)
+
+	// Pause quantiles in order
+	var quantiles []float64
+	for _, suffix := range []string{"min", "25p", "50p", "75p", "max"} {
+		quantiles = append(quantiles, test_value(t, values, "runtime.go.gc_stats.pause_quantiles."+suffix))
+	}
+	assert.IsNonDecreasing(t, quantiles)
+	assert.Positive(t, quantiles[4])
+}
@@ -0,0 +1,207 @@
+package ddsampler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/detectors/unifiedtagging"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/naming"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+)
+
+// _____________________ With option functions _____________________
+
+// WithService sets the service of sampled spans, used to match sample rates.
+// It defaults to the service of the unified service tagging detector.
+func WithService(value string) configFn {
+	return func(conf *config) {
+		conf.service = value
+	}
+}
+
+// WithEnv sets the environment of sampled spans, used to match sample rates.
+// It defaults to the environment of the unified service tagging detector.
+func WithEnv(value string) configFn {
+	return func(conf *config) {
+		conf.env = value
+	}
+}
+
+// WithSamplingRules sets the trace sampling rules, applied in order.
+// It defaults to DD_TRACE_SAMPLING_RULES environment variable (JSON array).
+func WithSamplingRules(rules []SamplingRule) configFn {
+	return func(conf *config) {
+		conf.rules = rules
+	}
+}
+
+// WithSampleRate sets the sample rate of traces matching no rule, applied as a
+// last rule matching all traces.
+// It defaults to DD_TRACE_SAMPLE_RATE environment variable, agent rates being used when not set.
+func WithSampleRate(value float64) configFn {
+	return func(conf *config) {
+		conf.sampleRate = &value
+	}
+}
+
+// WithRateLimit sets the maximum number of traces per second kept by rules.
+// It defaults to DD_TRACE_RATE_LIMIT environment variable or DefaultRateLimit.
+func WithRateLimit(value float64) configFn {
+	return func(conf *config) {
+		conf.rateLimit = &value
+	}
+}
+
+// WithReevaluateAutoPriority enables the re-evaluation by the root sampler of
+// the AUTO_KEEP and AUTO_REJECT decisions of remote parents, by the parent
+// based sampler. User decisions of remote parents are always kept.
+// It defaults to false, all upstream decisions being kept.
+func WithReevaluateAutoPriority(enabled bool) configFn {
+	return func(conf *config) {
+		conf.reevaluateAuto = enabled
+	}
+}
+
+// WithStatsComputation records the spans of rejected traces (RecordOnly
+// decision instead of Drop), so that the client-side stats of the Datadog
+// exporter count every trace.
+// It defaults to DD_TRACE_STATS_COMPUTATION_ENABLED environment variable or false.
+func WithStatsComputation(enabled bool) configFn {
+	return func(conf *config) {
+		conf.statsComputation = &enabled
+	}
+}
+
+// _____________________ Definition _____________________
+
+type configFn func(*config)
+
+var (
+	ErrInvalidSamplingRules = errors.New("invalid sampling rules")
+	ErrInvalidSampleRate    = errors.New("invalid sample rate")
+	ErrInvalidRateLimit     = errors.New("invalid rate limit")
+)
+
+// Ref https://docs.datadoghq.com/tracing/trace_collection/library_config/go/#traces
+
+const (
+	envSamplingRules = "DD_TRACE_SAMPLING_RULES"
+	envSampleRate    = "DD_TRACE_SAMPLE_RATE"
+	envRateLimit     = "DD_TRACE_RATE_LIMIT"
+
+	envStatsComputation = "DD_TRACE_STATS_COMPUTATION_ENABLED"
+)
+
+const (
+	// DefaultRateLimit specifies the maximum number of traces per second kept by rules.
+	DefaultRateLimit = 100
+)
+
+// _____________________ Configuration _____________________
+
+func newConfig(cfg ...configFn) (*config, error) {
+	var conf = &config{}
+
+	// Apply configurations
+	for _, v := range cfg {
+		if v != nil {
+			v(conf)
+		}
+	}
+
+	// Apply default value on empty
+	if err := conf.applyDefault(); err != nil {
+		return nil, err
+	}
+
+	// Check configuration is valid
+	if err := conf.parse(); err != nil {
+		return nil, err
+	}
+
+	return conf, nil
+}
+
+type config struct {
+	service    string
+	env        string
+	rules      []SamplingRule
+	sampleRate *float64
+	rateLimit  *float64
+
+	reevaluateAuto   bool
+	statsComputation *bool
+}
+
+func (obj *config) applyDefault() error {
+	// Set default service and env from environment
+	if obj.service == "" || obj.env == "" {
+		res, _ := unifiedtagging.New().Detect(context.Background())
+		for _, kv := range res.Attributes() {
+			switch {
+			case kv.Key == semconv.ServiceNameKey && obj.service == "":
+				obj.service = kv.Value.Emit()
+			case kv.Key == semconv.DeploymentEnvironmentKey && obj.env == "":
+				obj.env = kv.Value.Emit()
+			}
+		}
+	}
+	if obj.service == "" {
+		obj.service = naming.DefaultServiceName
+	}
+
+	// Set default rules from environment
+	if obj.rules == nil {
+		if value := strings.TrimSpace(os.Getenv(envSamplingRules)); value != "" {
+			if err := json.Unmarshal([]byte(value), &obj.rules); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidSamplingRules, err)
+			}
+		}
+	}
+	if obj.sampleRate == nil {
+		if value := strings.TrimSpace(os.Getenv(envSampleRate)); value != "" {
+			rate, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return ErrInvalidSampleRate
+			}
+			obj.sampleRate = &rate
+		}
+	}
+	if obj.rateLimit == nil {
+		var limit float64 = DefaultRateLimit
+		if value := strings.TrimSpace(os.Getenv(envRateLimit)); value != "" {
+			var err error
+			if limit, err = strconv.ParseFloat(value, 64); err != nil {
+				return ErrInvalidRateLimit
+			}
+		}
+		obj.rateLimit = &limit
+	}
+
+	// Set default stats computation
+	if obj.statsComputation == nil {
+		var enabled, _ = strconv.ParseBool(os.Getenv(envStatsComputation))
+		obj.statsComputation = &enabled
+	}
+	return nil
+}
+
+func (obj *config) parse() error {
+	for i := range obj.rules {
+		if err := obj.rules[i].compile(); err != nil {
+			return fmt.Errorf("%w: rule %d: %v", ErrInvalidSamplingRules, i, err)
+		}
+	}
+	if obj.sampleRate != nil && (*obj.sampleRate < 0 || *obj.sampleRate > 1) {
+		return ErrInvalidSampleRate
+	}
+	if *obj.rateLimit < 0 {
+		return ErrInvalidRateLimit
+	}
+	return nil
+}
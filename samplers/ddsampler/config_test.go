@@ -0,0 +1,110 @@
+package ddsampler
+
+import (
+	"os"
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/naming"
+	"github.com/stretchr/testify/assert"
+)
+
+func setenv(t *testing.T, key, value string) {
+	prev, exists := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if exists {
+			os.Setenv(key, prev)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func Test_Config_NewConfig(t *testing.T) {
+	setenv(t, "DD_SERVICE", "")
+	setenv(t, "DD_ENV", "")
+	setenv(t, "DD_TAGS", "")
+	setenv(t, "OTEL_SERVICE_NAME", "")
+	setenv(t, "OTEL_RESOURCE_ATTRIBUTES", "")
+
+	setenv(t, envSamplingRules, "")
+	setenv(t, envSampleRate, "")
+	setenv(t, envRateLimit, "")
+	setenv(t, envStatsComputation, "")
+
+	// Check default values applied
+	if conf, err := newConfig(); assert.NoError(t, err) {
+		assert.Equal(t, naming.DefaultServiceName, conf.service)
+		assert.Equal(t, "", conf.env)
+		assert.Empty(t, conf.rules)
+		assert.Nil(t, conf.sampleRate)
+		assert.Equal(t, float64(DefaultRateLimit), *conf.rateLimit)
+		assert.False(t, *conf.statsComputation)
+	}
+
+	// Check environment
+	setenv(t, "DD_SERVICE", "env-service")
+	setenv(t, "DD_ENV", "prod")
+	setenv(t, envStatsComputation, "true")
+	if conf, err := newConfig(); assert.NoError(t, err) {
+		assert.Equal(t, "env-service", conf.service)
+		assert.Equal(t, "prod", conf.env)
+		assert.True(t, *conf.statsComputation)
+	}
+	if conf, err := newConfig(WithStatsComputation(false)); assert.NoError(t, err) {
+		assert.False(t, *conf.statsComputation)
+	}
+
+	// Check options applied
+	if conf, err := newConfig(WithService("api"), WithEnv("dev"), WithSamplingRules([]SamplingRule{{Service: "api", SampleRate: 0.5}}), WithSampleRate(0.1), WithRateLimit(10)); assert.NoError(t, err) {
+		assert.Equal(t, "api", conf.service)
+		assert.Equal(t, "dev", conf.env)
+		assert.Len(t, conf.rules, 1)
+		assert.Equal(t, 0.1, *conf.sampleRate)
+		assert.Equal(t, 10.0, *conf.rateLimit)
+	}
+
+	// Check invalid configuration
+	_, err := newConfig(WithSamplingRules([]SamplingRule{{SampleRate: 2}}))
+	assert.ErrorIs(t, err, ErrInvalidSamplingRules)
+	_, err = newConfig(WithSampleRate(-1))
+	assert.ErrorIs(t, err, ErrInvalidSampleRate)
+	_, err = newConfig(WithRateLimit(-1))
+	assert.ErrorIs(t, err, ErrInvalidRateLimit)
+}
+
+func Test_Config_Env(t *testing.T) {
+	setenv(t, envSamplingRules, `[{"service": "api", "name": "http.*", "sample_rate": 0.5}, {"tags": {"tenant": "a*"}}]`)
+	setenv(t, envSampleRate, "0.2")
+	setenv(t, envRateLimit, "50")
+
+	if conf, err := newConfig(); assert.NoError(t, err) {
+		if assert.Len(t, conf.rules, 2) {
+			assert.Equal(t, "api", conf.rules[0].Service)
+			assert.Equal(t, "http.*", conf.rules[0].Name)
+			assert.Equal(t, 0.5, conf.rules[0].SampleRate)
+			assert.Equal(t, map[string]string{"tenant": "a*"}, conf.rules[1].Tags)
+			assert.Equal(t, 1.0, conf.rules[1].SampleRate)
+		}
+		assert.Equal(t, 0.2, *conf.sampleRate)
+		assert.Equal(t, 50.0, *conf.rateLimit)
+	}
+
+	// Options take precedence
+	if conf, err := newConfig(WithSamplingRules([]SamplingRule{}), WithSampleRate(1), WithRateLimit(1)); assert.NoError(t, err) {
+		assert.Empty(t, conf.rules)
+		assert.Equal(t, 1.0, *conf.sampleRate)
+		assert.Equal(t, 1.0, *conf.rateLimit)
+	}
+
+	// Invalid values
+	for key, value := range map[string]string{envSamplingRules: "{", envSampleRate: "high", envRateLimit: "none"} {
+		setenv(t, key, value)
+	}
+	_, err := newConfig()
+	assert.ErrorIs(t, err, ErrInvalidSamplingRules)
+	_, err = newConfig(WithSamplingRules([]SamplingRule{}), WithRateLimit(1))
+	assert.ErrorIs(t, err, ErrInvalidSampleRate)
+	_, err = newConfig(WithSamplingRules([]SamplingRule{}), WithSampleRate(1))
+	assert.ErrorIs(t, err, ErrInvalidRateLimit)
+}
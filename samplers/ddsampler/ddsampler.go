@@ -0,0 +1,83 @@
+// Package ddsampler provides OpenTelemetry samplers taking the same decisions as
+// the Datadog tracing library: sampling priority, decision maker and sample
+// rates are recorded as span attributes, mapped by the Datadog exporter.
+package ddsampler
+
+import (
+	"strconv"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/sampling"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/sampler.go
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/internal/samplernames/samplernames.go
+
+// Sampling priorities
+const (
+	PriorityUserReject = -1
+	PriorityAutoReject = 0
+	PriorityAutoKeep   = 1
+	PriorityUserKeep   = 2
+)
+
+// Sampling mechanisms, decision makers of the sampling priority
+const (
+	MechanismDefault   = 0
+	MechanismAgentRate = 1
+	MechanismRule      = 3
+	MechanismManual    = 4
+)
+
+// Attributes set on sampled spans
+const (
+	// AttributeSamplingPriority stores the sampling priority of the trace.
+	AttributeSamplingPriority = attribute.Key("_sampling_priority_v1")
+	// AttributeDecisionMaker stores the mechanism of a keep decision (ex: "-1").
+	AttributeDecisionMaker = attribute.Key("_dd.p.dm")
+
+	attributeAgentRate = attribute.Key("_dd.agent_psr")
+)
+
+// sampledByRate returns true if the trace is kept for a sample rate, from the
+// lower 64 bits of the trace ID.
+func sampledByRate(traceID trace.TraceID, rate float64) bool {
+	var low, _ = tracecontext.TraceIDToUint64(traceID)
+	return sampling.SampledByRate(low, rate)
+}
+
+// decisionMaker returns the _dd.p.dm value of a mechanism.
+func decisionMaker(mechanism int) string {
+	return "-" + strconv.Itoa(mechanism)
+}
+
+// rejectDecision returns the decision of rejected traces: recorded without
+// being sampled when client-side stats are computed, so that they are counted.
+func (obj *config) rejectDecision() sdktrace.SamplingDecision {
+	if *obj.statsComputation {
+		return sdktrace.RecordOnly
+	}
+	return sdktrace.Drop
+}
+
+// samplingResult returns the decision of a priority, sampling attributes being
+// set on kept spans. The priority and decision maker are recorded in the trace
+// state of the parent, so that the Datadog propagator injects them downstream.
+func (obj *config) samplingResult(params sdktrace.SamplingParameters, priority, mechanism int, attrs ...attribute.KeyValue) sdktrace.SamplingResult {
+	var state = trace.SpanContextFromContext(params.ParentContext).TraceState()
+	var result = sdktrace.SamplingResult{
+		Decision:   obj.rejectDecision(),
+		Tracestate: tracecontext.WithSamplingDecision(state, priority, decisionMaker(mechanism)),
+	}
+	if priority > PriorityAutoReject {
+		result.Decision = sdktrace.RecordAndSample
+		result.Attributes = append([]attribute.KeyValue{
+			AttributeSamplingPriority.Int64(int64(priority)),
+			AttributeDecisionMaker.String(decisionMaker(mechanism)),
+		}, attrs...)
+	}
+	return result
+}
@@ -0,0 +1,121 @@
+package ddsampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// test_traceID returns a trace ID with lower 64 bits low.
+func test_traceID(low uint64) trace.TraceID {
+	var traceID = trace.TraceID{0: 0xff}
+	for i := 0; i < 8; i++ {
+		traceID[15-i] = byte(low >> (8 * i))
+	}
+	return traceID
+}
+
+func Test_sampledByRate(t *testing.T) {
+	// 1 * knuthFactor = 0.06 * MaxUint64, 9 * knuthFactor = 0.54 * MaxUint64
+	assert.True(t, sampledByRate(test_traceID(1), 0.5))
+	assert.False(t, sampledByRate(test_traceID(9), 0.5))
+	assert.True(t, sampledByRate(test_traceID(9), 0.55))
+
+	// Bounds
+	assert.True(t, sampledByRate(test_traceID(9), 1))
+	assert.False(t, sampledByRate(test_traceID(1), 0))
+
+	// Distribution
+	var kept int
+	for i := uint64(0); i < 10000; i++ {
+		if sampledByRate(test_traceID(i*7919+1), 0.3) {
+			kept++
+		}
+	}
+	assert.InDelta(t, 3000, kept, 150)
+}
+
+func Test_decisionMaker(t *testing.T) {
+	assert.Equal(t, "-1", decisionMaker(MechanismAgentRate))
+	assert.Equal(t, "-3", decisionMaker(MechanismRule))
+}
+
+func Test_samplingResult(t *testing.T) {
+	var (
+		state, _ = trace.ParseTraceState("dd=s:1")
+		parent   = trace.NewSpanContext(trace.SpanContextConfig{TraceID: test_traceID(1), SpanID: trace.SpanID{7: 1}, TraceState: state, Remote: true})
+		params   = sdktrace.SamplingParameters{ParentContext: trace.ContextWithSpanContext(context.Background(), parent), TraceID: parent.TraceID()}
+	)
+	conf, err := newConfig(WithStatsComputation(false))
+	require.NoError(t, err)
+
+	var result = conf.samplingResult(params, PriorityAutoKeep, MechanismAgentRate, attribute.Float64("rate", 0.5))
+	assert.Equal(t, sdktrace.RecordAndSample, result.Decision)
+	assert.Equal(t, []attribute.KeyValue{
+		AttributeSamplingPriority.Int64(PriorityAutoKeep),
+		AttributeDecisionMaker.String("-1"),
+		attribute.Float64("rate", 0.5),
+	}, result.Attributes)
+	assert.Equal(t, "s:1;t.dm:-1", result.Tracestate.Get("dd"))
+
+	result = conf.samplingResult(params, PriorityAutoReject, MechanismAgentRate)
+	assert.Equal(t, sdktrace.Drop, result.Decision)
+	assert.Empty(t, result.Attributes)
+	assert.Equal(t, "s:0", result.Tracestate.Get("dd"))
+
+	// Rejected traces recorded for client-side stats
+	conf, err = newConfig(WithStatsComputation(true))
+	require.NoError(t, err)
+	result = conf.samplingResult(params, PriorityUserReject, MechanismRule)
+	assert.Equal(t, sdktrace.RecordOnly, result.Decision)
+	assert.Empty(t, result.Attributes)
+	assert.Equal(t, "s:-1", result.Tracestate.Get("dd"))
+}
+
+func Test_samplingResult_propagation(t *testing.T) {
+	var (
+		ctx     = context.Background()
+		sampler = test_newRulesSampler(t, WithSamplingRules([]SamplingRule{
+			{Tags: map[string]string{"decision": "keep"}, SampleRate: 1},
+			{Tags: map[string]string{"decision": "reject"}, SampleRate: 0},
+		}))
+		provider = sdktrace.NewTracerProvider(sdktrace.WithSampler(sampler))
+	)
+	prop, err := tracecontext.New()
+	require.NoError(t, err)
+	parent, err := NewParentBasedSampler(sdktrace.NeverSample())
+	require.NoError(t, err)
+	var downstream = sdktrace.NewTracerProvider(sdktrace.WithSampler(parent))
+
+	// Root span kept by a user rule: USER_KEEP injected with its decision maker
+	_, span := provider.Tracer("test").Start(ctx, "root", trace.WithAttributes(attribute.String("decision", "keep")))
+	var carrier = propagation.MapCarrier{}
+	prop.Inject(trace.ContextWithSpan(ctx, span), carrier)
+	assert.Equal(t, "2", carrier.Get("x-datadog-sampling-priority"))
+	assert.Equal(t, "_dd.p.dm=-3", carrier.Get("x-datadog-tags"))
+
+	// Downstream, the upstream decision is honored and propagated again
+	_, child := downstream.Tracer("test").Start(prop.Extract(ctx, carrier), "child")
+	assert.True(t, child.SpanContext().IsSampled())
+	var next = propagation.MapCarrier{}
+	prop.Inject(trace.ContextWithSpan(ctx, child), next)
+	assert.Equal(t, "2", next.Get("x-datadog-sampling-priority"))
+	assert.Equal(t, "_dd.p.dm=-3", next.Get("x-datadog-tags"))
+
+	// Root span rejected by a user rule: USER_REJECT injected, without decision maker
+	_, span = provider.Tracer("test").Start(ctx, "root", trace.WithAttributes(attribute.String("decision", "reject")))
+	carrier = propagation.MapCarrier{}
+	prop.Inject(trace.ContextWithSpan(ctx, span), carrier)
+	assert.Equal(t, "-1", carrier.Get("x-datadog-sampling-priority"))
+	assert.Empty(t, carrier.Get("x-datadog-tags"))
+
+	_, child = downstream.Tracer("test").Start(prop.Extract(ctx, carrier), "child")
+	assert.False(t, child.SpanContext().IsSampled())
+}
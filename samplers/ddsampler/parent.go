@@ -0,0 +1,78 @@
+package ddsampler
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ParentBasedSampler follows the sampling decision of the parent span. For a
+// remote parent extracted by the Datadog propagator, the upstream priority and
+// decision maker are kept: USER_KEEP and USER_REJECT always win over the root
+// sampler, AUTO_KEEP and AUTO_REJECT being re-evaluated by the root sampler
+// when enabled by WithReevaluateAutoPriority. Traces without parent are
+// sampled by the root sampler.
+type ParentBasedSampler struct {
+	root sdktrace.Sampler
+	conf *config
+}
+
+var _ sdktrace.Sampler = (*ParentBasedSampler)(nil)
+
+// NewParentBasedSampler returns a sampler following parent decisions, root
+// sampling traces without parent (ex: RulesSampler).
+func NewParentBasedSampler(root sdktrace.Sampler, cfg ...configFn) (*ParentBasedSampler, error) {
+	conf, err := newConfig(cfg...)
+	if err != nil {
+		return nil, err
+	}
+	return &ParentBasedSampler{
+		root: root,
+		conf: conf,
+	}, nil
+}
+
+// ShouldSample returns the decision of the parent, or of the root sampler.
+func (obj *ParentBasedSampler) ShouldSample(params sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	var parent = trace.SpanContextFromContext(params.ParentContext)
+	if !parent.IsValid() {
+		return obj.root.ShouldSample(params)
+	}
+
+	// Local parent or remote parent without Datadog priority
+	priority, ok := tracecontext.SamplingPriority(parent)
+	if !parent.IsRemote() || !ok {
+		var result = sdktrace.SamplingResult{Decision: obj.conf.rejectDecision(), Tracestate: parent.TraceState()}
+		if parent.IsSampled() {
+			result.Decision = sdktrace.RecordAndSample
+		}
+		return result
+	}
+
+	// Automatic decision of upstream re-evaluated
+	if obj.conf.reevaluateAuto && (priority == PriorityAutoKeep || priority == PriorityAutoReject) {
+		return obj.root.ShouldSample(params)
+	}
+	return obj.conf.samplingResult(params, priority, upstreamMechanism(parent, priority))
+}
+
+// Description returns the name of the sampler.
+func (obj *ParentBasedSampler) Description() string {
+	return "DatadogParentBased{root:" + obj.root.Description() + "}"
+}
+
+// upstreamMechanism returns the mechanism of the upstream decision maker
+// (_dd.p.dm), manual for USER_KEEP and default for AUTO_KEEP when missing.
+func upstreamMechanism(parent trace.SpanContext, priority int) int {
+	var dm = tracecontext.PropagatedTags(parent)[string(AttributeDecisionMaker)]
+	if mechanism, err := strconv.Atoi(strings.TrimPrefix(dm, "-")); err == nil && strings.HasPrefix(dm, "-") {
+		return mechanism
+	}
+	if priority >= PriorityUserKeep {
+		return MechanismManual
+	}
+	return MechanismDefault
+}
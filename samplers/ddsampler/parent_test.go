@@ -0,0 +1,107 @@
+package ddsampler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/propagators/tracecontext"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// test_remoteParams returns sampling parameters of a remote parent extracted
+// from Datadog headers.
+func test_remoteParams(priority, tags string) sdktrace.SamplingParameters {
+	var carrier = propagation.MapCarrier{
+		tracecontext.DefaultTraceIDHeader:  "9",
+		tracecontext.DefaultParentIDHeader: "2",
+		tracecontext.DefaultPriorityHeader: priority,
+		tracecontext.DefaultTagsHeader:     tags,
+	}
+	var ctx = tracecontext.NewDefault().Extract(context.Background(), carrier)
+	return sdktrace.SamplingParameters{ParentContext: ctx, TraceID: trace.SpanContextFromContext(ctx).TraceID()}
+}
+
+func Test_ParentBasedSampler_ShouldSample(t *testing.T) {
+	sampler, err := NewParentBasedSampler(sdktrace.NeverSample())
+	require.NoError(t, err)
+	assert.Equal(t, "DatadogParentBased{root:AlwaysOffSampler}", sampler.Description())
+
+	// Root sampler without parent
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(sdktrace.SamplingParameters{ParentContext: context.Background()}).Decision)
+
+	// Upstream USER_KEEP kept with its decision maker
+	var params = test_remoteParams("2", "_dd.p.dm=-3")
+	var result = sampler.ShouldSample(params)
+	assert.Equal(t, sdktrace.RecordAndSample, result.Decision)
+	assert.Equal(t, []attribute.KeyValue{
+		AttributeSamplingPriority.Int64(PriorityUserKeep),
+		AttributeDecisionMaker.String("-3"),
+	}, result.Attributes)
+	assert.Equal(t, trace.SpanContextFromContext(params.ParentContext).TraceState(), result.Tracestate)
+
+	// Upstream AUTO_KEEP kept, manual decision maker by default for USER_KEEP
+	result = sampler.ShouldSample(test_remoteParams("1", "_dd.p.dm=-1"))
+	assert.Equal(t, sdktrace.RecordAndSample, result.Decision)
+	assert.Contains(t, result.Attributes, AttributeSamplingPriority.Int64(PriorityAutoKeep))
+	assert.Contains(t, result.Attributes, AttributeDecisionMaker.String("-1"))
+	result = sampler.ShouldSample(test_remoteParams("2", ""))
+	assert.Contains(t, result.Attributes, AttributeDecisionMaker.String("-4"))
+
+	// Upstream rejections kept
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(test_remoteParams("0", "")).Decision)
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(test_remoteParams("-1", "")).Decision)
+
+	// Sampled flag of local parents and remote parents without priority
+	for _, sampled := range []bool{true, false} {
+		var parent = trace.NewSpanContext(trace.SpanContextConfig{
+			TraceID:    test_traceID(1),
+			SpanID:     trace.SpanID{7: 1},
+			TraceFlags: trace.TraceFlags(0).WithSampled(sampled),
+		})
+		var expected = sdktrace.Drop
+		if sampled {
+			expected = sdktrace.RecordAndSample
+		}
+		for _, ctx := range []context.Context{
+			trace.ContextWithSpanContext(context.Background(), parent),
+			trace.ContextWithRemoteSpanContext(context.Background(), parent),
+		} {
+			result = sampler.ShouldSample(sdktrace.SamplingParameters{ParentContext: ctx})
+			assert.Equal(t, expected, result.Decision)
+			assert.Empty(t, result.Attributes)
+		}
+	}
+}
+
+func Test_ParentBasedSampler_ReevaluateAuto(t *testing.T) {
+	sampler, err := NewParentBasedSampler(sdktrace.NeverSample(), WithReevaluateAutoPriority(true))
+	require.NoError(t, err)
+
+	// Automatic decisions re-evaluated by the root sampler
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(test_remoteParams("1", "")).Decision)
+
+	// User decisions always kept
+	assert.Equal(t, sdktrace.RecordAndSample, sampler.ShouldSample(test_remoteParams("2", "")).Decision)
+
+	sampler, err = NewParentBasedSampler(sdktrace.AlwaysSample(), WithReevaluateAutoPriority(true))
+	require.NoError(t, err)
+	assert.Equal(t, sdktrace.RecordAndSample, sampler.ShouldSample(test_remoteParams("0", "")).Decision)
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(test_remoteParams("-1", "")).Decision)
+}
+
+func Test_ParentBasedSampler_StatsComputation(t *testing.T) {
+	sampler, err := NewParentBasedSampler(sdktrace.NeverSample(), WithStatsComputation(true))
+	require.NoError(t, err)
+
+	// Upstream rejections and unsampled local parents recorded
+	assert.Equal(t, sdktrace.RecordOnly, sampler.ShouldSample(test_remoteParams("-1", "")).Decision)
+	var parent = trace.NewSpanContext(trace.SpanContextConfig{TraceID: test_traceID(1), SpanID: trace.SpanID{7: 1}})
+	var params = sdktrace.SamplingParameters{ParentContext: trace.ContextWithSpanContext(context.Background(), parent)}
+	assert.Equal(t, sdktrace.RecordOnly, sampler.ShouldSample(params).Decision)
+	assert.Equal(t, sdktrace.RecordAndSample, sampler.ShouldSample(test_remoteParams("1", "")).Decision)
+}
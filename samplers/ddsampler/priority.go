@@ -0,0 +1,85 @@
+package ddsampler
+
+import (
+	"sync"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// defaultRateKey is the key of the default rate in agent rates
+const defaultRateKey = "service:,env:"
+
+// PrioritySampler samples traces with the rates computed by the Datadog agent
+// for each service and environment, keeping the agent target throughput.
+// Rates are updated from agent responses, by UpdateRates. All traces are kept
+// until rates are received.
+type PrioritySampler struct {
+	conf *config
+	key  string
+
+	mu          sync.RWMutex
+	rates       map[string]float64
+	defaultRate float64
+}
+
+var _ sdktrace.Sampler = (*PrioritySampler)(nil)
+
+// NewPrioritySampler returns a sampler applying agent rates.
+// To use the defaults, call with nothing.
+func NewPrioritySampler(cfg ...configFn) (*PrioritySampler, error) {
+	conf, err := newConfig(cfg...)
+	if err != nil {
+		return nil, err
+	}
+	return newPrioritySampler(conf), nil
+}
+
+func newPrioritySampler(conf *config) *PrioritySampler {
+	return &PrioritySampler{
+		conf:        conf,
+		key:         "service:" + conf.service + ",env:" + conf.env,
+		defaultRate: 1,
+	}
+}
+
+// ShouldSample keeps the trace with AUTO_KEEP priority if sampled by the rate
+// of the service, using the trace ID hash, AUTO_REJECT otherwise.
+func (obj *PrioritySampler) ShouldSample(params sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	var rate = obj.rate()
+	var priority = PriorityAutoReject
+	if sampledByRate(params.TraceID, rate) {
+		priority = PriorityAutoKeep
+	}
+	return obj.conf.samplingResult(params, priority, MechanismAgentRate, attributeAgentRate.Float64(rate))
+}
+
+// Description returns the name of the sampler.
+func (obj *PrioritySampler) Description() string {
+	return "DatadogPrioritySampler"
+}
+
+// UpdateRates sets the rates by service received from the agent, keyed by
+// "service:<service>,env:<env>".
+func (obj *PrioritySampler) UpdateRates(rates map[string]float64) {
+	var copied = make(map[string]float64, len(rates))
+	for k, v := range rates {
+		copied[k] = v
+	}
+
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	obj.rates = copied
+	if rate, ok := copied[defaultRateKey]; ok {
+		obj.defaultRate = rate
+	}
+}
+
+// rate returns the rate of the service, the default rate if not received.
+func (obj *PrioritySampler) rate() float64 {
+	obj.mu.RLock()
+	defer obj.mu.RUnlock()
+	if rate, ok := obj.rates[obj.key]; ok {
+		return rate
+	}
+	return obj.defaultRate
+}
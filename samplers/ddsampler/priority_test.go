@@ -0,0 +1,49 @@
+package ddsampler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+func Test_PrioritySampler_ShouldSample(t *testing.T) {
+	sampler, err := NewPrioritySampler(WithService("api"), WithEnv("prod"))
+	require.NoError(t, err)
+	assert.Equal(t, "DatadogPrioritySampler", sampler.Description())
+
+	// All traces kept until rates are received
+	var result = sampler.ShouldSample(sdktrace.SamplingParameters{TraceID: test_traceID(9)})
+	assert.Equal(t, sdktrace.RecordAndSample, result.Decision)
+	assert.Contains(t, result.Attributes, AttributeSamplingPriority.Int64(PriorityAutoKeep))
+	assert.Contains(t, result.Attributes, AttributeDecisionMaker.String("-1"))
+	assert.Contains(t, result.Attributes, attributeAgentRate.Float64(1))
+
+	// Rate of the service
+	sampler.UpdateRates(map[string]float64{defaultRateKey: 0.1, "service:api,env:prod": 0.5})
+	result = sampler.ShouldSample(sdktrace.SamplingParameters{TraceID: test_traceID(1)})
+	assert.Equal(t, sdktrace.RecordAndSample, result.Decision)
+	assert.Contains(t, result.Attributes, attributeAgentRate.Float64(0.5))
+	result = sampler.ShouldSample(sdktrace.SamplingParameters{TraceID: test_traceID(9)})
+	assert.Equal(t, sdktrace.Drop, result.Decision)
+
+	// Default rate for other services
+	sampler, err = NewPrioritySampler(WithService("other"), WithEnv("prod"))
+	require.NoError(t, err)
+	sampler.UpdateRates(map[string]float64{defaultRateKey: 0, "service:api,env:prod": 1})
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(sdktrace.SamplingParameters{TraceID: test_traceID(1)}).Decision)
+}
+
+func Test_PrioritySampler_UpdateRates(t *testing.T) {
+	sampler, err := NewPrioritySampler(WithService("api"), WithEnv("prod"))
+	require.NoError(t, err)
+	var rates = map[string]float64{"service:api,env:prod": 0.5}
+	sampler.UpdateRates(rates)
+	rates["service:api,env:prod"] = 0
+	assert.Equal(t, 0.5, sampler.rate())
+
+	// Default rate kept when not received
+	sampler.UpdateRates(map[string]float64{})
+	assert.Equal(t, 1.0, sampler.rate())
+}
@@ -0,0 +1,77 @@
+package ddsampler
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/naming"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/sampling"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Ref https://github.com/DataDog/dd-trace-go/blob/v1/ddtrace/tracer/rules_sampler.go
+
+// SamplingRule keeps traces matching all its patterns with its sample rate.
+// Patterns are globs, case insensitive: "*" matches any sequence of characters
+// and "?" any single character. An empty pattern matches all values. Numeric
+// attributes with a fractional part only match patterns made of "*".
+type SamplingRule struct {
+	// Service is the pattern of the service.
+	Service string `json:"service,omitempty"`
+	// Name is the pattern of the Datadog operation name of the root span.
+	Name string `json:"name,omitempty"`
+	// Resource is the pattern of the Datadog resource name of the root span.
+	Resource string `json:"resource,omitempty"`
+	// Tags are the patterns of attributes of the root span, by key.
+	Tags map[string]string `json:"tags,omitempty"`
+	// SampleRate is the rate of kept traces, in [0, 1].
+	SampleRate float64 `json:"sample_rate"`
+
+	service  sampling.Glob
+	name     sampling.Glob
+	resource sampling.Glob
+	tags     map[attribute.Key]sampling.Glob
+}
+
+var errRuleSampleRate = errors.New("sample rate out of [0, 1] range")
+
+// UnmarshalJSON decodes a rule of DD_TRACE_SAMPLING_RULES, the sample rate
+// defaulting to 1 when missing.
+func (obj *SamplingRule) UnmarshalJSON(data []byte) error {
+	type jsonRule SamplingRule
+	var rule = jsonRule{SampleRate: 1}
+	if err := json.Unmarshal(data, &rule); err != nil {
+		return err
+	}
+	*obj = SamplingRule(rule)
+	return nil
+}
+
+// compile checks the sample rate and compiles the patterns of the rule.
+func (obj *SamplingRule) compile() error {
+	if obj.SampleRate < 0 || obj.SampleRate > 1 || math.IsNaN(obj.SampleRate) {
+		return errRuleSampleRate
+	}
+	obj.service = sampling.NewGlob(obj.Service)
+	obj.name = sampling.NewGlob(obj.Name)
+	obj.resource = sampling.NewGlob(obj.Resource)
+	obj.tags = make(map[attribute.Key]sampling.Glob, len(obj.Tags))
+	for k, v := range obj.Tags {
+		obj.tags[attribute.Key(k)] = sampling.NewGlob(v)
+	}
+	return nil
+}
+
+// match returns true if the trace matches all the patterns of the rule.
+func (obj *SamplingRule) match(service, name, resource string, attrs naming.Attributes) bool {
+	if !obj.service.Match(service) || !obj.name.Match(name) || !obj.resource.Match(resource) {
+		return false
+	}
+	for key, pattern := range obj.tags {
+		if value, ok := attrs[key]; !ok || !pattern.MatchValue(value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package ddsampler
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/naming"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func Test_SamplingRule_UnmarshalJSON(t *testing.T) {
+	var rules []SamplingRule
+	require.NoError(t, json.Unmarshal([]byte(`[{"service": "api", "resource": "GET /*", "sample_rate": 0}, {"name": "db.*"}]`), &rules))
+	assert.Equal(t, []SamplingRule{
+		{Service: "api", Resource: "GET /*", SampleRate: 0},
+		{Name: "db.*", SampleRate: 1},
+	}, rules)
+
+	assert.Error(t, json.Unmarshal([]byte(`[{"sample_rate": "high"}]`), &rules))
+}
+
+func Test_SamplingRule_compile(t *testing.T) {
+	for _, rate := range []float64{-0.1, 1.1} {
+		var rule = SamplingRule{SampleRate: rate}
+		assert.ErrorIs(t, rule.compile(), errRuleSampleRate)
+	}
+	var rule = SamplingRule{Service: "api", SampleRate: 0.5}
+	assert.NoError(t, rule.compile())
+}
+
+func Test_SamplingRule_match(t *testing.T) {
+	var attrs = naming.NewAttributes([]attribute.KeyValue{
+		attribute.String("tenant", "Acme"),
+		attribute.Int64("shard", 12),
+		attribute.Float64("ratio", 3),
+		attribute.Float64("score", 0.5),
+	})
+
+	for name, tc := range map[string]struct {
+		rule     SamplingRule
+		expected bool
+	}{
+		"empty":                {SamplingRule{}, true},
+		"service":              {SamplingRule{Service: "api"}, true},
+		"service case":         {SamplingRule{Service: "API"}, true},
+		"service other":        {SamplingRule{Service: "api2"}, false},
+		"name star":            {SamplingRule{Name: "http.*"}, true},
+		"name question":        {SamplingRule{Name: "http.?erver.request"}, true},
+		"name partial":         {SamplingRule{Name: "http"}, false},
+		"resource meta":        {SamplingRule{Resource: "GET /users/(id)"}, false},
+		"resource":             {SamplingRule{Resource: "GET /users/*"}, true},
+		"tag":                  {SamplingRule{Tags: map[string]string{"tenant": "acme"}}, true},
+		"tag missing":          {SamplingRule{Tags: map[string]string{"region": "*"}}, false},
+		"tag int":              {SamplingRule{Tags: map[string]string{"shard": "1?"}}, true},
+		"tag float integer":    {SamplingRule{Tags: map[string]string{"ratio": "3"}}, true},
+		"tag float fractional": {SamplingRule{Tags: map[string]string{"score": "0.5"}}, false},
+		"tag float wildcard":   {SamplingRule{Tags: map[string]string{"score": "**"}}, true},
+		"all":                  {SamplingRule{Service: "a*", Name: "http.server.request", Tags: map[string]string{"tenant": "ac*", "shard": "12"}}, true},
+		"all one mismatch":     {SamplingRule{Service: "a*", Name: "http.server.request", Tags: map[string]string{"tenant": "b*"}}, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			require.NoError(t, tc.rule.compile())
+			assert.Equal(t, tc.expected, tc.rule.match("api", "http.server.request", "GET /users/:id", attrs))
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package ddsampler
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/naming"
+	"github.com/SylvainDumas/opentelemetry-datadog-go/internal/sampling"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// Attributes set on spans sampled by rules
+const (
+	attributeRuleRate  = attribute.Key("_dd.rule_psr")
+	attributeLimitRate = attribute.Key("_dd.limit_psr")
+)
+
+// RulesSampler samples traces with the rate of the first matching sampling
+// rule, the global sample rate being applied as a last rule. Traces kept by a
+// rule are limited per second. Traces matching no rule are sampled with agent
+// rates, updated by UpdateRates. Rules can be replaced at runtime by SetRules
+// (ex: by remote configuration).
+type RulesSampler struct {
+	mu       sync.RWMutex
+	rules    []SamplingRule
+	conf     *config
+	service  string
+	limiter  *sampling.RateLimiter
+	fallback *PrioritySampler
+	now      func() time.Time
+}
+
+var _ sdktrace.Sampler = (*RulesSampler)(nil)
+
+// NewRulesSampler returns a sampler applying sampling rules.
+// To use the defaults, call with nothing.
+func NewRulesSampler(cfg ...configFn) (*RulesSampler, error) {
+	conf, err := newConfig(cfg...)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RulesSampler{
+		rules:    withSampleRate(conf.rules, conf.sampleRate),
+		conf:     conf,
+		service:  conf.service,
+		limiter:  sampling.NewRateLimiter(*conf.rateLimit),
+		fallback: newPrioritySampler(conf),
+		now:      time.Now,
+	}, nil
+}
+
+// ShouldSample keeps the trace with USER_KEEP priority if sampled by the rate
+// of the first matching rule and allowed by the rate limiter, USER_REJECT
+// otherwise. Agent rates are applied if no rule matches.
+func (obj *RulesSampler) ShouldSample(params sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	var rule = obj.match(params)
+	if rule == nil {
+		return obj.fallback.ShouldSample(params)
+	}
+
+	var attrs = []attribute.KeyValue{attributeRuleRate.Float64(rule.SampleRate)}
+	if !sampledByRate(params.TraceID, rule.SampleRate) {
+		return obj.conf.samplingResult(params, PriorityUserReject, MechanismRule, attrs...)
+	}
+	var allowed, rate = obj.limiter.AllowOne(obj.now())
+	if !allowed {
+		return obj.conf.samplingResult(params, PriorityUserReject, MechanismRule, attrs...)
+	}
+	return obj.conf.samplingResult(params, PriorityUserKeep, MechanismRule, append(attrs, attributeLimitRate.Float64(rate))...)
+}
+
+// Description returns the name of the sampler.
+func (obj *RulesSampler) Description() string {
+	return "DatadogRulesSampler"
+}
+
+// UpdateRates sets the rates by service received from the agent, applied to
+// traces matching no rule.
+func (obj *RulesSampler) UpdateRates(rates map[string]float64) {
+	obj.fallback.UpdateRates(rates)
+}
+
+// Rules returns the sampling rules applied in order, including the global
+// sample rate.
+func (obj *RulesSampler) Rules() []SamplingRule {
+	obj.mu.RLock()
+	defer obj.mu.RUnlock()
+	return append([]SamplingRule(nil), obj.rules...)
+}
+
+// SetRules replaces atomically the sampling rules and the global sample rate,
+// applied to the traces sampled from now on. Nil rules or sample rate are the
+// ones of the configuration. The current rules are kept if a rule is invalid.
+func (obj *RulesSampler) SetRules(rules []SamplingRule, sampleRate *float64) error {
+	if rules == nil {
+		rules = obj.conf.rules
+	}
+	if sampleRate == nil {
+		sampleRate = obj.conf.sampleRate
+	}
+
+	var compiled = append([]SamplingRule(nil), rules...)
+	for i := range compiled {
+		if err := compiled[i].compile(); err != nil {
+			return fmt.Errorf("%w: rule %d: %v", ErrInvalidSamplingRules, i, err)
+		}
+	}
+	if sampleRate != nil && (*sampleRate < 0 || *sampleRate > 1) {
+		return ErrInvalidSampleRate
+	}
+
+	compiled = withSampleRate(compiled, sampleRate)
+	obj.mu.Lock()
+	obj.rules = compiled
+	obj.mu.Unlock()
+	return nil
+}
+
+// ResetRules restores the sampling rules and the global sample rate of the
+// configuration.
+func (obj *RulesSampler) ResetRules() {
+	obj.mu.Lock()
+	obj.rules = withSampleRate(obj.conf.rules, obj.conf.sampleRate)
+	obj.mu.Unlock()
+}
+
+// match returns the first rule matching the span, nil if none.
+func (obj *RulesSampler) match(params sdktrace.SamplingParameters) *SamplingRule {
+	obj.mu.RLock()
+	var rules = obj.rules
+	obj.mu.RUnlock()
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var (
+		attrs    = naming.NewAttributes(params.Attributes)
+		name     = naming.OperationName(params.Kind, attrs)
+		resource = naming.ResourceName(params.Name, params.Kind, attrs)
+	)
+	for i := range rules {
+		if rules[i].match(obj.service, name, resource, attrs) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+// withSampleRate returns the rules followed by a rule matching all traces with
+// the sample rate, the rules unchanged if nil.
+func withSampleRate(rules []SamplingRule, sampleRate *float64) []SamplingRule {
+	if sampleRate == nil {
+		return rules
+	}
+	return append(rules[:len(rules):len(rules)], SamplingRule{SampleRate: *sampleRate})
+}
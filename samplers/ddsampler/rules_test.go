@@ -0,0 +1,126 @@
+package ddsampler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func test_newRulesSampler(t *testing.T, cfg ...configFn) *RulesSampler {
+	sampler, err := NewRulesSampler(append([]configFn{WithService("api"), WithEnv("prod")}, cfg...)...)
+	require.NoError(t, err)
+	var now = time.Unix(1000, 0)
+	sampler.now = func() time.Time { return now }
+	return sampler
+}
+
+func Test_NewRulesSampler(t *testing.T) {
+	_, err := NewRulesSampler(WithSamplingRules([]SamplingRule{{SampleRate: 2}}))
+	assert.ErrorIs(t, err, ErrInvalidSamplingRules)
+
+	// Global sample rate applied as last rule
+	var (
+		rules   = []SamplingRule{{Service: "api", SampleRate: 0.5}}
+		sampler = test_newRulesSampler(t, WithSamplingRules(rules), WithSampleRate(0.1))
+	)
+	assert.Equal(t, "DatadogRulesSampler", sampler.Description())
+	if actual := sampler.Rules(); assert.Len(t, actual, 2) {
+		assert.Equal(t, "api", actual[0].Service)
+		assert.Equal(t, 0.1, actual[1].SampleRate)
+	}
+	assert.Len(t, rules, 1)
+}
+
+func Test_RulesSampler_ShouldSample(t *testing.T) {
+	var sampler = test_newRulesSampler(t, WithSamplingRules([]SamplingRule{
+		{Name: "http.server.request", Resource: "GET /health", SampleRate: 0},
+		{Tags: map[string]string{"tenant": "vip-*"}, SampleRate: 1},
+		{Service: "api", Name: "http.*", SampleRate: 0.5},
+	}))
+	var params = func(low uint64, name string, attrs ...attribute.KeyValue) sdktrace.SamplingParameters {
+		return sdktrace.SamplingParameters{TraceID: test_traceID(low), Name: name, Kind: trace.SpanKindServer, Attributes: attrs}
+	}
+	var httpAttrs = []attribute.KeyValue{attribute.String("http.method", "GET"), attribute.String("http.route", "/health")}
+
+	// First matching rule applied
+	var result = sampler.ShouldSample(params(1, "health", httpAttrs...))
+	assert.Equal(t, sdktrace.Drop, result.Decision)
+
+	result = sampler.ShouldSample(params(9, "health", append(httpAttrs, attribute.String("tenant", "vip-1"))...))
+	assert.Equal(t, sdktrace.Drop, result.Decision)
+
+	result = sampler.ShouldSample(params(9, "query", attribute.String("tenant", "vip-1")))
+	assert.Equal(t, sdktrace.RecordAndSample, result.Decision)
+	assert.Equal(t, []attribute.KeyValue{
+		AttributeSamplingPriority.Int64(PriorityUserKeep),
+		AttributeDecisionMaker.String("-3"),
+		attributeRuleRate.Float64(1),
+		attributeLimitRate.Float64(0.5),
+	}, result.Attributes)
+
+	// Sampled by the rule rate
+	var users = []attribute.KeyValue{attribute.String("http.method", "GET"), attribute.String("http.route", "/users")}
+	result = sampler.ShouldSample(params(1, "users", users...))
+	assert.Equal(t, sdktrace.RecordAndSample, result.Decision)
+	assert.Contains(t, result.Attributes, attributeRuleRate.Float64(0.5))
+	result = sampler.ShouldSample(params(9, "users", users...))
+	assert.Equal(t, sdktrace.Drop, result.Decision)
+
+	// Agent rates applied when no rule matches
+	sampler.UpdateRates(map[string]float64{"service:api,env:prod": 0.5})
+	result = sampler.ShouldSample(params(1, "query"))
+	assert.Equal(t, sdktrace.RecordAndSample, result.Decision)
+	assert.Contains(t, result.Attributes, AttributeDecisionMaker.String("-1"))
+	assert.Contains(t, result.Attributes, attributeAgentRate.Float64(0.5))
+}
+
+func Test_RulesSampler_RateLimit(t *testing.T) {
+	var sampler = test_newRulesSampler(t, WithSamplingRules([]SamplingRule{}), WithSampleRate(1), WithRateLimit(1))
+
+	var result = sampler.ShouldSample(sdktrace.SamplingParameters{TraceID: test_traceID(1)})
+	assert.Equal(t, sdktrace.RecordAndSample, result.Decision)
+	assert.Contains(t, result.Attributes, attributeLimitRate.Float64(0.5))
+
+	// Kept traces over the limit rejected
+	result = sampler.ShouldSample(sdktrace.SamplingParameters{TraceID: test_traceID(2)})
+	assert.Equal(t, sdktrace.Drop, result.Decision)
+}
+
+func Test_RulesSampler_SetRules(t *testing.T) {
+	var sampler = test_newRulesSampler(t, WithSamplingRules([]SamplingRule{{Service: "api", SampleRate: 0}}))
+	var params = sdktrace.SamplingParameters{TraceID: test_traceID(1)}
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(params).Decision)
+
+	// Invalid rules rejected, current rules kept
+	var rate = 2.0
+	assert.ErrorIs(t, sampler.SetRules([]SamplingRule{{SampleRate: -1}}, nil), ErrInvalidSamplingRules)
+	assert.ErrorIs(t, sampler.SetRules(nil, &rate), ErrInvalidSampleRate)
+	assert.Len(t, sampler.Rules(), 1)
+
+	// Rules replaced, global sample rate applied as last rule
+	rate = 1
+	require.NoError(t, sampler.SetRules([]SamplingRule{{Service: "other", SampleRate: 0}}, &rate))
+	if actual := sampler.Rules(); assert.Len(t, actual, 2) {
+		assert.Equal(t, "other", actual[0].Service)
+		assert.Equal(t, 1.0, actual[1].SampleRate)
+	}
+	assert.Equal(t, sdktrace.RecordAndSample, sampler.ShouldSample(params).Decision)
+
+	// Nil rules being the ones of the configuration
+	require.NoError(t, sampler.SetRules(nil, &rate))
+	if actual := sampler.Rules(); assert.Len(t, actual, 2) {
+		assert.Equal(t, "api", actual[0].Service)
+	}
+
+	// Rules of the configuration restored
+	sampler.ResetRules()
+	if actual := sampler.Rules(); assert.Len(t, actual, 1) {
+		assert.Equal(t, "api", actual[0].Service)
+	}
+	assert.Equal(t, sdktrace.Drop, sampler.ShouldSample(params).Decision)
+}